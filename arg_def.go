@@ -1,11 +1,39 @@
 package cliutil
 
+import (
+	"errors"
+)
+
 // ArgDef defines a positional command argument
 type ArgDef struct {
-	Name     string
-	Usage    string
-	Required bool
-	Default  any
-	String   *string // Where to assign the argument value
-	Example  string  // OPTIONAL: sample value for example generation (e.g., "www")
+	Name      string
+	Usage     string
+	Required  bool
+	Default   any
+	String    *string      // Where to assign the argument value
+	Remainder *[]string    // OPTIONAL: on the last ArgDef, where to assign it and every later arg, as for run <script> [args...]
+	Example   string       // OPTIONAL: sample value for example generation (e.g., "www")
+	Stdin     bool         // When given as "-", the value is read from stdin instead
+	Complete  CompleteFunc // OPTIONAL: shell completion candidates for the value
+}
+
+var ErrRemainderNotLast = errors.New("only the last argument can take the remaining args")
+
+// IsVariadic returns true if the argument takes every remaining arg, as it
+// sets Remainder
+func (ad *ArgDef) IsVariadic() bool {
+	return ad.Remainder != nil
+}
+
+// validateArgDefs returns an error if an ArgDef other than the last sets
+// Remainder, since no args would be left for the ones after it
+func validateArgDefs(argDefs []*ArgDef) (err error) {
+	for i, ad := range argDefs {
+		if ad.IsVariadic() && i < len(argDefs)-1 {
+			err = NewErr(ErrRemainderNotLast, "argument", ad.Name)
+			goto end
+		}
+	}
+end:
+	return err
 }
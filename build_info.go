@@ -0,0 +1,179 @@
+package cliutil
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// Build metadata that overrides what the Go toolchain embeds in the binary,
+// for builds without VCS stamping (e.g. from a source tarball or with
+// -buildvcs=false). Set them at link time:
+//
+//	go build -ldflags "-X github.com/mikeschinkel/go-cliutil.BuildVersion=v1.2.3
+//	  -X github.com/mikeschinkel/go-cliutil.BuildCommit=$(git rev-parse HEAD)
+//	  -X github.com/mikeschinkel/go-cliutil.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	BuildVersion string
+	BuildCommit  string
+	BuildDirty   string // "true" or "false"
+	BuildDate    string // RFC 3339
+)
+
+// Keys of the build metadata WithBuildInfo adds to AppInfo.ExtraInfo
+const (
+	BuildCommitKey = "build_commit"
+	BuildDirtyKey  = "build_dirty"
+	BuildDateKey   = "build_date"
+	GoVersionKey   = "go_version"
+)
+
+// BuildInfo is the build metadata of the running binary
+type BuildInfo struct {
+	Version   dt.Version // Module version, or empty for a development build
+	Commit    string     // VCS revision
+	Dirty     bool       // Built with uncommitted changes
+	Date      time.Time  // Commit time from VCS stamping, or BuildDate
+	GoVersion string
+}
+
+// ReadBuildInfo returns the build metadata from runtime/debug.ReadBuildInfo,
+// overridden by any of BuildVersion, BuildCommit, BuildDirty, and BuildDate
+// that are set
+func ReadBuildInfo() BuildInfo {
+	info := embeddedBuildInfo()
+	if BuildVersion != "" {
+		info.Version = dt.Version(BuildVersion)
+	}
+	if BuildCommit != "" {
+		info.Commit = BuildCommit
+	}
+	if dirty, err := strconv.ParseBool(BuildDirty); err == nil {
+		info.Dirty = dirty
+	}
+	if date, err := time.Parse(time.RFC3339, BuildDate); err == nil {
+		info.Date = date
+	}
+	return info
+}
+
+// embeddedBuildInfo reads what the toolchain embedded, once, since it does
+// not change while the binary runs
+var embeddedBuildInfo = sync.OnceValue(func() (info BuildInfo) {
+	info.GoVersion = runtime.Version()
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = bi.GoVersion
+	if bi.Main.Version != "(devel)" {
+		info.Version = dt.Version(bi.Main.Version)
+	}
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.modified":
+			info.Dirty = setting.Value == "true"
+		case "vcs.time":
+			info.Date, _ = time.Parse(time.RFC3339, setting.Value)
+		}
+	}
+	return info
+})
+
+// WithBuildInfo returns args with the build metadata from ReadBuildInfo
+// filled in: Version when it is empty, and the commit, dirty flag, build
+// date, and Go version in ExtraInfo under BuildCommitKey, BuildDirtyKey,
+// BuildDateKey, and GoVersionKey. Values args already has are kept.
+//
+//	info := appinfo.New(cliutil.WithBuildInfo(appinfo.Args{Name: "myapp"}))
+func WithBuildInfo(args appinfo.Args) appinfo.Args {
+	build := ReadBuildInfo()
+	if args.Version == "" {
+		args.Version = build.Version
+	}
+	extra := map[string]any{
+		BuildDirtyKey: build.Dirty,
+		GoVersionKey:  build.GoVersion,
+	}
+	if build.Commit != "" {
+		extra[BuildCommitKey] = build.Commit
+	}
+	if !build.Date.IsZero() {
+		extra[BuildDateKey] = build.Date
+	}
+	maps.Copy(extra, args.ExtraInfo)
+	args.ExtraInfo = extra
+	return args
+}
+
+// BuildInfoOf returns the build metadata in info, as added by WithBuildInfo
+func BuildInfoOf(info appinfo.AppInfo) (build BuildInfo) {
+	build.Version = info.Version()
+	extra := info.ExtraInfo()
+	build.Commit, _ = extra[BuildCommitKey].(string)
+	build.Dirty, _ = extra[BuildDirtyKey].(bool)
+	build.Date, _ = extra[BuildDateKey].(time.Time)
+	build.GoVersion, _ = extra[GoVersionKey].(string)
+	return build
+}
+
+// buildDetails returns name and the metadata in bi one item per line, as
+// `version --verbose` and crash reports show it, omitting what is unknown
+func buildDetails(name string, bi BuildInfo) string {
+	var b strings.Builder
+
+	version := string(bi.Version)
+	if version == "" {
+		version = "devel"
+	}
+	fmt.Fprintf(&b, "%s %s\n", name, version)
+	if bi.Commit != "" {
+		fmt.Fprintf(&b, "  %-10s%s\n", "Commit:", bi.Commit)
+		fmt.Fprintf(&b, "  %-10s%t\n", "Dirty:", bi.Dirty)
+	}
+	if !bi.Date.IsZero() {
+		fmt.Fprintf(&b, "  %-10s%s\n", "Built:", bi.Date.UTC().Format(time.RFC3339))
+	}
+	if bi.GoVersion != "" {
+		fmt.Fprintf(&b, "  %-10s%s\n", "Go:", bi.GoVersion)
+	}
+	fmt.Fprintf(&b, "  %-10s%s/%s\n", "Platform:", runtime.GOOS, runtime.GOARCH)
+	return b.String()
+}
+
+// String returns the metadata on one line, e.g. "v1.2.3 (commit 1a2b3c4d5e6f,
+// dirty, built 2026-01-02T15:04:05Z, go1.25.3)", omitting what is unknown
+func (bi BuildInfo) String() string {
+	var details []string
+
+	version := string(bi.Version)
+	if version == "" {
+		version = "devel"
+	}
+	if bi.Commit != "" {
+		details = append(details, "commit "+bi.Commit[:min(len(bi.Commit), 12)])
+	}
+	if bi.Dirty {
+		details = append(details, "dirty")
+	}
+	if !bi.Date.IsZero() {
+		details = append(details, "built "+bi.Date.UTC().Format(time.RFC3339))
+	}
+	if bi.GoVersion != "" {
+		details = append(details, bi.GoVersion)
+	}
+	if len(details) == 0 {
+		return version
+	}
+	return version + " (" + strings.Join(details, ", ") + ")"
+}
@@ -0,0 +1,222 @@
+package cliutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Names of the commands registered by EnableBuiltins
+const (
+	DocsCmdName       = "docs"
+	CompletionCmdName = "completion"
+	VersionCmdName    = "version"
+	SettingsCmdName   = "settings"
+	CompleteCmdName   = "__complete" // Hidden; called by completion scripts
+	ManCmdName        = "gen-man"    // Hidden; run when packaging the app
+)
+
+// Builtins selects the standard auxiliary commands for EnableBuiltins. The
+// selected commands are listed in help after the app's own commands, except
+// for the hidden ones: the command completion scripts call, and gen-man.
+type Builtins struct {
+	Docs       bool // "docs" writes a Markdown reference of every visible command
+	Completion bool // "completion [<shell>]" writes a completion script for bash, zsh, fish, or PowerShell
+	Version    bool // "version [--verbose]" shows the app's name and build metadata; see WithBuildInfo
+	Settings   bool // "settings [<command>...]" shows each option's effective value and source
+	Man        bool // "gen-man [<dir>]" writes man pages for the app and its commands; see WriteManPages
+}
+
+// EnableBuiltins registers the commands selected by b. Call it from an init
+// func or an initializer, like any other command registration.
+func EnableBuiltins(b Builtins) (err error) {
+	var cmds []Command
+
+	if b.Docs {
+		cmds = append(cmds, &docsCmd{
+			CmdBase: NewCmdBase(CmdArgs{
+				Name:        DocsCmdName,
+				Description: "Write a Markdown reference of all commands",
+				NoExamples:  true,
+			}),
+		})
+	}
+	if b.Completion {
+		completion := &completionCmd{}
+		completion.CmdBase = NewCmdBase(CmdArgs{
+			Name:        CompletionCmdName,
+			Usage:       CompletionCmdName + " [<shell>]",
+			Description: "Write a shell completion script",
+			ArgDefs: []*ArgDef{{
+				Name:    "shell",
+				Usage:   "Shell to complete for: " + strings.Join(shellNames(completionShells), "|") + "; defaults to the current shell",
+				String:  &completion.shell,
+				Example: string(BashShell),
+			}},
+		})
+		cmds = append(cmds, completion, newCompleteCmd())
+	}
+	if b.Version {
+		version := &versionCmd{}
+		version.CmdBase = NewCmdBase(CmdArgs{
+			Name:        VersionCmdName,
+			Usage:       VersionCmdName + " [--verbose]",
+			Description: "Show the version",
+			FlagSets: []*FlagSet{{
+				Name: "version",
+				FlagDefs: []FlagDef{{
+					Name:  "verbose",
+					Usage: "Show the commit, build date, Go version, and platform, one per line",
+					Bool:  &version.verbose,
+				}},
+			}},
+			NoExamples: true,
+		})
+		cmds = append(cmds, version)
+	}
+	if b.Settings {
+		cmds = append(cmds, &settingsCmd{
+			CmdBase: NewCmdBase(CmdArgs{
+				Name:        SettingsCmdName,
+				Usage:       SettingsCmdName + " [<command>...]",
+				Description: "Show the effective value and source of each option",
+				NoExamples:  true,
+			}),
+		})
+	}
+	if b.Man {
+		man := &manCmd{}
+		man.CmdBase = NewCmdBase(CmdArgs{
+			Name:        ManCmdName,
+			Usage:       ManCmdName + " [<dir>]",
+			Description: "Write man pages",
+			ArgDefs: []*ArgDef{{
+				Name:    "dir",
+				Usage:   "Directory to write the pages to",
+				Default: ".",
+				String:  &man.dir,
+			}},
+			Hide: true,
+		})
+		cmds = append(cmds, man)
+	}
+	for _, cmd := range cmds {
+		err = RegisterCommand(cmd)
+		if err != nil {
+			goto end
+		}
+	}
+end:
+	return err
+}
+
+var _ CommandHandler = (*docsCmd)(nil)
+var _ CommandHandler = (*completionCmd)(nil)
+var _ CommandHandler = (*completeCmd)(nil)
+var _ CommandHandler = (*versionCmd)(nil)
+var _ CommandHandler = (*manCmd)(nil)
+
+type docsCmd struct {
+	*CmdBase
+}
+
+func (c *docsCmd) Handle() error {
+	return WriteDocs(c.Writer.Writer(), c.AppInfo)
+}
+
+type completionCmd struct {
+	*CmdBase
+	shell string
+}
+
+func (c *completionCmd) Handle() (err error) {
+	var shell Shell
+	var command string
+
+	name := c.shell
+	if name == "" {
+		name = string(DetectShell())
+	}
+	shell, err = ParseCompletionShell(name)
+	if err != nil {
+		err = WithErrClass(err, UsageErr)
+		goto end
+	}
+	command = c.CLIName()
+	if c.AppInfo != nil && c.AppInfo.ExeName() != "" {
+		command = string(c.AppInfo.ExeName())
+	}
+	err = WriteCompletion(c.Writer.Writer(), shell, CompletionArgs{Command: command})
+end:
+	return err
+}
+
+type completeCmd struct {
+	*CmdBase
+}
+
+// newCompleteCmd returns the hidden command completion scripts call. ParseCmd
+// dispatches to one even when EnableBuiltins did not register it, so scripts
+// from WriteCompletion work for any app.
+func newCompleteCmd() *completeCmd {
+	return &completeCmd{
+		CmdBase: NewCmdBase(CmdArgs{
+			Name:        CompleteCmdName,
+			Description: "Print completion candidates",
+			Hide:        true,
+		}),
+	}
+}
+
+func (c *completeCmd) Handle() (err error) {
+	var args []string
+
+	lines := os.Getenv(CompleteArgsEnv)
+	if lines != "" {
+		args = strings.Split(lines, "\n")
+	}
+	for _, candidate := range c.cli().Complete(args, os.Getenv(CompleteWordEnv)) {
+		_, err = fmt.Fprintln(c.Writer.Writer(), candidate)
+		if err != nil {
+			break
+		}
+	}
+	return err
+}
+
+type versionCmd struct {
+	*CmdBase
+	verbose bool
+}
+
+func (c *versionCmd) Handle() error {
+	name, build := c.CLIName(), ReadBuildInfo()
+	if c.AppInfo != nil {
+		name, build = c.AppInfo.Name(), BuildInfoOf(c.AppInfo)
+	}
+	if c.verbose {
+		c.Writer.Printf("%s", buildDetails(name, build))
+		return nil
+	}
+	c.Writer.Printf("%s %s\n", name, build)
+	return nil
+}
+
+type manCmd struct {
+	*CmdBase
+	dir string
+}
+
+func (c *manCmd) Handle() (err error) {
+	var files []string
+
+	dir := c.dir
+	if dir == "" {
+		dir = "."
+	}
+	files, err = WriteManPages(dir, c.AppInfo, ManArgs{})
+	for _, file := range files {
+		c.Writer.V2().Printf("Wrote %s\n", file)
+	}
+	return err
+}
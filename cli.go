@@ -0,0 +1,121 @@
+package cliutil
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// CLI holds the state of one command-line app: its command registry, its
+// global FlagSet and the GlobalOptions that FlagSet assigns, and its Writer.
+// The package-level functions such as RegisterCommand, ParseGlobalOptions,
+// and SetWriter act on a default CLI; create others with NewCLI so that two
+// independent CLIs, or parallel tests, can coexist in one process.
+//
+// A CmdRunner resolves and runs commands in CmdRunnerArgs.CLI, or in the
+// default CLI when that is nil, and follows that CLI's global options, such
+// as --strict and --output. ShowMainHelp and ShowCmdHelp render the help of
+// UsageArgs.CLI; a command's usage, docs, man page, schema, and settings
+// come from the CLI it was registered in. WriteDocs, WriteManPages, and
+// BuildCLISchema start from the default CLI's commands.
+type CLI struct {
+	// registryMu guards the command and global-flag registries.
+	// Registration, BuildCommandTree, ParseGlobalOptions, and RestoreRegistry
+	// take the write lock; lookups take the read lock, so they may run
+	// concurrently with each other. Exported methods lock; unexported
+	// helpers they share expect the caller to hold the lock.
+	registryMu sync.RWMutex
+
+	// registryGen changes whenever commands or global flags are registered,
+	// commands are built, or the registry is restored, which invalidates the
+	// full names cached in each cmdRegistration and the examples and help
+	// cached for rendering. Values come from registryGens, so no two CLIs
+	// share one.
+	registryGen uint64
+
+	// Final resolved command maps (built during BuildCommandTree)
+	commands        []Command
+	commandsTypeMap map[reflect.Type]Command
+	commandsPathMap map[string]Command
+	flagCommandMap  map[string]Command
+
+	// cmdTree indexes commandsPathMap. It is rebuilt by BuildCommandTree and
+	// reset to nil by RegisterCommand and RestoreRegistry, so the cached
+	// child lists never outlive a registration change. It is an
+	// atomic.Pointer because getCmdTree may build it while only holding the
+	// read lock.
+	cmdTree atomic.Pointer[cmdNode]
+
+	flagSet *FlagSet
+	options *GlobalOptions
+	name    string // Set by SetName
+
+	configFiles []string       // ConfigArgs.Files given EnableConfig
+	configFile  string         // The config file last loaded, if any
+	config      map[string]any // The values decoded from configFile
+
+	writerMu sync.RWMutex // synchronizes access to writer
+	writer   Writer       // the output writer used for CLI operations
+}
+
+// registryGens issues the registryGen of every CLI
+var registryGens atomic.Uint64
+
+// defaultCLI is the CLI the package-level functions act on
+var defaultCLI = NewCLI()
+
+// NewCLI returns a CLI with no commands, the standard global flags, and no
+// Writer; set one with SetWriter or Initialize before running commands
+func NewCLI() *CLI {
+	cli := &CLI{
+		registryGen:     registryGens.Add(1),
+		commands:        make([]Command, 0),
+		commandsTypeMap: make(map[reflect.Type]Command),
+		commandsPathMap: make(map[string]Command),
+		flagCommandMap:  make(map[string]Command),
+		options:         newGlobalOptions(),
+	}
+	cli.flagSet = newGlobalFlagSet(cli.options)
+	return cli
+}
+
+// DefaultCLI returns the CLI that the package-level functions act on
+func DefaultCLI() *CLI {
+	return defaultCLI
+}
+
+// changed gives cli a new registryGen; the caller holds registryMu for
+// writing
+func (cli *CLI) changed() {
+	cli.registryGen = registryGens.Add(1)
+}
+
+// SetCLIName sets the default CLI's name (see CLI.SetName)
+func SetCLIName(name string) {
+	defaultCLI.SetName(name)
+}
+
+// SetName sets the name that help shows the CLI is run as, instead of the
+// name of its executable, such as so help is the same however it was built
+func (cli *CLI) SetName(name string) {
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+	cli.name = name
+	cli.changed()
+}
+
+// Name returns the name set by SetName, or the empty string for the name of
+// the executable
+func (cli *CLI) Name() string {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	return cli.name
+}
+
+// cliOrDefault returns cli, or the default CLI when cli is nil
+func cliOrDefault(cli *CLI) *CLI {
+	if cli == nil {
+		return defaultCLI
+	}
+	return cli
+}
@@ -0,0 +1,114 @@
+package cliutil
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrClipboardUnsupported = errors.New("clipboard not supported")
+	ErrClipboardFailed      = errors.New("copying to clipboard failed")
+)
+
+// Clipboard abstracts the system clipboard so commands that copy tokens,
+// URLs, or IDs can be unit-tested with a fake (see clitest.FakeClipboard).
+type Clipboard interface {
+	// Copy replaces the clipboard's contents with text
+	Copy(text string) error
+}
+
+// Package-level clipboard instance
+var (
+	clipboard   Clipboard    = osClipboard{}
+	clipboardMu sync.RWMutex // synchronizes access to clipboard
+)
+
+// SetClipboard replaces the Clipboard used by ToClipboard (primarily for
+// testing) and returns the previous one so it can be restored.
+func SetClipboard(c Clipboard) (prev Clipboard) {
+	clipboardMu.Lock()
+	defer clipboardMu.Unlock()
+	prev = clipboard
+	clipboard = c
+	return prev
+}
+
+// GetClipboard returns the Clipboard used by ToClipboard
+func GetClipboard() Clipboard {
+	clipboardMu.RLock()
+	defer clipboardMu.RUnlock()
+	return clipboard
+}
+
+// ToClipboard puts text on the user's clipboard. It fails with
+// ErrClipboardUnsupported, with a suggestion of what to install, when the
+// platform has no clipboard tool, so commands can fall back to printing:
+//
+//	err := cliutil.ToClipboard(token)
+//	if errors.Is(err, cliutil.ErrClipboardUnsupported) {
+//		c.Writer.Printf("%s\n", token)
+//	}
+func ToClipboard(text string) error {
+	return GetClipboard().Copy(text)
+}
+
+var _ Clipboard = osClipboard{}
+
+// osClipboard copies by piping text to the platform's clipboard tool
+type osClipboard struct{}
+
+// clipboardTool is a command that reads text to copy from stdin
+type clipboardTool struct {
+	name string
+	args []string
+}
+
+// clipboardTools returns the tools to try, in order, for the platform
+func clipboardTools() []clipboardTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardTool{{name: "pbcopy"}}
+	case "windows":
+		return []clipboardTool{{name: "clip.exe"}}
+	}
+	var tools []clipboardTool
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		tools = append(tools, clipboardTool{name: "wl-copy"})
+	}
+	return append(tools,
+		clipboardTool{name: "xclip", args: []string{"-selection", "clipboard"}},
+		clipboardTool{name: "xsel", args: []string{"--clipboard", "--input"}},
+		clipboardTool{name: "termux-clipboard-set"},
+	)
+}
+
+func (osClipboard) Copy(text string) (err error) {
+	var path string
+	var names []string
+	var out []byte
+
+	for _, tool := range clipboardTools() {
+		names = append(names, tool.name)
+		path, err = exec.LookPath(tool.name)
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, tool.args...)
+		cmd.Stdin = strings.NewReader(text)
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			err = NewErr(ErrClipboardFailed, "tool", tool.name, "output", strings.TrimSpace(string(out)), err)
+		}
+		goto end
+	}
+	err = WithSuggestion(
+		NewErr(ErrClipboardUnsupported, "os", runtime.GOOS),
+		"install one of: "+strings.Join(names, ", "),
+	)
+end:
+	return err
+}
@@ -0,0 +1,211 @@
+package clitest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var _ cliutil.Writer = (*BufferedWriter)(nil)
+
+// BufferedWriter implements cliutil.Writer and captures stdout and stderr in
+// memory so tests can assert on command output.
+type BufferedWriter struct {
+	bufs      *writerBuffers
+	quiet     bool
+	verbosity cliutil.Verbosity
+	useLevel  cliutil.Verbosity
+	loud      *BufferedWriter
+	v2        *BufferedWriter
+	v3        *BufferedWriter
+}
+
+// writerBuffers are shared by a BufferedWriter and its Loud/V2/V3 variants
+type writerBuffers struct {
+	mu     sync.Mutex
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+// lockedWriter serializes writes to a buffer through the shared mutex
+type lockedWriter struct {
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+func (lw lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.buf.Write(p)
+}
+
+// buffersPool recycles the buffers of released BufferedWriters, so suites
+// that capture large outputs reuse already-grown buffers
+var buffersPool = sync.Pool{
+	New: func() any { return &writerBuffers{} },
+}
+
+// NewBufferedWriter returns a BufferedWriter at maximum verbosity
+func NewBufferedWriter() *BufferedWriter {
+	return &BufferedWriter{
+		bufs:      buffersPool.Get().(*writerBuffers),
+		verbosity: cliutil.HighVerbosity,
+		useLevel:  cliutil.LowVerbosity,
+	}
+}
+
+// GrowHint pre-sizes the buffers for at least stdout and stderr more bytes,
+// avoiding repeated growth when a test expects large output
+func (w *BufferedWriter) GrowHint(stdout, stderr int) {
+	w.bufs.mu.Lock()
+	defer w.bufs.mu.Unlock()
+	w.bufs.stdout.Grow(stdout)
+	w.bufs.stderr.Grow(stderr)
+}
+
+// Release clears the buffers and returns them for reuse by a later
+// NewBufferedWriter. Neither w nor its Loud/V2/V3 variants, nor any string or
+// iterator they returned that is still being read, may be used afterward.
+func (w *BufferedWriter) Release() {
+	w.Reset()
+	buffersPool.Put(w.bufs)
+	w.bufs = nil
+	w.loud, w.v2, w.v3 = nil, nil, nil
+}
+
+// Printf writes formatted output to the stdout buffer
+func (w *BufferedWriter) Printf(format string, args ...any) {
+	if w.quiet || w.verbosity < w.useLevel {
+		return
+	}
+	w.bufs.mu.Lock()
+	defer w.bufs.mu.Unlock()
+	_, _ = fmt.Fprintf(&w.bufs.stdout, format, args...)
+}
+
+// Errorf writes formatted output to the stderr buffer, flattening newlines
+// in error arguments the same way cliutil's console writer does
+func (w *BufferedWriter) Errorf(format string, args ...any) {
+	for i, arg := range args {
+		err, ok := arg.(error)
+		if !ok {
+			continue
+		}
+		args[i] = strings.ReplaceAll(err.Error(), "\n", "; ")
+	}
+	w.bufs.mu.Lock()
+	defer w.bufs.mu.Unlock()
+	_, _ = fmt.Fprintf(&w.bufs.stderr, format, args...)
+}
+
+func (w *BufferedWriter) Loud() cliutil.Writer {
+	if w.loud == nil {
+		w.loud = w.variant(false, w.useLevel)
+	}
+	return w.loud
+}
+
+func (w *BufferedWriter) V2() cliutil.Writer {
+	if w.v2 == nil {
+		w.v2 = w.variant(w.quiet, cliutil.MediumVerbosity)
+	}
+	return w.v2
+}
+
+func (w *BufferedWriter) V3() cliutil.Writer {
+	if w.v3 == nil {
+		w.v3 = w.variant(w.quiet, cliutil.HighVerbosity)
+	}
+	return w.v3
+}
+
+func (w *BufferedWriter) variant(quiet bool, useLevel cliutil.Verbosity) *BufferedWriter {
+	return &BufferedWriter{
+		bufs:      w.bufs,
+		quiet:     quiet,
+		verbosity: w.verbosity,
+		useLevel:  useLevel,
+	}
+}
+
+func (w *BufferedWriter) Writer() io.Writer {
+	return lockedWriter{mu: &w.bufs.mu, buf: &w.bufs.stdout}
+}
+
+func (w *BufferedWriter) ErrWriter() io.Writer {
+	return lockedWriter{mu: &w.bufs.mu, buf: &w.bufs.stderr}
+}
+
+// SetQuiet suppresses Printf output except through Loud()
+func (w *BufferedWriter) SetQuiet(quiet bool) {
+	w.quiet = quiet
+	w.loud, w.v2, w.v3 = nil, nil, nil
+}
+
+// IsQuiet returns true when Printf output is suppressed (see SetQuiet)
+func (w *BufferedWriter) IsQuiet() bool {
+	return w.quiet
+}
+
+// SetVerbosity sets the verbosity used to filter V2() and V3() output
+func (w *BufferedWriter) SetVerbosity(v cliutil.Verbosity) {
+	w.verbosity = v
+	w.loud, w.v2, w.v3 = nil, nil, nil
+}
+
+// Stdout returns everything written to stdout so far
+func (w *BufferedWriter) Stdout() string {
+	w.bufs.mu.Lock()
+	defer w.bufs.mu.Unlock()
+	return w.bufs.stdout.String()
+}
+
+// Stderr returns everything written to stderr so far
+func (w *BufferedWriter) Stderr() string {
+	w.bufs.mu.Lock()
+	defer w.bufs.mu.Unlock()
+	return w.bufs.stderr.String()
+}
+
+// StdoutLines iterates over the lines written to stdout so far without
+// copying them into one string. Writes made while iterating are not seen,
+// and w must not be Reset or Released until the iteration is done.
+func (w *BufferedWriter) StdoutLines() iter.Seq[string] {
+	return w.lines(&w.bufs.stdout)
+}
+
+// StderrLines iterates over the lines written to stderr so far; see
+// StdoutLines
+func (w *BufferedWriter) StderrLines() iter.Seq[string] {
+	return w.lines(&w.bufs.stderr)
+}
+
+func (w *BufferedWriter) lines(buf *bytes.Buffer) iter.Seq[string] {
+	// The buffers are only ever appended to, so the bytes written so far
+	// stay put while later writes land after them
+	w.bufs.mu.Lock()
+	data := buf.Bytes()
+	w.bufs.mu.Unlock()
+	return func(yield func(string) bool) {
+		for line := range bytes.Lines(data) {
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			if !yield(string(line)) {
+				return
+			}
+		}
+	}
+}
+
+// Reset clears both buffers
+func (w *BufferedWriter) Reset() {
+	w.bufs.mu.Lock()
+	defer w.bufs.mu.Unlock()
+	w.bufs.stdout.Reset()
+	w.bufs.stderr.Reset()
+}
@@ -0,0 +1,146 @@
+package clitest
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// OptionsOption sets one field of the options built by OptionsWith
+type OptionsOption func(*cliutil.GlobalOptionsArgs)
+
+// WithQuiet sets the Quiet() option
+func WithQuiet(quiet bool) OptionsOption {
+	return func(a *cliutil.GlobalOptionsArgs) { a.Quiet = &quiet }
+}
+
+// WithVerbosity sets the Verbosity() option
+func WithVerbosity(v cliutil.Verbosity) OptionsOption {
+	return func(a *cliutil.GlobalOptionsArgs) {
+		n := int(v)
+		a.Verbosity = &n
+	}
+}
+
+// WithTimeout sets the Timeout() option
+func WithTimeout(d time.Duration) OptionsOption {
+	return func(a *cliutil.GlobalOptionsArgs) { a.Timeout = &d }
+}
+
+// WithDryRun sets the DryRun() option
+func WithDryRun(dryRun bool) OptionsOption {
+	return func(a *cliutil.GlobalOptionsArgs) { a.DryRun = &dryRun }
+}
+
+// WithForce sets the Force() option
+func WithForce(force bool) OptionsOption {
+	return func(a *cliutil.GlobalOptionsArgs) { a.Force = &force }
+}
+
+// WithOutput sets the OutputFormat() option
+func WithOutput(format cliutil.OutputFormat) OptionsOption {
+	return func(a *cliutil.GlobalOptionsArgs) {
+		s := string(format)
+		a.Output = &s
+	}
+}
+
+// WithStrict sets the Strict() option
+func WithStrict(strict bool) OptionsOption {
+	return func(a *cliutil.GlobalOptionsArgs) { a.Strict = &strict }
+}
+
+// OptionsWith builds GlobalOptions for handler unit tests without going
+// through flag parsing; unset options take their flag defaults:
+//
+//	opts := clitest.OptionsWith(clitest.WithDryRun(true), clitest.WithVerbosity(cliutil.HighVerbosity))
+//
+// It panics if an option value is invalid, since that is a bug in the test.
+func OptionsWith(opts ...OptionsOption) *cliutil.GlobalOptions {
+	var args cliutil.GlobalOptionsArgs
+	for _, opt := range opts {
+		opt(&args)
+	}
+	o, err := cliutil.NewGlobalOptions(args)
+	if err != nil {
+		panic(fmt.Sprintf("clitest.OptionsWith: %v", err))
+	}
+	return o
+}
+
+// MapConfig is a cliutil.Config backed by a map, as returned by ConfigFromMap
+type MapConfig struct {
+	values map[string]any
+}
+
+var _ cliutil.Config = (*MapConfig)(nil)
+
+// ConfigFromMap returns a Config whose values come from m. Nested maps can
+// be addressed with dotted keys, e.g. Get("db.host") for
+// {"db": {"host": "localhost"}}. m is copied at the top level.
+func ConfigFromMap(m map[string]any) *MapConfig {
+	return &MapConfig{values: maps.Clone(m)}
+}
+
+// Config marks MapConfig as a cliutil.Config
+func (c *MapConfig) Config() {}
+
+// Map returns a copy of the top-level values
+func (c *MapConfig) Map() map[string]any {
+	return maps.Clone(c.values)
+}
+
+// Get returns the value at key, which may be a dotted path into nested maps
+func (c *MapConfig) Get(key string) (value any, ok bool) {
+	var m map[string]any
+	value = c.values
+	for part := range strings.SplitSeq(key, ".") {
+		m, ok = value.(map[string]any)
+		if !ok {
+			goto end
+		}
+		value, ok = m[part]
+		if !ok {
+			goto end
+		}
+	}
+end:
+	if !ok {
+		value = nil
+	}
+	return value, ok
+}
+
+// String returns the string at key, or "" if missing or not a string
+func (c *MapConfig) String(key string) string {
+	v, _ := c.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// Int returns the integer at key, or 0 if missing or not an integer. Whole
+// float64 values are accepted since that is how JSON decodes numbers.
+func (c *MapConfig) Int(key string) (n int) {
+	v, _ := c.Get(key)
+	switch t := v.(type) {
+	case int:
+		n = t
+	case int64:
+		n = int(t)
+	case float64:
+		if t == float64(int(t)) {
+			n = int(t)
+		}
+	}
+	return n
+}
+
+// Bool returns the bool at key, or false if missing or not a bool
+func (c *MapConfig) Bool(key string) bool {
+	v, _ := c.Get(key)
+	b, _ := v.(bool)
+	return b
+}
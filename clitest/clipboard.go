@@ -0,0 +1,44 @@
+package clitest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var _ cliutil.Clipboard = (*FakeClipboard)(nil)
+
+// FakeClipboard is a cliutil.Clipboard test double that keeps what was
+// copied in memory, or fails every copy with Err when it is set.
+type FakeClipboard struct {
+	mu   sync.Mutex
+	text string
+	Err  error
+}
+
+// UseClipboard installs cb as cliutil's Clipboard for the duration of the test
+func UseClipboard(t testing.TB, cb cliutil.Clipboard) {
+	t.Helper()
+	prev := cliutil.SetClipboard(cb)
+	t.Cleanup(func() {
+		cliutil.SetClipboard(prev)
+	})
+}
+
+func (fc *FakeClipboard) Copy(text string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.Err != nil {
+		return fc.Err
+	}
+	fc.text = text
+	return nil
+}
+
+// Text returns the text most recently copied
+func (fc *FakeClipboard) Text() string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.text
+}
@@ -0,0 +1,117 @@
+package clitest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var _ cliutil.Clock = (*FakeClock)(nil)
+
+// FakeClock is a cliutil.Clock test double whose time only moves when
+// Advance or Set is called, so timeouts and retries can be tested without
+// sleeping.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []clockWaiter
+}
+
+// clockWaiter is a pending After() call
+type clockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// DefaultFakeClockTime is the start time of a FakeClock created with a zero time
+var DefaultFakeClockTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NewFakeClock returns a FakeClock set to start, or to DefaultFakeClockTime
+// if start is zero
+func NewFakeClock(start time.Time) *FakeClock {
+	if start.IsZero() {
+		start = DefaultFakeClockTime
+	}
+	fc := &FakeClock{now: start}
+	fc.cond = sync.NewCond(&fc.mu)
+	return fc
+}
+
+// UseClock installs c as cliutil's Clock for the duration of the test
+func UseClock(t testing.TB, c cliutil.Clock) {
+	t.Helper()
+	prev := cliutil.SetClock(c)
+	t.Cleanup(func() {
+		cliutil.SetClock(prev)
+	})
+}
+
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+// After returns a channel that fires once the fake time reaches Now()+d;
+// a non-positive d fires immediately
+func (fc *FakeClock) After(d time.Duration) <-chan time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- fc.now
+		return ch
+	}
+	fc.waiters = append(fc.waiters, clockWaiter{deadline: fc.now.Add(d), ch: ch})
+	fc.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the fake time forward by d, firing any After() channels
+// whose deadline has been reached
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.setLocked(fc.now.Add(d))
+}
+
+// Set moves the fake time to t, firing any After() channels whose deadline
+// has been reached. Moving time backwards fires nothing.
+func (fc *FakeClock) Set(t time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.setLocked(t)
+}
+
+func (fc *FakeClock) setLocked(t time.Time) {
+	fc.now = t
+	pending := fc.waiters[:0]
+	for _, w := range fc.waiters {
+		if w.deadline.After(t) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- t
+	}
+	fc.waiters = pending
+}
+
+// Waiters returns the number of After() calls that have not yet fired
+func (fc *FakeClock) Waiters() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return len(fc.waiters)
+}
+
+// BlockUntil waits until at least n After() calls are pending, so a test can
+// be sure a goroutine is waiting on the clock before calling Advance
+func (fc *FakeClock) BlockUntil(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for len(fc.waiters) < n {
+		fc.cond.Wait()
+	}
+}
@@ -0,0 +1,73 @@
+package clitest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var _ cliutil.Environment = (*FakeEnvironment)(nil)
+
+// FakeEnvironment is a cliutil.Environment test double that returns fixed
+// answers regardless of the process's environment variables and terminal.
+type FakeEnvironment struct {
+	shell       cliutil.Shell
+	ci          bool
+	interactive bool
+	noColor     bool
+}
+
+// FakeEnvironmentArgs configures a FakeEnvironment
+type FakeEnvironmentArgs struct {
+	Shell       cliutil.Shell // Value returned by Shell()
+	CI          bool          // Value returned by IsCI()
+	Interactive bool          // Value returned by IsInteractive()
+	NoColor     bool          // Value returned by NoColor()
+}
+
+// NewFakeEnvironment returns a FakeEnvironment answering with args
+func NewFakeEnvironment(args FakeEnvironmentArgs) *FakeEnvironment {
+	return &FakeEnvironment{
+		shell:       args.Shell,
+		ci:          args.CI,
+		interactive: args.Interactive,
+		noColor:     args.NoColor,
+	}
+}
+
+// UseEnvironment installs env as cliutil's Environment for the duration of
+// the test
+func UseEnvironment(t testing.TB, env cliutil.Environment) {
+	t.Helper()
+	prev := cliutil.SetEnvironment(env)
+	t.Cleanup(func() {
+		cliutil.SetEnvironment(prev)
+	})
+}
+
+// UsePromptInput makes cliutil.Confirm read its answers from input for the
+// duration of the test, e.g. "y\n" to confirm one prompt
+func UsePromptInput(t testing.TB, input string) {
+	t.Helper()
+	prev := cliutil.SetPromptInput(strings.NewReader(input))
+	t.Cleanup(func() {
+		cliutil.SetPromptInput(prev)
+	})
+}
+
+func (fe *FakeEnvironment) Shell() cliutil.Shell {
+	return fe.shell
+}
+
+func (fe *FakeEnvironment) IsCI() bool {
+	return fe.ci
+}
+
+func (fe *FakeEnvironment) IsInteractive() bool {
+	return fe.interactive
+}
+
+func (fe *FakeEnvironment) NoColor() bool {
+	return fe.noColor
+}
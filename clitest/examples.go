@@ -0,0 +1,204 @@
+package clitest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// DefaultPlaceholderValue replaces <placeholders> that have no entry in
+// ExamplesArgs.Placeholders
+const DefaultPlaceholderValue = "example"
+
+// ExamplesArgs configures CheckExamples and VerifyExamples
+type ExamplesArgs struct {
+	// AppInfo is used to build the main help examples; defaults to an
+	// AppInfo named after the test binary
+	AppInfo appinfo.AppInfo
+	// Placeholders maps placeholder names (without angle brackets) to the
+	// values substituted for them, e.g. {"port": "8080"} for "<port>"
+	Placeholders map[string]string
+	// DefaultValue replaces placeholders missing from Placeholders;
+	// defaults to DefaultPlaceholderValue
+	DefaultValue string
+}
+
+// ExampleProblem describes a published example that failed to parse
+type ExampleProblem struct {
+	Example cliutil.Example
+	Args    []string // The argv parsed, after placeholder substitution
+	Err     error
+}
+
+func (p ExampleProblem) Error() string {
+	return fmt.Sprintf("example %q (%s) failed to parse: %v", p.Example.Cmd, p.Example.Descr, p.Err)
+}
+
+var placeholderRegex = regexp.MustCompile(`<([^<>]+)>`)
+
+// VerifyExamples fails t for every published help example that would not
+// parse, keeping documented command lines in sync with the registered
+// commands and flags. See CheckExamples.
+func VerifyExamples(t *testing.T, args ExamplesArgs) {
+	t.Helper()
+	for _, p := range CheckExamples(args) {
+		t.Error(p.Error())
+	}
+}
+
+// CheckExamples collects the examples shown in the main help and in each
+// visible command's help, substitutes <placeholder> values, and runs each
+// through global option parsing and CmdRunner.ParseCmd without calling any
+// handler. Examples for "help <command>" are checked by verifying the named
+// command exists, unless the app registers its own help command.
+// cliutil.Initialize must have been called beforehand.
+func CheckExamples(args ExamplesArgs) (problems []ExampleProblem) {
+	if args.AppInfo == nil {
+		exe := filepath.Base(os.Args[0])
+		args.AppInfo = appinfo.New(appinfo.Args{Name: exe, ExeName: dt.Filename(exe)})
+	}
+	if args.DefaultValue == "" {
+		args.DefaultValue = DefaultPlaceholderValue
+	}
+
+	w := NewBufferedWriter()
+	examples := cliutil.BuildUsage(cliutil.UsageArgs{AppInfo: args.AppInfo, Writer: w}).Examples
+	for _, path := range VisibleCmdPaths() {
+		examples = append(examples, cliutil.BuildCmdUsage(cliutil.GetExactCommand(path)).Examples...)
+	}
+
+	seen := make(map[string]struct{}, len(examples))
+	for _, ex := range examples {
+		if _, ok := seen[ex.Cmd]; ok {
+			continue
+		}
+		seen[ex.Cmd] = struct{}{}
+		argv, err := splitCommandLine(ex.Cmd)
+		if err == nil {
+			err = checkExample(argv, args, w)
+		}
+		if err != nil {
+			problems = append(problems, ExampleProblem{Example: ex, Args: argv, Err: err})
+		}
+	}
+	return problems
+}
+
+// checkExample parses a single example's argv, whose first element is the
+// executable name
+func checkExample(argv []string, args ExamplesArgs, w cliutil.Writer) (err error) {
+	var opts *cliutil.GlobalOptions
+	var rest []string
+
+	if len(argv) < 2 {
+		goto end
+	}
+
+	// Without an app-provided help command, "help" examples can only be
+	// checked for the existence of the command they name
+	if argv[1] == "help" && cliutil.GetExactCommand("help") == nil {
+		err = checkHelpExample(argv[2:])
+		goto end
+	}
+
+	for i, arg := range argv {
+		argv[i] = placeholderRegex.ReplaceAllStringFunc(arg, func(m string) string {
+			value, ok := args.Placeholders[m[1:len(m)-1]]
+			if !ok {
+				value = args.DefaultValue
+			}
+			return value
+		})
+	}
+
+	opts, rest, err = cliutil.ParseGlobalOptions(argv)
+	if err != nil {
+		goto end
+	}
+	_, err = cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context: context.Background(),
+		AppInfo: args.AppInfo,
+		Writer:  w,
+		Options: opts,
+		Args:    rest,
+	}).ParseCmd(rest)
+end:
+	return err
+}
+
+// checkHelpExample verifies that the command named by "help" args exists;
+// generic placeholders such as <command> cannot be checked and pass
+func checkHelpExample(words []string) (err error) {
+	if len(words) == 0 {
+		goto end
+	}
+	for _, word := range words {
+		if placeholderRegex.MatchString(word) {
+			goto end
+		}
+	}
+	if cliutil.GetExactCommand(strings.Join(words, ".")) == nil {
+		err = cliutil.NewErr(cliutil.ErrUnknownCommand, "command", strings.Join(words, " "))
+	}
+end:
+	return err
+}
+
+// splitCommandLine splits an example command line into words, honoring
+// single and double quotes; double-quoted words may use Go escapes since
+// help examples are quoted with %q
+func splitCommandLine(line string) (args []string, err error) {
+	var word strings.Builder
+	var inWord bool
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				err = fmt.Errorf("unterminated quote in %q", line)
+				goto end
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			var quoted, s string
+			quoted, err = strconv.QuotedPrefix(line[i:])
+			if err == nil {
+				s, err = strconv.Unquote(quoted)
+			}
+			if err != nil {
+				err = fmt.Errorf("invalid quoting in %q: %w", line, err)
+				goto end
+			}
+			word.WriteString(s)
+			i += len(quoted) - 1
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+end:
+	return args, err
+}
@@ -0,0 +1,94 @@
+package clitest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// FixtureArgs sizes the synthetic command set produced by GenerateCommands
+type FixtureArgs struct {
+	Commands        int    // Number of top-level commands
+	FlagsPerCommand int    // Number of string flags on each command
+	Prefix          string // Command name prefix; defaults to "fixture"
+}
+
+// FixtureCmd is a generated command whose Handle does nothing, for measuring
+// registry and parsing overhead in isolation
+type FixtureCmd struct {
+	*cliutil.CmdBase
+	Values []string // Flag values, indexed by flag position
+	Arg    string   // Value of the single optional positional argument
+}
+
+// Handle does nothing
+func (c *FixtureCmd) Handle() error {
+	return nil
+}
+
+// FixtureCmdName returns the name GenerateCommands gives the i-th command
+func FixtureCmdName(prefix string, i int) string {
+	if prefix == "" {
+		prefix = "fixture"
+	}
+	return fmt.Sprintf("%s-%04d", prefix, i)
+}
+
+// FixtureFlagName returns the name GenerateCommands gives the j-th flag
+func FixtureFlagName(j int) string {
+	return fmt.Sprintf("flag-%03d", j)
+}
+
+// GenerateCommands returns args.Commands new, unregistered commands, each
+// with args.FlagsPerCommand string flags and one optional positional arg.
+// Downstream packages can use it to benchmark their own registry-heavy code.
+func GenerateCommands(args FixtureArgs) (cmds []cliutil.Command) {
+	cmds = make([]cliutil.Command, args.Commands)
+	for i := range cmds {
+		name := FixtureCmdName(args.Prefix, i)
+		cmd := &FixtureCmd{Values: make([]string, args.FlagsPerCommand)}
+		fs := &cliutil.FlagSet{
+			Name:     name,
+			FlagDefs: make([]cliutil.FlagDef, args.FlagsPerCommand),
+		}
+		for j := range fs.FlagDefs {
+			fs.FlagDefs[j] = cliutil.FlagDef{
+				Name:   FixtureFlagName(j),
+				Usage:  fmt.Sprintf("Fixture flag %d", j),
+				String: &cmd.Values[j],
+			}
+		}
+		cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        name,
+			Description: fmt.Sprintf("Fixture command %d", i),
+			FlagSets:    []*cliutil.FlagSet{fs},
+			ArgDefs: []*cliutil.ArgDef{{
+				Name:   "item",
+				Usage:  "Fixture argument",
+				String: &cmd.Arg,
+			}},
+		})
+		cmds[i] = cmd
+	}
+	return cmds
+}
+
+// RegisterFixtures isolates the registry for tb (see IsolateRegistry), then
+// registers and builds the commands from GenerateCommands.
+func RegisterFixtures(tb testing.TB, args FixtureArgs) (cmds []cliutil.Command) {
+	tb.Helper()
+	IsolateRegistry(tb)
+	cmds = GenerateCommands(args)
+	for _, cmd := range cmds {
+		err := cliutil.RegisterCommand(cmd)
+		if err != nil {
+			tb.Fatalf("clitest.RegisterFixtures: %v", err)
+		}
+	}
+	err := cliutil.BuildCommandTree()
+	if err != nil {
+		tb.Fatalf("clitest.RegisterFixtures: %v", err)
+	}
+	return cmds
+}
@@ -0,0 +1,109 @@
+// Package clitest provides helpers for testing applications built on cliutil,
+// such as golden-file comparison of help and usage output.
+package clitest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// UpdateFlagName is the `go test` flag that regenerates golden files, e.g.
+//
+//	go test ./... -update
+const UpdateFlagName = "update"
+
+// UpdateEnvVar regenerates golden files when set to a non-empty value, for
+// cases where passing a test flag is inconvenient.
+const UpdateEnvVar = "CLITEST_UPDATE"
+
+func init() {
+	// Tolerate an application test package that already defines -update
+	if flag.Lookup(UpdateFlagName) == nil {
+		flag.Bool(UpdateFlagName, false, "update golden files instead of comparing against them")
+	}
+}
+
+// updating returns true when golden files should be rewritten
+func updating() bool {
+	if os.Getenv(UpdateEnvVar) != "" {
+		return true
+	}
+	f := flag.Lookup(UpdateFlagName)
+	return f != nil && f.Value.String() == "true"
+}
+
+var multiSpaceRegex = regexp.MustCompile(` {2,}`)
+
+// NormalizeOutput makes rendered output stable for comparison by converting
+// CRLF to LF, trimming trailing whitespace from every line, collapsing runs of
+// spaces used for column padding to a single space, and trimming leading and
+// trailing blank lines. Column widths therefore don't cause spurious diffs.
+func NormalizeOutput(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		lines[i] = line[:indent] + multiSpaceRegex.ReplaceAllString(line[indent:], " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// Golden compares got, after NormalizeOutput, against the golden file at path.
+// When run with -update (or CLITEST_UPDATE=1) the golden file is written
+// instead, creating parent directories as needed.
+func Golden(t testing.TB, got string, path string) {
+	var want []byte
+	var err error
+
+	t.Helper()
+	got = NormalizeOutput(got)
+
+	if updating() {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(got), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("clitest.Golden: failed to update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("clitest.Golden: failed to read %s (run with -%s to create it): %v", path, UpdateFlagName, err)
+	}
+	if got == NormalizeOutput(string(want)) {
+		return
+	}
+	t.Errorf("clitest.Golden: output does not match %s (run with -%s to accept):\n%s",
+		path, UpdateFlagName, diffLines(NormalizeOutput(string(want)), got))
+}
+
+// diffLines returns a minimal line-oriented report of where want and got differ
+func diffLines(want, got string) string {
+	var sb strings.Builder
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	n := max(len(wantLines), len(gotLines))
+	for i := 0; i < n; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("line %d:\n  - %s\n  + %s\n", i+1, w, g))
+	}
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package clitest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// HelpSnapshotExt is the file extension of golden files written by HelpSnapshots
+const HelpSnapshotExt = ".golden"
+
+// HelpSnapshots renders the help of every visible registered command and
+// compares it against one golden file per command in dir, named after the
+// command's dotted path (e.g. "db.migrate.golden"). Each command runs as a
+// subtest so failures name the command whose help changed. Golden files for
+// commands that no longer exist are reported as failures, or removed when
+// run with -update. cliutil.Initialize must have been called beforehand, and
+// cliutil.SetCLIName should be, so help does not show the test binary's name.
+func HelpSnapshots(t *testing.T, dir string) {
+	t.Helper()
+	paths := VisibleCmdPaths()
+	if len(paths) == 0 {
+		t.Fatal("clitest.HelpSnapshots: no visible commands; was cliutil.Initialize called?")
+	}
+
+	want := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		file := path + HelpSnapshotExt
+		want[file] = struct{}{}
+		t.Run(path, func(t *testing.T) {
+			var buf bytes.Buffer
+			cmd := cliutil.GetExactCommand(path)
+			tmpl, err := cliutil.GetCmdHelpTemplate(cmd)
+			if err == nil {
+				err = tmpl.Execute(&buf, cliutil.BuildCmdUsage(cmd))
+			}
+			if err != nil {
+				t.Fatalf("failed to render help for %s: %v", path, err)
+			}
+			Golden(t, buf.String(), filepath.Join(dir, file))
+		})
+	}
+
+	// Snapshots whose command was removed or hidden are stale
+	existing, err := filepath.Glob(filepath.Join(dir, "*"+HelpSnapshotExt))
+	if err != nil {
+		t.Fatalf("clitest.HelpSnapshots: %v", err)
+	}
+	for _, file := range existing {
+		_, ok := want[filepath.Base(file)]
+		switch {
+		case ok:
+		case updating():
+			err = os.Remove(file)
+			if err != nil {
+				t.Errorf("clitest.HelpSnapshots: failed to remove stale %s: %v", file, err)
+			}
+		default:
+			t.Errorf("clitest.HelpSnapshots: %s has no matching command (run with -%s to remove it)",
+				file, UpdateFlagName)
+		}
+	}
+}
+
+// VisibleCmdPaths returns the dotted paths of all non-hidden commands in the
+// built command tree, sorted. Children of hidden commands are omitted too.
+func VisibleCmdPaths() (paths []string) {
+	var walk func(prefix string, cmds []cliutil.Command)
+	walk = func(prefix string, cmds []cliutil.Command) {
+		for _, cmd := range cmds {
+			if cmd.IsHidden() {
+				continue
+			}
+			path := cmd.Name()
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			paths = append(paths, path)
+			walk(path, cliutil.GetSubCmds(path))
+		}
+	}
+	walk("", cliutil.GetTopLevelCmds())
+	slices.Sort(paths)
+	return paths
+}
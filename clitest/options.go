@@ -0,0 +1,19 @@
+package clitest
+
+import (
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// ResetGlobalOptions resets cliutil's global options to their defaults when
+// the test ends, so flags the test parsed do not leak into later tests
+func ResetGlobalOptions(t testing.TB) {
+	t.Helper()
+	t.Cleanup(func() {
+		err := cliutil.ResetGlobalOptions()
+		if err != nil {
+			t.Errorf("ResetGlobalOptions() failed: %v", err)
+		}
+	})
+}
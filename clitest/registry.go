@@ -0,0 +1,29 @@
+package clitest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// isolateMu serializes tests that mutate cliutil's command registry
+var isolateMu sync.Mutex
+
+// IsolateRegistry gives t exclusive use of cliutil's command and global-flag
+// registries until t completes, then restores them to their prior state,
+// including the subcommands BuildCommandTree added to existing commands.
+// Call it after t.Parallel() in any test that registers throwaway commands
+// or flags; such tests then run one at a time while the rest of the suite
+// stays parallel. Parallel tests that do not call it may still see the
+// throwaway commands, so a test that depends on exactly which commands are
+// registered should call it too.
+func IsolateRegistry(t testing.TB) {
+	t.Helper()
+	isolateMu.Lock()
+	snap := cliutil.SnapshotRegistry()
+	t.Cleanup(func() {
+		cliutil.RestoreRegistry(snap)
+		isolateMu.Unlock()
+	})
+}
@@ -0,0 +1,300 @@
+package clitest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// RunFunc runs the CLI in-process with args (excluding the program name),
+// writing all output to w, and returns the process exit code.
+type RunFunc func(args []string, w cliutil.Writer) (exitCode int)
+
+var initializeOnce sync.Once
+
+// DefaultRun is a RunFunc that mirrors a typical main(): it parses global
+// options, resolves and runs the command, reports any error, and returns its
+// exit code. cliutil.Initialize is called on first use. Applications whose
+// main() builds custom Options or Config should supply their own RunFunc.
+func DefaultRun(args []string, w cliutil.Writer) (exitCode int) {
+	var opts *cliutil.GlobalOptions
+	var runner *cliutil.CmdRunner
+	var cmd cliutil.Command
+	var err error
+
+	initializeOnce.Do(func() {
+		err = cliutil.Initialize(w)
+	})
+	if err != nil {
+		cliutil.ReportError(w, err)
+		exitCode = cliutil.ExitUnknownRuntimeError
+		goto end
+	}
+	cliutil.SetWriter(w)
+
+	opts, args, err = cliutil.ParseGlobalOptions(append([]string{os.Args[0]}, args...))
+	if err != nil {
+		cliutil.ReportError(w, err)
+		exitCode = cliutil.ExitOptionsParseError
+		goto end
+	}
+
+	runner = cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context: context.Background(),
+		Writer:  w,
+		Options: opts,
+		Args:    args,
+	})
+	cmd, err = runner.ParseCmd(args)
+	if err == nil {
+		err = runner.RunCmd(cmd)
+	}
+	exitCode = runner.HandleErr(err)
+end:
+	return exitCode
+}
+
+// RunScripts runs every *.txtar file in dir as a subtest. Each archive's
+// leading comment is a script and its files are extracted into a fresh
+// working directory ($WORK) before the script runs. Supported commands:
+//
+//	exec <tool> [args...]  run the CLI in-process via run (the tool name is ignored)
+//	! exec <tool> [args...] like exec but expect a non-zero exit code
+//	status <code>          assert the exit code of the last exec
+//	stdout <regexp>        assert stdout of the last exec matches
+//	stderr <regexp>        assert stderr of the last exec matches
+//	! stdout|stderr <re>   assert the output does NOT match
+//	cmp stdout|stderr <file> assert the output equals a file (normalized)
+//	env KEY=VALUE          set an environment variable for later commands
+//	exists <file>          assert a file exists
+//
+// Lines beginning with # are comments. Arguments may be single-quoted, and
+// $VAR references are expanded from the environment.
+func RunScripts(t *testing.T, dir string, run RunFunc) {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.txtar"))
+	if err != nil {
+		t.Fatalf("clitest.RunScripts: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("clitest.RunScripts: no *.txtar scripts found in %s", dir)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".txtar")
+		t.Run(name, func(t *testing.T) {
+			runScriptFile(t, file, run)
+		})
+	}
+}
+
+// scriptState tracks the results of the most recent exec
+type scriptState struct {
+	t        *testing.T
+	run      RunFunc
+	workDir  string
+	stdout   string
+	stderr   string
+	exitCode int
+	ran      bool
+}
+
+func runScriptFile(t *testing.T, file string, run RunFunc) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read script: %v", err)
+	}
+	a := parseArchive(string(data))
+
+	st := &scriptState{t: t, run: run, workDir: t.TempDir()}
+	for _, f := range a.Files {
+		path := filepath.Join(st.workDir, filepath.FromSlash(f.Name))
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(f.Data), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("failed to extract %s: %v", f.Name, err)
+		}
+	}
+	t.Setenv("WORK", st.workDir)
+	t.Chdir(st.workDir)
+
+	for i, line := range strings.Split(a.Comment, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		st.runLine(i+1, line)
+	}
+}
+
+func (st *scriptState) runLine(lineNo int, line string) {
+	t := st.t
+	negate := false
+	if strings.HasPrefix(line, "!") {
+		negate = true
+		line = strings.TrimSpace(line[1:])
+	}
+	args, err := splitScriptArgs(line)
+	if err != nil {
+		t.Fatalf("line %d: %v", lineNo, err)
+	}
+	if len(args) == 0 {
+		t.Fatalf("line %d: missing command after '!'", lineNo)
+	}
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "exec":
+		st.exec(lineNo, args, negate)
+	case "status":
+		st.expectArgs(lineNo, cmd, args, 1)
+		want, err := strconv.Atoi(args[0])
+		if err != nil {
+			t.Fatalf("line %d: invalid status %q", lineNo, args[0])
+		}
+		if st.exitCode != want {
+			t.Errorf("line %d: expected exit code %d, got %d\nstderr:\n%s", lineNo, want, st.exitCode, st.stderr)
+		}
+	case "stdout", "stderr":
+		st.expectArgs(lineNo, cmd, args, 1)
+		st.match(lineNo, cmd, args[0], negate)
+	case "cmp":
+		st.expectArgs(lineNo, cmd, args, 2)
+		st.cmp(lineNo, args[0], args[1])
+	case "env":
+		st.expectArgs(lineNo, cmd, args, 1)
+		key, value, ok := strings.Cut(args[0], "=")
+		if !ok {
+			t.Fatalf("line %d: env requires KEY=VALUE, got %q", lineNo, args[0])
+		}
+		t.Setenv(key, value)
+	case "exists":
+		st.expectArgs(lineNo, cmd, args, 1)
+		_, err = os.Stat(args[0])
+		if (err == nil) == negate {
+			t.Errorf("line %d: exists %s: %v", lineNo, args[0], err)
+		}
+	default:
+		t.Fatalf("line %d: unknown script command %q", lineNo, cmd)
+	}
+}
+
+func (st *scriptState) expectArgs(lineNo int, cmd string, args []string, n int) {
+	if len(args) != n {
+		st.t.Fatalf("line %d: %s expects %d argument(s), got %d", lineNo, cmd, n, len(args))
+	}
+}
+
+func (st *scriptState) exec(lineNo int, args []string, negate bool) {
+	if len(args) == 0 {
+		st.t.Fatalf("line %d: exec requires a tool name", lineNo)
+	}
+	w := NewBufferedWriter()
+	st.exitCode = st.run(args[1:], w)
+	st.stdout = w.Stdout()
+	st.stderr = w.Stderr()
+	st.ran = true
+	switch {
+	case negate && st.exitCode == 0:
+		st.t.Errorf("line %d: expected %s to fail, but it succeeded\nstdout:\n%s", lineNo, strings.Join(args, " "), st.stdout)
+	case !negate && st.exitCode != 0:
+		st.t.Errorf("line %d: %s failed with exit code %d\nstderr:\n%s", lineNo, strings.Join(args, " "), st.exitCode, st.stderr)
+	}
+}
+
+func (st *scriptState) output(lineNo int, stream string) (out string) {
+	if !st.ran {
+		st.t.Fatalf("line %d: %s used before exec", lineNo, stream)
+	}
+	switch stream {
+	case "stdout":
+		out = st.stdout
+	case "stderr":
+		out = st.stderr
+	default:
+		st.t.Fatalf("line %d: expected stdout or stderr, got %q", lineNo, stream)
+	}
+	return out
+}
+
+func (st *scriptState) match(lineNo int, stream, pattern string, negate bool) {
+	re, err := regexp.Compile(`(?m)` + pattern)
+	if err != nil {
+		st.t.Fatalf("line %d: invalid regexp %q: %v", lineNo, pattern, err)
+	}
+	out := st.output(lineNo, stream)
+	if re.MatchString(out) == negate {
+		verb := "match"
+		if negate {
+			verb = "not match"
+		}
+		st.t.Errorf("line %d: expected %s to %s %q, got:\n%s", lineNo, stream, verb, pattern, out)
+	}
+}
+
+func (st *scriptState) cmp(lineNo int, stream, file string) {
+	want, err := os.ReadFile(file)
+	if err != nil {
+		st.t.Fatalf("line %d: %v", lineNo, err)
+	}
+	got := NormalizeOutput(st.output(lineNo, stream))
+	if got != NormalizeOutput(string(want)) {
+		st.t.Errorf("line %d: %s does not match %s:\n%s", lineNo, stream, file,
+			diffLines(NormalizeOutput(string(want)), got))
+	}
+}
+
+// splitScriptArgs splits a script line into words, honoring single quotes
+// and expanding $VAR references outside of quotes
+func splitScriptArgs(line string) (args []string, err error) {
+	var word, segment strings.Builder
+	var inQuote, inWord bool
+
+	// flushSegment appends the pending unquoted text to word, expanded
+	flushSegment := func() {
+		word.WriteString(os.ExpandEnv(segment.String()))
+		segment.Reset()
+	}
+
+	for _, r := range line {
+		switch {
+		case r == '\'' && inQuote:
+			inQuote = false
+		case r == '\'':
+			flushSegment()
+			inQuote = true
+			inWord = true
+		case !inQuote && (r == ' ' || r == '\t'):
+			if inWord {
+				flushSegment()
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case inQuote:
+			word.WriteRune(r)
+		default:
+			segment.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inQuote {
+		err = fmt.Errorf("unterminated quote in %q", line)
+		goto end
+	}
+	if inWord {
+		flushSegment()
+		args = append(args, word.String())
+	}
+end:
+	return args, err
+}
@@ -0,0 +1,127 @@
+package clitest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var _ cliutil.Terminal = (*FakeTerminal)(nil)
+
+// FakeTerminal is a cliutil.Terminal test double that records raw mode and
+// echo changes so interactive features can be tested without a PTY.
+type FakeTerminal struct {
+	mu      sync.Mutex
+	tty     bool
+	width   int
+	raw     bool
+	echo    bool
+	rawErr  error
+	echoErr error
+}
+
+// FakeTerminalArgs configures a FakeTerminal
+type FakeTerminalArgs struct {
+	TTY     bool  // Value returned by IsTTY()
+	Width   int   // Value returned by Width(); defaults to cliutil.DefaultTerminalWidth
+	RawErr  error // Error returned by MakeRaw()
+	EchoErr error // Error returned by SetEcho()
+}
+
+// NewFakeTerminal returns a FakeTerminal with echo initially enabled
+func NewFakeTerminal(args FakeTerminalArgs) *FakeTerminal {
+	if args.Width == 0 {
+		args.Width = cliutil.DefaultTerminalWidth
+	}
+	return &FakeTerminal{
+		tty:     args.TTY,
+		width:   args.Width,
+		echo:    true,
+		rawErr:  args.RawErr,
+		echoErr: args.EchoErr,
+	}
+}
+
+// UseTerminal installs term as cliutil's Terminal for the duration of the test
+func UseTerminal(t testing.TB, term cliutil.Terminal) {
+	t.Helper()
+	prev := cliutil.SetTerminal(term)
+	t.Cleanup(func() {
+		cliutil.SetTerminal(prev)
+	})
+}
+
+// UseTheme installs theme as cliutil's Theme for the duration of the test
+func UseTheme(t testing.TB, theme cliutil.Theme) {
+	t.Helper()
+	prev := cliutil.SetTheme(theme)
+	t.Cleanup(func() {
+		cliutil.SetTheme(prev)
+	})
+}
+
+func (ft *FakeTerminal) IsTTY() bool {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	return ft.tty
+}
+
+func (ft *FakeTerminal) Width() int {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	return ft.width
+}
+
+func (ft *FakeTerminal) MakeRaw() (restore func() error, err error) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	if ft.rawErr != nil {
+		return nil, ft.rawErr
+	}
+	ft.raw = true
+	return func() error {
+		ft.mu.Lock()
+		defer ft.mu.Unlock()
+		ft.raw = false
+		return nil
+	}, nil
+}
+
+func (ft *FakeTerminal) SetEcho(enabled bool) error {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	if ft.echoErr != nil {
+		return ft.echoErr
+	}
+	ft.echo = enabled
+	return nil
+}
+
+// SetTTY changes the value returned by IsTTY()
+func (ft *FakeTerminal) SetTTY(tty bool) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.tty = tty
+}
+
+// SetWidth changes the value returned by Width(), e.g. to simulate a resize
+func (ft *FakeTerminal) SetWidth(width int) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.width = width
+}
+
+// IsRaw reports whether the terminal is currently in raw mode
+func (ft *FakeTerminal) IsRaw() bool {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	return ft.raw
+}
+
+// EchoEnabled reports whether input echo is currently enabled
+func (ft *FakeTerminal) EchoEnabled() bool {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	return ft.echo
+}
@@ -0,0 +1,59 @@
+package clitest
+
+import (
+	"strings"
+)
+
+// archiveFile is one file section of a txtar archive
+type archiveFile struct {
+	Name string
+	Data string
+}
+
+// archive is a parsed txtar archive: a leading comment (the script) followed
+// by zero or more "-- name --" delimited files.
+type archive struct {
+	Comment string
+	Files   []archiveFile
+}
+
+// parseArchive parses the txtar format used by Go's testscript tooling
+func parseArchive(data string) (a archive) {
+	var sb strings.Builder
+	var current *archiveFile
+
+	flush := func() {
+		if current == nil {
+			a.Comment = sb.String()
+		} else {
+			current.Data = sb.String()
+			a.Files = append(a.Files, *current)
+		}
+		sb.Reset()
+	}
+
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.SplitAfter(data, "\n") {
+		name, ok := archiveMarker(line)
+		if !ok {
+			sb.WriteString(line)
+			continue
+		}
+		flush()
+		current = &archiveFile{Name: name}
+	}
+	flush()
+	return a
+}
+
+// archiveMarker returns the file name if line is a "-- name --" marker
+func archiveMarker(line string) (name string, ok bool) {
+	line = strings.TrimRight(line, "\n")
+	if !strings.HasPrefix(line, "-- ") || !strings.HasSuffix(line, " --") || len(line) < 7 {
+		goto end
+	}
+	name = strings.TrimSpace(line[3 : len(line)-3])
+	ok = name != ""
+end:
+	return name, ok
+}
@@ -0,0 +1,141 @@
+package cliutil
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the passage of time for timeout enforcement, retries,
+// spinners, and timing reports, so time-dependent behavior can be
+// unit-tested with a fake (see clitest.FakeClock) instead of sleeping.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns a channel that receives the current time once d elapses
+	After(d time.Duration) <-chan time.Time
+}
+
+// Package-level clock instance
+var (
+	clock   Clock        = realClock{}
+	clockMu sync.RWMutex // synchronizes access to clock
+)
+
+// SetClock replaces the Clock used by the framework (primarily for testing)
+// and returns the previous one so it can be restored.
+func SetClock(c Clock) (prev Clock) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	prev = clock
+	clock = c
+	return prev
+}
+
+// GetClock returns the Clock used by the framework
+func GetClock() Clock {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return clock
+}
+
+var _ Clock = realClock{}
+
+// realClock implements Clock using the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep waits for d on the current Clock, returning early with the context's
+// error if ctx is done first. Use it instead of time.Sleep in retry and
+// polling loops so tests can advance a fake clock.
+func Sleep(ctx context.Context, d time.Duration) (err error) {
+	select {
+	case <-GetClock().After(d):
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return err
+}
+
+// TimeoutContext is like context.WithTimeout except the timeout is measured
+// on the current Clock. When it expires the context's Err() and
+// context.Cause() are context.DeadlineExceeded, and its Deadline() is the
+// Clock's Now() plus d.
+func TimeoutContext(parent context.Context, d time.Duration) (ctx context.Context, cancel context.CancelFunc) {
+	var c *clockDeadlineCtx
+	var timeout <-chan time.Time
+
+	clk := GetClock()
+	if _, ok := clk.(realClock); ok {
+		ctx, cancel = context.WithDeadline(parent, time.Now().Add(d))
+		goto end
+	}
+	c = &clockDeadlineCtx{
+		deadline: clk.Now().Add(d),
+		done:     make(chan struct{}),
+	}
+	c.Context, c.cancelCause = context.WithCancelCause(parent)
+	if pd, ok := parent.Deadline(); ok && pd.Before(c.deadline) {
+		c.deadline = pd
+	}
+	timeout = clk.After(d)
+	go func() {
+		select {
+		case <-timeout:
+			c.cancel(context.DeadlineExceeded, context.DeadlineExceeded)
+		case <-parent.Done():
+			c.cancel(parent.Err(), context.Cause(parent))
+		case <-c.done:
+		}
+	}()
+	ctx, cancel = c, func() { c.cancel(context.Canceled, context.Canceled) }
+end:
+	return ctx, cancel
+}
+
+// clockDeadlineCtx is a context whose deadline is measured on a Clock that
+// context.WithDeadline cannot wait on, such as clitest.FakeClock. Its Done
+// channel is its own, so contexts derived from it take their Err from it,
+// and context.Cause finds the cause given to its embedded cancel context.
+type clockDeadlineCtx struct {
+	context.Context // Cancelable child of the parent, holding the cause
+	cancelCause     context.CancelCauseFunc
+	deadline        time.Time
+	done            chan struct{}
+	once            sync.Once
+	mu              sync.Mutex
+	err             error
+}
+
+func (c *clockDeadlineCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *clockDeadlineCtx) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *clockDeadlineCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// cancel ends c with err as its Err() and cause as its context.Cause()
+func (c *clockDeadlineCtx) cancel(err, cause error) {
+	c.once.Do(func() {
+		// The cause is set first, so it is there once Done is closed
+		c.cancelCause(cause)
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+	})
+}
@@ -2,10 +2,11 @@ package cliutil
 
 import (
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
 )
 
 // FlagType represents the type of a command flag
@@ -17,8 +18,42 @@ const (
 	BoolFlag
 	IntFlag
 	Int64Flag
+	DurationFlag
+	Float64Flag
+	StringsFlag
+	IntsFlag
+	MapFlag
+	CountFlag
 )
 
+// String returns the name of the flag's value type, e.g. "string"
+func (ft FlagType) String() string {
+	switch ft {
+	case StringFlag:
+		return "string"
+	case BoolFlag:
+		return "bool"
+	case IntFlag:
+		return "int"
+	case Int64Flag:
+		return "int64"
+	case DurationFlag:
+		return "duration"
+	case Float64Flag:
+		return "float64"
+	case StringsFlag:
+		return "strings"
+	case IntsFlag:
+		return "ints"
+	case MapFlag:
+		return "map"
+	case CountFlag:
+		return "count"
+	case UnknownFlagType:
+	}
+	return "unknown"
+}
+
 var _ Command = (*CmdBase)(nil)
 
 // CmdBase provides common functionality for all commands
@@ -40,9 +75,22 @@ type CmdBase struct {
 	order        int       // Display order in help (0=last, 1+=ordered)
 	flagName     string    // Flag name that triggers this command (e.g., "setup" for --setup)
 	hide         bool      // Hide from help output
+	deprecated   string    // What to use instead, if deprecated
+	resultTmpl   string    // Renders a ResultHandler's result as text
+	helpTmpl     string    // Renders the command's help instead of CmdUsageTemplateText
+	category     string    // Group of commands it is listed under in the main help
+	hooks        cmdHooks  // Hooks given in CmdArgs
+	positional   []string  // Positional arguments given to AssignArgs
+	minArgs      int       // Fewest positional arguments accepted
+	maxArgs      int       // Most positional arguments accepted, NoArgs for none, or 0 for no limit
+	registration cmdRegistration
 	CmdRunnerArgs
 }
 
+// NoArgs is the CmdArgs.MaxArgs of a command that takes no positional args,
+// since a MaxArgs of 0 means no limit
+const NoArgs = -1
+
 type CmdArgs struct {
 	Name         string
 	Usage        string
@@ -51,12 +99,24 @@ type CmdArgs struct {
 	FlagDefs     []FlagDef  // Legacy flag definitions (will be deprecated)
 	FlagSets     []*FlagSet // New FlagSet-based approach
 	ArgDefs      []*ArgDef  // Positional argument definitions
+	MinArgs      int        // OPTIONAL: fewest positional args accepted, beyond the Required ArgDefs
+	MaxArgs      int        // OPTIONAL: most positional args accepted, or NoArgs for none; 0 means no limit
 	Examples     []Example  // Custom examples
 	NoExamples   bool       // Do not display any examples
 	AutoExamples bool       // Display auto-generated examples even if custom are provided
 	Order        int        // Display order in help (0=last, 1+=ordered)
 	FlagName     string     // Flag name that triggers this command (e.g., "setup" for --setup)
 	Hide         bool       // Hide from help output
+	Deprecated   string     // OPTIONAL: what to use instead; running the command warns (see IsStrict)
+
+	PreRun            HookFunc // OPTIONAL: runs before the handler (see PreRunner)
+	PostRun           HookFunc // OPTIONAL: runs after the handler (see PostRunner)
+	PersistentPreRun  HookFunc // OPTIONAL: also runs before subcommands' handlers
+	PersistentPostRun HookFunc // OPTIONAL: also runs after subcommands' handlers
+
+	ResultTemplate string // OPTIONAL: text/template rendering a ResultHandler's result for --output=text
+	HelpTemplate   string // OPTIONAL: text/template rendering the command's help from its CmdUsage instead of CmdUsageTemplateText
+	Category       string // OPTIONAL: group listed under in the main help, e.g. "Admin Commands"; see CommandsHeading
 }
 
 // NewCmdBase creates a new command base
@@ -69,6 +129,8 @@ func NewCmdBase(args CmdArgs) *CmdBase {
 		flagsDefs:    args.FlagDefs,
 		flagSets:     args.FlagSets, // Static FlagSets (legacy)
 		argDefs:      args.ArgDefs,  // Positional argument definitions
+		minArgs:      args.MinArgs,
+		maxArgs:      args.MaxArgs,
 		delegateTo:   args.DelegateTo,
 		examples:     args.Examples,
 		noExamples:   args.NoExamples,
@@ -76,8 +138,18 @@ func NewCmdBase(args CmdArgs) *CmdBase {
 		order:        args.Order,
 		flagName:     args.FlagName,
 		hide:         args.Hide,
+		deprecated:   args.Deprecated,
+		resultTmpl:   args.ResultTemplate,
+		helpTmpl:     args.HelpTemplate,
+		category:     args.Category,
 		parentTypes:  make([]reflect.Type, 0),
 		subCommands:  make([]Command, 0),
+		hooks: cmdHooks{
+			preRun:            args.PreRun,
+			postRun:           args.PostRun,
+			persistentPreRun:  args.PersistentPreRun,
+			persistentPostRun: args.PersistentPostRun,
+		},
 	}
 }
 
@@ -86,24 +158,33 @@ func (c *CmdBase) Name() string {
 	return c.name
 }
 
-// CLIName returns the name of the CLI app
+// CLIName returns the name of the CLI app: the name given to its CLI's
+// SetName, or else its executable's name
 func (c *CmdBase) CLIName() string {
+	if name := c.registration.registry().Name(); name != "" {
+		return name
+	}
 	return c.cliName
 }
 
 // FullNames returns the command names prefixed with any parent names
 func (c *CmdBase) FullNames() (names []string) {
-	names = make([]string, len(c.parentTypes))
-	for i, t := range c.parentTypes {
-		parent := commandsTypeMap[t]
-		for _, pn := range parent.FullNames() {
-			names[i] = fmt.Sprintf("%s.%s", pn, c.name)
-		}
-	}
-	if len(names) == 0 {
-		names = []string{c.name}
+	cli := c.registration.registry()
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	names = c.registration.fullNames
+	if c.registration.gen != cli.registryGen || names == nil {
+		return cli.cmdFullNames(c.name, c.parentTypes)
 	}
-	return names
+	return slices.Clone(names)
+}
+
+func (c *CmdBase) cmdRegistration() *cmdRegistration {
+	return &c.registration
+}
+
+func (c *CmdBase) subCommandList() *[]Command {
+	return &c.subCommands
 }
 
 // Usage returns the command usage string
@@ -118,8 +199,14 @@ func (c *CmdBase) Description() string {
 	return c.description
 }
 
-// AddSubCommand returns the subcommands map
+// AddSubCommand adds cmd as a subcommand; adding the same command twice is a
+// no-op so the command tree can be rebuilt safely
 func (c *CmdBase) AddSubCommand(cmd Command) {
+	for _, sub := range c.subCommands {
+		if sub == cmd {
+			return
+		}
+	}
 	c.subCommands = append(c.subCommands, cmd)
 }
 
@@ -185,39 +272,71 @@ func (c *CmdBase) ParseFlagSets(args []string) (remainingArgs []string, err erro
 
 // AssignArgs assigns positional arguments to their defined config fields
 func (c *CmdBase) AssignArgs(args []string) (err error) {
-	var errs []error
+	var missing []string
+	var requiredCount int
+
+	c.positional = args
 
-	// Check if we have enough arguments for required ones
-	requiredCount := 0
-	for _, argDef := range c.argDefs {
-		if argDef.Required {
-			requiredCount++
+	// Check if we have enough arguments for required ones, naming every
+	// missing argument so users can fix the invocation in one pass
+	for i, argDef := range c.argDefs {
+		if !argDef.Required {
+			continue
+		}
+		requiredCount++
+		if i >= len(args) {
+			missing = append(missing, argDef.Name)
 		}
 	}
 
-	if len(args) < requiredCount {
-		err = fmt.Errorf("expected at least %d arguments, got %d", requiredCount, len(args))
+	if len(missing) > 0 {
+		err = NewErr(ErrTooFewArgs,
+			"required", requiredCount,
+			"given", len(args),
+			"missing", strings.Join(missing, ", "),
+		)
+		goto end
+	}
+
+	if len(args) < c.minArgs {
+		err = NewErr(ErrTooFewArgs,
+			"min", c.minArgs,
+			"given", len(args),
+		)
+		goto end
+	}
+
+	// Rather than ignore args beyond MaxArgs, name them so users see what
+	// the command did not expect
+	if c.maxArgs != 0 && len(args) > max(c.maxArgs, 0) {
+		err = NewErr(ErrTooManyArgs,
+			"max", max(c.maxArgs, 0),
+			"given", len(args),
+			"unexpected", strings.Join(args[max(c.maxArgs, 0):], " "),
+		)
 		goto end
 	}
 
 	// Assign available arguments
 	for i, argDef := range c.argDefs {
-		if i >= len(args) {
-			if argDef.Required {
-				errs = append(errs, fmt.Errorf("required argument '%s' missing", argDef.Name))
+		// A variadic argument takes the rest, or none, so no earlier run's
+		// args linger
+		if argDef.Remainder != nil {
+			*argDef.Remainder = nil
+			if i < len(args) {
+				*argDef.Remainder = slices.Clone(args[i:])
 			}
 			continue
 		}
+		if i >= len(args) {
+			continue
+		}
 
 		if argDef.String != nil {
 			*argDef.String = args[i]
 		}
 	}
 
-	if len(errs) > 0 {
-		err = errors.Join(errs...)
-	}
-
 end:
 	return err
 }
@@ -234,6 +353,12 @@ func (c *CmdBase) AutoExamples() bool {
 	return c.autoExamples
 }
 
+// PositionalArgs returns the positional arguments the command was invoked
+// with, including any beyond its ArgDefs
+func (c *CmdBase) PositionalArgs() []string {
+	return c.positional
+}
+
 func (c *CmdBase) ArgDefs() []*ArgDef {
 	return c.argDefs
 }
@@ -269,6 +394,35 @@ func (c *CmdBase) FlagName() string {
 	return c.flagName
 }
 
+// Deprecated returns what to use instead of the command, or the empty string
+// when it is not deprecated
+func (c *CmdBase) Deprecated() string {
+	return c.deprecated
+}
+
+// Category returns the group the command is listed under in the main help,
+// or the empty string for CommandsHeading
+func (c *CmdBase) Category() string {
+	return c.category
+}
+
+// HelpTemplate returns the template that renders the command's help, or the
+// empty string for CmdUsageTemplateText
+func (c *CmdBase) HelpTemplate() string {
+	return c.helpTmpl
+}
+
+// ResultTemplate returns the template that renders the command's result as
+// text, or the empty string for the default (see WriteResult)
+func (c *CmdBase) ResultTemplate() string {
+	return c.resultTmpl
+}
+
+// usesLegacyFlagDefs returns true when the command was given CmdArgs.FlagDefs
+func (c *CmdBase) usesLegacyFlagDefs() bool {
+	return len(c.flagsDefs) > 0
+}
+
 func (c *CmdBase) IsHidden() bool {
 	return c.hide
 }
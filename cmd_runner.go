@@ -2,50 +2,153 @@ package cliutil
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
 	"strings"
+	"text/template"
 
 	"github.com/mikeschinkel/go-dt/appinfo"
 	"github.com/mikeschinkel/go-dt/dtx"
 )
 
 type CmdRunner struct {
-	Args CmdRunnerArgs
+	Args       CmdRunnerArgs
+	middleware []Middleware // Added by Use
 }
 
 type CmdRunnerArgs struct {
-	AppInfo appinfo.AppInfo
-	Logger  *slog.Logger
-	Writer  Writer
-	Context context.Context
-	Config  Config
-	Options Options
-	Args    []string
+	AppInfo      appinfo.AppInfo
+	Logger       *slog.Logger
+	Writer       Writer
+	Context      context.Context
+	Config       Config
+	Options      Options
+	Args         []string
+	InvocationID string    // Identifies this run in logs; generated by RunCmd if empty
+	Stdin        io.Reader // OPTIONAL: defaults to os.Stdin
+	CLI          *CLI      // OPTIONAL: where commands are found; defaults to the default CLI
+	stdinUsed    bool      // Stdin was consumed by --args-from-stdin or a FromFile flag
+	warnings     *runWarnings
 }
 
+// cli returns the CLI commands are run in
+func (args CmdRunnerArgs) cli() *CLI {
+	return cliOrDefault(args.CLI)
+}
+
+// Warnf records a non-fatal warning for the run, writing it to the error
+// stream of the command's Writer. RunCmd summarizes the run's warnings when
+// the command completes, and fails it when --strict is set.
+func (args CmdRunnerArgs) Warnf(format string, a ...any) {
+	args.warnings.warnf(format, a...)
+}
+
+// Deprecatedf records a deprecation warning for the run, such as for a config
+// key the app renamed. It is written and summarized like a Warnf warning but,
+// under --strict, fails the run with ErrDeprecatedUsage (see Deprecatedf).
+func (args CmdRunnerArgs) Deprecatedf(format string, a ...any) {
+	args.warnings.deprecatedf(format, a...)
+}
+
+// IsInteractive returns true when a person is likely at the terminal (see
+// IsInteractive), so a command may prompt rather than fail or assume defaults
+func (args CmdRunnerArgs) IsInteractive() bool {
+	return IsInteractive()
+}
+
+// Confirm asks msg on the command's Writer, going ahead without asking when
+// --yes was given to the CLI it runs in (see CLI.Confirm)
+func (args CmdRunnerArgs) Confirm(msg string) bool {
+	return args.cli().Confirm(args.Writer, msg)
+}
+
+// NewEmitter returns an Emitter that writes records to the command's Writer,
+// by default in the format selected by --output as given to the CLI the
+// command runs in (see CLI.NewEmitter)
+func (args CmdRunnerArgs) NewEmitter(ea EmitterArgs) *Emitter {
+	return args.cli().NewEmitter(args.Writer, ea)
+}
+
+// Styled returns text in style for writing to out, following --no-color as
+// given to the CLI the command runs in (see CLI.Styled)
+func (args CmdRunnerArgs) Styled(out io.Writer, style Style, text string) string {
+	return args.cli().Styled(out, style, text)
+}
+
+// IsTerminal returns true when the command's output goes to a terminal rather
+// than a pipe or file (see IsPiped), so it may use color, spinners, or a pager
+func (args CmdRunnerArgs) IsTerminal() bool {
+	if args.Writer == nil {
+		return IsTerminal(os.Stdout)
+	}
+	return !IsPiped(args.Writer)
+}
+
+// NewCmdRunner returns a CmdRunner for args. When args.Logger is nil and a
+// Writer is given, the Logger is derived from the Writer (see
+// NewWriterHandler) at the level from GlobalOptions.LogLevel(), so -v and
+// --quiet affect logger output the same way they affect Writer output, unless
+// --log-level was given, which then decides alone.
 func NewCmdRunner(args CmdRunnerArgs) *CmdRunner {
+	if args.Logger == nil && args.Writer != nil {
+		level, given := logLevelOf(args.Options)
+		args.Logger = slog.New(NewWriterHandler(args.Writer, &WriterHandlerOptions{
+			Level:           level,
+			IgnoreVerbosity: given,
+			CLI:             args.CLI,
+		}))
+	}
+	if args.Stdin == nil {
+		args.Stdin = os.Stdin
+	}
 	return &CmdRunner{
 		Args: args,
 	}
 }
 
+// logLevelOf returns the log level selected by opts and whether it was given
+// with --log-level, or the level for the default verbosity when opts does not
+// expose GlobalOptions
+func logLevelOf(opts Options) (level slog.Level, given bool) {
+	getter, ok := opts.(GlobalOptionsGetter)
+	if ok && getter.GlobalOptions() != nil {
+		level = getter.GlobalOptions().LogLevel()
+		given = getter.GlobalOptions().LogLevelGiven()
+		goto end
+	}
+	level = LogLevelForVerbosity(Verbosity(DefaultVerbosity), DefaultQuiet)
+end:
+	return level, given
+}
+
 func (cr CmdRunner) ParseCmd(args []string) (cmd Command, err error) {
 	var path string
+	var errs []error
 
+	defer profile.end("ParseCmd", profile.begin())
 	if len(args) == 0 {
 		args = []string{"help"}
 	}
 	osArgs := args
 
+	// Completion scripts call the hidden completion command whether or not
+	// the app registered it, and pass the words in the environment
+	if args[0] == CompleteCmdName && cr.Args.cli().GetExactCommand(CompleteCmdName) == nil {
+		cmd = newCompleteCmd()
+		goto end
+	}
+
 	// Validate commands first
-	err = ValidateCmds()
+	err = cr.Args.cli().ValidateCmds()
 	if err != nil {
 		goto end
 	}
 
 	// Try to find the most specific command match
-	path, args = findBestCmdMatch(args)
+	path, args = cr.Args.cli().findBestCmdMatch(args)
 	if path == "" {
 		err = NewErr(
 			ErrUnknownCommand,
@@ -54,7 +157,7 @@ func (cr CmdRunner) ParseCmd(args []string) (cmd Command, err error) {
 		goto end
 	}
 
-	cmd, _ = GetDefaultCommand(path, args)
+	cmd, _ = cr.Args.cli().GetDefaultCommand(path, args)
 	if cmd == nil {
 		err = NewErr(
 			ErrCommandNotFound,
@@ -64,22 +167,50 @@ func (cr CmdRunner) ParseCmd(args []string) (cmd Command, err error) {
 		goto end
 	}
 
+	// Collect every flag, unknown-flag, and argument problem so users can fix
+	// an entire invocation in one pass rather than one error at a time
 	args, err = cmd.ParseFlagSets(args)
 	if err != nil {
-		err = NewErr(ErrFlagsParsingFailed)
-		goto end
+		errs = append(errs, NewErr(ErrFlagsParsingFailed, err))
+	}
+
+	// Fill flags not given from the environment or the config file
+	err = cr.Args.cli().applyConfig(cmd)
+	if err != nil {
+		errs = append(errs, err)
 	}
 
 	// Validate original flags against known flags
 	err = cr.validateFlags(cmd)
 	if err != nil {
-		goto end
+		errs = append(errs, err)
+		// Unknown flags were left in args; don't let them pose as positional args
+		args = withoutFlagArgs(args)
 	}
 
-	err = cmd.AssignArgs(args)
-	if err != nil {
-		err = NewErr(ErrAssigningArgsFailed)
-		goto end
+	// Args after ArgsTerminator are positional even if they start with "-"
+	args = withoutTerminator(args)
+
+	// With --args-from-stdin, RunCmd appends each line of stdin and parses
+	// again, so positional args are checked then
+	if !cr.argsFromStdin() {
+		err = cmd.AssignArgs(args)
+		if err != nil {
+			errs = append(errs, NewErr(ErrAssigningArgsFailed, err))
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		err = nil
+	case 1:
+		err = errs[0]
+	default:
+		// Keep the problems in a combined error so ReportError can list them
+		err = NewErr(ErrInvalidInvocation,
+			"problem_count", len(errs),
+			CombineErrs(errs),
+		)
 	}
 
 end:
@@ -94,12 +225,29 @@ end:
 
 func (cr CmdRunner) RunCmd(cmd Command) (err error) {
 	var handler CommandHandler
+	var ctxHandler ContextHandler
+	var resultHandler ResultHandler
+	var cancel context.CancelFunc
 	var ok bool
 	var args []string
+	var endSpan SpanEndFunc
+	var notifyUpdate func(Writer)
+	var stdinUsed bool
 
-	// Command resolution should ensure we only get CommandHandler implementations
+	// Startup is over once a command runs
+	_ = profile.report(os.Stderr)
+	start := GetClock().Now()
+
+	if cr.argsFromStdin() {
+		err = cr.runArgsFromStdin()
+		goto end
+	}
+
+	// Command resolution should ensure we only get handler implementations
+	ctxHandler, _ = cmd.(ContextHandler)
+	resultHandler, _ = cmd.(ResultHandler)
 	handler, ok = cmd.(CommandHandler)
-	if !ok {
+	if !ok && ctxHandler == nil && resultHandler == nil {
 		err = fmt.Errorf("command '%s' does not implement handler logic", cmd.Name())
 		goto end
 	}
@@ -109,14 +257,87 @@ func (cr CmdRunner) RunCmd(cmd Command) (err error) {
 	if cmd.Name() == "help" && len(args) != 0 && args[0] == "help" {
 		cr.Args.Args = args[1:]
 	}
-	handler.SetCommandRunnerArgs(cr.Args)
 
-	err = handler.Handle()
+	// Make the handler's log records attributable to this command and run
+	if cr.Args.InvocationID == "" {
+		cr.Args.InvocationID = NewInvocationID()
+	}
+	if cr.Args.Logger != nil {
+		cr.Args.Logger = decorateLogger(cr.Args.Logger, cmd, cr.Args.InvocationID)
+	}
+
+	stdinUsed, err = readFlagFiles(cmd, cr.Args)
+	if err != nil {
+		goto end
+	}
+	cr.Args.stdinUsed = cr.Args.stdinUsed || stdinUsed
+	err = readStdinArg(cmd, cr.Args)
+	if err != nil {
+		goto end
+	}
+
+	// Warnings are kept per run, so concurrent runs never report each other's
+	cr.Args.warnings = newRunWarnings(cr.Args.Writer)
+
+	// Under --strict, deprecated usage fails before the handler acts on it
+	err = checkDeprecations(cmd, cr.Args)
+	if err != nil {
+		goto end
+	}
+
+	// The handler's Context carries the command's span, if tracing
+	cr.Args.Context, endSpan = startCommandSpan(cr.Args.Context, cmd, cr.Args.InvocationID)
+	if ctxHandler != nil || resultHandler != nil {
+		// Only context-aware handlers are bound by --timeout
+		cr.Args.Context, cancel = cr.handlerContext()
+		defer cancel()
+	}
+	cmd.SetCommandRunnerArgs(cr.Args)
+
+	notifyUpdate = startUpdateCheck(cr.Args.Context, cr.Args.AppInfo)
+	err = cr.chain(func(ctx context.Context, cmd Command) error {
+		return cr.Args.cli().runWithHooks(cmd, func() error {
+			if resultHandler != nil {
+				return cr.handleResult(ctx, resultHandler)
+			}
+			if ctxHandler != nil {
+				return cr.timedOutErr(ctx, ctxHandler.HandleContext(ctx))
+			}
+			return handler.Handle()
+		})
+	})(cr.Args.Context, cmd)
+	endSpan(err)
+	notifyUpdate(cr.Args.Writer)
+
+	// Usage errors from handlers get the same hint as parse-time usage errors
+	if ErrClassOf(err) == UsageErr && !errors.Is(err, ErrShowUsage) {
+		err = WithErr(err, ErrShowUsage, "command", cmd.Name())
+	}
+
+	// Summarize any warnings recorded while handling; in --strict mode they
+	// fail an otherwise successful run
+	err = CombineErrs([]error{err, cr.Args.cli().reportRunWarnings(cr.Args.Writer, cr.Args.warnings)})
+	recordHistory(cmd, cr.Args, start, err)
 
 end:
 	return err
 }
 
+// ReportErr reports err via the runner's Writer, printing any attached
+// suggestions beneath the error message.
+func (cr CmdRunner) ReportErr(err error) {
+	cr.Args.cli().ReportError(cr.Args.Writer, err)
+}
+
+// HandleErr reports err according to its ErrClass and returns the exit code
+// the process should exit with, so main() can end with:
+//
+//	os.Exit(runner.HandleErr(err))
+func (cr CmdRunner) HandleErr(err error) int {
+	cr.ReportErr(err)
+	return ExitCode(err)
+}
+
 type GlobalOptionsGetter interface {
 	GlobalOptions() *GlobalOptions
 }
@@ -125,16 +346,12 @@ type GlobalOptionsGetter interface {
 func (cr CmdRunner) validateFlags(cmd Command) (err error) {
 	var getter GlobalOptionsGetter
 	var originalFlags []string
-	var knownFlags []string
-	var globalFlagSet *FlagSet
-	var cmdFlagSets []*FlagSet
-	var flagSet *FlagSet
+	var flagSets []*FlagSet
+	var fs *FlagSet
 	var unknownFlags []string
 	var flag string
 	var flagName string
-	var equalPos int
 	var isKnown bool
-	var known string
 	var flagList string
 
 	// Get original flags from options
@@ -148,31 +365,31 @@ func (cr CmdRunner) validateFlags(cmd Command) (err error) {
 		goto end
 	}
 
-	// Collect all known flag names
-	globalFlagSet = GetGlobalFlagSet()
-	if globalFlagSet != nil {
-		knownFlags = append(knownFlags, globalFlagSet.FlagNames()...)
-	}
-
-	cmdFlagSets = cmd.FlagSets()
-	for _, flagSet = range cmdFlagSets {
-		knownFlags = append(knownFlags, flagSet.FlagNames()...)
+	// Collect all FlagSets whose flags are known
+	if cr.Args.cli().GlobalFlagSet() != nil {
+		flagSets = append(flagSets, cr.Args.cli().GlobalFlagSet())
 	}
+	flagSets = append(flagSets, cmd.FlagSets()...)
 
 	// Check each original flag against known flags
 	for _, flag = range originalFlags {
+		if GetFlagSyntax() == PFlagSyntax {
+			if !isKnownPFlag(flag, flagSets) {
+				unknownFlags = append(unknownFlags, flag)
+			}
+			continue
+		}
+
 		// Extract flag name (remove - prefix and =value suffix)
 		flagName = strings.TrimPrefix(flag, "-")
 		flagName = strings.TrimPrefix(flagName, "-")
-		equalPos = strings.Index(flagName, "=")
-		if equalPos != -1 {
-			flagName = flagName[:equalPos]
-		}
+		flagName, _, _ = strings.Cut(flagName, "=")
 
 		// Check if flag is known
 		isKnown = false
-		for _, known = range knownFlags {
-			if known == flagName {
+		for _, fs = range flagSets {
+			_, isBundle := fs.goBundle(flag)
+			if fs.HasFlag(flagName) || isBundle {
 				isKnown = true
 				break
 			}
@@ -186,7 +403,10 @@ func (cr CmdRunner) validateFlags(cmd Command) (err error) {
 	// Report unknown flags
 	if len(unknownFlags) > 0 {
 		flagList = strings.Join(unknownFlags, ", ")
-		err = fmt.Errorf("unknown flag(s): %s", flagList)
+		err = NewErr(ErrUnknownFlags, "flags", flagList)
+		for _, flag = range unknownFlags {
+			err = withFlagSuggestion(err, flag, flagSets)
+		}
 		goto end
 	}
 
@@ -194,50 +414,69 @@ end:
 	return err
 }
 
-// findBestCmdMatch finds the longest matching command path
-func findBestCmdMatch(args []string) (path string, remainingArgs []string) {
-	var cmd Command
-	var tryPath string
-	var n int
-	tryPaths := make([]string, len(args))
-
-	for i, arg := range args {
-		if strings.HasPrefix(arg, "-") {
-			break
-		}
-		tryPath = fmt.Sprintf("%s.%s", tryPath, arg)
-		if i == 0 {
-			tryPath = strings.TrimLeft(tryPath, ".")
-		}
-		n++
-		tryPaths[len(tryPaths)-i-1] = tryPath
-	}
-	if n < len(args) {
-		tryPaths = tryPaths[len(tryPaths)-n:]
+// withoutTerminator returns args without the first ArgsTerminator, once
+// flags have been parsed and the args after it are positional
+func withoutTerminator(args []string) []string {
+	flagArgs, tail := splitAtTerminator(args)
+	if tail == nil {
+		return args
 	}
+	return append(flagArgs, tail[1:]...)
+}
 
-	// Try progressively longer paths
-	for _, p := range tryPaths {
-		cmd, p = GetDefaultCommand(p, args)
-		if cmd != nil {
-			path = p
-			remainingArgs = args[n:]
-			break
+// withoutFlagArgs returns args with any flags (see isFlagArg) before
+// ArgsTerminator removed
+func withoutFlagArgs(args []string) (positional []string) {
+	args, tail := splitAtTerminator(args)
+	for _, arg := range args {
+		if isFlagArg(arg) {
+			continue
 		}
-		n--
+		positional = append(positional, arg)
 	}
+	return append(positional, tail...)
+}
 
-	// If no match found, return empty path with original osArgs
-	if path == "" {
+// findBestCmdMatch finds the longest matching command path
+func (cli *CLI) findBestCmdMatch(args []string) (path string, remainingArgs []string) {
+	var cmd Command
+
+	cli.registryMu.RLock()
+	n := cli.matchCmdPath(args)
+	cli.registryMu.RUnlock()
+	if n == 0 {
+		// No match found, return empty path with original osArgs
 		remainingArgs = args
+		goto end
 	}
-
+	cmd, path = cli.GetDefaultCommand(strings.Join(args[:n], "."), args)
+	if cmd == nil {
+		path = ""
+		remainingArgs = args
+		goto end
+	}
+	remainingArgs = args[n:]
+end:
 	return path, remainingArgs
 }
 
 // ShowMainHelp displays the main help screen
-func ShowMainHelp(args UsageArgs) error {
-	return UsageTemplate.Execute(args.Writer.Writer(), BuildUsage(args))
+func ShowMainHelp(args UsageArgs) (err error) {
+	var tmpl *template.Template
+
+	tmpl, err = GetUsageTemplate()
+	if err != nil {
+		goto end
+	}
+	err = writeHelp(args.cli(), args.Writer.Writer(), newHelpKey(tmpl, "", args), func(w io.Writer) error {
+		// Stream rows to w as they render unless the template needs them all
+		if usesIndexing(tmpl) {
+			return executeTemplate(args.cli(), tmpl, w, args.Writer.Writer(), BuildUsage(args))
+		}
+		return executeTemplate(args.cli(), tmpl, w, args.Writer.Writer(), StreamUsage(args))
+	})
+end:
+	return err
 }
 
 // ShowCmdHelp displays help for a specific command
@@ -246,6 +485,7 @@ func ShowMainHelp(args UsageArgs) error {
 func ShowCmdHelp(cmdNameParts []string, args UsageArgs) (err error) {
 	var cmdName string
 	var cmd Command
+	var tmpl *template.Template
 
 	if len(cmdNameParts) == 0 {
 		err = fmt.Errorf("no command specified for help")
@@ -255,19 +495,25 @@ func ShowCmdHelp(cmdNameParts []string, args UsageArgs) (err error) {
 	// Build dot-notation path from parts
 	cmdName = strings.Join(cmdNameParts, ".")
 
-	cmd = GetExactCommand(cmdName)
+	cmd = args.cli().GetExactCommand(cmdName)
 	if cmd == nil {
-		err = fmt.Errorf("unknown command: %s", cmdName)
+		err = NewErr(ErrUnknownCommand, "command", cmdName)
 		goto end
 	}
 
 	// Hidden commands should not show help
 	if cmd.IsHidden() {
-		err = fmt.Errorf("unknown command: %s", cmdName)
+		err = NewErr(ErrUnknownCommand, "command", cmdName)
 		goto end
 	}
 
-	err = CmdUsageTemplate.Execute(args.Writer.Writer(), BuildCmdUsage(cmd))
+	tmpl, err = GetCmdHelpTemplate(cmd)
+	if err != nil {
+		goto end
+	}
+	err = writeHelp(args.cli(), args.Writer.Writer(), newHelpKey(tmpl, cmdName, args), func(w io.Writer) error {
+		return executeTemplate(args.cli(), tmpl, w, args.Writer.Writer(), BuildCmdUsage(cmd))
+	})
 
 end:
 	return err
@@ -0,0 +1,374 @@
+// Package cobracliutil converts an existing cobra command tree into cliutil
+// commands, so a large cobra CLI can migrate to cliutil one command at a
+// time:
+//
+//	_, err := cobracliutil.FromCobra(rootCmd)
+//	if err != nil {
+//		return err
+//	}
+//	err = cliutil.Initialize(writer)
+//
+// Each converted command keeps its cobra hooks and Run/RunE funcs; flags
+// parsed by cliutil are copied onto the cobra flags before they run.
+package cobracliutil
+
+import (
+	"errors"
+	"flag"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var ErrConversionFailed = errors.New("cobra command conversion failed")
+
+var _ cliutil.CommandTyper = (*Cmd)(nil)
+var _ cliutil.CommandHandler = (*handlerCmd)(nil)
+
+// Cmd is a cliutil.Command converted from a cobra.Command. Commands that
+// are runnable in cobra also implement cliutil.CommandHandler.
+type Cmd struct {
+	*cliutil.CmdBase
+	cobra   *cobra.Command
+	cmdType reflect.Type
+	flagSet *cliutil.FlagSet
+	args    []string
+}
+
+// handlerCmd is a Cmd whose cobra command has a Run or RunE func
+type handlerCmd struct {
+	*Cmd
+}
+
+// FromCobra registers root's subcommands, recursively, as cliutil commands
+// and returns them. Root itself is not converted because cliutil owns the
+// top level of the CLI, but its persistent flags are inherited by every
+// converted command. Cobra's own help command is skipped in favor of
+// cliutil's, and command aliases are registered as hidden commands.
+func FromCobra(root *cobra.Command) (cmds []cliutil.Command, err error) {
+	var errs []error
+
+	for _, child := range root.Commands() {
+		cmds, err = convert(child, nil, cmds)
+		errs = cliutil.AppendErr(errs, err)
+	}
+	err = cliutil.CombineErrs(errs)
+	if err != nil {
+		err = cliutil.WithErr(err, ErrConversionFailed, "root", root.Name())
+	}
+	return cmds, err
+}
+
+// convert registers cc and its subcommands as children of parent, which is
+// nil for top-level commands, appending them to cmds
+func convert(cc *cobra.Command, parent cliutil.Command, cmds []cliutil.Command) (_ []cliutil.Command, err error) {
+	var errs []error
+	var cmd cliutil.Command
+	var parents []cliutil.Command
+
+	if cc.Name() == "help" {
+		goto end
+	}
+	if parent != nil {
+		parents = []cliutil.Command{parent}
+	}
+
+	for i, name := range append([]string{cc.Name()}, cc.Aliases...) {
+		cmd = newCmd(cc, name, i > 0)
+		err = cliutil.RegisterCommand(cmd, parents...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cmds = append(cmds, cmd)
+		if i > 0 {
+			// Aliases run the command but don't repeat its subcommands
+			continue
+		}
+		for _, child := range cc.Commands() {
+			cmds, err = convert(child, cmd, cmds)
+			errs = cliutil.AppendErr(errs, err)
+		}
+	}
+end:
+	return cmds, cliutil.CombineErrs(errs)
+}
+
+// newCmd converts cc to a cliutil.Command named name
+func newCmd(cc *cobra.Command, name string, alias bool) (cmd cliutil.Command) {
+	c := &Cmd{
+		cobra:   cc,
+		cmdType: cliutil.NewCommandType(),
+		flagSet: &cliutil.FlagSet{Name: name},
+	}
+
+	// LocalFlags() and InheritedFlags() also merge in ancestors' persistent
+	// flags, so setting them through cc.Flags() reaches the ancestor's value
+	cc.LocalFlags().VisitAll(func(pf *pflag.Flag) {
+		c.flagSet.FlagDefs = appendFlagDef(c.flagSet.FlagDefs, pf)
+	})
+	cc.InheritedFlags().VisitAll(func(pf *pflag.Flag) {
+		c.flagSet.FlagDefs = appendFlagDef(c.flagSet.FlagDefs, pf)
+	})
+
+	usage := cc.Use
+	if alias {
+		usage = strings.Replace(usage, cc.Name(), name, 1)
+	}
+	description := cc.Short
+	if description == "" {
+		description = cc.Long
+	}
+	c.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        name,
+		Usage:       usage,
+		Description: description,
+		FlagSets:    []*cliutil.FlagSet{c.flagSet},
+		Examples:    examples(cc.Example),
+		NoExamples:  alias,
+		Hide:        alias || cc.Hidden || cc.Deprecated != "",
+	})
+
+	cmd = c
+	if cc.Runnable() {
+		cmd = &handlerCmd{Cmd: c}
+	}
+	return cmd
+}
+
+// CommandType identifies c in the cliutil registry, since every converted
+// command shares the Go type *Cmd
+func (c *Cmd) CommandType() reflect.Type {
+	return c.cmdType
+}
+
+// Cobra returns the cobra.Command c was converted from
+func (c *Cmd) Cobra() *cobra.Command {
+	return c.cobra
+}
+
+// AssignArgs records the positional args to pass to cobra's Run func
+func (c *Cmd) AssignArgs(args []string) error {
+	c.args = args
+	return c.CmdBase.AssignArgs(args)
+}
+
+// Handle copies the flags given on the command line to the cobra command,
+// validates its args, and runs its hooks and Run func in cobra's order
+func (c *handlerCmd) Handle() (err error) {
+	cc := c.cobra
+
+	if c.Context != nil {
+		cc.SetContext(c.Context)
+	}
+	if c.Writer != nil {
+		cc.SetOut(c.Writer.Writer())
+		cc.SetErr(c.Writer.ErrWriter())
+	}
+
+	err = c.applyFlags()
+	if err != nil {
+		err = cliutil.WithErrClass(err, cliutil.UsageErr)
+		goto end
+	}
+	err = cc.ValidateArgs(c.args)
+	if err != nil {
+		err = cliutil.WithErrClass(err, cliutil.UsageErr)
+		goto end
+	}
+	err = run(cc, c.args)
+end:
+	return err
+}
+
+// applyFlags sets each flag cliutil parsed from the command line on the
+// cobra command, which also marks it Changed
+func (c *Cmd) applyFlags() (err error) {
+	var errs []error
+
+	if c.flagSet.FlagSet == nil {
+		goto end
+	}
+	c.flagSet.FlagSet.Visit(func(f *flag.Flag) {
+		name := c.longName(f.Name)
+		errs = cliutil.AppendErr(errs, c.cobra.Flags().Set(name, f.Value.String()))
+	})
+	err = cliutil.CombineErrs(errs)
+end:
+	return err
+}
+
+// longName returns the long name of the flag whose name or shortcut is name
+func (c *Cmd) longName(name string) string {
+	for _, fd := range c.flagSet.FlagDefs {
+		if fd.Shortcut != 0 && string(fd.Shortcut) == name {
+			return fd.Name
+		}
+	}
+	return name
+}
+
+// run calls cc's hooks and Run func the way cobra's Execute does: the
+// nearest persistent pre-run, pre-run, run, post-run, then the nearest
+// persistent post-run
+func run(cc *cobra.Command, args []string) (err error) {
+	for p := cc; p != nil; p = p.Parent() {
+		if p.PersistentPreRunE != nil {
+			err = p.PersistentPreRunE(cc, args)
+			break
+		}
+		if p.PersistentPreRun != nil {
+			p.PersistentPreRun(cc, args)
+			break
+		}
+	}
+	if err != nil {
+		goto end
+	}
+	err = call(cc.PreRunE, cc.PreRun, cc, args)
+	if err != nil {
+		goto end
+	}
+	err = call(cc.RunE, cc.Run, cc, args)
+	if err != nil {
+		goto end
+	}
+	err = call(cc.PostRunE, cc.PostRun, cc, args)
+	if err != nil {
+		goto end
+	}
+	for p := cc; p != nil; p = p.Parent() {
+		if p.PersistentPostRunE != nil {
+			err = p.PersistentPostRunE(cc, args)
+			break
+		}
+		if p.PersistentPostRun != nil {
+			p.PersistentPostRun(cc, args)
+			break
+		}
+	}
+end:
+	return err
+}
+
+// call runs fnE if set, otherwise fn, as cobra prefers the E variants
+func call(fnE func(*cobra.Command, []string) error, fn func(*cobra.Command, []string), cc *cobra.Command, args []string) (err error) {
+	switch {
+	case fnE != nil:
+		err = fnE(cc, args)
+	case fn != nil:
+		fn(cc, args)
+	}
+	return err
+}
+
+// appendFlagDef appends a FlagDef for pf unless fds already defines it.
+// Bool, int, and int64 flags keep their type; all other pflag types are
+// parsed by cobra from the string given on the command line.
+func appendFlagDef(fds []cliutil.FlagDef, pf *pflag.Flag) []cliutil.FlagDef {
+	if pf.Name == "help" {
+		goto end
+	}
+	for _, fd := range fds {
+		if fd.Name == pf.Name {
+			goto end
+		}
+	}
+	fds = append(fds, flagDef(pf))
+end:
+	return fds
+}
+
+// flagDef converts pf to a cliutil.FlagDef
+func flagDef(pf *pflag.Flag) (fd cliutil.FlagDef) {
+	fd = cliutil.FlagDef{
+		Name:     pf.Name,
+		Usage:    pf.Usage,
+		Required: isRequired(pf),
+	}
+	if len(pf.Shorthand) == 1 {
+		fd.Shortcut = pf.Shorthand[0]
+	}
+	switch pf.Value.Type() {
+	case "bool":
+		value, _ := strconv.ParseBool(pf.DefValue)
+		fd.Bool, fd.Default = new(bool), value
+	case "int":
+		value, _ := strconv.Atoi(pf.DefValue)
+		fd.Int, fd.Default = new(int), value
+	case "count":
+		value, _ := strconv.Atoi(pf.DefValue)
+		fd.Count, fd.Default = new(int), value
+	case "int64":
+		value, _ := strconv.ParseInt(pf.DefValue, 10, 64)
+		fd.Int64, fd.Default = new(int64), value
+	case "float64":
+		value, _ := strconv.ParseFloat(pf.DefValue, 64)
+		fd.Float64, fd.Default = new(float64), value
+	case "duration":
+		value, _ := time.ParseDuration(pf.DefValue)
+		fd.Duration, fd.Default = new(time.Duration), value
+	case "stringSlice", "stringArray":
+		fd.Strings = new([]string)
+		if sv, ok := pf.Value.(pflag.SliceValue); ok && len(sv.GetSlice()) > 0 {
+			fd.Default = sv.GetSlice()
+		}
+	case "stringToString":
+		fd.Map = new(map[string]string)
+		if value := strings.Trim(pf.DefValue, "[]"); value != "" {
+			values := make(map[string]string)
+			for pair := range strings.SplitSeq(value, ",") {
+				k, v, _ := strings.Cut(pair, "=")
+				values[k] = v
+			}
+			fd.Default = values
+		}
+	case "intSlice":
+		fd.Ints = new([]int)
+		if sv, ok := pf.Value.(pflag.SliceValue); ok && len(sv.GetSlice()) > 0 {
+			var values []int
+			for _, s := range sv.GetSlice() {
+				n, _ := strconv.Atoi(s)
+				values = append(values, n)
+			}
+			fd.Default = values
+		}
+	default:
+		fd.String, fd.Default = new(string), pf.DefValue
+	}
+	return fd
+}
+
+// isRequired returns true if pf was marked with MarkFlagRequired
+func isRequired(pf *pflag.Flag) bool {
+	values := pf.Annotations[cobra.BashCompOneRequiredFlag]
+	return len(values) > 0 && values[0] == "true"
+}
+
+// examples converts cobra's free-form Example text to cliutil Examples,
+// using a "# comment" line as the description of the command after it
+func examples(text string) (examples []cliutil.Example) {
+	var descr string
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			descr = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		default:
+			examples = append(examples, cliutil.Example{
+				Descr: descr,
+				Cmd:   strings.TrimPrefix(line, "$ "),
+			})
+			descr = ""
+		}
+	}
+	return examples
+}
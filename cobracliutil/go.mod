@@ -0,0 +1,18 @@
+module github.com/mikeschinkel/go-cliutil/cobracliutil
+
+go 1.25.3
+
+replace github.com/mikeschinkel/go-cliutil => ..
+
+require (
+	github.com/mikeschinkel/go-cliutil v0.3.0
+	github.com/spf13/cobra v1.10.2
+	github.com/spf13/pflag v1.0.9
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/mikeschinkel/go-dt v0.3.3 // indirect
+	github.com/mikeschinkel/go-dt/appinfo v0.2.1 // indirect
+	github.com/mikeschinkel/go-dt/dtx v0.2.1 // indirect
+)
@@ -0,0 +1,73 @@
+package cliutil
+
+import (
+	"io"
+	"strings"
+)
+
+// Style is an ANSI SGR code, such as "1" for bold or "31" for red, used to
+// color output with Styled; combine them with Styles
+type Style string
+
+const (
+	NoStyle        Style = ""
+	BoldStyle      Style = "1"
+	DimStyle       Style = "2"
+	UnderlineStyle Style = "4"
+	RedStyle       Style = "31"
+	GreenStyle     Style = "32"
+	YellowStyle    Style = "33"
+	BlueStyle      Style = "34"
+	MagentaStyle   Style = "35"
+	CyanStyle      Style = "36"
+)
+
+// Styles combines styles into one Style, as in Styles(BoldStyle, RedStyle)
+func Styles(styles ...Style) Style {
+	codes := make([]string, 0, len(styles))
+	for _, s := range styles {
+		if s != NoStyle {
+			codes = append(codes, string(s))
+		}
+	}
+	return Style(strings.Join(codes, ";"))
+}
+
+// ColorEnabled returns true when output written to out may be colored under
+// the default CLI's --no-color (see CLI.ColorEnabled)
+func ColorEnabled(out io.Writer) bool {
+	return defaultCLI.ColorEnabled(out)
+}
+
+// ColorEnabled returns true when output written to out may be colored: out
+// is a terminal (see IsTerminal), --no-color was not given to cli, and the
+// Environment does not ask for no color, as NO_COLOR or TERM=dumb do.
+// Pass a Writer's Writer() or ErrWriter(), since either may be piped.
+func (cli *CLI) ColorEnabled(out io.Writer) (enabled bool) {
+	if cli.options.NoColor() {
+		goto end
+	}
+	if GetEnvironment().NoColor() {
+		goto end
+	}
+	enabled = IsTerminal(out)
+end:
+	return enabled
+}
+
+// Styled returns text in style for writing to out under the default CLI's
+// --no-color (see CLI.Styled)
+func Styled(out io.Writer, style Style, text string) string {
+	return defaultCLI.Styled(out, style, text)
+}
+
+// Styled returns text in style for writing to out, or text as is when color
+// is not enabled for out (see CLI.ColorEnabled), as in:
+//
+//	w.Printf("%s\n", cli.Styled(w.Writer(), cliutil.GreenStyle, "Done"))
+func (cli *CLI) Styled(out io.Writer, style Style, text string) string {
+	if style == NoStyle || text == "" || !cli.ColorEnabled(out) {
+		return text
+	}
+	return "\x1b[" + string(style) + "m" + text + "\x1b[0m"
+}
@@ -0,0 +1,61 @@
+package cliutil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Attribute keys CmdRunner adds to the logger passed to each command
+const (
+	LogKeyCommand      = "command"
+	LogKeyInvocationID = "invocation_id"
+	LogKeyFlags        = "flags"
+)
+
+// NewInvocationID returns a random 16-character hex ID identifying one run of
+// the CLI, so log lines from the same invocation can be correlated
+func NewInvocationID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// CmdPath returns cmd's path as typed on the command line, e.g. "db migrate"
+func CmdPath(cmd Command) (path string) {
+	names := cmd.FullNames()
+	if len(names) == 0 {
+		path = cmd.Name()
+		goto end
+	}
+	path = strings.ReplaceAll(names[0], ".", " ")
+end:
+	return path
+}
+
+// decorateLogger returns logger with the command path, invocation ID, and
+// sanitized flags attached so every record is attributable to this run
+func decorateLogger(logger *slog.Logger, cmd Command, invocationID string) *slog.Logger {
+	var flagAttrs []any
+
+	flags := SanitizedFlags(cmd)
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		flagAttrs = append(flagAttrs, slog.Any(name, flags[name]))
+	}
+
+	attrs := []any{
+		slog.String(LogKeyCommand, CmdPath(cmd)),
+		slog.String(LogKeyInvocationID, invocationID),
+	}
+	if len(flagAttrs) > 0 {
+		attrs = append(attrs, slog.Group(LogKeyFlags, flagAttrs...))
+	}
+	return logger.With(attrs...)
+}
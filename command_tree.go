@@ -0,0 +1,191 @@
+package cliutil
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// cmdNode is one name in the tree of command paths, so lookups take one
+// map access per path segment and child lists are computed once rather than
+// by scanning every registered path
+type cmdNode struct {
+	cmd      Command             // nil for a path segment with no command of its own
+	children map[string]*cmdNode // Keyed by command name
+	subCmds  []Command           // Commands of the children, in help order; see compareCmds
+}
+
+func newCmdNode() *cmdNode {
+	return &cmdNode{children: make(map[string]*cmdNode)}
+}
+
+// buildCmdTree returns the tree for the dot-separated paths in pathMap
+func buildCmdTree(pathMap map[string]Command) (root *cmdNode) {
+	var node, child *cmdNode
+
+	root = newCmdNode()
+	for path, cmd := range pathMap {
+		node = root
+		for _, name := range strings.Split(path, ".") {
+			child = node.children[name]
+			if child == nil {
+				child = newCmdNode()
+				node.children[name] = child
+			}
+			node = child
+		}
+		node.cmd = cmd
+	}
+	root.cacheSubCmds()
+	return root
+}
+
+// cacheSubCmds fills subCmds for n and all of its descendants
+func (n *cmdNode) cacheSubCmds() {
+	for _, child := range n.children {
+		child.cacheSubCmds()
+		if child.cmd != nil {
+			n.subCmds = append(n.subCmds, child.cmd)
+		}
+	}
+	slices.SortFunc(n.subCmds, compareCmds)
+}
+
+// compareCmds orders commands by Order() (1-N), then alphabetically by name
+// within each order. Commands with Order=0 (unspecified) come last.
+func compareCmds(a, b Command) int {
+	if a.Order() != b.Order() {
+		switch {
+		case a.Order() == 0:
+			return 1
+		case b.Order() == 0:
+			return -1
+		}
+		return a.Order() - b.Order()
+	}
+	return strings.Compare(a.Name(), b.Name())
+}
+
+// getCmdTree returns cmdTree, building it first if needed; the caller holds
+// registryMu for reading or writing
+func (cli *CLI) getCmdTree() (tree *cmdNode) {
+	tree = cli.cmdTree.Load()
+	if tree == nil {
+		// Concurrent readers may each build an identical tree; any of them
+		// can be kept
+		tree = buildCmdTree(cli.commandsPathMap)
+		cli.cmdTree.Store(tree)
+	}
+	return tree
+}
+
+// cmdFullNames returns the dot-separated paths of a command with the given
+// name and parent types, one per path through its parents; the caller holds
+// registryMu
+func (cli *CLI) cmdFullNames(name string, parentTypes []reflect.Type) (names []string) {
+	for _, t := range parentTypes {
+		parent, ok := cli.commandsTypeMap[t]
+		if !ok {
+			continue
+		}
+		for _, pn := range cli.cmdFullNames(parent.Name(), parent.ParentTypes()) {
+			names = append(names, pn+"."+name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{name}
+	}
+	return names
+}
+
+// resolveCmd adds cmd to its parents' subcommands and returns its full
+// names, resolving each parent first. Results are memoized by command in
+// resolved for the current build, since commands may share a type, and
+// cached in cmd's registration for FullNames. The caller holds registryMu
+// for writing.
+func (cli *CLI) resolveCmd(cmd Command, resolved map[Command][]string) (names []string, err error) {
+	var parent Command
+	var parentNames []string
+	var reg registrant
+	var ok bool
+
+	names, ok = resolved[cmd]
+	if ok {
+		goto end
+	}
+	for _, t := range cmd.ParentTypes() {
+		parent, ok = cli.commandsTypeMap[t]
+		if !ok {
+			err = fmt.Errorf("parent command type %s not found for command %s",
+				t.Name(), cmd.Name())
+			goto end
+		}
+		parentNames, err = cli.resolveCmd(parent, resolved)
+		if err != nil {
+			goto end
+		}
+		parent.AddSubCommand(cmd)
+		for _, pn := range parentNames {
+			names = append(names, pn+"."+cmd.Name())
+		}
+	}
+	if len(names) == 0 {
+		names = []string{cmd.Name()}
+	}
+	resolved[cmd] = names
+	reg, ok = cmd.(registrant)
+	if ok {
+		*reg.cmdRegistration() = cmdRegistration{
+			cmdType:   CommandTypeOf(cmd),
+			fullNames: names,
+			gen:       cli.registryGen,
+			cli:       cli,
+		}
+	}
+end:
+	return names, err
+}
+
+// registeredFullNames returns cmd's full names, from its registration cache
+// when still valid; the caller holds registryMu
+func (cli *CLI) registeredFullNames(cmd Command) []string {
+	reg, ok := cmd.(registrant)
+	if ok && reg.cmdRegistration().gen == cli.registryGen && reg.cmdRegistration().fullNames != nil {
+		return reg.cmdRegistration().fullNames
+	}
+	return cli.cmdFullNames(cmd.Name(), cmd.ParentTypes())
+}
+
+// findCmdNode returns the node for a dot-separated path, or nil; the caller
+// holds registryMu
+func (cli *CLI) findCmdNode(path string) (node *cmdNode) {
+	node = cli.getCmdTree()
+	for _, name := range strings.Split(path, ".") {
+		node = node.children[name]
+		if node == nil {
+			break
+		}
+	}
+	return node
+}
+
+// matchCmdPath returns how many of the leading non-flag args name a
+// registered command, walking the tree one arg at a time and keeping the
+// deepest match; the caller holds registryMu
+func (cli *CLI) matchCmdPath(args []string) (depth int) {
+	node := cli.getCmdTree()
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		node = node.children[arg]
+		if node == nil {
+			break
+		}
+		if node.cmd != nil {
+			depth = i + 1
+		}
+	}
+	return depth
+}
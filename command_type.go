@@ -0,0 +1,84 @@
+package cliutil
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// CommandTyper is implemented by commands that share a Go type with other
+// commands, such as those built at runtime by an adapter for another CLI
+// library. The registry identifies commands and their parents by type, so
+// each such command returns its own type from NewCommandType.
+type CommandTyper interface {
+	CommandType() reflect.Type
+}
+
+// CommandTypeOf returns the type the registry uses to identify cmd: its
+// CommandType() if it implements CommandTyper, otherwise its element type.
+// Registered commands that embed CmdBase return the type cached by
+// RegisterCommand.
+func CommandTypeOf(cmd Command) reflect.Type {
+	reg, ok := cmd.(registrant)
+	if ok && reg.cmdRegistration().cmdType != nil {
+		return reg.cmdRegistration().cmdType
+	}
+	return commandTypeOf(cmd)
+}
+
+func commandTypeOf(cmd Command) reflect.Type {
+	ct, ok := cmd.(CommandTyper)
+	if ok {
+		return ct.CommandType()
+	}
+	return reflect.TypeOf(cmd).Elem()
+}
+
+// cmdRegistration caches what the registry resolves for a command, so
+// building the tree and rendering help don't repeat reflection and parent
+// lookups. fullNames is only valid while gen matches registryGen.
+type cmdRegistration struct {
+	cmdType   reflect.Type // Set by RegisterCommand
+	fullNames []string     // Set by BuildCommandTree from the resolved parents
+	gen       uint64       // registryGen when fullNames was set
+	cli       *CLI         // Set by RegisterCommand
+}
+
+// registry returns the CLI the command was registered in, or the default
+// CLI when it has not been registered
+func (r *cmdRegistration) registry() *CLI {
+	return cliOrDefault(r.cli)
+}
+
+// cliOf returns the CLI cmd was registered in, or the default CLI when it
+// was not or does not embed *CmdBase
+func cliOf(cmd Command) *CLI {
+	reg, ok := cmd.(registrant)
+	if !ok {
+		return defaultCLI
+	}
+	return reg.cmdRegistration().registry()
+}
+
+// registrant is implemented by *CmdBase, and so by every command that
+// embeds it
+type registrant interface {
+	cmdRegistration() *cmdRegistration
+}
+
+// subCommandLister is implemented by commands embedding *CmdBase, so a
+// RegistrySnapshot can restore the subcommands BuildCommandTree added
+type subCommandLister interface {
+	subCommandList() *[]Command
+}
+
+var commandTypeSeq atomic.Int64
+
+// NewCommandType returns a type distinct from every other type, for use as
+// the CommandType() of a CommandTyper
+func NewCommandType() reflect.Type {
+	return reflect.StructOf([]reflect.StructField{{
+		Name: fmt.Sprintf("Cmd%d", commandTypeSeq.Add(1)),
+		Type: reflect.TypeOf(NULL{}),
+	}})
+}
@@ -4,15 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 )
 
-// Final resolved command maps (built during BuildCommandTree)
-var commands = make([]Command, 0)
-var commandsTypeMap = make(map[reflect.Type]Command)
-var commandsPathMap = make(map[string]Command)
-var flagCommandMap = make(map[string]Command)
-
 // Command interface for basic command metadata and delegation
 type Command interface {
 	CLIName() string
@@ -43,15 +38,22 @@ type CommandHandler interface {
 	Handle() error
 }
 
+// Initialize initializes the default CLI (see CLI.Initialize)
 func Initialize(w Writer) (err error) {
-	SetWriter(w)
+	return defaultCLI.Initialize(w)
+}
+
+// Initialize sets the CLI's Writer, then validates its commands and builds
+// their tree
+func (cli *CLI) Initialize(w Writer) (err error) {
+	cli.SetWriter(w)
 
-	err = ValidateCommands()
+	err = cli.ValidateCommands()
 	if err != nil {
 		goto end
 	}
 
-	err = BuildCommandTree()
+	err = cli.BuildCommandTree()
 	if err != nil {
 		goto end
 	}
@@ -60,53 +62,77 @@ end:
 	return err
 }
 
+// RegisteredCommands returns a copy of the commands registered in the
+// default CLI (see CLI.RegisteredCommands)
 func RegisteredCommands() (cmds []Command) {
-	return commands
+	return defaultCLI.RegisteredCommands()
+}
+
+// RegisteredCommands returns a copy of the registered commands in
+// registration order
+func (cli *CLI) RegisteredCommands() (cmds []Command) {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	return slices.Clone(cli.commands)
+}
+
+// RegisterCommand registers a command in the default CLI (see
+// CLI.RegisterCommand)
+func RegisterCommand(cmd Command, parents ...Command) (err error) {
+	return defaultCLI.RegisterCommand(cmd, parents...)
 }
 
 // RegisterCommand registers a command with optional parent type declarations
 // First argument is the actual command, remaining arguments are parent type prototypes
 // Example: RegisterCommand(&JobRunCmd{...}, &JobCmd{})
-func RegisterCommand(cmd Command, parents ...Command) (err error) {
+func (cli *CLI) RegisterCommand(cmd Command, parents ...Command) (err error) {
 	var errs []error
 	var parent Command
 	var flagName string
 	var globalFS *FlagSet
-	var fd FlagDef
+	var fs *FlagSet
+	var cmdType reflect.Type
+	var reg registrant
+	var ok bool
+
+	defer profile.endRegister(profile.begin())
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
 
 	for _, parent = range parents {
-		cmd.AddParent(reflect.TypeOf(parent).Elem())
+		cmd.AddParent(CommandTypeOf(parent))
+	}
+	cmdType = commandTypeOf(cmd)
+	reg, ok = cmd.(registrant)
+	if ok {
+		reg.cmdRegistration().cmdType = cmdType
+		reg.cmdRegistration().cli = cli
 	}
-	commands = append(commands, cmd)
-	commandsTypeMap[reflect.TypeOf(cmd).Elem()] = cmd
+	cli.commands = append(cli.commands, cmd)
+	cli.commandsTypeMap[cmdType] = cmd
+	cli.cmdTree.Store(nil)
+	cli.changed()
 
-	// Auto-register flag commands as global GlobalOptions
-	flagName = cmd.FlagName()
-	if flagName == "" {
+	// Validate: only the last ArgDef may take the remaining args
+	err = validateArgDefs(cmd.ArgDefs())
+	if err != nil {
+		err = WithErr(err, ErrCommandRegistrationFailed, "command_name", cmd.Name())
 		goto end
 	}
 
-	// Validate: Check for conflict with existing global flags
-	globalFS = GetGlobalFlagSet()
+	// Validate: command flags must not clash with global flags, which are
+	// parsed first and would silently take them over
+	globalFS = cli.flagSet
 	if globalFS != nil {
-		for _, fd = range globalFS.FlagDefs {
-			if fd.Name == flagName {
-				errs = append(errs, fmt.Errorf("FlagName '%s' conflicts with existing global flag '%s'",
-					flagName, fd.Name))
-			}
+		for _, fs = range cmd.FlagSets() {
+			errs = append(errs, globalFlagClashes(globalFS, fs)...)
 		}
 	}
 
-	// TODO: Add more validations here in Part 8
-
-	// Auto-register as global CLIOption so it appears in help
-	err = AddCLIOption(FlagDef{
-		Name:  flagName,
-		Usage: fmt.Sprintf("Run %s command", cmd.Name()),
-		Bool:  new(bool),
-	})
-	if err != nil {
-		errs = append(errs, err)
+	// Auto-register flag commands as global GlobalOptions
+	flagName = cmd.FlagName()
+	if flagName != "" {
+		errs = append(errs, cli.registerFlagCommand(cmd, flagName)...)
 	}
 
 	err = CombineErrs(errs)
@@ -121,50 +147,88 @@ end:
 
 var ErrCommandRegistrationFailed = errors.New("command registration failed")
 
+// globalFlagClashes returns an error for each flag in fs whose name or
+// shortcut is already used by a flag in globalFS
+func globalFlagClashes(globalFS, fs *FlagSet) (errs []error) {
+	for _, fd := range fs.FlagDefs {
+		for _, gfd := range globalFS.FlagDefs {
+			if fd.Name == gfd.Name {
+				errs = append(errs, fmt.Errorf("flag '--%s' in FlagSet '%s' conflicts with global flag '--%s'",
+					fd.Name, fs.Name, gfd.Name))
+			}
+			if fd.Shortcut != 0 && fd.Shortcut == gfd.Shortcut {
+				errs = append(errs, fmt.Errorf("flag '-%c' in FlagSet '%s' conflicts with global flag '--%s'",
+					fd.Shortcut, fs.Name, gfd.Name))
+			}
+		}
+	}
+	return errs
+}
+
+// registerFlagCommand adds the global flag that routes to cmd, such as
+// --setup for a command with FlagName "setup"; the caller holds registryMu
+func (cli *CLI) registerFlagCommand(cmd Command, flagName string) (errs []error) {
+	// Validate: Check for conflict with existing global flags
+	for _, fd := range cli.flagSet.FlagDefs {
+		if fd.Name == flagName {
+			errs = append(errs, fmt.Errorf("FlagName '%s' conflicts with existing global flag '%s'",
+				flagName, fd.Name))
+		}
+	}
+
+	// Auto-register as global CLIOption so it appears in help
+	err := cli.addCLIOption(FlagDef{
+		Name:  flagName,
+		Usage: fmt.Sprintf("Run %s command", cmd.Name()),
+		Bool:  new(bool),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// BuildCommandTree builds the default CLI's command hierarchy (see
+// CLI.BuildCommandTree)
+func BuildCommandTree() (err error) {
+	return defaultCLI.BuildCommandTree()
+}
+
 // BuildCommandTree builds the command hierarchy from registrations
 // This should be called by gmover.Initialize() after all init() functions complete
-func BuildCommandTree() (err error) {
-	//var topLevelCmds []Command
-	var parentCmd Command
-	var exists bool
+func (cli *CLI) BuildCommandTree() (err error) {
 	var cmd Command
 	var flagName string
-
-	// Second pass: build parent-child relationships
-	for _, cmd = range commands {
-		pts := cmd.ParentTypes()
-		if len(pts) == 0 {
-			// Top-level command
-			//topLevelCmds = append(topLevelCmds, cmd.cmd)
-			commandsPathMap[cmd.Name()] = cmd
-			continue
+	var names []string
+	var resolved map[Command][]string
+
+	defer profile.end("BuildCommandTree", profile.begin())
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+	cli.changed()
+
+	// Second pass: build parent-child relationships, resolving each command
+	// once and its parents before it
+	resolved = make(map[Command][]string, len(cli.commands))
+	for _, cmd = range cli.commands {
+		names, err = cli.resolveCmd(cmd, resolved)
+		if err != nil {
+			goto end
 		}
-		// Child command - add to all parents
-		for _, parentType := range pts {
-			parentCmd, exists = commandsTypeMap[parentType]
-			if !exists {
-				err = fmt.Errorf("parent command type %s not found for command %s",
-					parentType.Name(), cmd.Name())
-				goto end
-			}
-
-			// Add child to parent's SubCommands
-			parentCmd.AddSubCommand(cmd)
-
-			// Add to commands map with parent path prefix
-			for _, fn := range cmd.FullNames() {
-				commandsPathMap[fn] = cmd
-			}
+		// Add to commands map with parent path prefix
+		for _, fn := range names {
+			cli.commandsPathMap[fn] = cmd
 		}
 	}
 
 	// Build flag command map
-	for _, cmd = range commands {
+	for _, cmd = range cli.commands {
 		flagName = cmd.FlagName()
 		if flagName != "" {
-			flagCommandMap[flagName] = cmd
+			cli.flagCommandMap[flagName] = cmd
 		}
 	}
+	cli.cmdTree.Store(buildCmdTree(cli.commandsPathMap))
 
 end:
 	return err
@@ -172,17 +236,29 @@ end:
 
 type NULL = struct{}
 
+// ValidateCommands validates the default CLI's commands (see
+// CLI.ValidateCommands)
 func ValidateCommands() (err error) {
+	return defaultCLI.ValidateCommands()
+}
+
+// ValidateCommands checks the registered commands' FlagSets, flag
+// shortcuts, and FlagNames
+func (cli *CLI) ValidateCommands() (err error) {
 	var errs []error
 	var ok bool
 	var cmd Command
 	var fs *FlagSet
 	var fd FlagDef
 
+	defer profile.end("ValidateCommands", profile.begin())
 	flagSets := make(map[*FlagSet]struct{})
 
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+
 	// 1. Existing: Check for duplicate FlagDefs within FlagSets
-	for _, cmd = range commands {
+	for _, cmd = range cli.commands {
 		for _, fs = range cmd.FlagSets() {
 			fdNames := make(map[string]struct{})
 			_, ok = flagSets[fs]
@@ -203,7 +279,7 @@ func ValidateCommands() (err error) {
 	}
 
 	// 2. New: Validate single-dash flags are only one character
-	for _, cmd = range commands {
+	for _, cmd = range cli.commands {
 		for _, fs = range cmd.FlagSets() {
 			for _, fd = range fs.FlagDefs {
 				if fd.Shortcut != 0 && fd.Shortcut > 127 {
@@ -214,7 +290,7 @@ func ValidateCommands() (err error) {
 	}
 
 	// 3. New: Validate subcommands cannot have FlagName
-	for _, cmd = range commands {
+	for _, cmd = range cli.commands {
 		if len(cmd.ParentTypes()) > 0 && cmd.FlagName() != "" {
 			errs = append(errs, fmt.Errorf("command '%s': subcommands cannot have FlagName (only top-level commands can use flag routing)", cmd.Name()))
 		}
@@ -223,18 +299,35 @@ func ValidateCommands() (err error) {
 	return errors.Join(errs...)
 }
 
-// GetExactCommand retrieves a command at any depth using dot notation
+// GetExactCommand retrieves a command of the default CLI (see
+// CLI.GetExactCommand)
 func GetExactCommand(path string) Command {
-	return commandsPathMap[path]
+	return defaultCLI.GetExactCommand(path)
 }
 
-// GetDefaultCommand retrieves a command or its default at any depth using dot notation
+// GetExactCommand retrieves a command at any depth using dot notation
+func (cli *CLI) GetExactCommand(path string) Command {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	return cli.commandsPathMap[path]
+}
+
+// GetDefaultCommand retrieves a command of the default CLI or its default
+// (see CLI.GetDefaultCommand)
 func GetDefaultCommand(path string, args []string) (cmd Command, _ string) {
+	return defaultCLI.GetDefaultCommand(path, args)
+}
+
+// GetDefaultCommand retrieves a command or its default at any depth using dot notation
+func (cli *CLI) GetDefaultCommand(path string, args []string) (cmd Command, _ string) {
 	var defaultCmd Command
 	var delegateType reflect.Type
 	var exists bool
 
-	cmd = GetExactCommand(path)
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+
+	cmd = cli.commandsPathMap[path]
 	if cmd == nil {
 		goto end
 	}
@@ -260,11 +353,11 @@ func GetDefaultCommand(path string, args []string) (cmd Command, _ string) {
 
 	// Delegate to a default subcommand
 	// Look up delegate by type
-	delegateType = reflect.TypeOf(cmd.DelegateTo()).Elem()
-	defaultCmd, exists = commandsTypeMap[delegateType]
+	delegateType = CommandTypeOf(cmd.DelegateTo())
+	defaultCmd, exists = cli.commandsTypeMap[delegateType]
 	if exists {
 		cmd = defaultCmd
-		for _, p := range cmd.FullNames() {
+		for _, p := range cli.registeredFullNames(cmd) {
 			if !strings.HasPrefix(p, path) {
 				continue
 			}
@@ -277,36 +370,50 @@ end:
 	return cmd, path
 }
 
-// GetTopLevelCmds returns all top-level commands sorted by name
+// GetTopLevelCmds returns the default CLI's top-level commands (see
+// CLI.GetTopLevelCmds)
 func GetTopLevelCmds() []Command {
-	var topCmds []Command
-	for name, cmd := range commandsPathMap {
-		if !strings.Contains(name, ".") {
-			topCmds = append(topCmds, cmd)
-		}
-	}
-	return topCmds
+	return defaultCLI.GetTopLevelCmds()
 }
 
-// GetSubCmds returns all subcommands for a given path
-func GetSubCmds(path string) []Command {
-	var subCmds []Command
-	prefix := path + "."
-	for name, cmd := range commandsPathMap {
-		if strings.HasPrefix(name, prefix) {
-			// Only include direct children, not grandchildren
-			remaining := strings.TrimPrefix(name, prefix)
-			if !strings.Contains(remaining, ".") {
-				subCmds = append(subCmds, cmd)
-			}
-		}
+// GetTopLevelCmds returns all top-level commands in help order: by Order(),
+// then by name, with unordered commands last. The order is computed once per
+// registration change; each call returns a copy the caller may modify.
+func (cli *CLI) GetTopLevelCmds() []Command {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	return slices.Clone(cli.getCmdTree().subCmds)
+}
+
+// GetSubCmds returns the direct subcommands of a command of the default CLI
+// (see CLI.GetSubCmds)
+func GetSubCmds(path string) (subCmds []Command) {
+	return defaultCLI.GetSubCmds(path)
+}
+
+// GetSubCmds returns the direct subcommands for a dot-separated path, in the
+// same order as GetTopLevelCmds, as a copy the caller may modify
+func (cli *CLI) GetSubCmds(path string) (subCmds []Command) {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	node := cli.findCmdNode(path)
+	if node != nil {
+		subCmds = slices.Clone(node.subCmds)
 	}
 	return subCmds
 }
 
-// ValidateCmds ensures all registered commands have handlers
+// ValidateCmds ensures all commands registered in the default CLI have
+// handlers (see CLI.ValidateCmds)
 func ValidateCmds() (err error) {
-	return validateCmdTree(commandsPathMap, "")
+	return defaultCLI.ValidateCmds()
+}
+
+// ValidateCmds ensures all registered commands have handlers
+func (cli *CLI) ValidateCmds() (err error) {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	return validateCmdTree(cli.commandsPathMap, "")
 }
 
 // validateCmdTree recursively validates the command tree
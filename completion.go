@@ -0,0 +1,284 @@
+package cliutil
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Environment variables the completion scripts pass the command line in, so
+// flags being completed are not parsed as flags of the completion command
+const (
+	CompleteArgsEnv = "CLIUTIL_COMPLETE_ARGS" // Words before the one being completed, one per line
+	CompleteWordEnv = "CLIUTIL_COMPLETE_WORD" // Word being completed, possibly empty
+)
+
+// CompletionArgs configures WriteCompletion
+type CompletionArgs struct {
+	Command string // Name of the CLI as users type it, e.g. "myapp"
+}
+
+// CompleteFunc returns the completion candidates for a flag's or argument's
+// value starting with prefix, e.g. the names of existing jobs. Candidates not
+// starting with prefix are dropped.
+type CompleteFunc func(prefix string) []string
+
+// Complete returns the candidates for word from the default CLI (see
+// CLI.Complete)
+func Complete(args []string, word string) (candidates []string) {
+	return defaultCLI.Complete(args, word)
+}
+
+// Complete returns the candidates for word, the word being completed after
+// the words in args (which exclude the CLI's own name). Words starting with
+// "-" complete to the flags of the command named by args and the global
+// flags; other words complete to the visible subcommands of that command and
+// to values from the Complete func of the ArgDef at that position. The value
+// of a flag, given as the next word or after "=", completes from the FlagDef's
+// Complete func; without one there are no candidates, so shells fall back to
+// completing file names.
+func (cli *CLI) Complete(args []string, word string) (candidates []string) {
+	var complete CompleteFunc
+	var prefix string
+
+	cli.registryMu.RLock()
+	candidates, complete, prefix = cli.completeStatic(args, word)
+	cli.registryMu.RUnlock()
+
+	// Callbacks run unlocked, so they may use the registry themselves
+	if complete == nil {
+		goto end
+	}
+	for _, value := range complete(word[len(prefix):]) {
+		if strings.HasPrefix(value, word[len(prefix):]) {
+			candidates = append(candidates, prefix+value)
+		}
+	}
+end:
+	return candidates
+}
+
+// completeStatic returns the candidates for word known from the command tree,
+// and the func completing values for it, if any, with the prefix of word
+// that precedes the value, e.g. "--job=". The caller holds registryMu.
+func (cli *CLI) completeStatic(args []string, word string) (candidates []string, complete CompleteFunc, prefix string) {
+	var cmd Command
+	var fd *FlagDef
+	var depth int
+
+	node := cli.getCmdTree()
+	for i, arg := range args {
+		if isFlagArg(arg) {
+			continue
+		}
+		child := node.children[arg]
+		if child == nil {
+			break
+		}
+		node, depth = child, i+1
+		if child.cmd != nil {
+			cmd = child.cmd
+		}
+	}
+	if len(args) > 0 {
+		fd = cli.valueFlag(cmd, args[len(args)-1])
+	}
+	if fd != nil {
+		complete = fd.Complete
+		goto end
+	}
+	if isFlagArg(word) {
+		name, _, hasValue := strings.Cut(word, "=")
+		if !hasValue {
+			candidates = cli.completeFlags(cmd, word)
+			goto end
+		}
+		fd = cli.valueFlag(cmd, name)
+		if fd != nil {
+			complete, prefix = fd.Complete, name+"="
+		}
+		goto end
+	}
+	for _, sub := range node.subCmds {
+		if sub.IsHidden() || !strings.HasPrefix(sub.Name(), word) {
+			continue
+		}
+		candidates = append(candidates, sub.Name())
+	}
+	if cmd != nil {
+		complete = argCompleteFunc(cmd, cli.positionalCount(cmd, args[depth:]))
+	}
+end:
+	return candidates, complete, prefix
+}
+
+// completionFlagSets returns the global FlagSet and cmd's; the caller holds
+// registryMu
+func (cli *CLI) completionFlagSets(cmd Command) (flagSets []*FlagSet) {
+	if cli.flagSet != nil {
+		flagSets = append(flagSets, cli.flagSet)
+	}
+	if cmd != nil {
+		flagSets = append(flagSets, cmd.FlagSets()...)
+	}
+	return flagSets
+}
+
+// valueFlag returns the FlagDef of cmd or the global flags that arg names
+// when it takes its value from the next word, or nil; the caller holds
+// registryMu
+func (cli *CLI) valueFlag(cmd Command, arg string) *FlagDef {
+	if !isFlagArg(arg) || strings.Contains(arg, "=") {
+		return nil
+	}
+	name := strings.TrimLeft(arg, "-")
+	for _, fs := range cli.completionFlagSets(cmd) {
+		for i, fd := range fs.FlagDefs {
+			if fd.Name != name && (len(name) != 1 || fd.Shortcut != name[0]) {
+				continue
+			}
+			if fd.Type() == BoolFlag || fd.Type() == CountFlag {
+				return nil
+			}
+			return &fs.FlagDefs[i]
+		}
+	}
+	return nil
+}
+
+// positionalCount returns how many of args, which follow cmd's path, are
+// positional rather than flags or flag values; the caller holds registryMu
+func (cli *CLI) positionalCount(cmd Command, args []string) (n int) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case !isFlagArg(args[i]):
+			n++
+		case cli.valueFlag(cmd, args[i]) != nil:
+			i++ // Skip the flag's value
+		}
+	}
+	return n
+}
+
+// argCompleteFunc returns the Complete func of cmd's ArgDef at position i,
+// which is the last one's from there on if it is variadic, or nil; the
+// caller holds registryMu
+func argCompleteFunc(cmd Command, i int) CompleteFunc {
+	argDefs := cmd.ArgDefs()
+	if i >= len(argDefs) && len(argDefs) > 0 && argDefs[len(argDefs)-1].IsVariadic() {
+		i = len(argDefs) - 1
+	}
+	if i >= len(argDefs) {
+		return nil
+	}
+	return argDefs[i].Complete
+}
+
+// completeFlags returns the long flags of cmd and the global flags starting
+// with word; the caller holds registryMu
+func (cli *CLI) completeFlags(cmd Command, word string) (candidates []string) {
+	for _, fs := range cli.completionFlagSets(cmd) {
+		for _, fd := range fs.FlagDefs {
+			flag := "--" + fd.Name
+			if !fd.IsDeprecated() && strings.HasPrefix(flag, word) {
+				candidates = append(candidates, flag)
+			}
+		}
+	}
+	return candidates
+}
+
+// WriteCompletion writes a completion script for shell that asks the CLI's
+// hidden "__complete" command for candidates; see EnableBuiltins. Users
+// install it from their shell's startup file, e.g.:
+//
+//	source <(myapp completion bash)        # bash
+//	source <(myapp completion zsh)         # zsh
+//	myapp completion fish | source         # fish
+//	myapp completion powershell | Out-String | Invoke-Expression  # PowerShell
+func WriteCompletion(w io.Writer, shell Shell, args CompletionArgs) (err error) {
+	var tmpl *template.Template
+	var name, text string
+
+	if !completionCmdRegex.MatchString(args.Command) {
+		err = NewErr(ErrInvalidShell, "command", args.Command, "rule", "may contain only letters, numbers, '.', '-', and '_'")
+		goto end
+	}
+	switch shell {
+	case BashShell:
+		name, text = "bash_completion", bashCompletionText
+	case ZshShell:
+		name, text = "zsh_completion", zshCompletionText
+	case FishShell:
+		name, text = "fish_completion", fishCompletionText
+	case PowerShellShell:
+		name, text = "powershell_completion", powerShellCompletionText
+	default:
+		_, err = ParseCompletionShell(string(shell))
+		goto end
+	}
+	tmpl, err = parseTemplate(name, text)
+	if err != nil {
+		goto end
+	}
+	err = tmpl.Execute(w, map[string]string{
+		"Func":     nonIdentRegex.ReplaceAllString(args.Command, "_"),
+		"Command":  args.Command,
+		"Complete": CompleteCmdName,
+		"ArgsEnv":  CompleteArgsEnv,
+		"WordEnv":  CompleteWordEnv,
+	})
+end:
+	return err
+}
+
+var (
+	completionCmdRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	nonIdentRegex      = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+const bashCompletionText = `_{{.Func}}_complete() {
+	local IFS=$'\n'
+	COMPREPLY=($({{.ArgsEnv}}="$(printf '%s\n' "${COMP_WORDS[@]:1:COMP_CWORD-1}")" {{.WordEnv}}="${COMP_WORDS[COMP_CWORD]}" command {{.Command}} {{.Complete}} 2>/dev/null))
+}
+complete -o default -F _{{.Func}}_complete {{.Command}}
+`
+
+const zshCompletionText = `#compdef {{.Command}}
+_{{.Func}}_complete() {
+	local -a candidates
+	candidates=(${(f)"$({{.ArgsEnv}}="${(F)words[2,CURRENT-1]}" {{.WordEnv}}="${words[CURRENT]}" command {{.Command}} {{.Complete}} 2>/dev/null)"})
+	compadd -a candidates
+}
+compdef _{{.Func}}_complete {{.Command}}
+`
+
+const fishCompletionText = `function __{{.Func}}_complete
+	set -l tokens (commandline -opc)
+	set -e tokens[1]
+	set -lx {{.ArgsEnv}} (string join \n -- $tokens | string collect)
+	set -lx {{.WordEnv}} (commandline -ct)
+	command {{.Command}} {{.Complete}} 2>/dev/null
+end
+complete -c {{.Command}} -f -a '(__{{.Func}}_complete)'
+`
+
+// powerShellCompletionText passes the words before the cursor, which are the
+// command elements ending before it, less the command itself
+const powerShellCompletionText = `Register-ArgumentCompleter -Native -CommandName '{{.Command}}' -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
+	$words = @($commandAst.CommandElements | Select-Object -Skip 1 |
+		Where-Object { $_.Extent.EndOffset -lt $cursorPosition } |
+		ForEach-Object { $_.ToString() })
+	$env:{{.ArgsEnv}} = $words -join "` + "`" + `n"
+	$env:{{.WordEnv}} = $wordToComplete
+	try {
+		& '{{.Command}}' {{.Complete}} 2>$null | ForEach-Object {
+			[System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+		}
+	} finally {
+		Remove-Item Env:{{.ArgsEnv}}, Env:{{.WordEnv}} -ErrorAction SilentlyContinue
+	}
+}
+`
@@ -1,7 +1,444 @@
 package cliutil
 
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
 // Config represents any config object that can be passed to commands
 
 type Config interface {
 	Config()
 }
+
+// ErrLoadingConfigFile is returned when the file named by --config, or the
+// first of ConfigArgs.Files that exists, cannot be read or decoded
+var ErrLoadingConfigFile = newMessageErr(MsgLoadingConfigFile)
+
+// ErrUnsupportedConfigFormat is returned for a config file whose extension
+// has no ConfigDecoder; see RegisterConfigFormat
+var ErrUnsupportedConfigFormat = errors.New("unsupported config file format")
+
+// ConfigDecoder decodes the contents of a config file into its keys and
+// values. Keys naming a global flag set that flag; a key naming a command
+// holds a nested map of that command's flags, and of its subcommands:
+//
+//	{"verbosity": 2, "db": {"host": "localhost", "migrate": {"steps": 3}}}
+type ConfigDecoder func(data []byte) (map[string]any, error)
+
+var (
+	configDecoders = map[string]ConfigDecoder{
+		".json": decodeJSONConfig,
+	}
+	configDecodersMu sync.RWMutex // synchronizes access to configDecoders
+)
+
+// RegisterConfigFormat makes config files with extension ext, such as
+// ".yaml", decodable by decode, replacing any decoder for ext. JSON is built
+// in; yamlcliutil registers YAML and tomlcliutil registers TOML.
+func RegisterConfigFormat(ext string, decode ConfigDecoder) {
+	configDecodersMu.Lock()
+	defer configDecodersMu.Unlock()
+	configDecoders[strings.ToLower(ext)] = decode
+}
+
+// configDecoder returns the decoder for the extension of path, if any
+func configDecoder(path string) (decode ConfigDecoder, ok bool) {
+	configDecodersMu.RLock()
+	defer configDecodersMu.RUnlock()
+	decode, ok = configDecoders[strings.ToLower(filepath.Ext(path))]
+	return decode, ok
+}
+
+// decodeJSONConfig decodes a JSON object
+func decodeJSONConfig(data []byte) (values map[string]any, err error) {
+	err = json.Unmarshal(data, &values)
+	return values, err
+}
+
+// ConfigFlagName is the name of the global flag EnableConfig adds
+const ConfigFlagName = "config"
+
+// ConfigArgs configures EnableConfig
+type ConfigArgs struct {
+	Files []string // OPTIONAL: looked for, in order, when --config is not given
+}
+
+// EnableConfig turns on config files for the default CLI (see
+// CLI.EnableConfig)
+func EnableConfig(args ConfigArgs) (err error) {
+	return defaultCLI.EnableConfig(args)
+}
+
+// EnableConfig makes ParseGlobalOptions load flag values from a config file:
+// the one named by the --config global flag, which EnableConfig adds, or
+// else the first of args.Files that exists, such as one in the working
+// directory and then one in the user's config directory. None need exist.
+//
+// Values from a config file apply to flags not given on the command line
+// and not set by their FlagDef.EnvVar, so the precedence is flags, then the
+// environment, then the config file, then defaults. Keys that name no flag
+// are ignored so one file can serve several versions of an app.
+func (cli *CLI) EnableConfig(args ConfigArgs) (err error) {
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+	if !cli.configEnabled() {
+		err = cli.addCLIOption(FlagDef{
+			Name:    ConfigFlagName,
+			Default: DefaultConfig,
+			Usage:   "Config file to load flag values from",
+			String:  cli.options.config,
+		})
+	}
+	if err == nil {
+		cli.configFiles = args.Files
+	}
+	return err
+}
+
+// configEnabled reports whether EnableConfig added the --config flag, which
+// RestoreRegistry may since have removed; the caller holds registryMu
+func (cli *CLI) configEnabled() bool {
+	fd := cli.flagSet.lookupName(ConfigFlagName)
+	return fd != nil && fd.String == cli.options.config
+}
+
+// ConfigFile returns the config file the default CLI loaded (see
+// CLI.ConfigFile)
+func ConfigFile() string {
+	return defaultCLI.ConfigFile()
+}
+
+// ConfigFile returns the path of the config file ParseGlobalOptions loaded,
+// or "" when none was loaded
+func (cli *CLI) ConfigFile() string {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	return cli.configFile
+}
+
+// loadConfig loads the file named by --config or, when that is not given,
+// the first of ConfigArgs.Files that exists, if EnableConfig was called; the
+// caller holds registryMu for writing
+func (cli *CLI) loadConfig() (err error) {
+	var data []byte
+	var path string
+
+	cli.config = nil
+	cli.configFile = ""
+	if !cli.configEnabled() {
+		goto end
+	}
+	path = *cli.options.config
+	if path != "" {
+		data, err = os.ReadFile(path)
+		goto decode
+	}
+	for _, path = range cli.configFiles {
+		data, err = os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		goto decode
+	}
+	goto end
+
+decode:
+	if err != nil {
+		goto end
+	}
+	err = cli.decodeConfig(path, data)
+end:
+	if err != nil {
+		err = NewErr(ErrLoadingConfigFile, "config_file", path, err)
+	}
+	return err
+}
+
+// decodeConfig decodes data, the contents of the config file at path; the
+// caller holds registryMu for writing
+func (cli *CLI) decodeConfig(path string, data []byte) (err error) {
+	var values map[string]any
+
+	decode, ok := configDecoder(path)
+	if !ok {
+		err = NewErr(ErrUnsupportedConfigFormat, "extension", filepath.Ext(path))
+		goto end
+	}
+	values, err = decode(data)
+	if err != nil {
+		goto end
+	}
+	cli.config = values
+	cli.configFile = path
+end:
+	return err
+}
+
+// configSection returns the values the loaded config file holds for the
+// flags of the command at cmdPath, or for the global flags when cmdPath is
+// empty
+func (cli *CLI) configSection(cmdPath string) (section map[string]any) {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	return cli.configSectionLocked(cmdPath)
+}
+
+// configSectionLocked returns configSection(cmdPath); the caller holds
+// registryMu
+func (cli *CLI) configSectionLocked(cmdPath string) (section map[string]any) {
+	section = cli.config
+	for _, name := range strings.Fields(cmdPath) {
+		section, _ = section[name].(map[string]any)
+	}
+	return section
+}
+
+// applyConfig assigns the flags of cmd's FlagSets not given on the command
+// line from the environment or the loaded config file
+func (cli *CLI) applyConfig(cmd Command) error {
+	var errs []error
+
+	section := cli.configSection(CmdPath(cmd))
+	for _, fs := range cmd.FlagSets() {
+		errs = append(errs, fs.applySources(section))
+	}
+	return CombineErrs(errs)
+}
+
+// applySources assigns each flag of fs not given on the command line from
+// its FlagDef.EnvVar when that is set, or else from config, recording the
+// source for Settings. Values are validated as if given as flags.
+func (fs *FlagSet) applySources(config map[string]any) (err error) {
+	var errs []error
+
+	given := fs.givenFlags()
+	for i := range fs.FlagDefs {
+		var value any
+		var source SettingSource
+
+		fd := &fs.FlagDefs[i]
+		delete(fs.sources, fd.Name)
+		if given[fd.Name] {
+			continue
+		}
+		env, ok := os.LookupEnv(fd.EnvVar)
+		switch {
+		case fd.EnvVar != "" && ok:
+			value, source = env, EnvSource
+		case config[fd.Name] != nil:
+			value, source = config[fd.Name], ConfigSource
+		default:
+			continue
+		}
+		err = fd.assign(value)
+		if err == nil {
+			err = fd.ValidateValue(fd.Value())
+		}
+		if err != nil {
+			errs = append(errs, NewErr(ErrFlagsParsingFailed, "flag_name", fd.Name, "source", source, err))
+			continue
+		}
+		fs.SetSource(fd.Name, source)
+	}
+	return CombineErrs(errs)
+}
+
+// givenFlags returns the names of the flags of fs given on the command line,
+// by long name whether given by it, by shortcut, or negated
+func (fs *FlagSet) givenFlags() (given map[string]bool) {
+	given = make(map[string]bool)
+	if fs.FlagSet == nil {
+		goto end
+	}
+	fs.FlagSet.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+		if fd := fs.negatedFlag(f.Name); fd != nil {
+			given[fd.Name] = true
+		}
+	})
+	for _, fd := range fs.FlagDefs {
+		if fd.Shortcut != 0 && given[string(fd.Shortcut)] {
+			given[fd.Name] = true
+		}
+	}
+end:
+	return given
+}
+
+// assign sets the target of fd to value, a string from the environment or
+// a value decoded from a config file, converted to fd's type
+func (fd *FlagDef) assign(value any) (err error) {
+	var n int64
+	var b bool
+
+	switch fd.Type() {
+	case StringFlag:
+		switch v := value.(type) {
+		case string, bool, int, int64, float64:
+			*fd.String = fmt.Sprint(v)
+		default:
+			err = fmt.Errorf("expected a string, got %T", value)
+		}
+	case BoolFlag:
+		switch v := value.(type) {
+		case string:
+			b, err = strconv.ParseBool(v)
+		case bool:
+			b = v
+		default:
+			err = fmt.Errorf("expected a bool, got %T", value)
+		}
+		if err == nil {
+			*fd.Bool = b
+		}
+	case IntFlag, Int64Flag, CountFlag:
+		switch v := value.(type) {
+		case string:
+			n, err = strconv.ParseInt(v, 10, 64)
+		case int:
+			n = int64(v)
+		case int64:
+			n = v
+		case float64:
+			n = int64(v)
+			if float64(n) != v {
+				err = fmt.Errorf("expected an integer, got %v", v)
+			}
+		default:
+			err = fmt.Errorf("expected an integer, got %T", value)
+		}
+		if err != nil {
+			break
+		}
+		switch {
+		case fd.Int != nil:
+			*fd.Int = int(n)
+		case fd.Count != nil:
+			*fd.Count = int(n)
+		default:
+			*fd.Int64 = n
+		}
+	case DurationFlag:
+		var d durationValue
+		switch v := value.(type) {
+		case string:
+			err = d.Set(v)
+		case int, int64, float64:
+			err = d.Set(fmt.Sprint(v))
+		default:
+			err = fmt.Errorf("expected a duration, got %T", value)
+		}
+		if err == nil {
+			*fd.Duration = time.Duration(d)
+		}
+	case Float64Flag:
+		var f float64
+		switch v := value.(type) {
+		case string:
+			f, err = strconv.ParseFloat(v, 64)
+		case int:
+			f = float64(v)
+		case int64:
+			f = float64(v)
+		case float64:
+			f = v
+		default:
+			err = fmt.Errorf("expected a number, got %T", value)
+		}
+		if err == nil {
+			*fd.Float64 = f
+		}
+	case StringsFlag:
+		var values []string
+		switch v := value.(type) {
+		case string:
+			values = []string{v}
+		case []string:
+			values = slices.Clone(v)
+		case []any:
+			for _, item := range v {
+				switch item.(type) {
+				case string, bool, int, int64, float64:
+					values = append(values, fmt.Sprint(item))
+				default:
+					err = fmt.Errorf("expected a list of strings, got a %T in it", item)
+				}
+			}
+		default:
+			err = fmt.Errorf("expected a list of strings, got %T", value)
+		}
+		if err == nil {
+			*fd.Strings = values
+		}
+	case IntsFlag:
+		var values []int
+		var items []any
+		switch v := value.(type) {
+		case string:
+			err = (&intsValue{values: &values}).Set(v)
+		case int, int64, float64:
+			items = []any{v}
+		case []any:
+			items = v
+		default:
+			err = fmt.Errorf("expected a list of integers, got %T", value)
+		}
+		for _, item := range items {
+			switch n := item.(type) {
+			case int:
+				values = append(values, n)
+			case int64:
+				values = append(values, int(n))
+			case float64:
+				values = append(values, int(n))
+				if float64(int(n)) != n {
+					err = fmt.Errorf("expected a list of integers, got %v in it", n)
+				}
+			default:
+				err = fmt.Errorf("expected a list of integers, got a %T in it", item)
+			}
+		}
+		if err == nil {
+			*fd.Ints = values
+		}
+	case MapFlag:
+		values := make(map[string]string)
+		switch v := value.(type) {
+		case string:
+			mv := &mapValue{values: &values, unique: fd.UniqueKeys}
+			for pair := range strings.SplitSeq(v, ",") {
+				err = mv.Set(strings.TrimSpace(pair))
+				if err != nil {
+					break
+				}
+			}
+		case map[string]any:
+			for key, item := range v {
+				switch item.(type) {
+				case string, bool, int, int64, float64:
+					values[key] = fmt.Sprint(item)
+				default:
+					err = fmt.Errorf("expected string values, got a %T for %s", item, key)
+				}
+			}
+		default:
+			err = fmt.Errorf("expected a table of strings, got %T", value)
+		}
+		if err == nil {
+			*fd.Map = values
+		}
+	case UnknownFlagType:
+		err = fmt.Errorf("unknown flag type for %s", fd.Name)
+	}
+	return err
+}
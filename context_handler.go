@@ -0,0 +1,56 @@
+package cliutil
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCommandTimedOut is returned when a ContextHandler fails after its
+// Context's --timeout deadline passed
+var ErrCommandTimedOut = newMessageErr(MsgCommandTimedOut)
+
+// ContextHandler is implemented by commands that honor cancellation. RunCmd
+// calls HandleContext instead of Handle, with a Context derived from
+// CmdRunnerArgs.Context that is canceled once the --timeout option's duration
+// elapses on the current Clock, or when HandleContext returns.
+type ContextHandler interface {
+	Command
+	HandleContext(ctx context.Context) error
+}
+
+// IsRunnable returns true if cmd implements CommandHandler, ContextHandler,
+// or ResultHandler
+func IsRunnable(cmd Command) bool {
+	switch cmd.(type) {
+	case ContextHandler, CommandHandler, ResultHandler:
+		return true
+	}
+	return false
+}
+
+// handlerContext returns the runner's Context, or context.Background() when
+// it has none, carrying the run's warnings (see WarnfContext) and bounded by
+// the Options' Timeout() when that is positive (see TimeoutContext)
+func (cr CmdRunner) handlerContext() (ctx context.Context, cancel context.CancelFunc) {
+	ctx = cr.Args.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithValue(ctx, runWarningsKey{}, cr.Args.warnings)
+	if cr.Args.Options == nil || cr.Args.Options.Timeout() <= 0 {
+		ctx, cancel = context.WithCancel(ctx)
+		goto end
+	}
+	ctx, cancel = TimeoutContext(ctx, cr.Args.Options.Timeout())
+end:
+	return ctx, cancel
+}
+
+// timedOutErr wraps err with ErrCommandTimedOut when ctx's deadline passed
+// before the handler returned it
+func (cr CmdRunner) timedOutErr(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		return err
+	}
+	return NewErr(ErrCommandTimedOut, "timeout", cr.Args.Options.Timeout().String(), err)
+}
@@ -0,0 +1,68 @@
+package cliutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// ErrPanic is returned by RecoverPanics for a command that panicked
+var ErrPanic = errors.New("command panicked")
+
+// CrashReportArgs configures RecoverPanics
+type CrashReportArgs struct {
+	AppInfo  appinfo.AppInfo // OPTIONAL: name and build metadata to report (see WithBuildInfo); defaults to ReadBuildInfo
+	Writer   Writer          // OPTIONAL: whose error stream reports are written to; defaults to GetWriter()
+	IssueURL string          // OPTIONAL: where users are asked to report crashes
+}
+
+// RecoverPanics returns Middleware that recovers a panic in a command and
+// writes a crash report for users to pass on: the panic, the app's build
+// metadata as `version --verbose` shows it, and the stack. The command then
+// fails with ErrPanic, classified NotifiedErr since the report explains it.
+//
+//	runner.Use(cliutil.RecoverPanics(cliutil.CrashReportArgs{
+//		AppInfo:  appInfo,
+//		IssueURL: "https://github.com/acme/myapp/issues",
+//	}))
+func RecoverPanics(args CrashReportArgs) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, cmd Command) (err error) {
+			defer func() {
+				r := recover()
+				if r != nil {
+					err = reportCrash(args, cmd, r, debug.Stack())
+				}
+			}()
+			return next(ctx, cmd)
+		}
+	}
+}
+
+// reportCrash writes the crash report for r, recovered from cmd, and returns
+// the ErrPanic error for it
+func reportCrash(args CrashReportArgs, cmd Command, r any, stack []byte) error {
+	var b strings.Builder
+
+	name, build := cmd.CLIName(), ReadBuildInfo()
+	if args.AppInfo != nil {
+		name, build = args.AppInfo.Name(), BuildInfoOf(args.AppInfo)
+	}
+	w := args.Writer
+	if w == nil {
+		w = GetWriter()
+	}
+	fmt.Fprintf(&b, "%s crashed running %q: %v\n", name, CmdPath(cmd), r)
+	if args.IssueURL != "" {
+		fmt.Fprintf(&b, "Please report this at %s, including the details below.\n", args.IssueURL)
+	} else {
+		b.WriteString("Please report this, including the details below.\n")
+	}
+	fmt.Fprintf(&b, "\n%s\n%s", buildDetails(name, build), stack)
+	_, _ = fmt.Fprint(w.ErrWriter(), b.String())
+	return WithErrClass(NewErr(ErrPanic, "command", CmdPath(cmd), "panic", fmt.Sprint(r)), NotifiedErr)
+}
@@ -0,0 +1,103 @@
+package cliutil
+
+import (
+	"sync/atomic"
+)
+
+// ErrDeprecatedUsage is returned under --strict when the run used something
+// deprecated; ExitCode maps it to ExitDeprecatedUsage
+var ErrDeprecatedUsage = newMessageErr(MsgDeprecatedUsage)
+
+// strict is set by SetStrict
+var strict atomic.Bool
+
+// SetStrict turns strict mode on for every run, as if --strict were always
+// given, e.g. from a CI build of the app; it returns the previous setting
+func SetStrict(enabled bool) (prev bool) {
+	return strict.Swap(enabled)
+}
+
+// IsStrict returns true when --strict was given to the default CLI or
+// SetStrict(true) called (see CLI.IsStrict)
+func IsStrict() bool {
+	return defaultCLI.IsStrict()
+}
+
+// IsStrict returns true when --strict was given to cli or SetStrict(true)
+// called
+func (cli *CLI) IsStrict() bool {
+	return strict.Load() || cli.options.Strict()
+}
+
+// Deprecatedf records a deprecation warning, such as for a config key the app
+// renamed. It is written and summarized like a Warnf warning but, under
+// --strict, fails the run with ErrDeprecatedUsage rather than
+// ErrWarningsAsErrors, so CI can enforce migrations before removals land.
+//
+// Deprecated: Use CmdRunnerArgs.Deprecatedf in a command, or
+// DeprecatedfContext with the handler's Context, so the warning is written to
+// and summarized by the run it belongs to.
+func Deprecatedf(format string, args ...any) {
+	deprecationCount.Add(1)
+	Warnf(format, args...)
+}
+
+// deprecationCount is how many of the recorded warnings are deprecations
+var deprecationCount atomic.Int64
+
+// checkDeprecations records a deprecation warning for the run of args when
+// cmd is deprecated, for each deprecated flag of cmd or of the run's global
+// flags given, and, under --strict, for cmd's use of the legacy
+// CmdArgs.FlagDefs. It returns ErrDeprecatedUsage under --strict when any
+// were recorded, so the handler does not run.
+func checkDeprecations(cmd Command, args CmdRunnerArgs) (err error) {
+	var n int
+	var flagSets []*FlagSet
+
+	cli := args.cli()
+	globalFS := cli.GlobalFlagSet()
+	dc, ok := cmd.(interface{ Deprecated() string })
+	if ok && dc.Deprecated() != "" {
+		args.warnings.deprecatedf("%s", Msg(MsgDeprecatedCommand, CmdPath(cmd), dc.Deprecated()))
+		n++
+	}
+	if globalFS != nil {
+		flagSets = append(flagSets, globalFS)
+	}
+	flagSets = append(flagSets, cmd.FlagSets()...)
+	for _, fs := range flagSets {
+		set := fs.SetFlags()
+		for _, fd := range fs.FlagDefs {
+			_, given := set[fd.Name]
+			if !fd.IsDeprecated() || !given {
+				continue
+			}
+			args.warnings.deprecatedf("%s", Msg(MsgDeprecatedFlag, fd.Name, fd.deprecationNote()))
+			n++
+		}
+	}
+	if !cli.IsStrict() {
+		goto end
+	}
+	// Legacy FlagDefs concern the app's developers rather than its users, so
+	// they are only reported when strict mode can make them fail CI
+	if lc, ok := cmd.(interface{ usesLegacyFlagDefs() bool }); ok && lc.usesLegacyFlagDefs() {
+		args.warnings.deprecatedf("%s", Msg(MsgDeprecatedFlagDefs, CmdPath(cmd)))
+		n++
+	}
+	if n == 0 {
+		goto end
+	}
+	err = NewErr(ErrDeprecatedUsage, "deprecation_count", n)
+end:
+	return err
+}
+
+// strictErr returns the error ReportWarnings returns under --strict for n
+// recorded warnings, of which deprecations were deprecations
+func strictErr(n int, deprecations int64) error {
+	if deprecations > 0 {
+		return NewErr(ErrDeprecatedUsage, "deprecation_count", deprecations, "warning_count", n)
+	}
+	return NewErr(ErrWarningsAsErrors, "warning_count", n)
+}
@@ -0,0 +1,77 @@
+package cliutil
+
+import (
+	_ "embed"
+	"io"
+	"iter"
+	"strings"
+	"text/template"
+
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// DocsTemplateText renders the Markdown reference written by WriteDocs from
+// a Docs. Assign a custom template before docs are written to replace it.
+//
+//go:embed templates/docs.gotmpl
+var DocsTemplateText string
+
+// GetDocsTemplate returns DocsTemplateText compiled, parsing it on first use
+func GetDocsTemplate() (*template.Template, error) {
+	return parseTemplate("docs", DocsTemplateText)
+}
+
+// Docs is the data for the docs template. Commands are produced one at a
+// time as the template ranges over them, in help order, each followed by
+// its visible subcommands.
+type Docs struct {
+	appinfo.AppInfo
+	Commands iter.Seq[DocsCmd]
+}
+
+// DocsCmd is one command in the docs
+type DocsCmd struct {
+	CmdUsage
+	Path string // Space-separated full name, e.g. "db migrate"
+}
+
+// WriteDocs writes a Markdown reference of every visible command to w
+func WriteDocs(w io.Writer, info appinfo.AppInfo) (err error) {
+	var tmpl *template.Template
+
+	tmpl, err = GetDocsTemplate()
+	if err != nil {
+		goto end
+	}
+	err = tmpl.Execute(w, Docs{
+		AppInfo: info,
+		Commands: func(yield func(DocsCmd) bool) {
+			docsCmds("", GetTopLevelCmds(), yield)
+		},
+	})
+end:
+	return err
+}
+
+// docsCmds yields the visible cmds under the dot-separated parent path and
+// their subcommands, depth first; it returns false once yield does
+func docsCmds(parent string, cmds []Command, yield func(DocsCmd) bool) bool {
+	var path string
+
+	for _, cmd := range cmds {
+		if cmd.IsHidden() {
+			continue
+		}
+		path = cmd.Name()
+		if parent != "" {
+			path = parent + "." + cmd.Name()
+		}
+		if !yield(DocsCmd{CmdUsage: BuildCmdUsage(cmd), Path: strings.ReplaceAll(path, ".", " ")}) {
+			return false
+		}
+		if !docsCmds(path, cliOf(cmd).GetSubCmds(path), yield) {
+			return false
+		}
+	}
+	return true
+}
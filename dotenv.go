@@ -0,0 +1,136 @@
+package cliutil
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DotEnvFile is the env file ParseGlobalOptions loads when --env-file is
+// not given, if it exists in the working directory
+const DotEnvFile = ".env"
+
+// ErrLoadingEnvFile is returned when the file named by --env-file, or an
+// existing DotEnvFile, cannot be read or parsed
+var ErrLoadingEnvFile = newMessageErr(MsgLoadingEnvFile)
+
+// errInvalidEnvFileLine is returned for a line parseDotEnv cannot parse
+var errInvalidEnvFileLine = errors.New("invalid env file line; expected KEY=value")
+
+// envFileFlagDef returns the --env-file flag, which assigns options
+func envFileFlagDef(options *GlobalOptions) FlagDef {
+	return FlagDef{
+		Name:    "env-file",
+		Default: DefaultEnvFile,
+		Usage:   "File of environment variables to load (default " + DotEnvFile + " if it exists)",
+		String:  options.envFile,
+	}
+}
+
+// loadEnvFile sets the environment variables in the file named by
+// --env-file or, when that is not given, in DotEnvFile if it exists.
+// Variables already in the environment are left as they are, so the
+// environment overrides the file. Builds with the nodotenv tag load no file.
+func loadEnvFile(options *GlobalOptions) (err error) {
+	var data []byte
+	var vars [][2]string
+
+	path := options.EnvFile()
+	if !dotEnvEnabled {
+		goto end
+	}
+	if path == "" {
+		path = DotEnvFile
+		data, err = os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+			goto end
+		}
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		goto end
+	}
+	vars, err = parseDotEnv(data)
+	if err != nil {
+		goto end
+	}
+	for _, kv := range vars {
+		_, ok := os.LookupEnv(kv[0])
+		if ok {
+			continue
+		}
+		err = os.Setenv(kv[0], kv[1])
+		if err != nil {
+			goto end
+		}
+	}
+end:
+	if err != nil {
+		err = NewErr(ErrLoadingEnvFile, "env_file", path, err)
+	}
+	return err
+}
+
+// parseDotEnv parses lines of the form KEY=value, optionally prefixed by
+// "export". Blank lines and lines starting with # are skipped. Values may be
+// double-quoted, with Go escapes such as \n, or single-quoted, taken as is;
+// an unquoted value ends at a " #" comment.
+func parseDotEnv(data []byte) (vars [][2]string, err error) {
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			err = NewErr(errInvalidEnvFileLine, "line", i+1)
+			goto end
+		}
+		value, err = parseDotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			err = NewErr(errInvalidEnvFileLine, "line", i+1, err)
+			goto end
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+end:
+	return vars, err
+}
+
+// parseDotEnvValue unquotes value, or strips its trailing comment
+func parseDotEnvValue(value string) (_ string, err error) {
+	var quoted, rest string
+
+	switch {
+	case strings.HasPrefix(value, `"`):
+		quoted, err = strconv.QuotedPrefix(value)
+		if err != nil {
+			goto end
+		}
+		rest = value[len(quoted):]
+		value, err = strconv.Unquote(quoted)
+	case strings.HasPrefix(value, "'"):
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			err = errors.New("unterminated single-quoted value")
+			goto end
+		}
+		rest = value[end+2:]
+		value = value[1 : end+1]
+	default:
+		value, _, _ = strings.Cut(value, " #")
+		value = strings.TrimSpace(value)
+	}
+	// Only a comment may follow a quoted value
+	rest = strings.TrimSpace(rest)
+	if err == nil && rest != "" && !strings.HasPrefix(rest, "#") {
+		err = errors.New("unexpected text after quoted value")
+	}
+end:
+	return value, err
+}
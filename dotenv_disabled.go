@@ -0,0 +1,7 @@
+//go:build nodotenv
+
+package cliutil
+
+// dotEnvEnabled is false with the nodotenv tag, for production builds that
+// must take their environment only from the process
+const dotEnvEnabled = false
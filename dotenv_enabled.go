@@ -0,0 +1,7 @@
+//go:build !nodotenv
+
+package cliutil
+
+// dotEnvEnabled reports whether ParseGlobalOptions loads env files and
+// defines --env-file; see dotenv_disabled.go
+const dotEnvEnabled = true
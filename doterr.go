@@ -484,6 +484,7 @@ func extractTrailingCause(parts []any) (_ []any, err error) {
 
 	// If we only have errors (all sentinels), the last one is not a cause
 	if sentinelCount == len(parts) {
+		err = nil
 		goto end
 	}
 
@@ -492,6 +493,7 @@ func extractTrailingCause(parts []any) (_ []any, err error) {
 	nonSentinelCount = len(parts) - sentinelCount
 	if (nonSentinelCount-1)%2 != 0 {
 		// Removing last error leaves odd count - it's a value, not a cause
+		err = nil
 		goto end
 	}
 
@@ -0,0 +1,93 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrUnsupportedOutputFormat is returned by Emit for a machine-readable
+// OutputFormat with no OutputEncoder; see RegisterOutputFormat
+var ErrUnsupportedOutputFormat = errors.New("unsupported output format")
+
+// EmitterArgs configures an Emitter
+type EmitterArgs struct {
+	Format OutputFormat                        // OPTIONAL: defaults to the format selected by the CLI's --output
+	Text   func(w io.Writer, record any) error // OPTIONAL: writes a record as text; defaults to one line as by fmt.Println
+}
+
+// Emitter writes the records a command outputs, such as one per file found,
+// in the format selected by --output, so that every command's structured
+// output is consistent. Text is for people; JSON is one object per line (JSON
+// Lines), so records can be streamed and read with jq; other formats are as
+// registered, such as a YAML document per record once yamlcliutil.Register
+// has been called. Records are results, so --quiet does not suppress them.
+//
+//	e := c.NewEmitter(cliutil.EmitterArgs{})
+//	for _, f := range files {
+//		err = e.Emit(f)
+//		if err != nil {
+//			break
+//		}
+//	}
+type Emitter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format OutputFormat
+	text   func(w io.Writer, record any) error
+}
+
+// NewEmitter returns an Emitter that writes records to w's output stream in
+// the format selected by the default CLI's --output (see CLI.NewEmitter)
+func NewEmitter(w Writer, args EmitterArgs) *Emitter {
+	return defaultCLI.NewEmitter(w, args)
+}
+
+// NewEmitter returns an Emitter that writes records to w's output stream,
+// by default in the format selected by cli's --output
+func (cli *CLI) NewEmitter(w Writer, args EmitterArgs) *Emitter {
+	if args.Format == "" {
+		args.Format = cli.options.OutputFormat()
+	}
+	if args.Text == nil {
+		args.Text = writeTextRecord
+	}
+	return &Emitter{
+		out:    w.Writer(),
+		format: args.Format,
+		text:   args.Text,
+	}
+}
+
+// Format returns the OutputFormat e writes records in
+func (e *Emitter) Format() OutputFormat {
+	return e.format
+}
+
+// Emit writes record in e's format
+func (e *Emitter) Emit(record any) (err error) {
+	var encode OutputEncoder
+	var ok bool
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.format.IsMachineReadable() {
+		err = e.text(e.out, record)
+		goto end
+	}
+	encode, ok = outputEncoder(e.format)
+	if !ok {
+		err = NewErr(ErrUnsupportedOutputFormat, "output", string(e.format))
+		goto end
+	}
+	err = encode(e.out, record)
+end:
+	return err
+}
+
+// writeTextRecord writes record to w on a line of its own
+func writeTextRecord(w io.Writer, record any) (err error) {
+	_, err = fmt.Fprintln(w, record)
+	return err
+}
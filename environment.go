@@ -0,0 +1,132 @@
+package cliutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Environment abstracts detecting where the CLI runs, which shell completion
+// install, prompts, color decisions, and update checks depend on, so that
+// behavior can be unit-tested with a fake (see clitest.FakeEnvironment)
+// instead of by setting environment variables.
+type Environment interface {
+	// Shell returns the user's shell, or the empty Shell when it is not one
+	// of the supported shells
+	Shell() Shell
+	// IsCI reports whether the CLI runs under a CI system
+	IsCI() bool
+	// IsInteractive reports whether a person is likely at the terminal
+	IsInteractive() bool
+	// NoColor reports whether the user asked for output without color
+	NoColor() bool
+}
+
+// Package-level environment instance
+var (
+	environment   Environment  = osEnvironment{}
+	environmentMu sync.RWMutex // synchronizes access to environment
+)
+
+// SetEnvironment replaces the Environment used by the framework (primarily
+// for testing) and returns the previous one so it can be restored.
+func SetEnvironment(e Environment) (prev Environment) {
+	environmentMu.Lock()
+	defer environmentMu.Unlock()
+	prev = environment
+	environment = e
+	return prev
+}
+
+// GetEnvironment returns the Environment used by the framework
+func GetEnvironment() Environment {
+	environmentMu.RLock()
+	defer environmentMu.RUnlock()
+	return environment
+}
+
+// DetectShell returns the user's shell, or the empty Shell when it is not
+// one of the supported shells
+func DetectShell() Shell {
+	return GetEnvironment().Shell()
+}
+
+// IsCI returns true when running under a CI system
+func IsCI() bool {
+	return GetEnvironment().IsCI()
+}
+
+// IsInteractive returns true when a person is likely at the terminal: input
+// and output are terminals, TERM is not "dumb", and not running under CI
+func IsInteractive() bool {
+	return GetEnvironment().IsInteractive()
+}
+
+// ciEnvs are set by common CI systems, and only by them; generic names like
+// BUILD_NUMBER or RUN_ID are left out as other tools set them too
+var ciEnvs = []string{
+	"CI",
+	"CONTINUOUS_INTEGRATION",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"BUILDKITE",
+	"TF_BUILD",
+	"CIRCLECI",
+	"TRAVIS",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"CODEBUILD_BUILD_ID",
+}
+
+var _ Environment = osEnvironment{}
+
+// osEnvironment implements Environment from the process's environment
+type osEnvironment struct{}
+
+// Shell prefers the shell the shell wrapper reported, since $SHELL names the
+// login shell rather than the one running the CLI
+func (osEnvironment) Shell() (shell Shell) {
+	var err error
+
+	if directivesShell != "" {
+		shell = directivesShell
+		goto end
+	}
+	if os.Getenv("SHELL") == "" {
+		goto end
+	}
+	shell, err = ParseCompletionShell(strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe"))
+	if err != nil {
+		shell = ""
+	}
+end:
+	return shell
+}
+
+func (osEnvironment) IsCI() bool {
+	for _, env := range ciEnvs {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// NoColor follows https://no-color.org: any non-empty NO_COLOR turns color
+// off, as does a TERM of "dumb"
+func (osEnvironment) NoColor() bool {
+	return os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+}
+
+func (e osEnvironment) IsInteractive() bool {
+	switch {
+	case e.IsCI():
+		return false
+	case os.Getenv("TERM") == "dumb":
+		return false
+	case !GetTerminal().IsTTY():
+		return false
+	}
+	return isTerminalFile(os.Stdin)
+}
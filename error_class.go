@@ -0,0 +1,90 @@
+package cliutil
+
+import (
+	"errors"
+)
+
+// ErrClass classifies an error so CmdRunner can choose the appropriate output,
+// exit code, and whether to append the ErrShowUsage hint.
+type ErrClass int
+
+const (
+	// UnclassifiedErr is reported in full with the default exit code
+	UnclassifiedErr ErrClass = iota
+	// NotifiedErr has already been explained to the user, so only its
+	// suggestions are shown (see ErrOmitUserNotify)
+	NotifiedErr
+	// SilentErr produces no output at all; only the exit code reflects it
+	SilentErr
+	// RetryableErr is transient; the user is told a retry may succeed
+	RetryableErr
+	// UsageErr means the command was invoked incorrectly; the ErrShowUsage
+	// hint is appended and the exit code is ExitOptionsParseError
+	UsageErr
+)
+
+// Sentinels attached to errors to classify them. ErrOmitUserNotify marks
+// NotifiedErr.
+var (
+	ErrSilent    = errors.New("silent error")
+	ErrRetryable = errors.New("retryable error")
+	ErrUsage     = errors.New("usage error")
+)
+
+func (c ErrClass) String() (s string) {
+	switch c {
+	case NotifiedErr:
+		s = "notified"
+	case SilentErr:
+		s = "silent"
+	case RetryableErr:
+		s = "retryable"
+	case UsageErr:
+		s = "usage"
+	case UnclassifiedErr:
+		s = ""
+	}
+	return s
+}
+
+// WithErrClass attaches the sentinel for class to err. Returns err unchanged
+// if it is nil or class is UnclassifiedErr.
+func WithErrClass(err error, class ErrClass) error {
+	var sentinel error
+
+	switch class {
+	case NotifiedErr:
+		sentinel = ErrOmitUserNotify
+	case SilentErr:
+		sentinel = ErrSilent
+	case RetryableErr:
+		sentinel = ErrRetryable
+	case UsageErr:
+		sentinel = ErrUsage
+	case UnclassifiedErr:
+	}
+	if err == nil || sentinel == nil {
+		return err
+	}
+	return WithErr(err, sentinel)
+}
+
+// ErrClassOf returns the classification of err. When an error carries more
+// than one class the most output-suppressing wins: silent, then notified,
+// then usage, then retryable. Framework parse errors carrying ErrShowUsage
+// are classified as UsageErr.
+func ErrClassOf(err error) (class ErrClass) {
+	switch {
+	case err == nil:
+		class = UnclassifiedErr
+	case errors.Is(err, ErrSilent):
+		class = SilentErr
+	case errors.Is(err, ErrOmitUserNotify):
+		class = NotifiedErr
+	case errors.Is(err, ErrUsage), errors.Is(err, ErrShowUsage):
+		class = UsageErr
+	case errors.Is(err, ErrRetryable):
+		class = RetryableErr
+	}
+	return class
+}
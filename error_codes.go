@@ -0,0 +1,112 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// ErrorCodeInfo documents one stable error code
+type ErrorCodeInfo struct {
+	Code        string // Stable identifier, e.g. "CLI101"
+	Err         error  // Sentinel matched via errors.Is
+	Description string // Human-readable description of the failure class
+}
+
+// errorCodes lists framework codes in match precedence order: the most
+// specific failures come first so ErrorCodeOf prefers e.g. CLI204 (required
+// flag missing) over CLI201 (flags parsing failed) for the same error.
+//
+// Codes are grouped by hundreds:
+//   - CLI1xx: command resolution
+//   - CLI2xx: flags and options
+//   - CLI3xx: positional arguments
+//   - CLI4xx: execution
+var errorCodes = []ErrorCodeInfo{
+	{Code: "CLI204", Err: ErrFlagRequired},
+	{Code: "CLI203", Err: ErrUnknownFlags},
+	{Code: "CLI205", Err: ErrInvalidOutputFormat},
+	{Code: "CLI206", Err: ErrInvalidLogLevel},
+	{Code: "CLI207", Err: ErrLoadingConfigFile},
+	{Code: "CLI208", Err: ErrLoadingEnvFile},
+	{Code: "CLI202", Err: dt.ErrFlagValidationFailed},
+	{Code: "CLI302", Err: ErrTooFewArgs},
+	{Code: "CLI303", Err: ErrTooManyArgs},
+	{Code: "CLI101", Err: ErrUnknownCommand},
+	{Code: "CLI102", Err: ErrCommandNotFound},
+	{Code: "CLI201", Err: ErrFlagsParsingFailed},
+	{Code: "CLI301", Err: ErrAssigningArgsFailed},
+	{Code: "CLI103", Err: ErrInvalidInvocation},
+	{Code: "CLI401", Err: ErrWarningsAsErrors},
+	{Code: "CLI402", Err: ErrDeprecatedUsage},
+	{Code: "CLI403", Err: ErrCommandTimedOut},
+	{Code: "CLI100", Err: ErrShowUsage},
+}
+
+var errorCodeRegex = regexp.MustCompile(`^[A-Z][A-Z0-9]*[0-9]+$`)
+
+// RegisterErrorCode assigns a stable code such as "APP301" to an application
+// sentinel error. Application codes take precedence over framework codes, and
+// later registrations over earlier ones. Registering a code again for the
+// same sentinel does nothing.
+//
+// Panics if code is malformed, already registered for another sentinel, or
+// sentinel is nil.
+func RegisterErrorCode(code string, sentinel error, description string) {
+	if sentinel == nil {
+		panic("cliutil.RegisterErrorCode() requires a non-nil sentinel error")
+	}
+	if !errorCodeRegex.MatchString(code) {
+		panic(fmt.Sprintf("Invalid error code for cliutil.RegisterErrorCode(); must be uppercase letters followed by digits; got %q", code))
+	}
+	for _, info := range errorCodes {
+		if info.Code == code && info.Err == sentinel {
+			return
+		}
+		if info.Code == code {
+			panic(fmt.Sprintf("Duplicate error code for cliutil.RegisterErrorCode(); %s is already registered", code))
+		}
+	}
+	errorCodes = append([]ErrorCodeInfo{{
+		Code:        code,
+		Err:         sentinel,
+		Description: description,
+	}}, errorCodes...)
+}
+
+// ErrorCodeOf returns the stable code for the most specific registered
+// sentinel matching err, or "" if none match.
+func ErrorCodeOf(err error) (code string) {
+	if err == nil {
+		goto end
+	}
+	for _, info := range errorCodes {
+		if errors.Is(err, info.Err) {
+			code = info.Code
+			goto end
+		}
+	}
+end:
+	return code
+}
+
+// ErrorCatalog returns every registered error code sorted by code so support
+// teams and scripts can document and match on codes rather than text.
+// Descriptions default to the sentinel's (possibly localized) message.
+func ErrorCatalog() []ErrorCodeInfo {
+	catalog := make([]ErrorCodeInfo, len(errorCodes))
+	copy(catalog, errorCodes)
+	for i, info := range catalog {
+		if info.Description == "" {
+			catalog[i].Description = info.Err.Error()
+		}
+	}
+	slices.SortFunc(catalog, func(a, b ErrorCodeInfo) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return catalog
+}
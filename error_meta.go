@@ -0,0 +1,55 @@
+package cliutil
+
+// NewKV returns a KV pair for use with NewErr and WithErr when a key/value
+// pair must be passed as a single argument.
+func NewKV(key string, value any) KV {
+	return kv{k: key, v: value}
+}
+
+// AllErrMeta returns the key/value pairs of every structured entry anywhere in
+// err's tree, outermost first. Unlike ErrMeta, which only inspects err and its
+// immediate children, AllErrMeta also finds metadata attached by callees
+// further down the chain. Duplicate keys are preserved in the order found.
+func AllErrMeta(err error) (kvs []KV) {
+	walkErrTree(err, func(e error) {
+		//goland:noinspection GoTypeAssertionOnErrors
+		_, ok := e.(entry)
+		if !ok {
+			return
+		}
+		kvs = append(kvs, ErrMeta(e)...)
+	})
+	return kvs
+}
+
+// FindErrValue walks err's entire tree and returns the first value stored
+// under key that is of type T. Handlers and tests can use it to inspect
+// context attached anywhere in a chain of NewErr/WithErr calls.
+//
+// Example:
+//
+//	name, ok := cliutil.FindErrValue[string](err, "command")
+func FindErrValue[T any](err error, key string) (value T, ok bool) {
+	for _, pair := range AllErrMeta(err) {
+		if pair.Key() != key {
+			continue
+		}
+		value, ok = pair.Value().(T)
+		if ok {
+			goto end
+		}
+	}
+end:
+	return value, ok
+}
+
+// HasErrKey returns true if any structured entry in err's tree carries key
+func HasErrKey(err error, key string) (has bool) {
+	for _, pair := range AllErrMeta(err) {
+		if pair.Key() == key {
+			has = true
+			break
+		}
+	}
+	return has
+}
@@ -2,17 +2,29 @@ package cliutil
 
 import (
 	"errors"
-	"fmt"
 	"os"
+
+	"github.com/mikeschinkel/go-dt"
 )
 
+// Framework sentinel errors. Their text comes from the message catalog so
+// they can be localized with RegisterMessages.
 var (
-	ErrShowUsage           = fmt.Errorf("run '%s help' for usage", os.Args[0])
-	ErrUnknownCommand      = errors.New("unknown command")
-	ErrCommandNotFound     = errors.New("command not found")
-	ErrFlagsParsingFailed  = errors.New("flags parsing failed")
-	ErrAssigningArgsFailed = errors.New("assigning args failed")
+	ErrShowUsage           = newMessageErr(MsgShowUsage, os.Args[0])
+	ErrUnknownCommand      = newMessageErr(MsgUnknownCommand)
+	ErrCommandNotFound     = newMessageErr(MsgCommandNotFound)
+	ErrFlagsParsingFailed  = newMessageErr(MsgFlagsParsingFailed)
+	ErrAssigningArgsFailed = newMessageErr(MsgAssigningArgs)
+	ErrUnknownFlags        = newMessageErr(MsgUnknownFlags)
+	ErrInvalidInvocation   = newMessageErr(MsgInvalidInvocation)
+	ErrTooFewArgs          = newMessageErr(MsgTooFewArgs)
+	ErrTooManyArgs         = newMessageErr(MsgTooManyArgs)
+
+	// ErrFlagRequired also matches dt.ErrFlagIsRequired via errors.Is
+	ErrFlagRequired = &messageErr{id: MsgFlagRequired, base: dt.ErrFlagIsRequired}
+)
 
+var (
 	// ErrOmitUserNotify signals that the error has already been displayed to the user
 	// in a user-friendly format, and the technical error message should be omitted
 	// from user output (but can still be logged).
@@ -1,5 +1,10 @@
 package cliutil
 
+import (
+	"errors"
+	"fmt"
+)
+
 // Exit codes for CLI applications following lifecycle progression.
 // Lower numbers indicate earlier failures in the application startup sequence.
 //
@@ -11,12 +16,14 @@ package cliutil
 //   - 4: Expected/handled error during execution
 //   - 5: Unexpected/unhandled error during execution
 //   - 6: Failed to initialize logging infrastructure
+//   - 7: Used something deprecated while --strict was set
 //
 // Scripts can use these codes to determine appropriate retry/recovery strategies:
 //   - Exit 1-3: Likely user/config error, fix and retry immediately
 //   - Exit 4: Known error condition, check logs, may be retryable
 //   - Exit 5: Unexpected error, investigate before retry
 //   - Exit 6: Infrastructure failure, check system resources
+//   - Exit 7: Migrate off the deprecated usage before it is removed
 //
 // Note: Exit codes 128 and above are reserved for signal-related exits.
 // See: https://tldp.org/LDP/abs/html/exitcodes.html
@@ -30,4 +37,81 @@ const (
 	ExitKnownRuntimeError   = 4 // Expected/known runtime error during execution
 	ExitUnknownRuntimeError = 5 // Unexpected/unknown runtime error
 	ExitLoggerSetupError    = 6 // Logger initialization failed
+	ExitDeprecatedUsage     = 7 // Deprecated usage rejected by --strict
 )
+
+// exitCodeMapping associates a sentinel error with an exit code
+type exitCodeMapping struct {
+	err  error
+	code int
+}
+
+// exitCodeMappings holds registered mappings in registration order
+var exitCodeMappings = []exitCodeMapping{
+	{err: ErrShowUsage, code: ExitOptionsParseError},
+	{err: ErrUnknownCommand, code: ExitOptionsParseError},
+	{err: ErrCommandNotFound, code: ExitOptionsParseError},
+	{err: ErrFlagsParsingFailed, code: ExitOptionsParseError},
+	{err: ErrAssigningArgsFailed, code: ExitOptionsParseError},
+	{err: ErrUnknownFlags, code: ExitOptionsParseError},
+	{err: ErrInvalidInvocation, code: ExitOptionsParseError},
+	{err: ErrLoadingConfigFile, code: ExitOptionsParseError},
+	{err: ErrLoadingEnvFile, code: ExitOptionsParseError},
+	{err: ErrWarningsAsErrors, code: ExitKnownRuntimeError},
+	{err: ErrDeprecatedUsage, code: ExitDeprecatedUsage},
+	{err: ErrCommandTimedOut, code: ExitKnownRuntimeError},
+	{err: ErrRetryable, code: ExitKnownRuntimeError},
+	{err: ErrUsage, code: ExitOptionsParseError},
+}
+
+// RegisterExitCode maps an application sentinel error to an exit code so that
+// ExitCode returns it for any error where errors.Is(err, sentinel) is true.
+// Later registrations take precedence over earlier ones, which allows apps to
+// override the framework's default mappings.
+//
+// Panics if sentinel is nil or code is outside 0..125, since exit codes 126
+// and above are reserved by shells.
+func RegisterExitCode(sentinel error, code int) {
+	if sentinel == nil {
+		panic("cliutil.RegisterExitCode() requires a non-nil sentinel error")
+	}
+	if code < 0 || 125 < code {
+		panic(fmt.Sprintf("Invalid exit code for cliutil.RegisterExitCode(); must be between 0-125; got %d", code))
+	}
+	exitCodeMappings = append(exitCodeMappings, exitCodeMapping{
+		err:  sentinel,
+		code: code,
+	})
+}
+
+// ExitCoder is implemented by errors that carry their own exit code, such as
+// the exit code of a command run in another process
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// ExitCode returns the exit code to use for err. It returns ExitSuccess for a
+// nil error, the code of the first ExitCoder in err's chain, the code of the
+// most recently registered sentinel matching err, or ExitUnknownRuntimeError
+// if no registered sentinel matches.
+func ExitCode(err error) (code int) {
+	var coder ExitCoder
+
+	if err == nil {
+		code = ExitSuccess
+		goto end
+	}
+	if errors.As(err, &coder) {
+		code = coder.ExitCode()
+		goto end
+	}
+	for i := len(exitCodeMappings) - 1; i >= 0; i-- {
+		if errors.Is(err, exitCodeMappings[i].err) {
+			code = exitCodeMappings[i].code
+			goto end
+		}
+	}
+	code = ExitUnknownRuntimeError
+end:
+	return code
+}
@@ -1,7 +1,10 @@
 package cliutil
 
 import (
+	"maps"
 	"regexp"
+	"slices"
+	"time"
 
 	"github.com/mikeschinkel/go-dt"
 )
@@ -22,7 +25,22 @@ type FlagDef struct {
 	Bool           *bool
 	Int64          *int64
 	Int            *int
-	Example        string // OPTIONAL: sample value for example generation (e.g., "www")
+	Duration       *time.Duration
+	Float64        *float64
+	Strings        *[]string
+	Ints           *[]int
+	Map            *map[string]string
+	Count          *int
+	Example        string       // OPTIONAL: sample value for example generation (e.g., "www")
+	Sensitive      bool         // OPTIONAL: redact the value from logs and traces
+	Deprecated     string       // OPTIONAL: why the flag is deprecated or what to use instead; giving the flag warns (see IsStrict)
+	ReplacedBy     string       // OPTIONAL: name of the flag to use instead, which also deprecates this one
+	Complete       CompleteFunc // OPTIONAL: shell completion candidates for the value
+	EnvVar         string       // OPTIONAL: environment variable giving the value when the flag is not given
+	UniqueKeys     bool         // OPTIONAL: reject a Map key given twice rather than keep its last value
+	FromFile       bool         // OPTIONAL: a String value given as "@path" is read from the file, or stdin for "@-"
+	DefaultFunc    func() any   // OPTIONAL: computes the default when flags are parsed, in place of Default
+	DefaultText    string       // OPTIONAL: describes DefaultFunc's default in help, e.g. "current directory"
 }
 
 func (fd *FlagDef) Type() (ft FlagType) {
@@ -35,19 +53,33 @@ func (fd *FlagDef) Type() (ft FlagType) {
 		return IntFlag
 	case fd.Int64 != nil:
 		return Int64Flag
+	case fd.Duration != nil:
+		return DurationFlag
+	case fd.Float64 != nil:
+		return Float64Flag
+	case fd.Strings != nil:
+		return StringsFlag
+	case fd.Ints != nil:
+		return IntsFlag
+	case fd.Map != nil:
+		return MapFlag
+	case fd.Count != nil:
+		return CountFlag
 	}
 	return UnknownFlagType
 }
 
-// ValidateValue validates the flag value using the defined validation rules
+// ValidateValue validates the flag value using the defined validation rules.
+// The rules apply to each element of a repeatable flag's value, which is
+// missing for Required when it has none.
 func (fd *FlagDef) ValidateValue(value any) error {
 	var err error
-	var stringValue string
-	var ok bool
+
+	values, repeated := repeatedValues(value)
 
 	// Check required
-	if fd.Required && (value == nil || value == "") {
-		err = NewErr(dt.ErrFlagIsRequired)
+	if fd.Required && (value == nil || value == "" || repeated && len(values) == 0) {
+		err = NewErr(ErrFlagRequired)
 		goto end
 	}
 
@@ -56,6 +88,30 @@ func (fd *FlagDef) ValidateValue(value any) error {
 		goto end
 	}
 
+	if !repeated {
+		err = fd.validateElement(value)
+		goto end
+	}
+	for _, v := range values {
+		err = fd.validateElement(v)
+		if err != nil {
+			goto end
+		}
+	}
+
+end:
+	if err != nil {
+		err = WithErr(err, dt.ErrFlagValidationFailed, "flag_name", fd.Name)
+	}
+	return err
+}
+
+// validateElement applies Regex and ValidationFunc to value, or to one
+// element of a repeatable flag's value
+func (fd *FlagDef) validateElement(value any) (err error) {
+	var stringValue string
+	var ok bool
+
 	// Regex validation (only for string values)
 	if fd.Regex != nil {
 		stringValue, ok = value.(string)
@@ -68,18 +124,72 @@ func (fd *FlagDef) ValidateValue(value any) error {
 	// Custom validation function
 	if fd.ValidationFunc != nil {
 		err = fd.ValidationFunc(value)
-		if err != nil {
-			goto end
-		}
 	}
-
 end:
-	if err != nil {
-		err = WithErr(err, dt.ErrFlagValidationFailed, "flag_name", fd.Name)
-	}
 	return err
 }
 
+// defaultValue returns the flag's default, calling DefaultFunc if it is set
+func (fd *FlagDef) defaultValue() any {
+	if fd.DefaultFunc != nil {
+		return fd.DefaultFunc()
+	}
+	return fd.Default
+}
+
+// IsDeprecated returns true if giving the flag warns, as it sets Deprecated
+// or ReplacedBy
+func (fd *FlagDef) IsDeprecated() bool {
+	return fd.Deprecated != "" || fd.ReplacedBy != ""
+}
+
+// deprecationNote returns the text shown after "deprecated:" in warnings and
+// help, combining Deprecated and ReplacedBy, or "" if fd is not deprecated
+func (fd *FlagDef) deprecationNote() (note string) {
+	switch {
+	case fd.ReplacedBy == "":
+		note = fd.Deprecated
+	case fd.Deprecated == "":
+		note = Msg(MsgUseFlagInstead, fd.ReplacedBy)
+	default:
+		note = fd.Deprecated + "; " + Msg(MsgUseFlagInstead, fd.ReplacedBy)
+	}
+	return note
+}
+
+// IsRepeatable returns true if the flag may be given more than once, its
+// values accumulating, as for a StringsFlag, IntsFlag, MapFlag, or CountFlag
+func (fd *FlagDef) IsRepeatable() bool {
+	switch fd.Type() {
+	case StringsFlag, IntsFlag, MapFlag, CountFlag:
+		return true
+	}
+	return false
+}
+
+// repeatedValues returns the elements of value when it is the value of a
+// repeatable flag, with the entries of a map as key=value sorted by key
+func repeatedValues(value any) (values []any, ok bool) {
+	switch v := value.(type) {
+	case []string:
+		for _, s := range v {
+			values = append(values, s)
+		}
+		ok = true
+	case []int:
+		for _, n := range v {
+			values = append(values, n)
+		}
+		ok = true
+	case map[string]string:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			values = append(values, key+"="+v[key])
+		}
+		ok = true
+	}
+	return values, ok
+}
+
 func (fd *FlagDef) SetValue(value any) {
 	switch fd.Type() {
 	case StringFlag:
@@ -102,6 +212,36 @@ func (fd *FlagDef) SetValue(value any) {
 		if fd.Int64 != nil {
 			*fd.Int64 = v
 		}
+	case DurationFlag:
+		v := *value.(*time.Duration)
+		if fd.Duration != nil {
+			*fd.Duration = v
+		}
+	case Float64Flag:
+		v := *value.(*float64)
+		if fd.Float64 != nil {
+			*fd.Float64 = v
+		}
+	case StringsFlag:
+		v := *value.(*[]string)
+		if fd.Strings != nil {
+			*fd.Strings = slices.Clone(v)
+		}
+	case IntsFlag:
+		v := *value.(*[]int)
+		if fd.Ints != nil {
+			*fd.Ints = slices.Clone(v)
+		}
+	case MapFlag:
+		v := *value.(*map[string]string)
+		if fd.Map != nil {
+			*fd.Map = maps.Clone(v)
+		}
+	case CountFlag:
+		v := *value.(*int)
+		if fd.Count != nil {
+			*fd.Count = v
+		}
 	case UnknownFlagType:
 		// Just here to have all flag types in the switch
 	}
@@ -0,0 +1,98 @@
+package cliutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// FlagFilePrefix starts the value of a FlagDef with FromFile set that names
+// the file to read the value from, e.g. --token=@/run/secrets/token, or
+// stdin for "@-". Doubling it, as in "@@name", gives a literal "@name".
+const FlagFilePrefix = "@"
+
+var ErrFlagFileIO = errors.New("reading flag value from file failed")
+
+// valueFile returns the file to read fd's value from when value is given as
+// "@path" to a StringFlag with FromFile set; path is StdinArg for stdin
+func (fd *FlagDef) valueFile(value string) (path string, ok bool) {
+	if !fd.FromFile || fd.Type() != StringFlag {
+		goto end
+	}
+	if !strings.HasPrefix(value, FlagFilePrefix) || strings.HasPrefix(value, FlagFilePrefix+FlagFilePrefix) {
+		goto end
+	}
+	path = strings.TrimPrefix(value, FlagFilePrefix)
+	ok = path != ""
+end:
+	return path, ok
+}
+
+// fileFlagArg returns the "@path" given for fd on the command line, so that
+// logs and history show where a value was read from rather than the value
+func (fs *FlagSet) fileFlagArg(fd *FlagDef) (arg string, ok bool) {
+	value, isString := fs.Values[fd.Name].(*string)
+	if !isString || !strings.HasPrefix(*value, FlagFilePrefix) || !fd.FromFile {
+		goto end
+	}
+	arg, ok = *value, true
+end:
+	return arg, ok
+}
+
+// readFlagFiles replaces the value of each flag of cmd with FromFile set that
+// was given as "@path" with the contents of the file, less one trailing
+// newline, and then validates it. A value of "@@..." loses its first "@".
+// Only one flag or argument may read stdin; stdinUsed reports whether a flag
+// did, so that readStdinArg can refuse a second reader.
+func readFlagFiles(cmd Command, args CmdRunnerArgs) (stdinUsed bool, err error) {
+	var errs []error
+	var data []byte
+	var readErr error
+
+	for _, fs := range cmd.FlagSets() {
+		for i := range fs.FlagDefs {
+			fd := &fs.FlagDefs[i]
+			if !fd.FromFile || fd.Type() != StringFlag {
+				continue
+			}
+			if strings.HasPrefix(*fd.String, FlagFilePrefix+FlagFilePrefix) {
+				*fd.String = strings.TrimPrefix(*fd.String, FlagFilePrefix)
+				continue
+			}
+			path, ok := fd.valueFile(*fd.String)
+			if !ok {
+				continue
+			}
+			switch {
+			case path != StdinArg:
+				data, readErr = os.ReadFile(path)
+			case stdinUsed || args.stdinUsed:
+				errs = append(errs, WithErrClass(NewErr(ErrStdinInUse,
+					"flag", fd.Name,
+					"reason", "only one flag or argument can be read from stdin",
+				), UsageErr))
+				continue
+			default:
+				stdinUsed = true
+				data, readErr = io.ReadAll(stdinOf(args))
+			}
+			if readErr != nil {
+				errs = append(errs, NewErr(ErrFlagFileIO,
+					"flag", fd.Name,
+					"file", path,
+					readErr,
+				))
+				continue
+			}
+			*fd.String = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+			readErr = fd.ValidateValue(*fd.String)
+			if readErr != nil {
+				errs = append(errs, WithErr(readErr, "flag", fd.Name))
+			}
+		}
+	}
+	err = CombineErrs(errs)
+	return stdinUsed, err
+}
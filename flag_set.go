@@ -4,8 +4,12 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // FlagSet combines a FlagSet with automatic config binding
@@ -15,11 +19,124 @@ type FlagSet struct {
 	FlagDefs     []FlagDef
 	Values       map[string]any
 	unknownFlags []string // Tracks flags that don't belong to this FlagSet
+	index        flagIndex
+	sources      map[string]SettingSource // Set by SetSource; see Settings
 }
 
-// Parse extracts flags and returns remaining args
+// flagIndex maps a FlagSet's flag names and shortcuts to positions in
+// FlagDefs, so parsing and validation look flags up in constant time rather
+// than scanning FlagDefs for every argument. It is rebuilt by Build, and
+// whenever FlagDefs has been appended to or replaced since it was built.
+type flagIndex struct {
+	defs      []FlagDef // FlagDefs as indexed
+	names     map[string]int
+	shortcuts map[byte]int
+}
+
+// indexed returns the index of fs's FlagDefs, rebuilding it if it is stale
+func (fs *FlagSet) indexed() *flagIndex {
+	idx := &fs.index
+	if idx.names != nil && len(idx.defs) == len(fs.FlagDefs) &&
+		(len(fs.FlagDefs) == 0 || &idx.defs[0] == &fs.FlagDefs[0]) {
+		goto end
+	}
+	fs.reindex()
+end:
+	return idx
+}
+
+func (fs *FlagSet) reindex() {
+	fs.index = flagIndex{
+		defs:      fs.FlagDefs,
+		names:     make(map[string]int, len(fs.FlagDefs)),
+		shortcuts: make(map[byte]int),
+	}
+	for i, fd := range fs.FlagDefs {
+		// The first definition wins, as it did for linear scans
+		if _, ok := fs.index.names[fd.Name]; !ok {
+			fs.index.names[fd.Name] = i
+		}
+		if fd.Shortcut == 0 {
+			continue
+		}
+		if _, ok := fs.index.shortcuts[fd.Shortcut]; !ok {
+			fs.index.shortcuts[fd.Shortcut] = i
+		}
+	}
+}
+
+// lookupName returns the FlagDef with the long name, or nil
+func (fs *FlagSet) lookupName(name string) (fd *FlagDef) {
+	i, ok := fs.indexed().names[name]
+	if ok {
+		fd = &fs.FlagDefs[i]
+	}
+	return fd
+}
+
+// lookupShortcut returns the FlagDef with the shortcut, or nil
+func (fs *FlagSet) lookupShortcut(c byte) (fd *FlagDef) {
+	i, ok := fs.indexed().shortcuts[c]
+	if ok {
+		fd = &fs.FlagDefs[i]
+	}
+	return fd
+}
+
+// lookupFlag returns the FlagDef whose long name or shortcut is name, as
+// listed by FlagNames, or nil
+func (fs *FlagSet) lookupFlag(name string) (fd *FlagDef) {
+	fd = fs.lookupName(name)
+	if fd == nil && len(name) == 1 {
+		fd = fs.lookupShortcut(name[0])
+	}
+	return fd
+}
+
+// HasFlag returns true if name is one of the names returned by FlagNames,
+// or the --no-<name> form of one of its BoolFlags
+func (fs *FlagSet) HasFlag(name string) bool {
+	return fs.lookupFlag(name) != nil || fs.negatedFlag(name) != nil
+}
+
+// NegatedFlagPrefix prefixes the name of a BoolFlag to turn it off, as
+// --no-color turns off --color
+const NegatedFlagPrefix = "no-"
+
+// negatedFlag returns the BoolFlag that name turns off, as no-color turns off
+// color, or nil; a flag actually named name is not a negation
+func (fs *FlagSet) negatedFlag(name string) (fd *FlagDef) {
+	base, ok := strings.CutPrefix(name, NegatedFlagPrefix)
+	if !ok || fs.lookupName(name) != nil {
+		goto end
+	}
+	fd = fs.lookupName(base)
+	if fd != nil && fd.Type() != BoolFlag {
+		fd = nil
+	}
+end:
+	return fd
+}
+
+// ArgsTerminator ends the flags of a command line, so every arg after it is
+// positional even if it starts with a dash, as in: app exec -- ls -la
+const ArgsTerminator = "--"
+
+// splitAtTerminator returns the args before ArgsTerminator, and the rest of
+// args from ArgsTerminator on, which is nil when args has none
+func splitAtTerminator(args []string) (flagArgs, tail []string) {
+	i := slices.Index(args, ArgsTerminator)
+	if i < 0 {
+		return args, nil
+	}
+	return args[:i:i], args[i:]
+}
+
+// Parse extracts flags and returns remaining args. Args from ArgsTerminator
+// on are not parsed and are returned as given, terminator included, so
+// FlagSets parsed later stop there too.
 func (fs *FlagSet) Parse(args []string) (remainingArgs []string, err error) {
-	var fsFlagNames, fsArgs, nonFSArgs []string
+	var fsArgs, nonFSArgs, tail []string
 
 	if fs == nil {
 		err = fmt.Errorf("FlagSet is nil")
@@ -31,9 +148,17 @@ func (fs *FlagSet) Parse(args []string) (remainingArgs []string, err error) {
 		goto end
 	}
 
+	args, tail = splitAtTerminator(args)
+
+	// Split bundled shortcuts like -qf so each can be classified
+	if GetFlagSyntax() == PFlagSyntax {
+		args = fs.expandShortcutBundles(args)
+	} else {
+		args = fs.expandGoBundles(args)
+	}
+
 	// Parse only the flags, collect non-flag arguments
-	fsFlagNames = fs.FlagNames()
-	fsArgs, nonFSArgs = fs.classifyFlagArgs(args, fsFlagNames)
+	fsArgs, nonFSArgs = fs.classifyFlagArgs(args)
 
 	if len(fsArgs) == 0 {
 		goto end
@@ -53,7 +178,7 @@ func (fs *FlagSet) Parse(args []string) (remainingArgs []string, err error) {
 	err = fs.Assign()
 
 end:
-	return nonFSArgs, err
+	return append(nonFSArgs, tail...), err
 }
 
 func (fs *FlagSet) Build() (err error) {
@@ -63,16 +188,20 @@ func (fs *FlagSet) Build() (err error) {
 		err = fmt.Errorf("name cannot be empty for FlagSet with flags %v", fs.FlagNames())
 	}
 
+	fs.reindex()
 	fs.FlagSet = flag.NewFlagSet(fs.Name, flag.ContinueOnError)
+	// Parse errors are returned and reported by cliutil, not printed by package flag
+	fs.FlagSet.SetOutput(io.Discard)
 	fs.Values = make(map[string]any)
 
 	// Add all defined flags to the flag set
 	for _, flagDef := range fs.FlagDefs {
+		def := flagDef.defaultValue()
 		switch flagDef.Type() {
 		case StringFlag:
 			defaultVal := ""
-			if flagDef.Default != nil {
-				defaultVal = flagDef.Default.(string)
+			if def != nil {
+				defaultVal = def.(string)
 				*flagDef.String = defaultVal
 			}
 			fs.Values[flagDef.Name] = fs.FlagSet.String(flagDef.Name, defaultVal, flagDef.Usage)
@@ -83,20 +212,26 @@ func (fs *FlagSet) Build() (err error) {
 			}
 		case BoolFlag:
 			defaultVal := false
-			if flagDef.Default != nil {
-				defaultVal = flagDef.Default.(bool)
+			if def != nil {
+				defaultVal = def.(bool)
 				*flagDef.Bool = defaultVal
 			}
 			fs.Values[flagDef.Name] = fs.FlagSet.Bool(flagDef.Name, defaultVal, flagDef.Usage)
-			// Register shortcut as alias if defined
+			// The shortcut shares the long name's value, as --no-<name> does,
+			// so whichever is given last wins
 			if flagDef.Shortcut != 0 {
 				shortcutName := string(flagDef.Shortcut)
-				fs.Values[shortcutName] = fs.FlagSet.Bool(shortcutName, defaultVal, flagDef.Usage)
+				fs.FlagSet.Var(fs.FlagSet.Lookup(flagDef.Name).Value, shortcutName, flagDef.Usage)
+				fs.Values[shortcutName] = fs.Values[flagDef.Name]
+			}
+			// Register --no-<name> unless a flag already has that name
+			if negated := NegatedFlagPrefix + flagDef.Name; fs.lookupName(negated) == nil {
+				fs.FlagSet.Var(&negatedValue{value: fs.Values[flagDef.Name].(*bool)}, negated, flagDef.Usage)
 			}
 		case Int64Flag:
 			defaultVal := int64(0)
-			if flagDef.Default != nil {
-				defaultVal = flagDef.Default.(int64)
+			if def != nil {
+				defaultVal = def.(int64)
 				*flagDef.Int64 = defaultVal
 			}
 			fs.Values[flagDef.Name] = fs.FlagSet.Int64(flagDef.Name, defaultVal, flagDef.Usage)
@@ -107,8 +242,8 @@ func (fs *FlagSet) Build() (err error) {
 			}
 		case IntFlag:
 			defaultVal := 0
-			if flagDef.Default != nil {
-				defaultVal = flagDef.Default.(int)
+			if def != nil {
+				defaultVal = def.(int)
 				*flagDef.Int = defaultVal
 			}
 			fs.Values[flagDef.Name] = fs.FlagSet.Int(flagDef.Name, defaultVal, flagDef.Usage)
@@ -117,6 +252,82 @@ func (fs *FlagSet) Build() (err error) {
 				shortcutName := string(flagDef.Shortcut)
 				fs.Values[shortcutName] = fs.FlagSet.Int(shortcutName, defaultVal, flagDef.Usage)
 			}
+		case DurationFlag:
+			defaultVal := time.Duration(0)
+			if def != nil {
+				defaultVal = def.(time.Duration)
+				*flagDef.Duration = defaultVal
+			}
+			fs.Values[flagDef.Name] = fs.durationVar(flagDef.Name, defaultVal, flagDef.Usage)
+			// Register shortcut as alias if defined
+			if flagDef.Shortcut != 0 {
+				shortcutName := string(flagDef.Shortcut)
+				fs.Values[shortcutName] = fs.durationVar(shortcutName, defaultVal, flagDef.Usage)
+			}
+		case Float64Flag:
+			defaultVal := float64(0)
+			if def != nil {
+				defaultVal = def.(float64)
+				*flagDef.Float64 = defaultVal
+			}
+			fs.Values[flagDef.Name] = fs.FlagSet.Float64(flagDef.Name, defaultVal, flagDef.Usage)
+			// Register shortcut as alias if defined
+			if flagDef.Shortcut != 0 {
+				shortcutName := string(flagDef.Shortcut)
+				fs.Values[shortcutName] = fs.FlagSet.Float64(shortcutName, defaultVal, flagDef.Usage)
+			}
+		case StringsFlag:
+			var defaultVal []string
+			if def != nil {
+				defaultVal = slices.Clone(def.([]string))
+			}
+			*flagDef.Strings = slices.Clone(defaultVal)
+			fs.Values[flagDef.Name] = fs.stringsVar(flagDef.Name, defaultVal, flagDef.Usage)
+			// The shortcut shares the long name's value so repeats accumulate
+			if flagDef.Shortcut != 0 {
+				shortcutName := string(flagDef.Shortcut)
+				fs.FlagSet.Var(fs.FlagSet.Lookup(flagDef.Name).Value, shortcutName, flagDef.Usage)
+				fs.Values[shortcutName] = fs.Values[flagDef.Name]
+			}
+		case CountFlag:
+			defaultVal := 0
+			if def != nil {
+				defaultVal = def.(int)
+			}
+			*flagDef.Count = defaultVal
+			fs.Values[flagDef.Name] = fs.countVar(flagDef.Name, defaultVal, flagDef.Usage)
+			// The shortcut shares the long name's count so -v -v counts 2
+			if flagDef.Shortcut != 0 {
+				shortcutName := string(flagDef.Shortcut)
+				fs.FlagSet.Var(fs.FlagSet.Lookup(flagDef.Name).Value, shortcutName, flagDef.Usage)
+				fs.Values[shortcutName] = fs.Values[flagDef.Name]
+			}
+		case MapFlag:
+			var defaultVal map[string]string
+			if def != nil {
+				defaultVal = maps.Clone(def.(map[string]string))
+			}
+			*flagDef.Map = maps.Clone(defaultVal)
+			fs.Values[flagDef.Name] = fs.mapVar(flagDef.Name, defaultVal, flagDef.UniqueKeys, flagDef.Usage)
+			// The shortcut shares the long name's value so repeats accumulate
+			if flagDef.Shortcut != 0 {
+				shortcutName := string(flagDef.Shortcut)
+				fs.FlagSet.Var(fs.FlagSet.Lookup(flagDef.Name).Value, shortcutName, flagDef.Usage)
+				fs.Values[shortcutName] = fs.Values[flagDef.Name]
+			}
+		case IntsFlag:
+			var defaultVal []int
+			if def != nil {
+				defaultVal = slices.Clone(def.([]int))
+			}
+			*flagDef.Ints = slices.Clone(defaultVal)
+			fs.Values[flagDef.Name] = fs.intsVar(flagDef.Name, defaultVal, flagDef.Usage)
+			// The shortcut shares the long name's value so repeats accumulate
+			if flagDef.Shortcut != 0 {
+				shortcutName := string(flagDef.Shortcut)
+				fs.FlagSet.Var(fs.FlagSet.Lookup(flagDef.Name).Value, shortcutName, flagDef.Usage)
+				fs.Values[shortcutName] = fs.Values[flagDef.Name]
+			}
 		default:
 			errs = append(errs, fmt.Errorf("unknown flag type for %s", flagDef.Name))
 		}
@@ -127,6 +338,46 @@ func (fs *FlagSet) Build() (err error) {
 	return err
 }
 
+// stringsVar defines a StringsFlag named name and returns its values
+func (fs *FlagSet) stringsVar(name string, value []string, usage string) *[]string {
+	p := new([]string)
+	*p = value
+	fs.FlagSet.Var(&stringsValue{values: p}, name, usage)
+	return p
+}
+
+// intsVar defines an IntsFlag named name and returns its values
+func (fs *FlagSet) intsVar(name string, value []int, usage string) *[]int {
+	p := new([]int)
+	*p = value
+	fs.FlagSet.Var(&intsValue{values: p}, name, usage)
+	return p
+}
+
+// countVar defines a CountFlag named name and returns its count
+func (fs *FlagSet) countVar(name string, value int, usage string) *int {
+	p := new(int)
+	*p = value
+	fs.FlagSet.Var(&countValue{count: p}, name, usage)
+	return p
+}
+
+// mapVar defines a MapFlag named name and returns its values
+func (fs *FlagSet) mapVar(name string, value map[string]string, unique bool, usage string) *map[string]string {
+	p := new(map[string]string)
+	*p = value
+	fs.FlagSet.Var(&mapValue{values: p, unique: unique}, name, usage)
+	return p
+}
+
+// durationVar defines a DurationFlag named name and returns its value
+func (fs *FlagSet) durationVar(name string, value time.Duration, usage string) *time.Duration {
+	p := new(time.Duration)
+	*p = value
+	fs.FlagSet.Var((*durationValue)(p), name, usage)
+	return p
+}
+
 func (fs *FlagSet) FlagNames() (names []string) {
 	for _, fd := range fs.FlagDefs {
 		names = append(names, fd.Name)
@@ -154,6 +405,10 @@ func (fs *FlagSet) Validate() (err error) {
 		case StringFlag:
 			stringPtr := fs.Values[flagDef.Name].(*string)
 			value = *stringPtr
+			// A value read from a file is validated once RunCmd reads it
+			if _, ok := flagDef.valueFile(*stringPtr); ok {
+				continue
+			}
 		case BoolFlag:
 			boolPtr := fs.Values[flagDef.Name].(*bool)
 			value = *boolPtr
@@ -163,6 +418,24 @@ func (fs *FlagSet) Validate() (err error) {
 		case IntFlag:
 			intPtr := fs.Values[flagDef.Name].(*int)
 			value = *intPtr
+		case DurationFlag:
+			durationPtr := fs.Values[flagDef.Name].(*time.Duration)
+			value = *durationPtr
+		case Float64Flag:
+			float64Ptr := fs.Values[flagDef.Name].(*float64)
+			value = *float64Ptr
+		case StringsFlag:
+			stringsPtr := fs.Values[flagDef.Name].(*[]string)
+			value = *stringsPtr
+		case IntsFlag:
+			intsPtr := fs.Values[flagDef.Name].(*[]int)
+			value = *intsPtr
+		case MapFlag:
+			mapPtr := fs.Values[flagDef.Name].(*map[string]string)
+			value = *mapPtr
+		case CountFlag:
+			countPtr := fs.Values[flagDef.Name].(*int)
+			value = *countPtr
 		default:
 			errs = append(errs, fmt.Errorf("unknown flag type for %s", flagDef.Name))
 			continue
@@ -179,14 +452,14 @@ func (fs *FlagSet) Validate() (err error) {
 }
 
 // classifyFlagArgs separates arguments into flag args and non-flag args
-func (fs *FlagSet) classifyFlagArgs(args []string, fsFlagNames []string) (fsArgs []string, nonFSArgs []string) {
+func (fs *FlagSet) classifyFlagArgs(args []string) (fsArgs []string, nonFSArgs []string) {
 	var i int
 
 	for i < len(args) {
 		arg := args[i]
 
 		// Non-flag argument
-		if !strings.HasPrefix(arg, "-") {
+		if !isFlagArg(arg) {
 			nonFSArgs = append(nonFSArgs, arg)
 			i++
 			continue
@@ -202,7 +475,7 @@ func (fs *FlagSet) classifyFlagArgs(args []string, fsFlagNames []string) (fsArgs
 		}
 
 		// Check if this flag belongs to this FlagSet
-		if !slices.Contains(fsFlagNames, flagName) {
+		if !fs.hasFlagArg(arg, flagName) {
 			// This flag doesn't belong to us - track it as unknown and preserve it in nonFSArgs
 			fs.unknownFlags = append(fs.unknownFlags, arg)
 			nonFSArgs = append(nonFSArgs, arg)
@@ -215,7 +488,7 @@ func (fs *FlagSet) classifyFlagArgs(args []string, fsFlagNames []string) (fsArgs
 
 			// Check if next argument is the flag value (not another flag)
 			// and include it with the unknown flag
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			if i+1 < len(args) && !isFlagArg(args[i+1]) {
 				fs.unknownFlags = append(fs.unknownFlags, args[i+1])
 				nonFSArgs = append(nonFSArgs, args[i+1])
 				i += 2 // Skip both flag and value
@@ -234,8 +507,26 @@ func (fs *FlagSet) classifyFlagArgs(args []string, fsFlagNames []string) (fsArgs
 			continue
 		}
 
+		// Boolean flags never take a separate value, so the next argument
+		// (e.g. a command name following --force) must not be consumed
+		if fs.isBoolFlag(flagName) {
+			i++
+			continue
+		}
+
+		// A count flag takes a separate value only when it is a count, as
+		// in -v 3, which package flag needs given as -v=3
+		if fs.isCountFlag(flagName) {
+			if i+1 < len(args) && isCount(args[i+1]) {
+				fsArgs[len(fsArgs)-1] = arg + "=" + args[i+1]
+				i++
+			}
+			i++
+			continue
+		}
+
 		// Check if next argument is the flag value (not another flag)
-		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+		if i+1 < len(args) && !isFlagArg(args[i+1]) {
 			fsArgs = append(fsArgs, args[i+1])
 			i += 2 // Skip both flag and value
 		} else {
@@ -246,6 +537,34 @@ func (fs *FlagSet) classifyFlagArgs(args []string, fsFlagNames []string) (fsArgs
 	return fsArgs, nonFSArgs
 }
 
+// hasFlagArg returns true if arg, whose name is flagName, names one of fs's
+// flags under the current FlagSyntax
+func (fs *FlagSet) hasFlagArg(arg, flagName string) bool {
+	if GetFlagSyntax() == PFlagSyntax {
+		return fs.hasPFlag(arg, flagName)
+	}
+	return fs.HasFlag(flagName)
+}
+
+// isBoolFlag returns true if name or shortcut identifies a boolean FlagDef,
+// or name is its --no-<name> form
+func (fs *FlagSet) isBoolFlag(name string) bool {
+	fd := fs.lookupFlag(name)
+	return fd != nil && fd.Type() == BoolFlag || fs.negatedFlag(name) != nil
+}
+
+// isCountFlag returns true if name or shortcut identifies a CountFlag
+func (fs *FlagSet) isCountFlag(name string) bool {
+	fd := fs.lookupFlag(name)
+	return fd != nil && fd.Type() == CountFlag
+}
+
+// isCount returns true if arg is a count such as the 3 of -v 3
+func isCount(arg string) bool {
+	_, err := strconv.Atoi(arg)
+	return err == nil
+}
+
 func (fs *FlagSet) Assign() (err error) {
 	var errs []error
 	for _, flagDef := range fs.FlagDefs {
@@ -268,6 +587,24 @@ func (fs *FlagSet) Assign() (err error) {
 		case IntFlag:
 			value := fs.Values[flagDef.Name].(*int)
 			*flagDef.Int = *value
+		case DurationFlag:
+			value := fs.Values[flagDef.Name].(*time.Duration)
+			*flagDef.Duration = *value
+		case Float64Flag:
+			value := fs.Values[flagDef.Name].(*float64)
+			*flagDef.Float64 = *value
+		case StringsFlag:
+			value := fs.Values[flagDef.Name].(*[]string)
+			*flagDef.Strings = slices.Clone(*value)
+		case IntsFlag:
+			value := fs.Values[flagDef.Name].(*[]int)
+			*flagDef.Ints = slices.Clone(*value)
+		case MapFlag:
+			value := fs.Values[flagDef.Name].(*map[string]string)
+			*flagDef.Map = maps.Clone(*value)
+		case CountFlag:
+			value := fs.Values[flagDef.Name].(*int)
+			*flagDef.Count = *value
 		default:
 			errs = append(errs, fmt.Errorf("unknown flag type for %s", flagDef.Name))
 		}
@@ -296,6 +633,10 @@ func (fs *FlagSet) syncFlagValues(longName, shortName string) {
 			*v = *shortVal.(*int64)
 		case *int:
 			*v = *shortVal.(*int)
+		case *time.Duration:
+			*v = *shortVal.(*time.Duration)
+		case *float64:
+			*v = *shortVal.(*float64)
 		}
 	}
 }
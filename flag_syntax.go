@@ -0,0 +1,186 @@
+package cliutil
+
+import (
+	"strings"
+	"sync"
+)
+
+// FlagSyntax selects how command-line flags are recognized
+type FlagSyntax int
+
+const (
+	// GoFlagSyntax follows package flag: -name and --name are equivalent.
+	// Shortcuts may be bundled or take an attached value, so -qf is -q -f,
+	// -n5 is -n=5, and -vt30 is -v -t=30, when the bundle names no flag and
+	// its shortcuts belong to one FlagSet.
+	GoFlagSyntax FlagSyntax = iota
+	// PFlagSyntax follows spf13/pflag as used by kubectl-style tools: long
+	// names need "--", shortcuts need "-" and may be bundled, so -qf is
+	// -q -f and -n3 or -n=3 is -n 3, and -f=false turns a bool shortcut off
+	PFlagSyntax
+)
+
+// Package-level flag syntax
+var (
+	flagSyntax   = GoFlagSyntax
+	flagSyntaxMu sync.RWMutex // synchronizes access to flagSyntax
+)
+
+// SetFlagSyntax selects the FlagSyntax used when parsing global and command
+// flags, returning the previous one. Call it before ParseGlobalOptions.
+func SetFlagSyntax(s FlagSyntax) (prev FlagSyntax) {
+	flagSyntaxMu.Lock()
+	defer flagSyntaxMu.Unlock()
+	prev = flagSyntax
+	flagSyntax = s
+	return prev
+}
+
+// GetFlagSyntax returns the FlagSyntax used when parsing flags
+func GetFlagSyntax() FlagSyntax {
+	flagSyntaxMu.RLock()
+	defer flagSyntaxMu.RUnlock()
+	return flagSyntax
+}
+
+// shortcutLookup reports whether c is a known shortcut and whether it is a bool
+type shortcutLookup func(c byte) (known, isBool bool)
+
+// isShortcutBundle returns true if arg is a single-dash flag such as -q,
+// -qf, or -n3 rather than a --long flag or a lone "-"
+func isShortcutBundle(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-' && arg[1] != '-'
+}
+
+// splitShortcutBundle splits a pflag-style bundle like -qfn3 into the
+// separate shortcuts lookup recognizes, e.g. -q, -f, and -n=3, stopping at the
+// first shortcut lookup does not recognize; rest is "-" plus the unrecognized
+// remainder, or "" if the whole bundle was recognized. A non-bool shortcut
+// takes the rest of the bundle as its value, or the next arg if none.
+func splitShortcutBundle(arg string, lookup shortcutLookup) (owned []string, rest string) {
+	var value string
+
+	for i := 1; i < len(arg); i++ {
+		c := arg[i]
+		known, isBool := lookup(c)
+		if !known {
+			rest = "-" + arg[i:]
+			goto end
+		}
+		value = arg[i+1:]
+		switch {
+		case strings.HasPrefix(value, "="):
+			// -f=false or -n=3 ends the bundle
+			owned = append(owned, "-"+string(c)+value)
+			goto end
+		case isBool:
+			owned = append(owned, "-"+string(c))
+		case value == "":
+			owned = append(owned, "-"+string(c))
+			goto end
+		default:
+			owned = append(owned, "-"+string(c)+"="+value)
+			goto end
+		}
+	}
+end:
+	return owned, rest
+}
+
+// expandShortcutBundles replaces each pflag-style bundle in args with the
+// separate shortcuts fs recognizes, leaving any unrecognized remainder as a
+// smaller bundle for the next FlagSet to parse
+func (fs *FlagSet) expandShortcutBundles(args []string) (expanded []string) {
+	for _, arg := range args {
+		if !isShortcutBundle(arg) {
+			expanded = append(expanded, arg)
+			continue
+		}
+		owned, rest := splitShortcutBundle(arg, fs.shortcutKind)
+		expanded = append(expanded, owned...)
+		if rest != "" {
+			expanded = append(expanded, rest)
+		}
+	}
+	return expanded
+}
+
+// shortcutKind implements shortcutLookup for the shortcuts defined by fs; a
+// CountFlag is bundled like a bool, so -vvv is -v -v -v
+func (fs *FlagSet) shortcutKind(c byte) (known, isBool bool) {
+	fd := fs.lookupShortcut(c)
+	if fd != nil {
+		known = true
+		isBool = fd.Type() == BoolFlag || fd.Type() == CountFlag
+	}
+	return known, isBool
+}
+
+// expandGoBundles replaces each bundle of fs's shortcuts in args, such as
+// -qf or -vt30, with the separate shortcuts. Under GoFlagSyntax -name is a
+// long flag too, so only a bundle that names no flag and whose shortcuts
+// all belong to fs is split.
+func (fs *FlagSet) expandGoBundles(args []string) (expanded []string) {
+	for _, arg := range args {
+		owned, ok := fs.goBundle(arg)
+		if !ok {
+			expanded = append(expanded, arg)
+			continue
+		}
+		expanded = append(expanded, owned...)
+	}
+	return expanded
+}
+
+// goBundle returns the separate shortcuts of arg when it is a bundle of fs's
+// shortcuts under GoFlagSyntax (see expandGoBundles)
+func (fs *FlagSet) goBundle(arg string) (owned []string, ok bool) {
+	var rest string
+
+	name, _, _ := strings.Cut(arg[min(1, len(arg)):], "=")
+	if len(arg) < 3 || !isShortcutBundle(arg) || fs.HasFlag(name) {
+		goto end
+	}
+	owned, rest = splitShortcutBundle(arg, fs.shortcutKind)
+	ok = rest == "" && len(owned) > 0
+end:
+	return owned, ok
+}
+
+// hasPFlag returns true if arg, whose name is flagName, names one of fs's
+// flags under PFlagSyntax: a single dash names a shortcut and a double dash
+// names a long flag
+func (fs *FlagSet) hasPFlag(arg, flagName string) bool {
+	if strings.HasPrefix(arg, "--") {
+		return fs.lookupName(flagName) != nil || fs.negatedFlag(flagName) != nil
+	}
+	return len(flagName) == 1 && fs.lookupShortcut(flagName[0]) != nil
+}
+
+// isKnownPFlag returns true if every flag in arg, which may be a bundle of
+// shortcuts, is defined by one of flagSets under PFlagSyntax. Bundles are
+// split by each FlagSet in turn, as FlagSet.Parse does, so a shortcut that
+// follows one belonging to a later FlagSet is not recognized.
+func isKnownPFlag(arg string, flagSets []*FlagSet) (known bool) {
+	var flagName string
+
+	if isShortcutBundle(arg) {
+		for _, fs := range flagSets {
+			_, arg = splitShortcutBundle(arg, fs.shortcutKind)
+			if arg == "" {
+				break
+			}
+		}
+		known = arg == ""
+		goto end
+	}
+	flagName, _, _ = strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+	for _, fs := range flagSets {
+		known = fs.hasPFlag(arg, flagName)
+		if known {
+			break
+		}
+	}
+end:
+	return known
+}
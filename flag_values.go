@@ -0,0 +1,342 @@
+package cliutil
+
+import (
+	"errors"
+	"flag"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces the value of sensitive flags in logs and traces
+const RedactedValue = "[REDACTED]"
+
+// sensitiveFlagWords mark a flag as sensitive when they appear in its name,
+// even if FlagDef.Sensitive was not set
+var sensitiveFlagWords = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apikey",
+	"api-key",
+	"credential",
+	"private-key",
+}
+
+// IsSensitive returns true if the flag's value must not be logged, either
+// because Sensitive is set or because its name suggests a secret
+func (fd *FlagDef) IsSensitive() bool {
+	if fd.Sensitive {
+		return true
+	}
+	name := strings.ToLower(fd.Name)
+	for _, word := range sensitiveFlagWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the flag's current value from its target pointer, or nil if
+// it has no target
+func (fd *FlagDef) Value() (value any) {
+	switch fd.Type() {
+	case StringFlag:
+		value = *fd.String
+	case BoolFlag:
+		value = *fd.Bool
+	case Int64Flag:
+		value = *fd.Int64
+	case IntFlag:
+		value = *fd.Int
+	case DurationFlag:
+		value = *fd.Duration
+	case Float64Flag:
+		value = *fd.Float64
+	case StringsFlag:
+		value = *fd.Strings
+	case IntsFlag:
+		value = *fd.Ints
+	case MapFlag:
+		value = *fd.Map
+	case CountFlag:
+		value = *fd.Count
+	}
+	return value
+}
+
+// SetFlags returns the flags explicitly given on the command line, keyed by
+// long name, for this FlagSet after Parse. Sensitive values are replaced with
+// RedactedValue so the result is safe to log.
+func (fs *FlagSet) SetFlags() (flags map[string]any) {
+	var given map[string]bool
+
+	if fs == nil || fs.FlagSet == nil {
+		goto end
+	}
+	given = fs.givenFlags()
+	for i := range fs.FlagDefs {
+		fd := &fs.FlagDefs[i]
+		if !given[fd.Name] {
+			continue
+		}
+		if flags == nil {
+			flags = make(map[string]any)
+		}
+		if fd.IsSensitive() {
+			flags[fd.Name] = RedactedValue
+			continue
+		}
+		if arg, ok := fs.fileFlagArg(fd); ok {
+			flags[fd.Name] = arg
+			continue
+		}
+		flags[fd.Name] = jsonSafeValue(fd.Value())
+	}
+end:
+	return flags
+}
+
+// SanitizedFlags returns the global flags of cmd's CLI and the flags of cmd
+// explicitly given, with sensitive values redacted (see FlagSet.SetFlags)
+func SanitizedFlags(cmd Command) (flags map[string]any) {
+	flags = make(map[string]any)
+	for name, value := range cliOf(cmd).GlobalFlagSet().SetFlags() {
+		flags[name] = value
+	}
+	if cmd == nil {
+		goto end
+	}
+	for _, fs := range cmd.FlagSets() {
+		for name, value := range fs.SetFlags() {
+			flags[name] = value
+		}
+	}
+end:
+	return flags
+}
+
+var _ flag.Value = (*durationValue)(nil)
+
+// durationValue parses a DurationFlag with time.ParseDuration, also taking a
+// bare number as seconds so that --timeout=30 means what it always has
+type durationValue time.Duration
+
+func (d *durationValue) Set(s string) (err error) {
+	var v time.Duration
+	var secs int64
+
+	v, err = time.ParseDuration(s)
+	if err == nil {
+		goto end
+	}
+	secs, err = strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		err = errors.New("expected a duration such as 90s or 5m, or a number of seconds")
+		goto end
+	}
+	v = time.Duration(secs) * time.Second
+end:
+	if err == nil {
+		*d = durationValue(v)
+	}
+	return err
+}
+
+func (d *durationValue) String() string {
+	return time.Duration(*d).String()
+}
+
+var _ flag.Value = (*stringsValue)(nil)
+
+// stringsValue accumulates a StringsFlag given more than once: the first Set
+// replaces the default and each later Set appends, so --tag=a --tag=b gives
+// [a b]. A flag and its shortcut share one stringsValue so that mixing them
+// accumulates too.
+type stringsValue struct {
+	values *[]string
+	set    bool
+}
+
+func (v *stringsValue) Set(s string) error {
+	if !v.set {
+		*v.values = nil
+		v.set = true
+	}
+	*v.values = append(*v.values, s)
+	return nil
+}
+
+func (v *stringsValue) String() string {
+	if v.values == nil {
+		return ""
+	}
+	return strings.Join(*v.values, ",")
+}
+
+var _ flag.Value = (*intsValue)(nil)
+
+// intsValue accumulates an IntsFlag like stringsValue, also taking several
+// comma-separated values at once, so --port=80,443 and --port=80 --port=443
+// are the same
+type intsValue struct {
+	values *[]int
+	set    bool
+}
+
+func (v *intsValue) Set(s string) (err error) {
+	var n int
+	var values []int
+
+	for item := range strings.SplitSeq(s, ",") {
+		n, err = strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			err = errors.New("expected an integer or comma-separated integers")
+			goto end
+		}
+		values = append(values, n)
+	}
+	if !v.set {
+		*v.values = nil
+		v.set = true
+	}
+	*v.values = append(*v.values, values...)
+end:
+	return err
+}
+
+func (v *intsValue) String() string {
+	var parts []string
+
+	if v.values == nil {
+		goto end
+	}
+	for _, n := range *v.values {
+		parts = append(parts, strconv.Itoa(n))
+	}
+end:
+	return strings.Join(parts, ",")
+}
+
+var _ flag.Value = (*mapValue)(nil)
+
+// errDuplicateMapKey is returned for a MapFlag with FlagDef.UniqueKeys set
+// when a key is given twice
+var errDuplicateMapKey = errors.New("key given more than once")
+
+// mapValue accumulates a MapFlag given as key=value, like stringsValue. A
+// key given again replaces its value unless unique is set, when it fails.
+type mapValue struct {
+	values *map[string]string
+	unique bool
+	set    bool
+}
+
+func (v *mapValue) Set(s string) (err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		err = errors.New("expected key=value")
+		goto end
+	}
+	if !v.set {
+		*v.values = make(map[string]string)
+		v.set = true
+	}
+	if _, ok = (*v.values)[key]; ok && v.unique {
+		err = NewErr(errDuplicateMapKey, "key", key)
+		goto end
+	}
+	(*v.values)[key] = value
+end:
+	return err
+}
+
+func (v *mapValue) String() string {
+	var parts []string
+
+	if v.values == nil {
+		goto end
+	}
+	for _, key := range slices.Sorted(maps.Keys(*v.values)) {
+		parts = append(parts, key+"="+(*v.values)[key])
+	}
+end:
+	return strings.Join(parts, ",")
+}
+
+var _ flag.Value = (*countValue)(nil)
+
+// countValue counts how many times a CountFlag is given, so -vvv or -v -v -v
+// gives 3. Like a bool flag it takes no value, though -v=2 or --verbosity=2
+// sets the count. The count starts from zero, not the default, the first time
+// the flag is given.
+type countValue struct {
+	count *int
+	set   bool
+}
+
+func (v *countValue) Set(s string) (err error) {
+	var n int
+
+	if s == "true" {
+		if !v.set {
+			*v.count = 0
+		}
+		*v.count++
+		goto end
+	}
+	n, err = strconv.Atoi(s)
+	if err != nil || n < 0 {
+		err = errors.New("expected a count of zero or more")
+		goto end
+	}
+	*v.count = n
+end:
+	if err == nil {
+		v.set = true
+	}
+	return err
+}
+
+func (v *countValue) String() string {
+	if v.count == nil {
+		return "0"
+	}
+	return strconv.Itoa(*v.count)
+}
+
+// IsBoolFlag lets package flag accept the flag without a value
+func (v *countValue) IsBoolFlag() bool {
+	return true
+}
+
+var _ flag.Value = (*negatedValue)(nil)
+
+// negatedValue is the --no-<name> form of a BoolFlag, which sets the flag's
+// value to the opposite of its own, so --no-color turns --color off
+type negatedValue struct {
+	value *bool
+}
+
+func (v *negatedValue) Set(s string) (err error) {
+	var b bool
+
+	b, err = strconv.ParseBool(s)
+	if err == nil {
+		*v.value = !b
+	}
+	return err
+}
+
+func (v *negatedValue) String() string {
+	return strconv.FormatBool(v.value != nil && !*v.value)
+}
+
+// IsBoolFlag lets package flag accept the flag without a value
+func (v *negatedValue) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,253 @@
+package cliutil
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Locale holds the conventions FormatInt, FormatBytes, and FormatDuration
+// follow for digits
+type Locale struct {
+	Tag     string // e.g. "de_DE"
+	Group   string // Thousands separator; empty for no grouping
+	Decimal string // Decimal separator
+}
+
+// localeSeparators maps a language, or a language and territory, to its
+// group and decimal separators; other locales use English conventions.
+// Languages that group with a space use a no-break space, so numbers are
+// never split across lines.
+var localeSeparators = map[string][2]string{
+	"C":     {"", "."},
+	"POSIX": {"", "."},
+	"de":    {".", ","},
+	"de_CH": {"'", "."},
+	"es":    {".", ","},
+	"it":    {".", ","},
+	"nl":    {".", ","},
+	"pt":    {".", ","},
+	"id":    {".", ","},
+	"tr":    {".", ","},
+	"da":    {".", ","},
+	"fr":    {"\u00a0", ","},
+	"ru":    {"\u00a0", ","},
+	"uk":    {"\u00a0", ","},
+	"pl":    {"\u00a0", ","},
+	"cs":    {"\u00a0", ","},
+	"sv":    {"\u00a0", ","},
+	"fi":    {"\u00a0", ","},
+	"nb":    {"\u00a0", ","},
+}
+
+// ParseLocale returns the Locale for a POSIX locale name such as
+// "de_DE.UTF-8" or a tag such as "fr-CA"
+func ParseLocale(name string) Locale {
+	tag, _, _ := strings.Cut(name, ".")
+	tag, _, _ = strings.Cut(tag, "@")
+	tag = strings.ReplaceAll(tag, "-", "_")
+	loc := Locale{Tag: tag, Group: ",", Decimal: "."}
+	lang, _, _ := strings.Cut(tag, "_")
+	seps, ok := localeSeparators[tag]
+	if !ok {
+		seps, ok = localeSeparators[lang]
+	}
+	if ok {
+		loc.Group, loc.Decimal = seps[0], seps[1]
+	}
+	return loc
+}
+
+// LocaleFromEnv returns the Locale named by LC_ALL, LC_NUMERIC, or LANG,
+// whichever is set first, or the "C" locale
+func LocaleFromEnv() Locale {
+	for _, env := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		name := os.Getenv(env)
+		if name != "" {
+			return ParseLocale(name)
+		}
+	}
+	return ParseLocale("C")
+}
+
+// Package-level locale instance, detected on first use
+var (
+	locale     *Locale
+	localeMu   sync.RWMutex // synchronizes access to locale
+	localeOnce sync.Once
+)
+
+// SetLocale replaces the Locale used by the Format funcs (primarily for
+// testing) and returns the previous one so it can be restored.
+func SetLocale(loc Locale) (prev Locale) {
+	prev = GetLocale()
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	locale = &loc
+	return prev
+}
+
+// GetLocale returns the Locale used by the Format funcs, LocaleFromEnv()
+// unless SetLocale was called
+func GetLocale() Locale {
+	localeOnce.Do(func() {
+		localeMu.Lock()
+		defer localeMu.Unlock()
+		if locale == nil {
+			loc := LocaleFromEnv()
+			locale = &loc
+		}
+	})
+	localeMu.RLock()
+	defer localeMu.RUnlock()
+	return *locale
+}
+
+// FormatInt formats n with the locale's thousands separator, e.g. 1,234,567
+func FormatInt(n int64) string {
+	return groupDigits(strconv.FormatInt(n, 10), GetLocale().Group)
+}
+
+// groupDigits inserts sep between each group of three digits of the
+// optionally signed integer s
+func groupDigits(s, sep string) string {
+	var b strings.Builder
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if sep == "" || len(s) <= 3 {
+		return sign + s
+	}
+	b.WriteString(sign)
+	first := len(s) % 3
+	if first > 0 {
+		b.WriteString(s[:first])
+	}
+	for i := first; i < len(s); i += 3 {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
+
+// formatDecimal formats f with one decimal place in the locale
+func formatDecimal(f float64) string {
+	loc := GetLocale()
+	whole, frac, _ := strings.Cut(strconv.FormatFloat(f, 'f', 1, 64), ".")
+	return groupDigits(whole, loc.Group) + loc.Decimal + frac
+}
+
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// FormatBytes formats n bytes in binary units with one decimal place, e.g.
+// 1.5 MiB; counts under 1 KiB are exact, e.g. 512 B
+func FormatBytes(n int64) string {
+	var unit int
+
+	f := math.Abs(float64(n))
+	if f < 1024 {
+		return FormatInt(n) + " B"
+	}
+	for f >= 1024 && unit < len(byteUnits)-1 {
+		f /= 1024
+		unit++
+	}
+	if n < 0 {
+		f = -f
+	}
+	return formatDecimal(f) + " " + byteUnits[unit]
+}
+
+// FormatDuration formats d at the precision a person reads it with, e.g.
+// 450ms, 3.2s, 4m 05s, 2h 10m, or 3d 4h
+func FormatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	switch {
+	case d < time.Second:
+		return sign + FormatInt(d.Milliseconds()) + "ms"
+	case d < time.Minute:
+		return sign + formatDecimal(d.Seconds()) + "s"
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%s%dm %02ds", sign, int(d.Minutes()), int(d.Seconds())%60)
+	case d < 24*time.Hour:
+		d = d.Round(time.Minute)
+		return fmt.Sprintf("%s%dh %02dm", sign, int(d.Hours()), int(d.Minutes())%60)
+	}
+	d = d.Round(time.Hour)
+	return fmt.Sprintf("%s%sd %dh", sign, FormatInt(int64(d.Hours())/24), int(d.Hours())%24)
+}
+
+// timeAgoUnits are the units FormatTimeAgo counts in, largest first
+var timeAgoUnits = []struct {
+	size time.Duration
+	name string
+}{
+	{365 * 24 * time.Hour, "year"},
+	{30 * 24 * time.Hour, "month"},
+	{7 * 24 * time.Hour, "week"},
+	{24 * time.Hour, "day"},
+	{time.Hour, "hour"},
+	{time.Minute, "minute"},
+	{time.Second, "second"},
+}
+
+// FormatTimeAgo formats t relative to the Clock's current time, e.g. "just
+// now", "5 minutes ago", or "in 2 days"
+func FormatTimeAgo(t time.Time) string {
+	d := GetClock().Now().Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < 10*time.Second {
+		return "just now"
+	}
+	for _, unit := range timeAgoUnits {
+		if d < unit.size {
+			continue
+		}
+		n := int64(d / unit.size)
+		s := FormatInt(n) + " " + unit.name
+		if n != 1 {
+			s += "s"
+		}
+		if future {
+			return "in " + s
+		}
+		return s + " ago"
+	}
+	return "just now"
+}
+
+// FormatTime formats t as a date and time with its zone, in UTC when --utc
+// was given to the default CLI and in local time otherwise (see
+// CLI.FormatTime)
+func FormatTime(t time.Time) string {
+	return defaultCLI.FormatTime(t)
+}
+
+// FormatTime formats t as a date and time with its zone, in UTC when --utc
+// was given to cli and in local time otherwise, e.g. 2026-01-02 15:04:05 UTC
+func (cli *CLI) FormatTime(t time.Time) string {
+	cli.registryMu.RLock()
+	utc := cli.options.UTC()
+	cli.registryMu.RUnlock()
+	if utc {
+		t = t.UTC()
+	} else {
+		t = t.Local()
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
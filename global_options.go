@@ -2,39 +2,70 @@ package cliutil
 
 import (
 	"errors"
+	"log/slog"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mikeschinkel/go-dt"
 )
 
+// GetGlobalOptions returns the default CLI's GlobalOptions (see
+// CLI.GlobalOptions)
+//
 //goland:noinspection GoUnusedExportedFunction
 func GetGlobalOptions() *GlobalOptions {
-	return options
+	return defaultCLI.options
+}
+
+// GlobalOptions returns the options assigned by the CLI's global flags
+func (cli *CLI) GlobalOptions() *GlobalOptions {
+	return cli.options
 }
 
 var _ Options = (*GlobalOptions)(nil)
 
 type GlobalOptions struct {
-	timeout       *int
+	timeout       *time.Duration
 	quiet         *bool
 	verbosity     *int
 	dryRun        *bool
 	force         *bool
+	yes           *bool
+	noColor       *bool
+	output        *string
+	strict        *bool
+	logLevel      *string
+	utc           *bool
+	argsFromStdin *bool
+	config        *string
+	envFile       *string
 	originalFlags []string // Flags from original command line for validation
-	//Strings   stringSliceFlag
 }
 
 func (o *GlobalOptions) Options() {}
 
+// GlobalOptions returns o so *GlobalOptions can be passed directly as
+// CmdRunnerArgs.Options when an app has no options of its own
+func (o *GlobalOptions) GlobalOptions() *GlobalOptions {
+	return o
+}
+
 type GlobalOptionsArgs struct {
-	Quiet     *bool
-	Verbosity *int
-	Timeout   *int
-	DryRun    *bool
-	Force     *bool
+	Quiet         *bool
+	Verbosity     *int
+	Timeout       *time.Duration
+	DryRun        *bool
+	Force         *bool
+	Yes           *bool
+	NoColor       *bool
+	Output        *string
+	Strict        *bool
+	LogLevel      *string
+	UTC           *bool
+	ArgsFromStdin *bool
+	Config        *string
+	EnvFile       *string
 }
 
 // NewGlobalOptions creates a new GlobalOptions instance from raw values.
@@ -47,17 +78,37 @@ func NewGlobalOptions(args GlobalOptionsArgs) (*GlobalOptions, error) {
 		return nil, err
 	}
 
+	output, err := ParseOutputFormat(valueOrDefault(args.Output, DefaultOutput))
+	if err != nil {
+		return nil, err
+	}
+
+	logLevel := valueOrDefault(args.LogLevel, DefaultLogLevel)
+	_, _, err = ParseLogLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+
 	return &GlobalOptions{
-		quiet:     ptr(valueOrDefault(args.Quiet, DefaultQuiet)),
-		verbosity: ptr(int(v)),
-		timeout:   ptr(valueOrDefault(args.Timeout, DefaultTimeout)),
-		dryRun:    ptr(valueOrDefault(args.DryRun, DefaultDryRun)),
-		force:     ptr(valueOrDefault(args.Force, DefaultForce)),
+		quiet:         ptr(valueOrDefault(args.Quiet, DefaultQuiet)),
+		verbosity:     ptr(int(v)),
+		timeout:       ptr(valueOrDefault(args.Timeout, DefaultTimeout)),
+		dryRun:        ptr(valueOrDefault(args.DryRun, DefaultDryRun)),
+		force:         ptr(valueOrDefault(args.Force, DefaultForce)),
+		yes:           ptr(valueOrDefault(args.Yes, DefaultYes)),
+		noColor:       ptr(valueOrDefault(args.NoColor, DefaultNoColor)),
+		output:        ptr(string(output)),
+		strict:        ptr(valueOrDefault(args.Strict, DefaultStrict)),
+		logLevel:      ptr(strings.ToLower(strings.TrimSpace(logLevel))),
+		utc:           ptr(valueOrDefault(args.UTC, DefaultUTC)),
+		argsFromStdin: ptr(valueOrDefault(args.ArgsFromStdin, DefaultArgsFromStdin)),
+		config:        ptr(valueOrDefault(args.Config, DefaultConfig)),
+		envFile:       ptr(valueOrDefault(args.EnvFile, DefaultEnvFile)),
 	}, nil
 }
 
 func (o *GlobalOptions) Timeout() time.Duration {
-	return time.Duration(*o.timeout) * time.Second
+	return *o.timeout
 }
 func (o *GlobalOptions) Quiet() bool {
 	return *o.quiet
@@ -72,56 +123,203 @@ func (o *GlobalOptions) Force() bool {
 	return *o.force
 }
 
+// Yes returns true when Confirm should go ahead without asking
+func (o *GlobalOptions) Yes() bool {
+	return o.yes != nil && *o.yes
+}
+
+// NoColor returns true when output must not be colored; see ColorEnabled
+func (o *GlobalOptions) NoColor() bool {
+	return o.noColor != nil && *o.noColor
+}
+
+// Strict returns true when warnings should fail the run
+func (o *GlobalOptions) Strict() bool {
+	return o.strict != nil && *o.strict
+}
+
+// UTC returns true when times should be shown in UTC rather than local time;
+// see FormatTime
+func (o *GlobalOptions) UTC() bool {
+	return o.utc != nil && *o.utc
+}
+
+// ArgsFromStdin returns true when the command should run once per line of
+// stdin, with the line as one more positional argument
+func (o *GlobalOptions) ArgsFromStdin() bool {
+	return o.argsFromStdin != nil && *o.argsFromStdin
+}
+
+// ConfigFile returns the config file named by --config, or "" when the
+// files given ConfigArgs.Files are looked for instead; see EnableConfig
+func (o *GlobalOptions) ConfigFile() string {
+	if o.config == nil {
+		return ""
+	}
+	return *o.config
+}
+
+// EnvFile returns the env file named by --env-file, or "" when DotEnvFile is
+// loaded if it exists
+func (o *GlobalOptions) EnvFile() string {
+	if o.envFile == nil {
+		return ""
+	}
+	return *o.envFile
+}
+
+// OutputFormat returns the format selected via --output, defaulting to TextOutput
+func (o *GlobalOptions) OutputFormat() OutputFormat {
+	if o.output == nil || *o.output == "" {
+		return TextOutput
+	}
+	return OutputFormat(*o.output)
+}
+
+// LogLevel returns the slog level selected via --log-level or, when that is
+// not given, the level matching Verbosity() and Quiet()
+func (o *GlobalOptions) LogLevel() slog.Level {
+	if o.logLevel != nil {
+		level, ok, _ := ParseLogLevel(*o.logLevel)
+		if ok {
+			return level
+		}
+	}
+	return LogLevelForVerbosity(o.Verbosity(), o.Quiet())
+}
+
+// LogLevelGiven returns true when --log-level was given, so LogLevel() alone
+// decides which log records are shown rather than Verbosity()
+func (o *GlobalOptions) LogLevelGiven() bool {
+	if o.logLevel == nil {
+		return false
+	}
+	_, ok, _ := ParseLogLevel(*o.logLevel)
+	return ok
+}
+
+// GetGlobalFlagSet returns the default CLI's global flags (see
+// CLI.GlobalFlagSet)
+//
 //goland:noinspection GoUnusedExportedFunction
 func GetGlobalFlagSet() *FlagSet {
-	return flagSet
+	return defaultCLI.flagSet
+}
+
+// GlobalFlagSet returns the global flags. The FlagSet is shared, so it must
+// not be read while AddCLIOption or RestoreRegistry may run.
+func (cli *CLI) GlobalFlagSet() *FlagSet {
+	return cli.flagSet
 }
 
 var (
 	flagNameRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
 )
 
-var flagSet = &FlagSet{
-	Name: "global",
-	FlagDefs: []FlagDef{
-		{
-			Name:     "verbosity",
-			Shortcut: 'v',
-			Default:  DefaultVerbosity,
-			Usage:    "Verbosity of most command line output (1 to 3, default 1)",
-			Int:      options.verbosity,
-		},
-		{
-			Name:     "quiet",
-			Shortcut: 'q',
-			Default:  DefaultQuiet,
-			Usage:    "Disable display of most command line output",
-			Bool:     options.quiet,
-		},
-		{
-			Name:     "timeout",
-			Shortcut: 't',
-			Default:  DefaultTimeout,
-			Usage:    "timeout(in seconds) (TODO explain what this controls)",
-			Int:      options.timeout,
+// newGlobalFlagSet returns the standard global flags, assigning options
+func newGlobalFlagSet(options *GlobalOptions) (fs *FlagSet) {
+	fs = &FlagSet{
+		Name: "global",
+		FlagDefs: []FlagDef{
+			{
+				Name:     "verbosity",
+				Shortcut: 'v',
+				Default:  DefaultVerbosity,
+				Usage:    "Verbosity of most command line output (1 to 3, default 1); -vv is 2",
+				Count:    options.verbosity,
+			},
+			{
+				Name:     "quiet",
+				Shortcut: 'q',
+				Default:  DefaultQuiet,
+				Usage:    "Disable display of most command line output",
+				Bool:     options.quiet,
+			},
+			{
+				Name:     "timeout",
+				Shortcut: 't',
+				Default:  DefaultTimeout,
+				Usage:    "Time before context-aware commands are canceled, e.g. 90s or 5m (0 for no limit)",
+				Duration: options.timeout,
+			},
+			{
+				Name:    "dry-run",
+				Default: DefaultDryRun,
+				Usage:   "Show what command results will be if command is run",
+				Bool:    options.dryRun,
+			},
+			{
+				Name:     "force",
+				Shortcut: 'f',
+				Default:  DefaultForce,
+				Usage:    "Force the action even if warnings",
+				Bool:     options.force,
+			},
+			{
+				Name:    "yes",
+				Default: DefaultYes,
+				Usage:   "Answer yes to confirmation prompts",
+				Bool:    options.yes,
+			},
+			{
+				Name:    "no-color",
+				Default: DefaultNoColor,
+				Usage:   "Do not color output, as when NO_COLOR is set",
+				Bool:    options.noColor,
+			},
+			{
+				Name:    "output",
+				Default: DefaultOutput,
+				Usage:   outputFormatUsage(),
+				String:  options.output,
+			},
+			{
+				Name:    "strict",
+				Default: DefaultStrict,
+				Usage:   "Treat warnings, including deprecations, as errors",
+				Bool:    options.strict,
+			},
+			{
+				Name:    "log-level",
+				Default: DefaultLogLevel,
+				Usage:   logLevelUsage(),
+				String:  options.logLevel,
+			},
+			{
+				Name:    "utc",
+				Default: DefaultUTC,
+				Usage:   "Show times in UTC instead of local time",
+				Bool:    options.utc,
+			},
+			{
+				Name:    "args-from-stdin",
+				Default: DefaultArgsFromStdin,
+				Usage:   "Run the command once per line of stdin, with the line as its last argument",
+				Bool:    options.argsFromStdin,
+			},
 		},
-		{
-			Name:    "dry-run",
-			Default: DefaultDryRun,
-			Usage:   "Show what command results will be if command is run",
-			Bool:    options.dryRun,
-		},
-		{
-			Name:     "force",
-			Shortcut: 'f',
-			Default:  DefaultForce,
-			Usage:    "Force the action even if warnings",
-			Bool:     options.force,
-		},
-	},
+	}
+	if dotEnvEnabled {
+		fs.FlagDefs = append(fs.FlagDefs, envFileFlagDef(options))
+	}
+	return fs
 }
 
+// AddCLIOption adds flagDef to the default CLI's global flags (see
+// CLI.AddCLIOption)
 func AddCLIOption(flagDef FlagDef) (err error) {
+	return defaultCLI.AddCLIOption(flagDef)
+}
+
+// AddCLIOption adds flagDef to the global flags
+func (cli *CLI) AddCLIOption(flagDef FlagDef) (err error) {
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+	return cli.addCLIOption(flagDef)
+}
+
+// addCLIOption adds flagDef to the global flags; the caller holds registryMu
+func (cli *CLI) addCLIOption(flagDef FlagDef) (err error) {
 	var errs []error
 	var types []string
 	var existing FlagDef
@@ -134,7 +332,7 @@ func AddCLIOption(flagDef FlagDef) (err error) {
 	}
 
 	// Validate no duplicate flag names
-	for _, existing = range flagSet.FlagDefs {
+	for _, existing = range cli.flagSet.FlagDefs {
 		if existing.Name == flagDef.Name {
 			errs = append(errs, NewErr(dt.ErrInvalidDuplicateFlag, "where", "global flags"))
 			break
@@ -154,7 +352,25 @@ func AddCLIOption(flagDef FlagDef) (err error) {
 	if flagDef.Int64 != nil {
 		types = append(types, "int64")
 	}
-	rule := "exactly one property of .String, .Bool, .Int, or .Int64 must be non-nil"
+	if flagDef.Duration != nil {
+		types = append(types, "duration")
+	}
+	if flagDef.Float64 != nil {
+		types = append(types, "float64")
+	}
+	if flagDef.Strings != nil {
+		types = append(types, "strings")
+	}
+	if flagDef.Ints != nil {
+		types = append(types, "ints")
+	}
+	if flagDef.Map != nil {
+		types = append(types, "map")
+	}
+	if flagDef.Count != nil {
+		types = append(types, "count")
+	}
+	rule := "exactly one property of .String, .Bool, .Int, .Int64, .Duration, .Float64, .Strings, .Ints, .Map, or .Count must be non-nil"
 	switch len(types) {
 	case 0:
 		errs = append(errs,
@@ -177,7 +393,8 @@ func AddCLIOption(flagDef FlagDef) (err error) {
 	if err != nil {
 		goto end
 	}
-	flagSet.FlagDefs = append(flagSet.FlagDefs, flagDef)
+	cli.flagSet.FlagDefs = append(cli.flagSet.FlagDefs, flagDef)
+	cli.changed()
 end:
 	if err != nil {
 		err = WithErr(err, dt.ErrFlagValidationFailed, "flag_name", flagDef.Name)
@@ -187,23 +404,35 @@ end:
 
 var ErrFlagTypeNotDiscoverable = errors.New("flag type is not discoverable")
 
+// ParseGlobalOptions parses the default CLI's global options (see
+// CLI.ParseGlobalOptions)
+func ParseGlobalOptions(osArgs []string) (_ *GlobalOptions, _ []string, err error) {
+	return defaultCLI.ParseGlobalOptions(osArgs)
+}
+
 // ParseGlobalOptions converts raw options into GlobalOptions.
 //
 // Expects os.Args as input. Strips program name and defaults to ["help"] if no args.
-func ParseGlobalOptions(osArgs []string) (_ *GlobalOptions, _ []string, err error) {
+// Parsing writes the shared GlobalOptions, so concurrent calls are serialized.
+func (cli *CLI) ParseGlobalOptions(osArgs []string) (_ *GlobalOptions, _ []string, err error) {
 	var errs []error
-	var timeout time.Duration
 	var verbosity Verbosity
+	var output OutputFormat
 	var args []string
 	var helpRequested bool
 
+	defer profile.end("ParseGlobalOptions", profile.begin())
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+	options := cli.options
+
 	// Strip program name from os.Args
 	if len(osArgs) > 0 {
 		args = osArgs[1:]
 	}
 
 	// Transform flag commands (e.g., --test-hidden -> test-hidden) BEFORE flag parsing
-	args = transformFlagCommands(args)
+	args = cli.transformFlagCommands(args)
 
 	// Check for --help and handle it first
 	helpRequested, args = containsHelpFlag(args)
@@ -219,34 +448,73 @@ func ParseGlobalOptions(osArgs []string) (_ *GlobalOptions, _ []string, err erro
 		args = []string{"help"}
 	}
 
-	args, err = flagSet.Parse(args)
+	args, err = cli.flagSet.Parse(args)
 	if err != nil {
 		goto end
 	}
 
-	timeout, err = dt.ParseTimeDurationEx(strconv.Itoa(*options.timeout))
+	verbosity, err = ParseVerbosity(*options.verbosity)
 	errs = AppendErr(errs, err)
 	if err == nil {
-		*options.timeout = int(timeout.Seconds())
+		*options.verbosity = int(verbosity)
 	}
 
-	verbosity, err = ParseVerbosity(*options.verbosity)
+	output, err = ParseOutputFormat(*options.output)
 	errs = AppendErr(errs, err)
 	if err == nil {
-		*options.verbosity = int(verbosity)
+		*options.output = string(output)
 	}
 
+	_, _, err = ParseLogLevel(*options.logLevel)
+	errs = AppendErr(errs, err)
+
+	// Precedence is flags > env > config > defaults, so the env file and
+	// config file are applied after the flags they must not override
+	err = loadEnvFile(options)
+	errs = AppendErr(errs, err)
+
+	err = cli.loadConfig()
+	if err == nil {
+		err = cli.flagSet.applySources(cli.configSectionLocked(""))
+	}
+	errs = AppendErr(errs, err)
+
 	err = CombineErrs(errs)
 end:
 	return options, args, err
 }
 
-// extractFlags returns all args that start with '-' (flags only, not values)
+// ResetGlobalOptions resets the default CLI's global options (see
+// CLI.ResetGlobalOptions)
+func ResetGlobalOptions() error {
+	return defaultCLI.ResetGlobalOptions()
+}
+
+// ResetGlobalOptions sets every global option back to its default, as if
+// ParseGlobalOptions had been given no flags, so one parse cannot leak into
+// the next, e.g. between tests sharing a CLI
+func (cli *CLI) ResetGlobalOptions() (err error) {
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+
+	cli.options.originalFlags = nil
+	err = cli.flagSet.Build()
+	if err != nil {
+		goto end
+	}
+	err = cli.flagSet.Assign()
+end:
+	return err
+}
+
+// extractFlags returns all args before any ArgsTerminator that are flags
+// (not values; see isFlagArg)
 func extractFlags(args []string) (flags []string) {
 	var arg string
 
+	args, _ = splitAtTerminator(args)
 	for _, arg = range args {
-		if strings.HasPrefix(arg, "-") {
+		if isFlagArg(arg) {
 			flags = append(flags, arg)
 		}
 	}
@@ -255,8 +523,9 @@ func extractFlags(args []string) (flags []string) {
 }
 
 // transformFlagCommands checks if first arg is a flag command (e.g., --test-hidden)
-// and transforms it to a command name (e.g., test-hidden) BEFORE flagSet.Parse() consumes it
-func transformFlagCommands(args []string) (transformed []string) {
+// and transforms it to a command name (e.g., test-hidden) BEFORE flagSet.Parse() consumes it;
+// the caller holds registryMu
+func (cli *CLI) transformFlagCommands(args []string) (transformed []string) {
 	var firstArg string
 	var flagName string
 	var cmd Command
@@ -278,13 +547,13 @@ func transformFlagCommands(args []string) (transformed []string) {
 	flagName = strings.TrimPrefix(firstArg, "--")
 
 	// Check if any registered command has this FlagName
-	for _, cmd = range RegisteredCommands() {
+	for _, cmd = range cli.commands {
 		if cmd.FlagName() != flagName {
 			continue
 		}
 
 		// Verify this flag exists in global flagSet
-		globalFS = GetGlobalFlagSet()
+		globalFS = cli.flagSet
 		if globalFS == nil {
 			goto end
 		}
@@ -319,6 +588,9 @@ func containsHelpFlag(args []string) (helpRequested bool, filteredArgs []string)
 	filteredArgs = args
 
 	for i, arg = range args {
+		if arg == ArgsTerminator {
+			break
+		}
 		if strings.HasPrefix(arg, "--help") {
 			filteredArgs = append(args[:i], args[i+1:]...)
 			helpRequested = true
@@ -0,0 +1,86 @@
+package cliutil
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// helpKey identifies one rendering of help. Rendered help depends on the
+// template, the command, the app's metadata, the terminal, and the Theme, so
+// scripts that show help repeatedly render it only once per combination.
+type helpKey struct {
+	tmpl        *template.Template
+	cmdPath     string // Empty for the main help
+	name        string
+	description string
+	version     string
+	exeName     string
+	infoURL     string
+	width       int
+	color       bool // See ColorEnabled
+	theme       Theme
+}
+
+func newHelpKey(tmpl *template.Template, cmdPath string, args UsageArgs) (key helpKey) {
+	term := GetTerminal()
+	key = helpKey{
+		tmpl:    tmpl,
+		cmdPath: cmdPath,
+		width:   term.Width(),
+		color:   args.cli().ColorEnabled(args.Writer.Writer()),
+		theme:   GetTheme(),
+	}
+	if args.AppInfo != nil {
+		key.name = args.Name()
+		key.description = args.Description()
+		key.version = string(args.Version())
+		key.exeName = string(args.ExeName())
+		key.infoURL = string(args.InfoURL())
+	}
+	return key
+}
+
+// helpCache holds rendered help until the registry changes
+var helpCache struct {
+	sync.Mutex
+	gen      uint64
+	rendered map[helpKey]string
+}
+
+// writeHelp writes the help for key to w, calling render only when it is not
+// already cached for cli's current registry. A render writes to w as it goes,
+// so help is shown while it renders, and is cached once it succeeds.
+func writeHelp(cli *CLI, w io.Writer, key helpKey, render func(io.Writer) error) (err error) {
+	var buf strings.Builder
+	var help string
+	var ok bool
+
+	cli.registryMu.RLock()
+	gen := cli.registryGen
+	cli.registryMu.RUnlock()
+
+	helpCache.Lock()
+	if helpCache.rendered == nil || helpCache.gen != gen {
+		helpCache.rendered = make(map[helpKey]string)
+		helpCache.gen = gen
+	}
+	help, ok = helpCache.rendered[key]
+	helpCache.Unlock()
+	if ok {
+		_, err = io.WriteString(w, help)
+		goto end
+	}
+	err = render(io.MultiWriter(w, &buf))
+	if err != nil {
+		goto end
+	}
+	helpCache.Lock()
+	if helpCache.gen == gen {
+		helpCache.rendered[key] = buf.String()
+	}
+	helpCache.Unlock()
+end:
+	return err
+}
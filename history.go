@@ -0,0 +1,360 @@
+package cliutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// HistoryCmdName is the name of the command registered by EnableHistory
+const HistoryCmdName = "history"
+
+// DefaultHistoryLimit is how many entries the history command lists
+const DefaultHistoryLimit = 20
+
+var (
+	ErrHistoryIO          = errors.New("history file access failed")
+	ErrHistoryNotFound    = errors.New("history entry not found")
+	ErrHistoryNotReusable = errors.New("history entry cannot be re-run")
+)
+
+// HistoryArgs configures EnableHistory
+type HistoryArgs struct {
+	File string // OPTIONAL: defaults to history.jsonl in LogDir(AppInfo)
+}
+
+// HistoryEntry is one recorded invocation. Flags hold the command's own
+// flags and GlobalFlags the global ones, both as explicitly given and with
+// sensitive values redacted (see SanitizedFlags).
+type HistoryEntry struct {
+	Time         time.Time      `json:"time"`
+	InvocationID string         `json:"invocation_id"`
+	Command      string         `json:"command"` // Path as typed, e.g. "db migrate"
+	Flags        map[string]any `json:"flags,omitempty"`
+	GlobalFlags  map[string]any `json:"global_flags,omitempty"`
+	Args         []string       `json:"args,omitempty"` // Positional arguments
+	ExitCode     int            `json:"exit_code"`
+	Duration     time.Duration  `json:"duration"`
+}
+
+var (
+	historyMu sync.Mutex
+	history   *HistoryArgs
+)
+
+// EnableHistory makes RunCmd append each invocation it runs to a local
+// history file, and registers a "history" command that lists recent
+// invocations and, given an entry's number, re-runs it. Parse errors are not
+// recorded, since no command ran.
+func EnableHistory(args HistoryArgs) (err error) {
+	cmd := &historyCmd{}
+	cmd.CmdBase = NewCmdBase(CmdArgs{
+		Name:        HistoryCmdName,
+		Usage:       HistoryCmdName + " [<number>] [flags]",
+		Description: "List recent invocations, or re-run one by number",
+		FlagSets: []*FlagSet{{
+			Name: "history",
+			FlagDefs: []FlagDef{{
+				Name:    "limit",
+				Usage:   "How many invocations to list",
+				Default: DefaultHistoryLimit,
+				Int:     &cmd.limit,
+			}},
+		}},
+		ArgDefs: []*ArgDef{{
+			Name:    "number",
+			Usage:   "Number of the invocation to re-run",
+			String:  &cmd.number,
+			Example: "12",
+		}},
+		NoExamples: true,
+	})
+	err = RegisterCommand(cmd)
+	if err != nil {
+		goto end
+	}
+	historyMu.Lock()
+	history = &args
+	historyMu.Unlock()
+end:
+	return err
+}
+
+// DisableHistory stops the recording turned on by EnableHistory; the
+// history command stays registered
+func DisableHistory() {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	history = nil
+}
+
+// HistoryFile returns the history file EnableHistory records to, or the
+// empty string when history is not enabled
+func HistoryFile(ai appinfo.AppInfo) (file string, err error) {
+	var dir string
+
+	historyMu.Lock()
+	args := history
+	historyMu.Unlock()
+	if args == nil {
+		goto end
+	}
+	file = args.File
+	if file != "" {
+		goto end
+	}
+	dir, err = LogDir(ai)
+	if err != nil {
+		goto end
+	}
+	file = filepath.Join(dir, "history.jsonl")
+end:
+	return file, err
+}
+
+// recordHistory appends cmd's invocation to the history file, if enabled.
+// Failures are ignored so the history never fails the command it records.
+func recordHistory(cmd Command, args CmdRunnerArgs, start time.Time, cmdErr error) {
+	var file string
+	var flags map[string]any
+	var data []byte
+	var f *os.File
+	var err error
+
+	if cmd.Name() == HistoryCmdName || cmd.Name() == CompleteCmdName {
+		goto end
+	}
+	file, err = HistoryFile(args.AppInfo)
+	if err != nil || file == "" {
+		goto end
+	}
+	for _, fs := range cmd.FlagSets() {
+		for name, value := range fs.SetFlags() {
+			if flags == nil {
+				flags = make(map[string]any)
+			}
+			flags[name] = value
+		}
+	}
+	data, err = json.Marshal(HistoryEntry{
+		Time:         start,
+		InvocationID: args.InvocationID,
+		Command:      CmdPath(cmd),
+		Flags:        flags,
+		GlobalFlags:  args.cli().GlobalFlagSet().SetFlags(),
+		Args:         positionalArgs(cmd),
+		ExitCode:     ExitCode(cmdErr),
+		Duration:     GetClock().Now().Sub(start),
+	})
+	if err != nil {
+		goto end
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0o755)
+	if err != nil {
+		goto end
+	}
+	f, err = os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		goto end
+	}
+	// One write per entry, so concurrent invocations do not interleave
+	_, _ = f.Write(append(data, '\n'))
+	_ = f.Close()
+end:
+	return
+}
+
+// positionalArgs returns the positional args cmd was invoked with, when it
+// exposes them like CmdBase does
+func positionalArgs(cmd Command) []string {
+	pa, ok := cmd.(interface{ PositionalArgs() []string })
+	if !ok {
+		return nil
+	}
+	return pa.PositionalArgs()
+}
+
+// ReadHistory returns the entries in the history file, oldest first;
+// malformed lines, such as one cut short by a crash, are skipped
+func ReadHistory(file string) (entries []HistoryEntry, err error) {
+	var f *os.File
+	var scanner *bufio.Scanner
+
+	f, err = os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+		goto end
+	}
+	if err != nil {
+		err = NewErr(ErrHistoryIO, "file", file, err)
+		goto end
+	}
+	defer func() { _ = f.Close() }()
+	scanner = bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	err = scanner.Err()
+	if err != nil {
+		err = NewErr(ErrHistoryIO, "file", file, err)
+	}
+end:
+	return entries, err
+}
+
+// CommandLine returns the args that re-run e: the command path, its flags in
+// name order, and its positional args. Global flags are not included, as
+// they are parsed before the command is. It fails with ErrHistoryNotReusable
+// when a flag's value was redacted.
+func (e HistoryEntry) CommandLine() (args []string, err error) {
+	names := make([]string, 0, len(e.Flags))
+	for name := range e.Flags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	args = strings.Fields(e.Command)
+	for _, name := range names {
+		if e.Flags[name] == RedactedValue {
+			err = NewErr(ErrHistoryNotReusable, "command", e.Command, "flag", name, "reason", "its value was not recorded")
+			goto end
+		}
+		args = append(args, flagArgs(name, e.Flags[name])...)
+	}
+	if slices.ContainsFunc(e.Args, isFlagArg) {
+		args = append(args, ArgsTerminator)
+	}
+	args = append(args, e.Args...)
+end:
+	return args, err
+}
+
+// flagArgs returns the arguments giving value for the flag name: one
+// --name=value, or one per element when value is a list or map, as recorded
+// for a repeatable flag
+func flagArgs(name string, value any) (args []string) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice:
+		for i := range rv.Len() {
+			args = append(args, fmt.Sprintf("--%s=%v", name, rv.Index(i).Interface()))
+		}
+	case reflect.Map:
+		for _, key := range rv.MapKeys() {
+			args = append(args, fmt.Sprintf("--%s=%v=%v", name, key.Interface(), rv.MapIndex(key).Interface()))
+		}
+		slices.Sort(args)
+	default:
+		args = []string{fmt.Sprintf("--%s=%v", name, value)}
+	}
+	return args
+}
+
+// String returns e as a command line, with any redacted values shown
+func (e HistoryEntry) String() string {
+	parts := []string{e.Command}
+	names := make([]string, 0, len(e.Flags))
+	for name := range e.Flags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		parts = append(parts, flagArgs(name, e.Flags[name])...)
+	}
+	if slices.ContainsFunc(e.Args, isFlagArg) {
+		parts = append(parts, ArgsTerminator)
+	}
+	for _, arg := range e.Args {
+		parts = append(parts, quoteIfNeeded(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+var _ CommandHandler = (*historyCmd)(nil)
+
+type historyCmd struct {
+	*CmdBase
+	limit  int
+	number string
+}
+
+func (c *historyCmd) Handle() (err error) {
+	var file string
+	var entries []HistoryEntry
+	var n int
+
+	file, err = HistoryFile(c.AppInfo)
+	if err != nil {
+		goto end
+	}
+	entries, err = ReadHistory(file)
+	if err != nil {
+		goto end
+	}
+	if c.number == "" {
+		c.list(entries)
+		goto end
+	}
+	n, err = strconv.Atoi(c.number)
+	if err != nil || n < 1 || n > len(entries) {
+		err = WithErrClass(NewErr(ErrHistoryNotFound, "number", c.number), UsageErr)
+		goto end
+	}
+	err = c.rerun(entries[n-1])
+end:
+	return err
+}
+
+// list writes the last c.limit entries, numbered from the oldest
+func (c *historyCmd) list(entries []HistoryEntry) {
+	first := max(len(entries)-c.limit, 0)
+	for i, e := range entries[first:] {
+		c.Writer.Printf("%5d  %s  %s  (exit %d, %s)\n",
+			first+i+1,
+			c.CmdRunnerArgs.cli().FormatTime(e.Time),
+			e.String(),
+			e.ExitCode,
+			FormatDuration(e.Duration),
+		)
+	}
+}
+
+// rerun runs e's command line with this invocation's runner args
+func (c *historyCmd) rerun(e HistoryEntry) (err error) {
+	var args []string
+	var runnerArgs CmdRunnerArgs
+	var runner *CmdRunner
+	var cmd Command
+
+	args, err = e.CommandLine()
+	if err != nil {
+		goto end
+	}
+	c.Writer.Printf("Running: %s\n", e.String())
+	runnerArgs = c.CmdRunnerArgs
+	runnerArgs.Args = args
+	runnerArgs.InvocationID = ""
+	runner = NewCmdRunner(runnerArgs)
+	cmd, err = runner.ParseCmd(args)
+	if err != nil {
+		goto end
+	}
+	err = runner.RunCmd(cmd)
+end:
+	return err
+}
@@ -0,0 +1,134 @@
+package cliutil
+
+import (
+	"strings"
+)
+
+// HookFunc runs before or after the handler of cmd, the command being run
+type HookFunc func(cmd Command) error
+
+// PreRunner is implemented by commands with a hook that runs before their
+// handler. An error from it fails the run without calling the handler.
+type PreRunner interface {
+	PreRun(cmd Command) error
+}
+
+// PostRunner is implemented by commands with a hook that runs after their
+// handler, even when the handler failed
+type PostRunner interface {
+	PostRun(cmd Command) error
+}
+
+// PersistentPreRunner is implemented by commands with a hook that runs
+// before their own handler and before those of all their subcommands, such
+// as an auth check for a group of commands
+type PersistentPreRunner interface {
+	PersistentPreRun(cmd Command) error
+}
+
+// PersistentPostRunner is implemented by commands with a hook that runs
+// after their own handler and after those of all their subcommands
+type PersistentPostRunner interface {
+	PersistentPostRun(cmd Command) error
+}
+
+// cmdHooks holds the hooks given in CmdArgs
+type cmdHooks struct {
+	preRun            HookFunc
+	postRun           HookFunc
+	persistentPreRun  HookFunc
+	persistentPostRun HookFunc
+}
+
+var (
+	_ PreRunner            = (*CmdBase)(nil)
+	_ PostRunner           = (*CmdBase)(nil)
+	_ PersistentPreRunner  = (*CmdBase)(nil)
+	_ PersistentPostRunner = (*CmdBase)(nil)
+)
+
+// PreRun calls CmdArgs.PreRun, if given
+func (c *CmdBase) PreRun(cmd Command) error {
+	return c.hooks.preRun.call(cmd)
+}
+
+// PostRun calls CmdArgs.PostRun, if given
+func (c *CmdBase) PostRun(cmd Command) error {
+	return c.hooks.postRun.call(cmd)
+}
+
+// PersistentPreRun calls CmdArgs.PersistentPreRun, if given
+func (c *CmdBase) PersistentPreRun(cmd Command) error {
+	return c.hooks.persistentPreRun.call(cmd)
+}
+
+// PersistentPostRun calls CmdArgs.PersistentPostRun, if given
+func (c *CmdBase) PersistentPostRun(cmd Command) error {
+	return c.hooks.persistentPostRun.call(cmd)
+}
+
+// call returns hook(cmd), or nil when hook is nil
+func (hook HookFunc) call(cmd Command) error {
+	if hook == nil {
+		return nil
+	}
+	return hook(cmd)
+}
+
+// runWithHooks calls handle between cmd's hooks. The persistent pre-run
+// hooks of cmd's parents run first, outermost first, then cmd's own
+// PersistentPreRun and PreRun; the first to fail ends the run. After handle,
+// cmd's PostRun and PersistentPostRun run, then those of its parents,
+// innermost first, with their errors combined with handle's.
+func (cli *CLI) runWithHooks(cmd Command, handle func() error) (err error) {
+	var errs []error
+
+	chain := cli.cmdChain(cmd)
+	for _, c := range chain {
+		pr, ok := c.(PersistentPreRunner)
+		if !ok {
+			continue
+		}
+		err = pr.PersistentPreRun(cmd)
+		if err != nil {
+			goto end
+		}
+	}
+	if pr, ok := cmd.(PreRunner); ok {
+		err = pr.PreRun(cmd)
+		if err != nil {
+			goto end
+		}
+	}
+
+	errs = append(errs, handle())
+
+	if pr, ok := cmd.(PostRunner); ok {
+		errs = append(errs, pr.PostRun(cmd))
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		pr, ok := chain[i].(PersistentPostRunner)
+		if ok {
+			errs = append(errs, pr.PersistentPostRun(cmd))
+		}
+	}
+	err = CombineErrs(errs)
+end:
+	return err
+}
+
+// cmdChain returns the commands on cmd's path, outermost first and ending
+// with cmd
+func (cli *CLI) cmdChain(cmd Command) (chain []Command) {
+	var path string
+
+	names := strings.Fields(CmdPath(cmd))
+	for i := range len(names) - 1 {
+		path = strings.Join(names[:i+1], ".")
+		parent := cli.GetExactCommand(path)
+		if parent != nil {
+			chain = append(chain, parent)
+		}
+	}
+	return append(chain, cmd)
+}
@@ -2,6 +2,18 @@ package cliutil
 
 import (
 	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var (
+	ErrDuplicateInitializer = errors.New("duplicate initializer")
+	ErrUnknownInitializer   = errors.New("unknown initializer dependency")
+	ErrInitializerCycle     = errors.New("initializer dependency cycle")
+	ErrInitializerSkipped   = errors.New("initializer skipped because a dependency failed")
 )
 
 type InitializerArgs struct {
@@ -10,16 +22,157 @@ type InitializerArgs struct {
 
 type InitializerFunc func(InitializerArgs) error
 
-var initializerFuncs []InitializerFunc
+// Initializer is a named InitializerFunc that runs once every initializer
+// named in DependsOn has succeeded. Initializers that do not depend on each
+// other run concurrently, so each must be safe to run alongside the others.
+type Initializer struct {
+	Name      string
+	DependsOn []string // OPTIONAL: names of initializers that must run first
+	Func      InitializerFunc
+}
+
+var (
+	initializersMu   sync.Mutex
+	initializerFuncs []InitializerFunc
+	initializers     []Initializer
+)
 
+// RegisterInitializerFunc registers f to be called by CallInitializerFuncs.
+// Funcs registered this way run one at a time in registration order, before
+// any Initializer.
 func RegisterInitializerFunc(f InitializerFunc) {
+	initializersMu.Lock()
+	defer initializersMu.Unlock()
 	initializerFuncs = append(initializerFuncs, f)
 }
 
+// RegisterInitializer registers ini to be called by CallInitializerFuncs
+// after its dependencies. Dependencies are resolved when the initializers
+// are called, so they may be registered in any order.
+func RegisterInitializer(ini Initializer) (err error) {
+	initializersMu.Lock()
+	defer initializersMu.Unlock()
+	switch {
+	case ini.Name == "":
+		err = NewErr(dt.ErrEmpty, "empty_property", "Name")
+	case ini.Func == nil:
+		err = NewErr(dt.ErrEmpty, "empty_property", "Func", "initializer", ini.Name)
+	case slices.ContainsFunc(initializers, func(i Initializer) bool { return i.Name == ini.Name }):
+		err = NewErr(ErrDuplicateInitializer, "initializer", ini.Name)
+	default:
+		initializers = append(initializers, ini)
+	}
+	return err
+}
+
+// CallInitializerFuncs calls the funcs from RegisterInitializerFunc in order,
+// then the Initializers, each as soon as its dependencies have succeeded.
+// Errors from all of them are joined; an Initializer whose dependency failed
+// is skipped and reported with ErrInitializerSkipped.
 func CallInitializerFuncs(args InitializerArgs) (err error) {
 	var errs []error
-	for _, f := range initializerFuncs {
+
+	initializersMu.Lock()
+	funcs := slices.Clone(initializerFuncs)
+	inits := slices.Clone(initializers)
+	initializersMu.Unlock()
+
+	for _, f := range funcs {
+		start := profile.begin()
 		errs = append(errs, f(args))
+		if !start.IsZero() {
+			profile.end(initializerName(f), start)
+		}
+	}
+	err = checkInitializers(inits)
+	if err != nil {
+		errs = append(errs, err)
+		goto end
 	}
+	errs = append(errs, runInitializers(inits, args)...)
+end:
 	return errors.Join(errs...)
 }
+
+// checkInitializers returns an error for any unknown dependency or cycle,
+// since either would leave initializers waiting forever
+func checkInitializers(inits []Initializer) (err error) {
+	var errs []error
+	var visit func(name string, path []string)
+
+	done := make(map[string]bool, len(inits))
+	byName := make(map[string]Initializer, len(inits))
+	for _, ini := range inits {
+		byName[ini.Name] = ini
+	}
+	for _, ini := range inits {
+		for _, dep := range ini.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				errs = append(errs, NewErr(ErrUnknownInitializer, "initializer", ini.Name, "dependency", dep))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		goto end
+	}
+	// Depth-first search, reporting the first cycle found
+	visit = func(name string, path []string) {
+		if slices.Contains(path, name) {
+			if len(errs) == 0 {
+				errs = append(errs, NewErr(ErrInitializerCycle, "cycle", strings.Join(append(path, name), " -> ")))
+			}
+			return
+		}
+		if done[name] {
+			return
+		}
+		for _, dep := range byName[name].DependsOn {
+			visit(dep, append(path, name))
+		}
+		done[name] = true
+	}
+	for _, ini := range inits {
+		visit(ini.Name, nil)
+	}
+end:
+	return errors.Join(errs...)
+}
+
+// runInitializers runs each initializer in its own goroutine once its
+// dependencies are done, and returns the errors of those that failed or
+// were skipped
+func runInitializers(inits []Initializer, args InitializerArgs) (errs []error) {
+	type result struct {
+		done chan struct{}
+		err  error
+	}
+	var wg sync.WaitGroup
+
+	results := make(map[string]*result, len(inits))
+	for _, ini := range inits {
+		results[ini.Name] = &result{done: make(chan struct{})}
+	}
+	for _, ini := range inits {
+		wg.Add(1)
+		go func(ini Initializer, r *result) {
+			defer wg.Done()
+			defer close(r.done)
+			for _, dep := range ini.DependsOn {
+				<-results[dep].done
+				if results[dep].err != nil {
+					r.err = NewErr(ErrInitializerSkipped, "initializer", ini.Name, "dependency", dep)
+					return
+				}
+			}
+			start := profile.begin()
+			r.err = ini.Func(args)
+			profile.end("initializer "+ini.Name, start)
+		}(ini, results[ini.Name])
+	}
+	wg.Wait()
+	// Report in registration order, so errors are deterministic
+	for _, ini := range inits {
+		errs = AppendErr(errs, results[ini.Name].err)
+	}
+	return errs
+}
@@ -0,0 +1,249 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+const (
+	DefaultLogMaxSize    = 10 << 20 // Rotate log files once they reach 10 MiB
+	DefaultLogMaxBackups = 5        // Keep this many rotated log files
+)
+
+var (
+	ErrLogFileOpenFailed   = errors.New("failed to open log file")
+	ErrLogFileRotateFailed = errors.New("failed to rotate log file")
+	ErrLogDirUnknown       = errors.New("unable to determine log directory")
+)
+
+// LogFileArgs configures OpenLogFile
+type LogFileArgs struct {
+	AppInfo    appinfo.AppInfo // Used to derive Dir and Name when they are empty
+	Dir        string          // Directory for the log; defaults to LogDir(AppInfo)
+	Name       string          // File name; defaults to AppInfo.LogFile() or "<exe>.log"
+	MaxSize    int64           // Rotate once the file would exceed this many bytes
+	MaxBackups int             // Rotated files to retain as <name>.1 ... <name>.N
+}
+
+var _ io.WriteCloser = (*LogFile)(nil)
+
+// LogFile is an append-only log file with size-based rotation. When a write
+// would grow the file past MaxSize, the file is renamed to <name>.1 (shifting
+// older backups up and deleting any beyond MaxBackups) and a new file is
+// started. It is safe for concurrent use.
+type LogFile struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	size       int64
+	maxSize    int64
+	maxBackups int
+}
+
+// OpenLogFile opens, creating if needed, the app's log file for appending.
+// Use its Handler() method, or pass it to slog.NewJSONHandler, for durable
+// logs from long-running commands:
+//
+//	lf, err := cliutil.OpenLogFile(cliutil.LogFileArgs{AppInfo: appInfo})
+//	...
+//	defer lf.Close()
+//	logger := slog.New(lf.Handler(nil))
+func OpenLogFile(args LogFileArgs) (lf *LogFile, err error) {
+	var name string
+
+	if args.Dir == "" {
+		args.Dir, err = LogDir(args.AppInfo)
+		if err != nil {
+			goto end
+		}
+	}
+	name = args.Name
+	if name == "" && args.AppInfo != nil {
+		name = string(args.AppInfo.LogFile())
+		if name == "" {
+			name = string(args.AppInfo.ExeName()) + ".log"
+		}
+	}
+	if name == "" || name == ".log" {
+		name = filepath.Base(os.Args[0]) + ".log"
+	}
+	if args.MaxSize <= 0 {
+		args.MaxSize = DefaultLogMaxSize
+	}
+	if args.MaxBackups <= 0 {
+		args.MaxBackups = DefaultLogMaxBackups
+	}
+
+	lf = &LogFile{
+		path:       filepath.Join(args.Dir, name),
+		maxSize:    args.MaxSize,
+		maxBackups: args.MaxBackups,
+	}
+	err = os.MkdirAll(args.Dir, 0o755)
+	if err == nil {
+		err = lf.open()
+	}
+end:
+	if err != nil {
+		lf = nil
+		err = WithErr(err, ErrLogFileOpenFailed, "dir", args.Dir, "name", name)
+	}
+	return lf, err
+}
+
+// LogDir returns the platform's conventional per-user log directory for the
+// app: ~/Library/Logs/<app> on macOS, %LOCALAPPDATA%\<app>\logs on Windows,
+// and $XDG_STATE_HOME/<app>/logs (default ~/.local/state) elsewhere. <app> is
+// AppInfo.LogPath() if set, else AppInfo.AppSlug(), else the executable name.
+func LogDir(ai appinfo.AppInfo) (dir string, err error) {
+	var home string
+	var app string
+
+	if ai != nil {
+		app = string(ai.LogPath())
+		if app == "" {
+			app = string(ai.AppSlug())
+		}
+	}
+	if app == "" {
+		app = filepath.Base(os.Args[0])
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		dir = os.Getenv("LOCALAPPDATA")
+		if dir == "" {
+			err = NewErr(ErrLogDirUnknown, "env", "LOCALAPPDATA")
+			goto end
+		}
+		dir = filepath.Join(dir, app, "logs")
+	case "darwin", "ios":
+		home, err = os.UserHomeDir()
+		if err != nil {
+			err = NewErr(ErrLogDirUnknown, err)
+			goto end
+		}
+		dir = filepath.Join(home, "Library", "Logs", app)
+	default:
+		dir = os.Getenv("XDG_STATE_HOME")
+		if dir == "" {
+			home, err = os.UserHomeDir()
+			if err != nil {
+				err = NewErr(ErrLogDirUnknown, err)
+				goto end
+			}
+			dir = filepath.Join(home, ".local", "state")
+		}
+		dir = filepath.Join(dir, app, "logs")
+	}
+end:
+	return dir, err
+}
+
+// Path returns the path of the active log file
+func (lf *LogFile) Path() string {
+	return lf.path
+}
+
+// Handler returns a slog.Handler that writes JSON records to the log file
+func (lf *LogFile) Handler(opts *slog.HandlerOptions) slog.Handler {
+	return slog.NewJSONHandler(lf, opts)
+}
+
+// Write appends p to the log, rotating first if p would exceed MaxSize
+func (lf *LogFile) Write(p []byte) (n int, err error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	if lf.file == nil {
+		err = os.ErrClosed
+		goto end
+	}
+	if lf.size > 0 && lf.size+int64(len(p)) > lf.maxSize {
+		err = lf.rotate()
+		if err != nil {
+			goto end
+		}
+	}
+	n, err = lf.file.Write(p)
+	lf.size += int64(n)
+end:
+	return n, err
+}
+
+// Close closes the active log file
+func (lf *LogFile) Close() (err error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	if lf.file != nil {
+		err = lf.file.Close()
+		lf.file = nil
+	}
+	return err
+}
+
+// open opens lf.path for appending and records its current size
+func (lf *LogFile) open() (err error) {
+	var info os.FileInfo
+
+	lf.file, err = os.OpenFile(lf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		goto end
+	}
+	info, err = lf.file.Stat()
+	if err != nil {
+		_ = lf.file.Close()
+		lf.file = nil
+		goto end
+	}
+	lf.size = info.Size()
+end:
+	return err
+}
+
+// rotate shifts <path>.N-1 to <path>.N down to <path> to <path>.1, dropping
+// the oldest backup, then reopens a fresh file; the caller holds lf.mu
+func (lf *LogFile) rotate() (err error) {
+	err = lf.file.Close()
+	lf.file = nil
+	if err != nil {
+		goto end
+	}
+	err = os.Remove(lf.backupPath(lf.maxBackups))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		goto end
+	}
+	for i := lf.maxBackups - 1; i >= 1; i-- {
+		err = os.Rename(lf.backupPath(i), lf.backupPath(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			goto end
+		}
+	}
+	err = os.Rename(lf.path, lf.backupPath(1))
+	if err != nil {
+		goto end
+	}
+	err = lf.open()
+end:
+	if err != nil {
+		err = NewErr(ErrLogFileRotateFailed, "path", lf.path, err)
+	}
+	if lf.file == nil {
+		// Keep logging to the current file rather than losing output
+		_ = lf.open()
+	}
+	return err
+}
+
+// backupPath returns the path of the i-th rotated file
+func (lf *LogFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", lf.path, i)
+}
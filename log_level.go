@@ -0,0 +1,71 @@
+package cliutil
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+var ErrInvalidLogLevel = errors.New("invalid log level")
+
+// logLevels maps --log-level values to slog levels, in display order
+var logLevels = []struct {
+	name  string
+	level slog.Level
+}{
+	{"debug", slog.LevelDebug},
+	{"info", slog.LevelInfo},
+	{"warn", slog.LevelWarn},
+	{"error", slog.LevelError},
+}
+
+// ParseLogLevel validates s as a --log-level value (debug, info, warn, or
+// error). An empty string is valid and means "derive from verbosity"; ok
+// reports whether s named a level.
+func ParseLogLevel(s string) (level slog.Level, ok bool, err error) {
+	var names []string
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		goto end
+	}
+	for _, ll := range logLevels {
+		if ll.name == s {
+			level, ok = ll.level, true
+			goto end
+		}
+		names = append(names, ll.name)
+	}
+	err = NewErr(
+		ErrInvalidLogLevel,
+		"log_level", s,
+		"valid", strings.Join(names, "|"),
+	)
+end:
+	return level, ok, err
+}
+
+// LogLevelForVerbosity maps CLI verbosity to the slog level that shows the
+// same amount of detail: quiet→Error, 1→Warn, 2→Info, 3→Debug.
+func LogLevelForVerbosity(v Verbosity, quiet bool) (level slog.Level) {
+	switch {
+	case quiet:
+		level = slog.LevelError
+	case v >= HighVerbosity:
+		level = slog.LevelDebug
+	case v == MediumVerbosity:
+		level = slog.LevelInfo
+	default:
+		level = slog.LevelWarn
+	}
+	return level
+}
+
+// logLevelUsage returns the usage text for the --log-level global option
+func logLevelUsage() string {
+	var names []string
+	for _, ll := range logLevels {
+		names = append(names, ll.name)
+	}
+	return "Log level (" + strings.Join(names, ", ") + "); defaults to match --verbosity and --quiet"
+}
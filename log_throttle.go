@@ -0,0 +1,101 @@
+package cliutil
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultThrottleLimit    = 1           // Identical messages allowed per interval
+	DefaultThrottleInterval = time.Minute // Length of each throttling window
+)
+
+// LogThrottleArgs configures NewLogThrottle
+type LogThrottleArgs struct {
+	Limit    int           // Identical messages allowed per interval; defaults to DefaultThrottleLimit
+	Interval time.Duration // Window length; defaults to DefaultThrottleInterval
+}
+
+// LogThrottle rate-limits identical log messages: at most Limit messages
+// with the same key are allowed per Interval, measured on the framework
+// Clock. It counts what it suppresses so a "suppressed N similar messages"
+// summary can be written once the window ends. It is safe for concurrent use.
+type LogThrottle struct {
+	mu       sync.Mutex
+	limit    int
+	interval time.Duration
+	windows  map[string]*throttleWindow
+}
+
+// throttleWindow tracks one key's current interval
+type throttleWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// ThrottleSummary reports messages suppressed for one key
+type ThrottleSummary struct {
+	Key        string
+	Suppressed int
+}
+
+// NewLogThrottle returns a LogThrottle; see WriterLogger.WithThrottle
+func NewLogThrottle(args LogThrottleArgs) *LogThrottle {
+	if args.Limit <= 0 {
+		args.Limit = DefaultThrottleLimit
+	}
+	if args.Interval <= 0 {
+		args.Interval = DefaultThrottleInterval
+	}
+	return &LogThrottle{
+		limit:    args.Limit,
+		interval: args.Interval,
+		windows:  make(map[string]*throttleWindow),
+	}
+}
+
+// Allow reports whether a message with key may be logged now. When it starts
+// a new window for key, suppressed is the number of messages dropped in the
+// previous window so the caller can log a summary first.
+func (lt *LogThrottle) Allow(key string) (ok bool, suppressed int) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := GetClock().Now()
+	w, exists := lt.windows[key]
+	if !exists || now.Sub(w.start) >= lt.interval {
+		if exists {
+			suppressed = w.suppressed
+		}
+		w = &throttleWindow{start: now}
+		lt.windows[key] = w
+	}
+	if w.count >= lt.limit {
+		w.suppressed++
+		goto end
+	}
+	w.count++
+	ok = true
+end:
+	return ok, suppressed
+}
+
+// Flush returns and clears the suppressed counts of all keys, sorted by key,
+// so a summary can be written when a retry or polling loop finishes
+func (lt *LogThrottle) Flush() (summaries []ThrottleSummary) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for key, w := range lt.windows {
+		if w.suppressed > 0 {
+			summaries = append(summaries, ThrottleSummary{Key: key, Suppressed: w.suppressed})
+		}
+		delete(lt.windows, key)
+	}
+	slices.SortFunc(summaries, func(a, b ThrottleSummary) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return summaries
+}
@@ -0,0 +1,339 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+var ErrManPageIO = errors.New("writing man page failed")
+
+// DefaultManSection is the man section of user commands
+const DefaultManSection = "1"
+
+// ManArgs configures WriteManPages and WriteManPage
+type ManArgs struct {
+	Section string    // OPTIONAL: defaults to DefaultManSection
+	Date    time.Time // OPTIONAL: defaults to the build date (see ReadBuildInfo), else today
+	Source  string    // OPTIONAL: e.g. "myapp 1.2.3"; defaults to the app's name and version
+	Manual  string    // OPTIONAL: e.g. "MyApp Manual"; defaults to "<name> Manual"
+}
+
+// WriteManPages writes a roff man page for the app and one for each visible
+// command to dir, named like "myapp.1" and "myapp-db-migrate.1", and returns
+// the files written. Install them into a man path such as
+// /usr/local/share/man/man1.
+func WriteManPages(dir string, info appinfo.AppInfo, args ManArgs) (files []string, err error) {
+	var cmds []Command
+
+	args = manDefaults(info, args)
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		err = NewErr(ErrManPageIO, "dir", dir, err)
+		goto end
+	}
+	cmds = append([]Command{nil}, manCmds("", GetTopLevelCmds())...)
+	for _, cmd := range cmds {
+		file := filepath.Join(dir, manPageName(info, cmd)+"."+args.Section)
+		err = writeManFile(file, info, cmd, args)
+		if err != nil {
+			goto end
+		}
+		files = append(files, file)
+	}
+end:
+	return files, err
+}
+
+// writeManFile writes cmd's man page to file
+func writeManFile(file string, info appinfo.AppInfo, cmd Command, args ManArgs) (err error) {
+	var f *os.File
+
+	f, err = os.Create(file)
+	if err != nil {
+		err = NewErr(ErrManPageIO, "file", file, err)
+		goto end
+	}
+	err = WriteManPage(f, info, cmd, args)
+	err = CombineErrs([]error{err, f.Close()})
+	if err != nil {
+		err = NewErr(ErrManPageIO, "file", file, err)
+	}
+end:
+	return err
+}
+
+// manCmds returns the visible cmds under the dot-separated parent path and
+// their subcommands, depth first
+func manCmds(parent string, cmds []Command) (all []Command) {
+	for _, cmd := range cmds {
+		if cmd.IsHidden() {
+			continue
+		}
+		path := cmd.Name()
+		if parent != "" {
+			path = parent + "." + cmd.Name()
+		}
+		all = append(all, cmd)
+		all = append(all, manCmds(path, cliOf(cmd).GetSubCmds(path))...)
+	}
+	return all
+}
+
+// manDefaults returns args with its defaults filled in for info
+func manDefaults(info appinfo.AppInfo, args ManArgs) ManArgs {
+	if args.Section == "" {
+		args.Section = DefaultManSection
+	}
+	if args.Date.IsZero() {
+		args.Date = ReadBuildInfo().Date
+	}
+	if args.Date.IsZero() {
+		args.Date = GetClock().Now()
+	}
+	name := manExeName(info)
+	if args.Source == "" {
+		args.Source = name
+		if info != nil && info.Version() != "" {
+			args.Source += " " + string(info.Version())
+		}
+	}
+	if args.Manual == "" {
+		args.Manual = name + " Manual"
+	}
+	return args
+}
+
+// manExeName returns the app's executable name
+func manExeName(info appinfo.AppInfo) string {
+	if info != nil && info.ExeName() != "" {
+		return string(info.ExeName())
+	}
+	if name := defaultCLI.Name(); name != "" {
+		return name
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// manPageName returns the page name for cmd, or for the app when cmd is nil,
+// e.g. "myapp-db-migrate"
+func manPageName(info appinfo.AppInfo, cmd Command) string {
+	if cmd == nil {
+		return manExeName(info)
+	}
+	return manExeName(info) + "-" + strings.ReplaceAll(CmdPath(cmd), " ", "-")
+}
+
+// WriteManPage writes the roff man page for cmd to w, or the app's top-level
+// page listing its commands and global flags when cmd is nil
+func WriteManPage(w io.Writer, info appinfo.AppInfo, cmd Command, args ManArgs) (err error) {
+	var b strings.Builder
+
+	args = manDefaults(info, args)
+	exe := manExeName(info)
+	page := manPageName(info, cmd)
+	fmt.Fprintf(&b, ".TH %s %s %s %s %s\n",
+		roffQuote(strings.ToUpper(page)),
+		roffQuote(args.Section),
+		roffQuote(args.Date.UTC().Format(time.DateOnly)),
+		roffQuote(args.Source),
+		roffQuote(args.Manual),
+	)
+	if cmd == nil {
+		writeAppManPage(&b, info, exe, args.Section)
+	} else {
+		writeCmdManPage(&b, cmd, exe, args.Section)
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeAppManPage writes the sections of the app's top-level page
+func writeAppManPage(b *strings.Builder, info appinfo.AppInfo, exe, section string) {
+	var descr string
+	var seeAlso []string
+
+	if info != nil {
+		descr = info.Description()
+	}
+	fmt.Fprintf(b, ".SH NAME\n%s", roffEscape(exe))
+	if descr != "" {
+		fmt.Fprintf(b, " \\- %s", roffEscape(descr))
+	}
+	fmt.Fprintf(b, "\n.SH SYNOPSIS\n.B %s\n<command> [flags] [arguments]\n", roffEscape(exe))
+	cmds := manCmds("", GetTopLevelCmds())
+	if len(cmds) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+	}
+	for _, cmd := range cmds {
+		fmt.Fprintf(b, ".TP\n.B %s\n%s\n", roffEscape(CmdPath(cmd)), roffEscape(cmd.Description()))
+		seeAlso = append(seeAlso, manPageName(info, cmd)+"("+section+")")
+	}
+	if GetGlobalFlagSet() != nil && len(GetGlobalFlagSet().FlagDefs) > 0 {
+		b.WriteString(".SH GLOBAL OPTIONS\n")
+		writeManFlags(b, GetGlobalFlagSet().FlagDefs)
+	}
+	writeManSeeAlso(b, seeAlso)
+}
+
+// writeCmdManPage writes the sections of cmd's page
+func writeCmdManPage(b *strings.Builder, cmd Command, exe, section string) {
+	var seeAlso []string
+
+	usage := BuildCmdUsage(cmd)
+	path := CmdPath(cmd)
+	fmt.Fprintf(b, ".SH NAME\n%s", roffEscape(exe+"-"+strings.ReplaceAll(path, " ", "-")))
+	if cmd.Description() != "" {
+		fmt.Fprintf(b, " \\- %s", roffEscape(cmd.Description()))
+	}
+	fmt.Fprintf(b, "\n.SH SYNOPSIS\n.B %s\n", roffEscape(exe+" "+path))
+	if synopsis := manSynopsis(cmd); synopsis != "" {
+		fmt.Fprintf(b, "%s\n", roffEscape(synopsis))
+	}
+	if cmd.Description() != "" {
+		fmt.Fprintf(b, ".SH DESCRIPTION\n%s\n", roffEscape(cmd.Description()))
+	}
+	if dc, ok := cmd.(interface{ Deprecated() string }); ok && dc.Deprecated() != "" {
+		fmt.Fprintf(b, ".PP\nDeprecated: %s\n", roffEscape(dc.Deprecated()))
+	}
+	if len(cmd.ArgDefs()) > 0 {
+		b.WriteString(".SH ARGUMENTS\n")
+	}
+	for _, ad := range cmd.ArgDefs() {
+		fmt.Fprintf(b, ".TP\n.I %s\n%s\n", roffEscape(ad.Name), roffEscape(manDescr(ad.Usage, ad.Default, ad.Required, "")))
+	}
+	var flagDefs []FlagDef
+	for _, fs := range cmd.FlagSets() {
+		flagDefs = append(flagDefs, fs.FlagDefs...)
+	}
+	if len(flagDefs) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		writeManFlags(b, flagDefs)
+	}
+	if len(usage.Examples) > 0 {
+		b.WriteString(".SH EXAMPLES\n")
+	}
+	for _, ex := range usage.Examples {
+		fmt.Fprintf(b, ".PP\n%s\n.PP\n.RS\n.nf\n%s\n.fi\n.RE\n", roffEscape(ex.Descr), roffEscape(ex.Cmd))
+	}
+	seeAlso = append(seeAlso, exe+"("+section+")")
+	for _, sub := range cliOf(cmd).GetSubCmds(strings.ReplaceAll(path, " ", ".")) {
+		if !sub.IsHidden() {
+			seeAlso = append(seeAlso, exe+"-"+strings.ReplaceAll(CmdPath(sub), " ", "-")+"("+section+")")
+		}
+	}
+	writeManSeeAlso(b, seeAlso)
+}
+
+// manSynopsis returns what follows the command's name in its usage: from
+// Usage() when set, else from its ArgDefs and flags
+func manSynopsis(cmd Command) string {
+	var parts []string
+
+	if cmd.Usage() != "" {
+		_, rest, _ := strings.Cut(cmd.Usage(), " ")
+		return rest
+	}
+	for _, ad := range cmd.ArgDefs() {
+		arg := "<" + ad.Name + ">"
+		if ad.IsVariadic() {
+			arg += "..."
+		}
+		if !ad.Required {
+			arg = "[" + arg + "]"
+		}
+		parts = append(parts, arg)
+	}
+	for _, fs := range cmd.FlagSets() {
+		if len(fs.FlagDefs) > 0 {
+			parts = append(parts, "[flags]")
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeManFlags writes a tagged paragraph per flag
+func writeManFlags(b *strings.Builder, flagDefs []FlagDef) {
+	for _, fd := range flagDefs {
+		flag := "--" + fd.Name
+		if fd.Shortcut != 0 {
+			flag = fmt.Sprintf("-%c, %s", fd.Shortcut, flag)
+		}
+		if negated := negatedName(fd); negated != "" {
+			flag += ", --" + negated
+		}
+		switch fd.Type() {
+		case BoolFlag, CountFlag:
+		case MapFlag:
+			flag += " key=value"
+		default:
+			flag += " value"
+		}
+		usage := fd.Usage
+		if fd.IsRepeatable() {
+			usage += " (repeatable)"
+		}
+		fmt.Fprintf(b, ".TP\n.B %s\n%s\n", roffEscape(flag), roffEscape(manDescr(usage, manDefault(fd), fd.Required, fd.deprecationNote())))
+	}
+}
+
+// manDefault returns the default manDescr shows for fd: its Default, or for
+// a DefaultFunc the text help shows
+func manDefault(fd FlagDef) any {
+	if fd.DefaultFunc != nil {
+		return helpDefault(fd)
+	}
+	return fd.Default
+}
+
+// manDescr returns usage with the default, whether it is required, and any
+// deprecation appended
+func manDescr(usage string, def any, required bool, deprecated string) string {
+	parts := []string{usage}
+	switch s := defaultText(def); {
+	case def == nil, s == "", s == "false":
+	default:
+		parts = append(parts, "(default: "+s+")")
+	}
+	if required {
+		parts = append(parts, "(required)")
+	}
+	if deprecated != "" {
+		parts = append(parts, "(deprecated: "+deprecated+")")
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// writeManSeeAlso writes the SEE ALSO section for pages
+func writeManSeeAlso(b *strings.Builder, pages []string) {
+	if len(pages) == 0 {
+		return
+	}
+	fmt.Fprintf(b, ".SH SEE ALSO\n%s\n", roffEscape(strings.Join(pages, ", ")))
+}
+
+// roffEscape escapes s for roff text: backslashes and hyphens are escaped,
+// and lines starting with a control character are guarded with \&
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffQuote returns s escaped and quoted as a macro argument
+func roffQuote(s string) string {
+	return `"` + strings.ReplaceAll(roffEscape(s), `"`, `\(dq`) + `"`
+}
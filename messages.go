@@ -0,0 +1,205 @@
+package cliutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MessageID identifies a user-facing framework message in the message catalog
+type MessageID string
+
+// Framework message IDs. Applications localize these by registering a catalog
+// for their language with RegisterMessages.
+const (
+	MsgShowUsage          MessageID = "show_usage"
+	MsgUnknownCommand     MessageID = "unknown_command"
+	MsgCommandNotFound    MessageID = "command_not_found"
+	MsgFlagsParsingFailed MessageID = "flags_parsing_failed"
+	MsgAssigningArgs      MessageID = "assigning_args_failed"
+	MsgUnknownFlags       MessageID = "unknown_flags"
+	MsgInvalidInvocation  MessageID = "invalid_invocation"
+	MsgFlagRequired       MessageID = "flag_required"
+	MsgTooFewArgs         MessageID = "too_few_args"
+	MsgTooManyArgs        MessageID = "too_many_args"
+	MsgConfirmPrompt      MessageID = "confirm_prompt"
+	MsgWarningsAsErrors   MessageID = "warnings_as_errors"
+	MsgErrorPrefix        MessageID = "error_prefix"
+	MsgHintPrefix         MessageID = "hint_prefix"
+	MsgRetryHint          MessageID = "retry_hint"
+	MsgWarningPrefix      MessageID = "warning_prefix"
+	MsgProblemsFound      MessageID = "problems_found"
+	MsgCompletedWarning   MessageID = "completed_with_warning"
+	MsgCompletedWarnings  MessageID = "completed_with_warnings"
+	MsgLogSuppressed      MessageID = "log_suppressed"
+	MsgLogSuppressedOne   MessageID = "log_suppressed_one"
+	MsgDeprecatedUsage    MessageID = "deprecated_usage"
+	MsgDeprecatedCommand  MessageID = "deprecated_command"
+	MsgDeprecatedFlag     MessageID = "deprecated_flag"
+	MsgDeprecatedFlagDefs MessageID = "deprecated_flag_defs"
+	MsgUseFlagInstead     MessageID = "use_flag_instead"
+	MsgCommandTimedOut    MessageID = "command_timed_out"
+	MsgDidYouMean         MessageID = "did_you_mean"
+	MsgLoadingConfigFile  MessageID = "loading_config_file"
+	MsgLoadingEnvFile     MessageID = "loading_env_file"
+	MsgUpdateAvailable    MessageID = "update_available"
+	MsgUpdateCommand      MessageID = "update_command"
+)
+
+// DefaultLanguage is used when no catalog exists for the selected language
+const DefaultLanguage = "en"
+
+// defaultMessages is the English catalog, which doubles as the fallback for
+// any message missing from another language's catalog.
+var defaultMessages = map[MessageID]string{
+	MsgShowUsage:          "run '%s help' for usage",
+	MsgUnknownCommand:     "unknown command",
+	MsgCommandNotFound:    "command not found",
+	MsgFlagsParsingFailed: "flags parsing failed",
+	MsgAssigningArgs:      "assigning args failed",
+	MsgUnknownFlags:       "unknown flag(s)",
+	MsgInvalidInvocation:  "invalid invocation",
+	MsgFlagRequired:       "flag is required",
+	MsgTooFewArgs:         "too few arguments",
+	MsgTooManyArgs:        "too many arguments",
+	MsgConfirmPrompt:      "%s [y/N] ",
+	MsgWarningsAsErrors:   "warnings treated as errors (--strict)",
+	MsgErrorPrefix:        "Error",
+	MsgHintPrefix:         "hint",
+	MsgRetryHint:          "this may be a temporary problem; retrying may succeed",
+	MsgWarningPrefix:      "Warning",
+	MsgProblemsFound:      "%d problems found:",
+	MsgCompletedWarning:   "Completed with 1 warning",
+	MsgCompletedWarnings:  "Completed with %d warnings",
+	MsgLogSuppressed:      "suppressed %d similar messages: %s",
+	MsgLogSuppressedOne:   "suppressed 1 similar message: %s",
+	MsgDeprecatedUsage:    "deprecated usage treated as an error (--strict)",
+	MsgDeprecatedCommand:  "command '%s' is deprecated: %s",
+	MsgDeprecatedFlag:     "flag --%s is deprecated: %s",
+	MsgDeprecatedFlagDefs: "command '%s' uses CmdArgs.FlagDefs, which is deprecated: use FlagSets",
+	MsgUseFlagInstead:     "use --%s instead",
+	MsgCommandTimedOut:    "command timed out (--timeout)",
+	MsgDidYouMean:         "%s: did you mean %s?",
+	MsgLoadingConfigFile:  "loading config file failed",
+	MsgLoadingEnvFile:     "loading env file failed",
+	MsgUpdateAvailable:    "A newer version %s is available (you have %s)",
+	MsgUpdateCommand:      "%s; run '%s' to update",
+}
+
+// Package-level message catalog
+var (
+	messageCatalogs = map[string]map[MessageID]string{
+		DefaultLanguage: defaultMessages,
+	}
+	language   = detectLanguage()
+	messagesMu sync.RWMutex // synchronizes access to catalogs and language
+)
+
+// RegisterMessages adds or replaces translations for lang (e.g. "de" or
+// "pt_BR"). Messages not present in msgs fall back to English.
+func RegisterMessages(lang string, msgs map[MessageID]string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	catalog, ok := messageCatalogs[lang]
+	if !ok {
+		catalog = make(map[MessageID]string, len(msgs))
+		messageCatalogs[lang] = catalog
+	}
+	for id, msg := range msgs {
+		catalog[id] = msg
+	}
+}
+
+// SetLanguage selects the catalog used for framework messages. By default the
+// language is detected from LC_ALL, LC_MESSAGES, or LANG.
+func SetLanguage(lang string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	language = lang
+}
+
+// Language returns the language currently used for framework messages
+func Language() string {
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+	return language
+}
+
+// Msg returns the message for id in the current language, formatted with args.
+// Lookup tries the full language (e.g. "pt_BR"), then its base ("pt"), then
+// English.
+func Msg(id MessageID, args ...any) string {
+	format := lookupMessage(id)
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func lookupMessage(id MessageID) (msg string) {
+	var ok bool
+
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+
+	for _, lang := range []string{language, baseLanguage(language), DefaultLanguage} {
+		msg, ok = messageCatalogs[lang][id]
+		if ok {
+			goto end
+		}
+	}
+	msg = string(id)
+end:
+	return msg
+}
+
+// baseLanguage returns "pt" for "pt_BR" or "pt-BR"
+func baseLanguage(lang string) string {
+	i := strings.IndexAny(lang, "_-")
+	if i == -1 {
+		return lang
+	}
+	return lang[:i]
+}
+
+// detectLanguage derives a language such as "de_DE" from the POSIX locale
+// environment variables, ignoring any encoding suffix like ".UTF-8".
+func detectLanguage() (lang string) {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		lang = os.Getenv(name)
+		if lang != "" {
+			break
+		}
+	}
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	if lang == "" || lang == "C" || lang == "POSIX" {
+		lang = DefaultLanguage
+	}
+	return lang
+}
+
+var _ error = (*messageErr)(nil)
+
+// messageErr is a sentinel error whose text is looked up in the message
+// catalog each time Error() is called, so sentinels declared at package init
+// still honor a language selected later. Identity is by pointer, so
+// errors.Is works as with errors.New.
+type messageErr struct {
+	id   MessageID
+	args []any
+	base error // optional error this sentinel also matches via errors.Is
+}
+
+func newMessageErr(id MessageID, args ...any) *messageErr {
+	return &messageErr{id: id, args: args}
+}
+
+func (e *messageErr) Error() string {
+	return Msg(e.id, e.args...)
+}
+
+func (e *messageErr) Unwrap() error {
+	return e.base
+}
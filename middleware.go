@@ -0,0 +1,38 @@
+package cliutil
+
+import (
+	"context"
+)
+
+// HandlerFunc runs cmd, which RunCmd has parsed and is about to handle. ctx
+// is the Context passed to a ContextHandler's HandleContext; a Handle()
+// command reads its Context from CmdRunnerArgs as before.
+type HandlerFunc func(ctx context.Context, cmd Command) error
+
+// Middleware wraps command execution, such as to log, measure, authorize,
+// or recover from panics, calling next to continue the run:
+//
+//	runner.Use(func(next cliutil.HandlerFunc) cliutil.HandlerFunc {
+//		return func(ctx context.Context, cmd cliutil.Command) error {
+//			start := time.Now()
+//			err := next(ctx, cmd)
+//			metrics.Observe(cliutil.CmdPath(cmd), time.Since(start))
+//			return err
+//		}
+//	})
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Use appends middleware to the chain that wraps each command RunCmd runs,
+// including its pre- and post-run hooks. The first middleware added is the
+// outermost, so it runs first and sees the final error.
+func (cr *CmdRunner) Use(middleware ...Middleware) {
+	cr.middleware = append(cr.middleware, middleware...)
+}
+
+// chain returns handle wrapped by the runner's middleware
+func (cr CmdRunner) chain(handle HandlerFunc) HandlerFunc {
+	for i := len(cr.middleware) - 1; i >= 0; i-- {
+		handle = cr.middleware[i](handle)
+	}
+	return handle
+}
@@ -0,0 +1,70 @@
+// Command cliutil-openapi-gen writes Go source that embeds an OpenAPI spec
+// and registers a cliutil command per operation. Typical use:
+//
+//	//go:generate go run github.com/mikeschinkel/go-cliutil/openapicliutil/cmd/cliutil-openapi-gen -spec petstore.json -package petcmds -o petstore_gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mikeschinkel/go-cliutil/openapicliutil"
+)
+
+func main() {
+	specPath := flag.String("spec", "", "OpenAPI spec (JSON) to embed")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package name of the generated file")
+	out := flag.String("o", "openapi_gen.go", "output file")
+	funcName := flag.String("func", "", "name of the generated registration func (default RegisterCommands)")
+	flag.Parse()
+
+	err := run(*specPath, *pkg, *out, *funcName)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "cliutil-openapi-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, pkg, out, funcName string) (err error) {
+	var data []byte
+	var spec *openapicliutil.Spec
+	var rel string
+	var buf bytes.Buffer
+
+	if specPath == "" {
+		err = fmt.Errorf("-spec is required")
+		goto end
+	}
+	data, err = os.ReadFile(specPath)
+	if err != nil {
+		goto end
+	}
+	spec, err = openapicliutil.ParseSpec(data)
+	if err != nil {
+		goto end
+	}
+	// go:embed paths are relative to the generated file's directory
+	rel, err = filepath.Rel(filepath.Dir(out), specPath)
+	if err != nil {
+		goto end
+	}
+	if strings.HasPrefix(filepath.ToSlash(rel), "../") {
+		err = fmt.Errorf("-spec must be in the directory of -o or below it, for go:embed")
+		goto end
+	}
+	err = openapicliutil.Generate(&buf, spec, openapicliutil.GenerateArgs{
+		Package:  pkg,
+		SpecFile: rel,
+		FuncName: funcName,
+	})
+	if err != nil {
+		goto end
+	}
+	err = os.WriteFile(out, buf.Bytes(), 0o644)
+end:
+	return err
+}
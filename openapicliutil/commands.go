@@ -0,0 +1,729 @@
+package openapicliutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var (
+	ErrNoBaseURL          = errors.New("no base URL; set Args.BaseURL or servers in the spec")
+	ErrInvalidFlagValue   = errors.New("invalid flag value")
+	ErrRequestFailed      = errors.New("API request failed")
+	ErrRegistrationFailed = errors.New("OpenAPI command registration failed")
+)
+
+// Args configures the commands built by Register
+type Args struct {
+	Spec    *Spec
+	BaseURL string       // OPTIONAL: defaults to the spec's first server URL
+	Client  *http.Client // OPTIONAL: defaults to http.DefaultClient
+	// Prepare is OPTIONAL and is called with each request before it is sent,
+	// e.g. to add an Authorization header
+	Prepare func(*http.Request) error
+}
+
+// BodyFlagName is the flag that takes a complete JSON request body.
+// Flags for individual body properties are applied on top of it.
+const BodyFlagName = "body"
+
+// Flag locations, as in a Parameter's "in"
+const (
+	inPath   = "path"
+	inQuery  = "query"
+	inHeader = "header"
+	inBody   = "body"
+)
+
+var _ cliutil.CommandTyper = (*Cmd)(nil)
+var _ cliutil.CommandHandler = (*Cmd)(nil)
+
+// Cmd is a cliutil command that calls one OpenAPI operation, printing the
+// response body. With --dry-run the request is printed instead of sent.
+type Cmd struct {
+	*cliutil.CmdBase
+	cmdType  reflect.Type
+	args     *Args
+	method   string
+	path     string
+	flagSet  *cliutil.FlagSet
+	bindings []binding
+	body     *string // value of --body, if the operation has a JSON body
+}
+
+// groupCmd is the parent command for the operations sharing a tag
+type groupCmd struct {
+	*cliutil.CmdBase
+	cmdType reflect.Type
+}
+
+// binding ties a flag to the parameter or body property it supplies
+type binding struct {
+	in     string
+	name   string // name in the spec
+	flag   string
+	schema *Schema
+	str    *string
+	num    *int64
+	bool   *bool
+}
+
+// Register registers a command for each operation in args.Spec, under a
+// parent command for the operation's first tag, and returns the commands.
+// Command names are the kebab-cased operationId, e.g. list-pets.
+func Register(args Args) (cmds []cliutil.Command, err error) {
+	var errs []error
+	var cmd *Cmd
+	var group cliutil.Command
+	var groups = make(map[string]cliutil.Command)
+
+	if args.Spec == nil {
+		err = cliutil.NewErr(ErrInvalidSpec, "rule", "Args.Spec is required")
+		goto end
+	}
+	for _, mo := range args.Spec.operations() {
+		cmd, err = newCmd(&args, mo)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		group = nil
+		if len(mo.op.Tags) > 0 {
+			group, cmds, err = tagGroup(args.Spec, mo.op.Tags[0], groups, cmds)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		errs = cliutil.AppendErr(errs, register(cmd, group))
+		cmds = append(cmds, cmd)
+	}
+	err = cliutil.CombineErrs(errs)
+end:
+	if err != nil {
+		err = cliutil.WithErr(err, ErrRegistrationFailed)
+	}
+	return cmds, err
+}
+
+// register registers cmd under parent, or at the top level if parent is nil
+func register(cmd cliutil.Command, parent cliutil.Command) error {
+	if parent == nil {
+		return cliutil.RegisterCommand(cmd)
+	}
+	return cliutil.RegisterCommand(cmd, parent)
+}
+
+// tagGroup returns the parent command for tag, registering it first if
+// needed
+func tagGroup(spec *Spec, tag string, groups map[string]cliutil.Command, cmds []cliutil.Command) (group cliutil.Command, _ []cliutil.Command, err error) {
+	var description string
+
+	group, ok := groups[tag]
+	if ok {
+		goto end
+	}
+	description = tag + " operations"
+	for _, t := range spec.Tags {
+		if t.Name == tag && t.Description != "" {
+			description = firstLine(t.Description)
+		}
+	}
+	group = &groupCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        kebab(tag),
+			Description: description,
+		}),
+		cmdType: cliutil.NewCommandType(),
+	}
+	err = cliutil.RegisterCommand(group)
+	if err != nil {
+		goto end
+	}
+	groups[tag] = group
+	cmds = append(cmds, group)
+end:
+	return group, cmds, err
+}
+
+// CommandType identifies g in the cliutil registry
+func (g *groupCmd) CommandType() reflect.Type {
+	return g.cmdType
+}
+
+// newCmd builds the command for mo
+func newCmd(args *Args, mo methodOperation) (c *Cmd, err error) {
+	var errs []error
+	var params []Parameter
+	var p Parameter
+	var rb *RequestBody
+	var description string
+
+	name := mo.op.OperationID
+	if name == "" {
+		name = strings.ToLower(mo.method) + " " + mo.path
+	}
+	name = kebab(name)
+	c = &Cmd{
+		cmdType: cliutil.NewCommandType(),
+		args:    args,
+		method:  mo.method,
+		path:    mo.path,
+		flagSet: &cliutil.FlagSet{Name: name},
+	}
+
+	// Operation parameters override path-level ones with the same name and location
+	params = append(params, mo.op.Parameters...)
+	for _, p = range mo.item.Parameters {
+		params = append(params, p)
+	}
+	seen := make(map[string]bool)
+	for _, p = range params {
+		p, err = args.Spec.parameter(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[p.In+"\x00"+p.Name] || p.In == "cookie" {
+			continue
+		}
+		seen[p.In+"\x00"+p.Name] = true
+		p.Schema, err = args.Spec.schema(p.Schema)
+		errs = cliutil.AppendErr(errs, err)
+		c.bind(p.In, p.Name, p.Description, p.Required || p.In == inPath, p.Schema)
+	}
+
+	rb, err = args.Spec.requestBody(mo.op.RequestBody)
+	errs = cliutil.AppendErr(errs, err)
+	errs = cliutil.AppendErr(errs, c.bindBody(rb))
+
+	err = cliutil.CombineErrs(errs)
+	if err != nil {
+		err = cliutil.WithErr(err, "operation", name)
+		goto end
+	}
+
+	description = mo.op.Summary
+	if description == "" {
+		description = firstLine(mo.op.Description)
+	}
+	if description == "" {
+		description = mo.method + " " + mo.path
+	}
+	c.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        name,
+		Description: description,
+		FlagSets:    []*cliutil.FlagSet{c.flagSet},
+		Hide:        mo.op.Deprecated,
+	})
+end:
+	return c, err
+}
+
+// bindBody adds --body and a flag per top-level property for a JSON body
+func (c *Cmd) bindBody(rb *RequestBody) (err error) {
+	var schema *Schema
+	var names []string
+
+	if rb == nil {
+		goto end
+	}
+	for contentType, mt := range rb.Content {
+		if contentType == "application/json" || strings.HasSuffix(contentType, "+json") {
+			schema = mt.Schema
+			break
+		}
+	}
+	if schema == nil {
+		goto end
+	}
+	schema, err = c.args.Spec.schema(schema)
+	if err != nil {
+		goto end
+	}
+
+	c.body = new(string)
+	c.flagSet.FlagDefs = append(c.flagSet.FlagDefs, cliutil.FlagDef{
+		Name:   c.flagName(inBody, BodyFlagName),
+		Usage:  "Request body as JSON; other body flags are applied on top of it",
+		String: c.body,
+	})
+
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, propErr := c.args.Spec.schema(schema.Properties[name])
+		if propErr != nil {
+			err = cliutil.CombineErrs([]error{err, propErr})
+			continue
+		}
+		usage := prop.Description
+		for _, req := range schema.Required {
+			if req == name {
+				usage = strings.TrimSpace(usage + " (required)")
+			}
+		}
+		c.bind(inBody, name, usage, false, prop)
+	}
+end:
+	return err
+}
+
+// bind adds a flag for the parameter or body property name
+func (c *Cmd) bind(in, name, usage string, required bool, schema *Schema) {
+	if schema == nil {
+		schema = &Schema{Type: "string"}
+	}
+	b := binding{
+		in:     in,
+		name:   name,
+		flag:   c.flagName(in, name),
+		schema: schema,
+	}
+	fd := cliutil.FlagDef{
+		Name:     b.flag,
+		Usage:    usage,
+		Required: required,
+	}
+	if fd.Usage == "" {
+		fd.Usage = fmt.Sprintf("%s %s", strings.ToUpper(in[:1])+in[1:], name)
+	}
+	if len(schema.Enum) > 0 {
+		fd.ValidationFunc = enumValidator(schema.Enum)
+		fd.Usage += " (" + joinAny(schema.Enum, ", ") + ")"
+	}
+	fd.ValidationFunc = withNumberValidator(fd.ValidationFunc, schema)
+	switch schema.Type {
+	case "integer":
+		b.num = new(int64)
+		fd.Int64 = b.num
+		if v, ok := schema.Default.(float64); ok {
+			fd.Default = int64(v)
+		}
+	case "boolean":
+		b.bool = new(bool)
+		fd.Bool = b.bool
+		if v, ok := schema.Default.(bool); ok {
+			fd.Default = v
+		}
+	default:
+		b.str = new(string)
+		fd.String = b.str
+		if schema.Default != nil && schema.Type != "object" && schema.Type != "array" {
+			fd.Default = fmt.Sprint(schema.Default)
+		}
+	}
+	c.bindings = append(c.bindings, b)
+	c.flagSet.FlagDefs = append(c.flagSet.FlagDefs, fd)
+}
+
+// flagName returns the kebab-cased flag name for name, prefixed with its
+// location if that name is already taken by a global or earlier flag
+func (c *Cmd) flagName(in, name string) (flagName string) {
+	flagName = kebab(name)
+	if c.flagTaken(flagName) {
+		flagName = in + "-" + flagName
+	}
+	return flagName
+}
+
+// flagTaken returns true if name is a global flag or one of c's flags
+func (c *Cmd) flagTaken(name string) bool {
+	for _, fs := range []*cliutil.FlagSet{cliutil.GetGlobalFlagSet(), c.flagSet} {
+		for _, fd := range fs.FlagDefs {
+			if fd.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CommandType identifies c in the cliutil registry, since every operation
+// command shares the Go type *Cmd
+func (c *Cmd) CommandType() reflect.Type {
+	return c.cmdType
+}
+
+// Handle sends the operation's request and prints the response body,
+// pretty-printing JSON. Responses with a 4xx or 5xx status are errors.
+func (c *Cmd) Handle() (err error) {
+	var req *http.Request
+	var resp *http.Response
+	var data []byte
+
+	req, err = c.newRequest()
+	if err != nil {
+		goto end
+	}
+	if c.Options != nil && c.Options.DryRun() {
+		c.printRequest(req)
+		goto end
+	}
+	if c.args.Prepare != nil {
+		err = c.args.Prepare(req)
+		if err != nil {
+			goto end
+		}
+	}
+	resp, err = c.client().Do(req)
+	if err != nil {
+		err = cliutil.WithErrClass(cliutil.NewErr(ErrRequestFailed, "url", req.URL.String(), err), cliutil.RetryableErr)
+		goto end
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		err = cliutil.NewErr(ErrRequestFailed, "url", req.URL.String(), err)
+		goto end
+	}
+	if resp.StatusCode >= 400 {
+		err = cliutil.NewErr(ErrRequestFailed,
+			"status", resp.Status,
+			"url", req.URL.String(),
+			"response", strings.TrimSpace(string(data)),
+		)
+		goto end
+	}
+	c.printBody(data)
+end:
+	return err
+}
+
+// client returns the configured http.Client or http.DefaultClient
+func (c *Cmd) client() *http.Client {
+	if c.args.Client != nil {
+		return c.args.Client
+	}
+	return http.DefaultClient
+}
+
+// newRequest builds the HTTP request from the flags given on the command
+// line; flags not given are left for the API to default, except path
+// parameters, which take their schema's default or are an error
+func (c *Cmd) newRequest() (req *http.Request, err error) {
+	var errs []error
+	var base string
+	var body map[string]any
+	var data []byte
+	var bodyReader io.Reader
+	var set = c.setFlags()
+	var query = url.Values{}
+	var header = http.Header{}
+	var path = c.path
+
+	base = c.args.BaseURL
+	if base == "" && len(c.args.Spec.Servers) > 0 {
+		base = c.args.Spec.Servers[0].URL
+	}
+	if base == "" {
+		err = cliutil.NewErr(ErrNoBaseURL)
+		goto end
+	}
+
+	if c.body != nil && *c.body != "" {
+		err = json.Unmarshal([]byte(*c.body), &body)
+		if err != nil {
+			errs = append(errs, cliutil.NewErr(ErrInvalidFlagValue, "flag", BodyFlagName, err))
+		}
+	}
+
+	for _, b := range c.bindings {
+		if b.in == inPath {
+			// A path parameter not given takes its default, which the flag
+			// already holds
+			if !set[b.flag] && b.schema.Default == nil {
+				errs = append(errs, cliutil.NewErr(ErrInvalidFlagValue, "flag", b.flag, "rule", "a value is required"))
+				continue
+			}
+			path = strings.ReplaceAll(path, "{"+b.name+"}", url.PathEscape(b.text()))
+			continue
+		}
+		if !set[b.flag] {
+			continue
+		}
+		switch b.in {
+		case inQuery:
+			for _, v := range b.texts() {
+				query.Add(b.name, v)
+			}
+		case inHeader:
+			header.Set(b.name, b.text())
+		case inBody:
+			value, valueErr := b.jsonValue()
+			if valueErr != nil {
+				errs = append(errs, valueErr)
+				continue
+			}
+			if body == nil {
+				body = make(map[string]any)
+			}
+			body[b.name] = value
+		}
+	}
+	err = cliutil.CombineErrs(errs)
+	if err != nil {
+		goto end
+	}
+
+	if body != nil {
+		data, err = json.Marshal(body)
+		if err != nil {
+			err = cliutil.NewErr(ErrInvalidFlagValue, "flag", BodyFlagName, err)
+			goto end
+		}
+		bodyReader = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	}
+	req, err = http.NewRequestWithContext(c.context(), c.method,
+		strings.TrimSuffix(base, "/")+path, bodyReader)
+	if err != nil {
+		goto end
+	}
+	req.URL.RawQuery = query.Encode()
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+end:
+	return req, err
+}
+
+// context returns the runner's Context, or context.Background()
+func (c *Cmd) context() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
+// setFlags returns the long names of the flags given on the command line
+func (c *Cmd) setFlags() (set map[string]bool) {
+	set = make(map[string]bool)
+	if c.flagSet.FlagSet == nil {
+		goto end
+	}
+	c.flagSet.FlagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+end:
+	return set
+}
+
+// printRequest writes req to the Writer for --dry-run
+func (c *Cmd) printRequest(req *http.Request) {
+	c.Writer.Printf("%s %s\n", req.Method, req.URL)
+	if req.GetBody == nil {
+		return
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return
+	}
+	data, _ := io.ReadAll(body)
+	c.printBody(data)
+}
+
+// printBody writes data to the Writer, indenting it if it is JSON
+func (c *Cmd) printBody(data []byte) {
+	var buf bytes.Buffer
+
+	if len(data) == 0 {
+		return
+	}
+	if json.Indent(&buf, data, "", "  ") == nil {
+		data = buf.Bytes()
+	}
+	c.Writer.Printf("%s\n", bytes.TrimRight(data, "\n"))
+}
+
+// text returns the binding's flag value as a string
+func (b binding) text() string {
+	switch {
+	case b.num != nil:
+		return fmt.Sprint(*b.num)
+	case b.bool != nil:
+		return fmt.Sprint(*b.bool)
+	}
+	return *b.str
+}
+
+// texts splits a comma-separated array value into its items, for query
+// parameters given once per item
+func (b binding) texts() []string {
+	if b.schema.Type != "array" {
+		return []string{b.text()}
+	}
+	return strings.Split(b.text(), ",")
+}
+
+// jsonValue converts the binding's flag value to a JSON body value per its
+// schema: arrays are comma-separated, objects are JSON, and numbers are
+// checked to be numeric
+func (b binding) jsonValue() (value any, err error) {
+	var items []any
+
+	switch {
+	case b.num != nil:
+		value = *b.num
+	case b.bool != nil:
+		value = *b.bool
+	case b.schema.Type == "number":
+		value = json.Number(*b.str)
+		err = validateNumber(b.schema, *b.str)
+	case b.schema.Type == "object":
+		value = json.RawMessage(*b.str)
+		if !json.Valid([]byte(*b.str)) {
+			err = errors.New("value must be a JSON object")
+		}
+	case b.schema.Type == "array":
+		for _, item := range strings.Split(*b.str, ",") {
+			items = append(items, arrayItem(b.schema.Items, item))
+		}
+		value = items
+	default:
+		value = *b.str
+	}
+	if err != nil {
+		err = cliutil.NewErr(ErrInvalidFlagValue, "flag", b.flag, err)
+	}
+	return value, err
+}
+
+// arrayItem converts a comma-separated array item per the items schema
+func arrayItem(items *Schema, item string) any {
+	if items != nil && (items.Type == "integer" || items.Type == "number") {
+		return json.Number(item)
+	}
+	return item
+}
+
+var (
+	jsonNumberRE  = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	jsonIntegerRE = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+)
+
+// validateNumber returns an error unless s is a JSON number, or a JSON
+// integer for an integer schema, so values such as NaN, 0x1p4, or " 2" are
+// rejected rather than sent
+func validateNumber(schema *Schema, s string) (err error) {
+	re := jsonNumberRE
+	if schema.Type == "integer" {
+		re = jsonIntegerRE
+	}
+	if !re.MatchString(s) {
+		err = fmt.Errorf("%q is not a JSON %s", s, schema.Type)
+	}
+	return err
+}
+
+// withNumberValidator returns a ValidationFunc that checks a number value, or
+// each comma-separated item of an array of numbers, per schema before
+// calling next, if any. Other schemas, including integers, which are parsed
+// as int64 flags, only get next.
+func withNumberValidator(next cliutil.ValidationFunc, schema *Schema) cliutil.ValidationFunc {
+	var items *Schema
+	var split = schema.Type == "array"
+
+	items = schema
+	if split {
+		items = schema.Items
+	}
+	if items == nil || (items.Type != "number" && (items.Type != "integer" || !split)) {
+		return next
+	}
+	return func(value any) (err error) {
+		s := fmt.Sprint(value)
+		values := []string{s}
+		if s == "" {
+			goto end
+		}
+		if split {
+			values = strings.Split(s, ",")
+		}
+		for _, item := range values {
+			err = validateNumber(items, item)
+			if err != nil {
+				err = cliutil.NewErr(ErrInvalidFlagValue, "value", s, err)
+				goto end
+			}
+		}
+		if next != nil {
+			err = next(value)
+		}
+	end:
+		return err
+	}
+}
+
+// enumValidator returns a ValidationFunc that accepts only values in enum
+func enumValidator(enum []any) cliutil.ValidationFunc {
+	return func(value any) error {
+		s := fmt.Sprint(value)
+		for _, e := range enum {
+			if fmt.Sprint(e) == s {
+				return nil
+			}
+		}
+		return cliutil.NewErr(ErrInvalidFlagValue, "value", s, "allowed", joinAny(enum, ", "))
+	}
+}
+
+// joinAny joins values formatted with %v
+func joinAny(values []any, sep string) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = fmt.Sprint(v)
+	}
+	return strings.Join(strs, sep)
+}
+
+// firstLine returns the first line of s, trimmed
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+// kebab converts names like listPets, get_user, "get /pets/{id}", or
+// HTTPServer to list-pets, get-user, get-pets-id, and http-server
+func kebab(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	dash := false
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if (prevLower || nextLower) && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			dash = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+			dash = false
+		default:
+			if !dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
@@ -0,0 +1,115 @@
+package openapicliutil
+
+import (
+	"bytes"
+	"errors"
+	"go/format"
+	"io"
+	"path/filepath"
+	"text/template"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+)
+
+var ErrGenerateFailed = errors.New("OpenAPI code generation failed")
+
+// GenerateArgs configures Generate
+type GenerateArgs struct {
+	Package  string // Package name of the generated file
+	SpecFile string // Spec path relative to the generated file, for go:embed
+	FuncName string // OPTIONAL: defaults to "RegisterCommands"
+}
+
+// Generate writes Go source that embeds the spec at args.SpecFile and
+// defines a func registering its commands:
+//
+//	func RegisterCommands(args openapicliutil.Args) ([]cliutil.Command, error)
+//
+// spec is the parsed SpecFile; generating from it checks at build time that
+// every operation can become a command, and the generated doc comment lists
+// the resulting commands.
+func Generate(w io.Writer, spec *Spec, args GenerateArgs) (err error) {
+	var buf bytes.Buffer
+	var src []byte
+	var paths []string
+
+	if args.FuncName == "" {
+		args.FuncName = "RegisterCommands"
+	}
+	if args.Package == "" || args.SpecFile == "" {
+		err = cliutil.NewErr(dt.ErrEmpty, "rule", "Package and SpecFile are required")
+		goto end
+	}
+	paths, err = commandPaths(spec)
+	if err != nil {
+		goto end
+	}
+	err = generateTemplate.Execute(&buf, map[string]any{
+		"Args":     args,
+		"SpecFile": filepath.ToSlash(args.SpecFile),
+		"Title":    spec.Info.Title,
+		"Paths":    paths,
+	})
+	if err != nil {
+		goto end
+	}
+	src, err = format.Source(buf.Bytes())
+	if err != nil {
+		goto end
+	}
+	_, err = w.Write(src)
+end:
+	if err != nil {
+		err = cliutil.WithErr(err, ErrGenerateFailed, "spec_file", args.SpecFile)
+	}
+	return err
+}
+
+// commandPaths returns the space-separated path of each operation's command,
+// failing if any operation cannot become a command
+func commandPaths(spec *Spec) (paths []string, err error) {
+	var c *Cmd
+
+	args := &Args{Spec: spec}
+	for _, mo := range spec.operations() {
+		c, err = newCmd(args, mo)
+		if err != nil {
+			goto end
+		}
+		path := c.Name()
+		if len(mo.op.Tags) > 0 {
+			path = kebab(mo.op.Tags[0]) + " " + path
+		}
+		paths = append(paths, path)
+	}
+end:
+	return paths, err
+}
+
+var generateTemplate = template.Must(template.New("openapi").Parse(`// Code generated by cliutil-openapi-gen; DO NOT EDIT.
+
+package {{.Args.Package}}
+
+import (
+	_ "embed"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/openapicliutil"
+)
+
+//go:embed {{.SpecFile}}
+var openAPISpec []byte
+
+// {{.Args.FuncName}} registers a command for each operation in {{.SpecFile}}{{if .Title}}
+// ({{.Title}}){{end}}:
+//{{range .Paths}}
+//   - {{.}}{{end}}
+func {{.Args.FuncName}}(args openapicliutil.Args) (_ []cliutil.Command, err error) {
+	args.Spec, err = openapicliutil.ParseSpec(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+	return openapicliutil.Register(args)
+}
+`))
@@ -0,0 +1,250 @@
+// Package openapicliutil turns the operations of an OpenAPI 3 spec into
+// cliutil commands, so a CLI wrapping an HTTP API can be produced mostly
+// automatically. Operations are grouped under a parent command per tag and
+// get flags derived from their parameters and JSON request body:
+//
+//	spec, err := openapicliutil.ParseSpec(specJSON)
+//	if err != nil {
+//		return err
+//	}
+//	_, err = openapicliutil.Register(openapicliutil.Args{Spec: spec})
+//
+// Generate writes Go source that embeds a spec and registers its commands,
+// for use with go:generate; see cmd/cliutil-openapi-gen.
+package openapicliutil
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var (
+	ErrInvalidSpec   = errors.New("invalid OpenAPI spec")
+	ErrUnresolvedRef = errors.New("unresolved OpenAPI $ref")
+)
+
+// Spec is the subset of an OpenAPI 3.0 or 3.1 document used to build
+// commands. Specs written in YAML can be converted to JSON first.
+type Spec struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers"`
+	Tags       []Tag               `json:"tags"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PathItem holds the operations for a path, by HTTP method
+type PathItem struct {
+	Parameters []Parameter `json:"parameters"`
+	Get        *Operation  `json:"get"`
+	Put        *Operation  `json:"put"`
+	Post       *Operation  `json:"post"`
+	Delete     *Operation  `json:"delete"`
+	Patch      *Operation  `json:"patch"`
+	Head       *Operation  `json:"head"`
+}
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	Description string       `json:"description"`
+	Tags        []string     `json:"tags"`
+	Parameters  []Parameter  `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+	Deprecated  bool         `json:"deprecated"`
+}
+
+// Parameter is a path, query, or header parameter; cookie parameters are
+// ignored
+type Parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref"`
+	Type        SchemaType         `json:"type"`
+	Description string             `json:"description"`
+	Default     any                `json:"default"`
+	Enum        []any              `json:"enum"`
+	Properties  map[string]*Schema `json:"properties"`
+	Required    []string           `json:"required"`
+	Items       *Schema            `json:"items"`
+}
+
+type Components struct {
+	Schemas       map[string]*Schema     `json:"schemas"`
+	Parameters    map[string]Parameter   `json:"parameters"`
+	RequestBodies map[string]RequestBody `json:"requestBodies"`
+}
+
+// SchemaType is a schema's type, such as "string" or "integer". OpenAPI 3.1
+// allows a list of types; the first one other than "null" is used.
+type SchemaType string
+
+func (t *SchemaType) UnmarshalJSON(data []byte) (err error) {
+	var types []string
+	var s string
+
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &types)
+		for _, s = range types {
+			if s != "null" {
+				break
+			}
+		}
+	} else {
+		err = json.Unmarshal(data, &s)
+	}
+	*t = SchemaType(s)
+	return err
+}
+
+// ParseSpec parses a JSON OpenAPI document
+func ParseSpec(data []byte) (spec *Spec, err error) {
+	spec = &Spec{}
+	err = json.Unmarshal(data, spec)
+	if err != nil {
+		err = cliutil.NewErr(ErrInvalidSpec, err)
+		goto end
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		err = cliutil.NewErr(ErrInvalidSpec, "openapi", spec.OpenAPI, "rule", "only OpenAPI 3.x is supported")
+		goto end
+	}
+end:
+	if err != nil {
+		spec = nil
+	}
+	return spec, err
+}
+
+// methodOperation pairs an operation with its HTTP method and path
+type methodOperation struct {
+	method string
+	path   string
+	op     *Operation
+	item   PathItem
+}
+
+// operations returns the spec's operations sorted by path, then by method
+// in the order GET, POST, PUT, PATCH, DELETE, HEAD
+func (s *Spec) operations() (ops []methodOperation) {
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := s.Paths[path]
+		for _, mo := range []methodOperation{
+			{method: "GET", op: item.Get},
+			{method: "POST", op: item.Post},
+			{method: "PUT", op: item.Put},
+			{method: "PATCH", op: item.Patch},
+			{method: "DELETE", op: item.Delete},
+			{method: "HEAD", op: item.Head},
+		} {
+			if mo.op == nil {
+				continue
+			}
+			mo.path, mo.item = path, item
+			ops = append(ops, mo)
+		}
+	}
+	return ops
+}
+
+// refName returns the component name a local $ref such as
+// "#/components/schemas/Pet" points to in section
+func refName(ref, section string) (name string, ok bool) {
+	return strings.CutPrefix(ref, "#/components/"+section+"/")
+}
+
+// parameter resolves p's $ref, if any
+func (s *Spec) parameter(p Parameter) (_ Parameter, err error) {
+	if p.Ref == "" {
+		goto end
+	}
+	if name, ok := refName(p.Ref, "parameters"); ok {
+		if resolved, ok := s.Components.Parameters[name]; ok {
+			p = resolved
+			goto end
+		}
+	}
+	err = cliutil.NewErr(ErrUnresolvedRef, "ref", p.Ref)
+end:
+	return p, err
+}
+
+// requestBody resolves rb's $ref, if any
+func (s *Spec) requestBody(rb *RequestBody) (_ *RequestBody, err error) {
+	if rb == nil || rb.Ref == "" {
+		goto end
+	}
+	if name, ok := refName(rb.Ref, "requestBodies"); ok {
+		if resolved, ok := s.Components.RequestBodies[name]; ok {
+			rb = &resolved
+			goto end
+		}
+	}
+	err = cliutil.NewErr(ErrUnresolvedRef, "ref", rb.Ref)
+end:
+	return rb, err
+}
+
+// maxRefDepth bounds $ref chains so cyclic schemas cannot loop forever
+const maxRefDepth = 16
+
+// schema resolves sc's $ref, if any, following chains of refs
+func (s *Spec) schema(sc *Schema) (_ *Schema, err error) {
+	for range maxRefDepth {
+		if sc == nil || sc.Ref == "" {
+			goto end
+		}
+		name, ok := refName(sc.Ref, "schemas")
+		resolved := s.Components.Schemas[name]
+		if !ok || resolved == nil {
+			break
+		}
+		sc = resolved
+	}
+	err = cliutil.NewErr(ErrUnresolvedRef, "ref", sc.Ref)
+end:
+	return sc, err
+}
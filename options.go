@@ -14,17 +14,38 @@ type Options interface {
 }
 
 const (
-	DefaultTimeout   = 3
-	DefaultQuiet     = false
-	DefaultDryRun    = false
-	DefaultForce     = false
-	DefaultVerbosity = int(LowVerbosity)
+	DefaultTimeout       = 3 * time.Second
+	DefaultQuiet         = false
+	DefaultDryRun        = false
+	DefaultForce         = false
+	DefaultYes           = false
+	DefaultNoColor       = false
+	DefaultVerbosity     = int(LowVerbosity)
+	DefaultOutput        = string(TextOutput)
+	DefaultStrict        = false
+	DefaultLogLevel      = "" // Derive the log level from verbosity
+	DefaultUTC           = false
+	DefaultArgsFromStdin = false
+	DefaultConfig        = "" // Look for the files given ConfigArgs.Files
+	DefaultEnvFile       = "" // Load DotEnvFile if it exists
 )
 
-var options = &GlobalOptions{
-	timeout:   new(int),
-	quiet:     new(bool),
-	verbosity: new(int),
-	dryRun:    new(bool),
-	force:     new(bool),
+// newGlobalOptions returns the GlobalOptions a CLI's global flags assign
+func newGlobalOptions() *GlobalOptions {
+	return &GlobalOptions{
+		timeout:       new(time.Duration),
+		quiet:         new(bool),
+		verbosity:     new(int),
+		dryRun:        new(bool),
+		force:         new(bool),
+		yes:           new(bool),
+		noColor:       new(bool),
+		output:        new(string),
+		strict:        new(bool),
+		logLevel:      new(string),
+		utc:           new(bool),
+		argsFromStdin: new(bool),
+		config:        new(string),
+		envFile:       new(string),
+	}
 }
@@ -0,0 +1,17 @@
+module github.com/mikeschinkel/go-cliutil/otelcliutil
+
+go 1.25.3
+
+replace github.com/mikeschinkel/go-cliutil => ..
+
+require (
+	github.com/mikeschinkel/go-cliutil v0.3.0
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+)
+
+require (
+	github.com/mikeschinkel/go-dt v0.3.3 // indirect
+	github.com/mikeschinkel/go-dt/appinfo v0.2.1 // indirect
+	github.com/mikeschinkel/go-dt/dtx v0.2.1 // indirect
+)
@@ -0,0 +1,70 @@
+// Package otelcliutil adapts OpenTelemetry tracing to cliutil so that each
+// command run by cliutil.CmdRunner becomes a span, a child of any span in the
+// runner's Context:
+//
+//	cliutil.SetTracer(otelcliutil.NewTracer(otel.Tracer("myapp")))
+//
+// Handlers receive the span in CmdRunnerArgs.Context, so spans they start
+// become children of the command's span.
+package otelcliutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ cliutil.Tracer = (*Tracer)(nil)
+
+// Tracer implements cliutil.Tracer using an OpenTelemetry trace.Tracer
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a cliutil.Tracer that starts spans with t
+func NewTracer(t trace.Tracer) *Tracer {
+	return &Tracer{tracer: t}
+}
+
+// StartCommandSpan starts a span named after the command path, as a child of
+// any span in ctx. Errors passed to the returned func are recorded and set the
+// span status to Error.
+func (t *Tracer) StartCommandSpan(ctx context.Context, name string, attrs map[string]any) (context.Context, cliutil.SpanEndFunc) {
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(Attributes(attrs)...),
+	)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// Attributes converts cliutil span attributes to OpenTelemetry attributes,
+// formatting values of unsupported types with %v
+func Attributes(attrs map[string]any) (kvs []attribute.KeyValue) {
+	for k, v := range attrs {
+		switch t := v.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(k, t))
+		case bool:
+			kvs = append(kvs, attribute.Bool(k, t))
+		case int:
+			kvs = append(kvs, attribute.Int(k, t))
+		case int64:
+			kvs = append(kvs, attribute.Int64(k, t))
+		case float64:
+			kvs = append(kvs, attribute.Float64(k, t))
+		default:
+			kvs = append(kvs, attribute.String(k, fmt.Sprintf("%v", t)))
+		}
+	}
+	return kvs
+}
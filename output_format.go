@@ -0,0 +1,128 @@
+package cliutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// OutputFormat identifies how commands and the framework render output
+type OutputFormat string
+
+const (
+	TextOutput OutputFormat = "text"
+	JSONOutput OutputFormat = "json"
+	YAMLOutput OutputFormat = "yaml" // Available once registered by yamlcliutil
+)
+
+var ErrInvalidOutputFormat = errors.New("invalid output format")
+
+// OutputEncoder writes record to w as one document of a machine-readable
+// OutputFormat, including anything that separates it from the next record
+// (see Emitter)
+type OutputEncoder func(w io.Writer, record any) error
+
+var (
+	// outputFormats lists the valid values for the --output global option
+	outputFormats = []OutputFormat{
+		TextOutput,
+		JSONOutput,
+	}
+	outputEncoders = map[OutputFormat]OutputEncoder{
+		JSONOutput: encodeJSONRecord,
+	}
+	outputFormatsMu sync.RWMutex // synchronizes access to outputFormats and outputEncoders
+)
+
+// RegisterOutputFormat makes format, such as YAMLOutput, a valid value for
+// --output, with records encoded by encode, replacing any encoder for format.
+// Text and JSON are built in; yamlcliutil registers YAML.
+func RegisterOutputFormat(format OutputFormat, encode OutputEncoder) {
+	outputFormatsMu.Lock()
+	if !slices.Contains(outputFormats, format) {
+		outputFormats = append(outputFormats, format)
+	}
+	outputEncoders[format] = encode
+	outputFormatsMu.Unlock()
+	defaultCLI.updateOutputUsage()
+}
+
+// outputEncoder returns the encoder for format, if any
+func outputEncoder(format OutputFormat) (encode OutputEncoder, ok bool) {
+	outputFormatsMu.RLock()
+	defer outputFormatsMu.RUnlock()
+	encode, ok = outputEncoders[format]
+	return encode, ok
+}
+
+// encodeJSONRecord writes record as one line of JSON, so records can be
+// streamed and read one at a time, as by jq
+func encodeJSONRecord(w io.Writer, record any) error {
+	return json.NewEncoder(w).Encode(record)
+}
+
+// ParseOutputFormat validates s as one of the supported output formats.
+// An empty string yields TextOutput.
+func ParseOutputFormat(s string) (of OutputFormat, err error) {
+	var names []string
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		of = TextOutput
+		goto end
+	}
+	outputFormatsMu.RLock()
+	defer outputFormatsMu.RUnlock()
+	for _, f := range outputFormats {
+		if string(f) == s {
+			of = f
+			goto end
+		}
+		names = append(names, string(f))
+	}
+	err = NewErr(
+		ErrInvalidOutputFormat,
+		"output", s,
+		"valid", strings.Join(names, "|"),
+	)
+end:
+	return of, err
+}
+
+// IsMachineReadable returns true for formats intended for automation
+func (of OutputFormat) IsMachineReadable() bool {
+	return of != TextOutput && of != ""
+}
+
+func (of OutputFormat) String() string {
+	return string(of)
+}
+
+// outputFormatUsage returns the usage text for the --output global option
+func outputFormatUsage() string {
+	var names []string
+
+	outputFormatsMu.RLock()
+	defer outputFormatsMu.RUnlock()
+	for _, f := range outputFormats {
+		names = append(names, string(f))
+	}
+	return fmt.Sprintf("Output format (%s)", strings.Join(names, ", "))
+}
+
+// updateOutputUsage refreshes the usage of the --output global option after
+// RegisterOutputFormat, so help lists the new format
+func (cli *CLI) updateOutputUsage() {
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+	for i := range cli.flagSet.FlagDefs {
+		if cli.flagSet.FlagDefs[i].Name == "output" {
+			cli.flagSet.FlagDefs[i].Usage = outputFormatUsage()
+		}
+	}
+	cli.changed()
+}
@@ -0,0 +1,48 @@
+package cliutil
+
+import (
+	"io"
+	"os"
+)
+
+// IsPiped returns true when w's output does not go to a terminal, e.g. when
+// it is piped to another program or redirected to a file. Output for people,
+// such as color, spinners, and columns truncated to the terminal width,
+// should be left out then in favor of stable output other programs can parse.
+//
+// The Writer from NewWriter answers for itself; for other Writers the answer
+// comes from IsPipedOutput(w.Writer()).
+func IsPiped(w Writer) bool {
+	pw, ok := w.(interface{ IsPiped() bool })
+	if ok {
+		return pw.IsPiped()
+	}
+	return IsPipedOutput(w.Writer())
+}
+
+// IsPipedOutput returns true when out is not a terminal. Writers other than
+// files, such as buffers, are taken to stand in for stdout, so the answer for
+// them and for os.Stdout comes from the Terminal (see clitest.FakeTerminal).
+func IsPipedOutput(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if ok && f != os.Stdout {
+		return !isTerminalFile(f)
+	}
+	return !GetTerminal().IsTTY()
+}
+
+// IsTerminal returns true when out is a terminal; it is the opposite of
+// IsPipedOutput, for code that works with an io.Writer rather than a Writer.
+func IsTerminal(out io.Writer) bool {
+	return !IsPipedOutput(out)
+}
+
+// IsPiped returns true when w's output does not go to a terminal
+func (w *cliWriter) IsPiped() bool {
+	return IsPipedOutput(w.writer)
+}
+
+// IsQuiet returns true when w's Printf writes nothing because of --quiet
+func (w *cliWriter) IsQuiet() bool {
+	return w.quiet
+}
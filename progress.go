@@ -0,0 +1,102 @@
+package cliutil
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProgressWidth is the number of characters in a progress bar
+const DefaultProgressWidth = 30
+
+// ProgressArgs configures a Progress
+type ProgressArgs struct {
+	Label    string               // OPTIONAL: shown before the bar, e.g. "Downloading"
+	Total    int64                // OPTIONAL: 0 when unknown, to show only the count
+	Width    int                  // OPTIONAL: defaults to DefaultProgressWidth
+	Interval time.Duration        // OPTIONAL: defaults to DefaultStatusInterval
+	Format   func(n int64) string // OPTIONAL: formats counts, e.g. FormatBytes; defaults to FormatInt
+}
+
+// Progress shows how far a long-running command has got on the error stream
+// of a Writer, leaving its output stream for results. On a terminal it
+// redraws a bar in place; otherwise, as in CI logs, it writes a line at most
+// once per Interval (see StatusLine). It writes nothing when the Writer is
+// quiet.
+//
+//	p := cliutil.NewProgress(c.Writer, cliutil.ProgressArgs{Label: "Copying", Total: n})
+//	for _, f := range files {
+//		copyFile(f)
+//		p.Add(1)
+//	}
+//	p.Done()
+type Progress struct {
+	mu      sync.Mutex
+	args    ProgressArgs
+	status  *StatusLine
+	current int64
+}
+
+// NewProgress returns a Progress at 0 that writes to w's error stream
+func NewProgress(w Writer, args ProgressArgs) *Progress {
+	if args.Width <= 0 {
+		args.Width = DefaultProgressWidth
+	}
+	if args.Format == nil {
+		args.Format = FormatInt
+	}
+	return &Progress{
+		args:   args,
+		status: NewStatusLine(w, StatusLineArgs{Interval: args.Interval}),
+	}
+}
+
+// Add advances p by n
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current += n
+	p.status.Update("%s", p.line())
+}
+
+// Set moves p to n
+func (p *Progress) Set(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = n
+	p.status.Update("%s", p.line())
+}
+
+// Done writes p's final state and ends its line; later calls do nothing
+func (p *Progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Update("%s", p.line())
+	p.status.Done()
+}
+
+// line returns p's state, with a bar on a terminal when Total is known
+func (p *Progress) line() string {
+	var b strings.Builder
+
+	if p.args.Label != "" {
+		b.WriteString(p.args.Label)
+		b.WriteByte(' ')
+	}
+	if p.args.Total <= 0 {
+		b.WriteString(p.args.Format(p.current))
+		return b.String()
+	}
+	current := min(max(p.current, 0), p.args.Total)
+	if p.status.tty {
+		filled := int(current * int64(p.args.Width) / p.args.Total)
+		b.WriteString("[" + strings.Repeat("=", filled) + strings.Repeat(" ", p.args.Width-filled) + "] ")
+	}
+	fmt.Fprintf(&b, "%3d%% %s/%s",
+		current*100/p.args.Total,
+		p.args.Format(current),
+		p.args.Format(p.args.Total),
+	)
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var ErrPromptIO = errors.New("reading prompt answer failed")
+
+// Package-level prompt input
+var (
+	promptInput   io.Reader    = os.Stdin
+	promptInputMu sync.RWMutex // synchronizes access to promptInput
+)
+
+// SetPromptInput replaces the reader Confirm reads answers from, os.Stdin by
+// default (primarily for testing), and returns the previous one so it can be
+// restored.
+func SetPromptInput(r io.Reader) (prev io.Reader) {
+	promptInputMu.Lock()
+	defer promptInputMu.Unlock()
+	prev = promptInput
+	promptInput = r
+	return prev
+}
+
+// GetPromptInput returns the reader Confirm reads answers from
+func GetPromptInput() io.Reader {
+	promptInputMu.RLock()
+	defer promptInputMu.RUnlock()
+	return promptInput
+}
+
+// Confirm asks msg on w's error stream, going ahead without asking when
+// --yes was given to the default CLI (see CLI.Confirm)
+func Confirm(w Writer, msg string) (ok bool) {
+	return defaultCLI.Confirm(w, msg)
+}
+
+// Confirm asks msg, such as "Delete 3 backups?", on w's error stream and
+// returns true only if the user answers y or yes. It returns true without
+// asking when --yes was given to cli, and false without asking when no one
+// is at the terminal (see IsInteractive), so scripts must pass --yes to go
+// ahead.
+func (cli *CLI) Confirm(w Writer, msg string) (ok bool) {
+	var answer string
+
+	if cli.options.Yes() {
+		ok = true
+		goto end
+	}
+	if !IsInteractive() {
+		goto end
+	}
+	fmt.Fprint(w.ErrWriter(), Msg(MsgConfirmPrompt, msg))
+	answer, _ = readAnswer(GetPromptInput())
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		ok = true
+	}
+end:
+	return ok
+}
+
+// PromptSecret asks msg, such as "Password: ", on the error stream of the
+// Writer (see GetWriter) and returns the line typed, without echoing it when
+// the Terminal is a TTY. Otherwise, as when a secret is piped in, the line is
+// read as is. It fails rather than echo the secret if echo cannot be turned
+// off, and with ErrPromptIO if no line can be read.
+func PromptSecret(msg string) (secret string, err error) {
+	var readErr error
+
+	term := GetTerminal()
+	errWriter := GetWriter().ErrWriter()
+	fmt.Fprint(errWriter, msg)
+	if term.IsTTY() {
+		err = term.SetEcho(false)
+		if err != nil {
+			err = NewErr(ErrPromptIO, "reason", "could not turn off echo", err)
+			goto end
+		}
+		defer func() {
+			err = CombineErrs([]error{err, term.SetEcho(true)})
+			// The Enter the user typed was not echoed either
+			fmt.Fprintln(errWriter)
+		}()
+	}
+	secret, readErr = readAnswer(GetPromptInput())
+	if readErr != nil {
+		err = NewErr(ErrPromptIO, readErr)
+		goto end
+	}
+	secret = strings.TrimSuffix(secret, "\r")
+end:
+	return secret, err
+}
+
+// readAnswer returns the next line of r without its newline, failing only if
+// r ends or fails before any of the line is read. It reads a byte at a time so
+// that input after the line is left for the next prompt.
+func readAnswer(r io.Reader) (answer string, err error) {
+	var b strings.Builder
+	var n int
+
+	buf := make([]byte, 1)
+	for {
+		n, err = r.Read(buf)
+		if n == 1 && buf[0] == '\n' {
+			err = nil
+			break
+		}
+		if n == 1 {
+			b.WriteByte(buf[0])
+		}
+		if err != nil {
+			break
+		}
+	}
+	if errors.Is(err, io.EOF) && b.Len() > 0 {
+		err = nil
+	}
+	return b.String(), err
+}
@@ -0,0 +1,91 @@
+package cliutil
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// RegistrySnapshot is an opaque copy of the command and global-flag
+// registries, captured by SnapshotRegistry and reinstated by RestoreRegistry.
+type RegistrySnapshot struct {
+	commands        []Command
+	commandsTypeMap map[reflect.Type]Command
+	commandsPathMap map[string]Command
+	flagCommandMap  map[string]Command
+	globalFlagDefs  []FlagDef
+	initializers    []Initializer
+	subCommands     map[Command][]Command
+	cmdTree         *cmdNode
+}
+
+// SnapshotRegistry captures the registered commands, global flags, and
+// initializers so a test can register throwaway ones and then undo them with
+// RestoreRegistry:
+//
+//	snap := cliutil.SnapshotRegistry()
+//	t.Cleanup(func() { cliutil.RestoreRegistry(snap) })
+//
+// Tests that use t.Parallel() should prefer clitest.IsolateRegistry, which
+// also serializes registry-mutating tests against each other, or a CLI of
+// their own from NewCLI.
+func SnapshotRegistry() *RegistrySnapshot {
+	return defaultCLI.SnapshotRegistry()
+}
+
+// SnapshotRegistry captures the CLI's registered commands and their
+// subcommands, global flags, and the initializers, for RestoreRegistry
+func (cli *CLI) SnapshotRegistry() *RegistrySnapshot {
+	cli.registryMu.RLock()
+	defer cli.registryMu.RUnlock()
+	initializersMu.Lock()
+	defer initializersMu.Unlock()
+	subCommands := make(map[Command][]Command, len(cli.commands))
+	for _, cmd := range cli.commands {
+		lister, ok := cmd.(subCommandLister)
+		if ok {
+			subCommands[cmd] = slices.Clone(*lister.subCommandList())
+		}
+	}
+	return &RegistrySnapshot{
+		subCommands:     subCommands,
+		cmdTree:         cli.cmdTree.Load(),
+		commands:        slices.Clone(cli.commands),
+		commandsTypeMap: maps.Clone(cli.commandsTypeMap),
+		commandsPathMap: maps.Clone(cli.commandsPathMap),
+		flagCommandMap:  maps.Clone(cli.flagCommandMap),
+		globalFlagDefs:  slices.Clone(cli.flagSet.FlagDefs),
+		initializers:    slices.Clone(initializers),
+	}
+}
+
+// RestoreRegistry reinstates the registries captured by SnapshotRegistry.
+// The snapshot is copied again, so it may be restored more than once.
+func RestoreRegistry(snap *RegistrySnapshot) {
+	defaultCLI.RestoreRegistry(snap)
+}
+
+// RestoreRegistry reinstates the registries captured by
+// CLI.SnapshotRegistry
+func (cli *CLI) RestoreRegistry(snap *RegistrySnapshot) {
+	if snap == nil {
+		return
+	}
+	cli.registryMu.Lock()
+	defer cli.registryMu.Unlock()
+	cli.commands = slices.Clone(snap.commands)
+	cli.commandsTypeMap = maps.Clone(snap.commandsTypeMap)
+	cli.commandsPathMap = maps.Clone(snap.commandsPathMap)
+	// BuildCommandTree adds subcommands to the parents, which outlive the
+	// snapshot, so those are restored too
+	for cmd, subs := range snap.subCommands {
+		*cmd.(subCommandLister).subCommandList() = slices.Clone(subs)
+	}
+	cli.changed()
+	cli.cmdTree.Store(snap.cmdTree)
+	cli.flagCommandMap = maps.Clone(snap.flagCommandMap)
+	cli.flagSet.FlagDefs = slices.Clone(snap.globalFlagDefs)
+	initializersMu.Lock()
+	initializers = slices.Clone(snap.initializers)
+	initializersMu.Unlock()
+}
@@ -0,0 +1,212 @@
+package remotecliutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// positionalArgser is implemented by commands embedding *cliutil.CmdBase
+type positionalArgser interface {
+	PositionalArgs() []string
+}
+
+// RunCmd runs cmd, as parsed by cr.ParseCmd, in the daemon listening on
+// socket instead of in this process. The daemon's stdout and stderr are
+// written to cr's Writer as they arrive.
+//
+// When the command fails, the returned error carries the daemon's exit code
+// for cliutil.ExitCode and is classified as cliutil.NotifiedErr, since the
+// daemon has already reported it.
+func RunCmd(socket string, cr *cliutil.CmdRunner, cmd cliutil.Command) (err error) {
+	var conn net.Conn
+	var req Request
+	var frame Frame
+	var scanner *bufio.Scanner
+	var dialer net.Dialer
+	var stop func() bool
+
+	ctx := cr.Args.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err = NewRequest(cr, cmd)
+	if err != nil {
+		goto end
+	}
+	conn, err = dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		err = cliutil.NewErr(ErrConnectFailed, "socket", socket, err)
+		goto end
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Abandon the command when the context is cancelled
+	stop = context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		err = cliutil.NewErr(ErrConnectFailed, "socket", socket, err)
+		goto end
+	}
+
+	scanner = bufio.NewScanner(conn)
+	scanner.Buffer(nil, maxFrameSize)
+	for scanner.Scan() {
+		frame = Frame{}
+		err = json.Unmarshal(scanner.Bytes(), &frame)
+		if err != nil {
+			err = cliutil.NewErr(ErrProtocol, err)
+			goto end
+		}
+		if frame.Done {
+			err = frameErr(req.Command, frame)
+			goto end
+		}
+		err = writeFrame(cr.Args.Writer, frame)
+		if err != nil {
+			goto end
+		}
+	}
+	err = scanner.Err()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	err = cliutil.NewErr(ErrConnectionClosed, "command", req.Command, err)
+end:
+	return err
+}
+
+// NewRequest returns the Request for running cmd, as parsed by cr.ParseCmd.
+// Flags are sent with their actual values, including RedactedValue flags, so
+// the socket should only be reachable by the user running the client.
+func NewRequest(cr *cliutil.CmdRunner, cmd cliutil.Command) (req Request, err error) {
+	var pa positionalArgser
+	var ok bool
+
+	if cmd == nil {
+		err = cliutil.NewErr(ErrInvalidRequest, "rule", "a command is required")
+		goto end
+	}
+	req = Request{
+		Command:      cliutil.CmdPath(cmd),
+		GlobalFlags:  setFlags([]*cliutil.FlagSet{cliutil.GetGlobalFlagSet()}),
+		Flags:        setFlags(cmd.FlagSets()),
+		InvocationID: cr.Args.InvocationID,
+	}
+	pa, ok = cmd.(positionalArgser)
+	if ok {
+		req.Args = pa.PositionalArgs()
+	}
+	if req.InvocationID == "" {
+		req.InvocationID = cliutil.NewInvocationID()
+	}
+end:
+	return req, err
+}
+
+// setFlags returns the values of each flag set on the command line, keyed by
+// the flag's long name even when its shortcut was used. A repeatable flag has
+// one value per time it is to be given.
+func setFlags(flagSets []*cliutil.FlagSet) (flags map[string][]string) {
+	flags = make(map[string][]string)
+	for _, fs := range flagSets {
+		if fs == nil || fs.FlagSet == nil {
+			continue
+		}
+		defs := make(map[string]*cliutil.FlagDef, len(fs.FlagDefs))
+		for i := range fs.FlagDefs {
+			fd := &fs.FlagDefs[i]
+			defs[fd.Name] = fd
+			if fd.Shortcut != 0 {
+				defs[string(fd.Shortcut)] = fd
+			}
+		}
+		fs.FlagSet.Visit(func(f *flag.Flag) {
+			fd, ok := defs[f.Name]
+			if ok {
+				flags[fd.Name] = flagValues(fd, f)
+			}
+		})
+	}
+	return flags
+}
+
+// flagValues returns the value of f, or the values of a repeatable flag,
+// formatted to be given on a command line
+func flagValues(fd *cliutil.FlagDef, f *flag.Flag) (values []string) {
+	switch v := fd.Value().(type) {
+	case []string:
+		values = slices.Clone(v)
+	case []int:
+		for _, n := range v {
+			values = append(values, strconv.Itoa(n))
+		}
+	case map[string]string:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			values = append(values, key+"="+v[key])
+		}
+	default:
+		values = []string{f.Value.String()}
+	}
+	return values
+}
+
+// writeFrame writes an output frame to the matching stream of w
+func writeFrame(w cliutil.Writer, frame Frame) (err error) {
+	var out io.Writer
+
+	switch frame.Stream {
+	case StdoutStream:
+		out = w.Writer()
+	case StderrStream:
+		out = w.ErrWriter()
+	default:
+		err = cliutil.NewErr(ErrProtocol, "stream", frame.Stream)
+		goto end
+	}
+	_, err = io.WriteString(out, frame.Data)
+end:
+	return err
+}
+
+// frameErr returns the error for a final frame, or nil if the command
+// succeeded
+func frameErr(command string, frame Frame) (err error) {
+	if frame.ExitCode == cliutil.ExitSuccess {
+		goto end
+	}
+	err = cliutil.NewErr(ErrRemoteCmdFailed,
+		"command", command,
+		"exit_code", frame.ExitCode,
+		&remoteError{code: frame.ExitCode, msg: frame.Error},
+	)
+	err = cliutil.WithErrClass(err, cliutil.NotifiedErr)
+end:
+	return err
+}
+
+// remoteError is the error a command returned in the daemon
+type remoteError struct {
+	code int
+	msg  string
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+// ExitCode implements cliutil.ExitCoder so the client exits with the
+// daemon's exit code
+func (e *remoteError) ExitCode() int {
+	return e.code
+}
@@ -0,0 +1,166 @@
+package remotecliutil
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+)
+
+// HTTPArgs configures the handler returned by Server.HTTPHandler
+type HTTPArgs struct {
+	// Token must be sent as "Authorization: Bearer <token>" with every request
+	Token string
+	// Allow lists the command paths that may be run, e.g. "status" or
+	// "db migrate"; no other commands can be run or listed
+	Allow []string
+}
+
+// Response is the JSON body returned for a POST to /run
+type Response struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CommandInfo describes an allowed command in the JSON body returned for a
+// GET of /commands
+type CommandInfo struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// httpHandler serves Server's commands over HTTP
+type httpHandler struct {
+	server *Server
+	args   HTTPArgs
+}
+
+// HTTPHandler returns an http.Handler letting GUIs and editor extensions run
+// the allowed commands without spawning a process. It serves:
+//
+//	GET  /commands  the allowed commands, as a JSON array of CommandInfo
+//	POST /run       a JSON Request, answered with a JSON Response
+//
+// A command that runs and fails is still answered with 200 OK and its exit
+// code in the Response. Requests without the token are answered with 401,
+// and requests for commands not in Allow with 403.
+//
+// The handler should only be served on a loopback address, e.g.:
+//
+//	h, err := server.HTTPHandler(remotecliutil.HTTPArgs{Token: token, Allow: allow})
+//	err = http.ListenAndServe("127.0.0.1:7070", h)
+func (s *Server) HTTPHandler(args HTTPArgs) (handler http.Handler, err error) {
+	var mux *http.ServeMux
+	var h *httpHandler
+
+	if args.Token == "" {
+		err = cliutil.NewErr(dt.ErrEmpty, "empty_property", "Token")
+		goto end
+	}
+	h = &httpHandler{server: s, args: args}
+	mux = http.NewServeMux()
+	mux.HandleFunc("GET /commands", h.commands)
+	mux.HandleFunc("POST /run", h.run)
+	handler = h.authorize(mux)
+end:
+	return handler, err
+}
+
+// authorize rejects requests without the bearer token
+func (h *httpHandler) authorize(next http.Handler) http.Handler {
+	want := []byte("Bearer " + h.args.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowed returns true if path is in the allowlist
+func (h *httpHandler) allowed(path string) bool {
+	return slices.Contains(h.args.Allow, path)
+}
+
+func (h *httpHandler) commands(w http.ResponseWriter, _ *http.Request) {
+	infos := make([]CommandInfo, 0, len(h.args.Allow))
+	for _, path := range h.args.Allow {
+		cmd := cliutil.GetExactCommand(strings.ReplaceAll(path, " ", "."))
+		if cmd == nil {
+			continue
+		}
+		infos = append(infos, CommandInfo{Command: path, Description: cmd.Description()})
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (h *httpHandler) run(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	var stdout, stderr syncBuffer
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFrameSize)).Decode(&req)
+	if err != nil {
+		http.Error(w, cliutil.NewErr(ErrInvalidRequest, err).Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.allowed(req.Command) {
+		http.Error(w, cliutil.NewErr(ErrCommandNotAllowed, "command", req.Command).Error(), http.StatusForbidden)
+		return
+	}
+	if req.InvocationID == "" {
+		req.InvocationID = cliutil.NewInvocationID()
+	}
+	err = h.server.run(r.Context(), req, runArgs{
+		stdout: &stdout,
+		stderr: &stderr,
+		allow:  h.allowed,
+	})
+	if errors.Is(err, ErrCommandNotAllowed) || errors.Is(err, ErrFlagNotAllowed) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	resp := Response{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: cliutil.ExitCode(err),
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// syncBuffer is a bytes.Buffer safe for writes from several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,428 @@
+package remotecliutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var ErrInvalidToolArgs = errors.New("invalid tool arguments")
+
+// MCPArgs configures a Model Context Protocol server
+type MCPArgs struct {
+	Name    string // OPTIONAL: server name reported to clients; defaults to the CLI's name
+	Version string // OPTIONAL: server version reported to clients
+
+	// Allow lists the command paths exposed as tools, e.g. "status" or
+	// "db migrate". OPTIONAL: defaults to every command that is runnable
+	// and not hidden.
+	Allow []string
+}
+
+// mcpProtocolVersion is used when a client does not request a version
+const mcpProtocolVersion = "2025-06-18"
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool is a command exposed as an MCP tool
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	path        string
+	argNames    []string // Tool argument names of the positional args, in order
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpCallResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+// mcpServer is one MCP session over a reader and writer
+type mcpServer struct {
+	server *Server
+	args   MCPArgs
+	tools  []*mcpTool
+	enc    *json.Encoder
+}
+
+// ServeMCP serves the Model Context Protocol over newline-delimited JSON-RPC
+// read from r and written to w, until r is exhausted or ctx is done. Each
+// allowed command is a tool whose input schema is derived from the
+// command's flags and positional args; calling the tool runs the command
+// through the normal parse and run pipeline, and returns its captured
+// output. Tool names are command paths joined with "_", e.g. db_migrate.
+func (s *Server) ServeMCP(ctx context.Context, r io.Reader, w io.Writer, args MCPArgs) (err error) {
+	var req rpcRequest
+
+	if args.Name == "" {
+		args.Name = filepath.Base(os.Args[0])
+	}
+	ms := &mcpServer{
+		server: s,
+		args:   args,
+		tools:  mcpTools(args.Allow),
+		enc:    json.NewEncoder(w),
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxFrameSize)
+	for ctx.Err() == nil && scanner.Scan() {
+		req = rpcRequest{}
+		err = json.Unmarshal(scanner.Bytes(), &req)
+		if err != nil {
+			err = ms.reply(json.RawMessage("null"), nil, &rpcError{Code: rpcParseError, Message: err.Error()})
+		} else {
+			err = ms.handle(ctx, req)
+		}
+		if err != nil {
+			goto end
+		}
+	}
+	err = scanner.Err()
+end:
+	return err
+}
+
+// handle answers req, unless it is a notification
+func (ms *mcpServer) handle(ctx context.Context, req rpcRequest) (err error) {
+	var result any
+	var rpcErr *rpcError
+
+	switch req.Method {
+	case "initialize":
+		result = ms.initialize(req.Params)
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = map[string]any{"tools": ms.tools}
+	case "tools/call":
+		result, rpcErr = ms.callTool(ctx, req.Params)
+	default:
+		rpcErr = &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	// Notifications, such as notifications/initialized, have no ID and get
+	// no response
+	if len(req.ID) == 0 {
+		goto end
+	}
+	err = ms.reply(req.ID, result, rpcErr)
+end:
+	return err
+}
+
+func (ms *mcpServer) reply(id json.RawMessage, result any, rpcErr *rpcError) error {
+	resp := rpcResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		resp.Result = result
+	}
+	return ms.enc.Encode(resp)
+}
+
+func (ms *mcpServer) initialize(params json.RawMessage) any {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(params, &p)
+	if p.ProtocolVersion == "" {
+		p.ProtocolVersion = mcpProtocolVersion
+	}
+	info := map[string]string{"name": ms.args.Name}
+	if ms.args.Version != "" {
+		info["version"] = ms.args.Version
+	}
+	return map[string]any{
+		"protocolVersion": p.ProtocolVersion,
+		"capabilities":    map[string]any{"tools": map[string]any{}},
+		"serverInfo":      info,
+	}
+}
+
+// callTool runs the command for a tools/call request
+func (ms *mcpServer) callTool(ctx context.Context, params json.RawMessage) (result *mcpCallResult, rpcErr *rpcError) {
+	var p struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	var tool *mcpTool
+	var req Request
+	var stdout, stderr syncBuffer
+	var err error
+
+	err = json.Unmarshal(params, &p)
+	if err != nil {
+		rpcErr = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		goto end
+	}
+	for _, t := range ms.tools {
+		if t.Name == p.Name {
+			tool = t
+		}
+	}
+	if tool == nil {
+		rpcErr = &rpcError{Code: rpcInvalidParams, Message: "unknown tool: " + p.Name}
+		goto end
+	}
+	req, err = tool.request(p.Arguments)
+	if err != nil {
+		rpcErr = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		goto end
+	}
+	err = ms.server.run(ctx, req, runArgs{
+		stdout: &stdout,
+		stderr: &stderr,
+		allow:  func(path string) bool { return path == tool.path },
+	})
+	result = &mcpCallResult{
+		Content: []mcpContent{{Type: "text", Text: stdout.String()}},
+		IsError: err != nil,
+	}
+	if stderr.String() != "" {
+		result.Content = append(result.Content, mcpContent{Type: "text", Text: "stderr:\n" + stderr.String()})
+	}
+end:
+	return result, rpcErr
+}
+
+// request returns the Request running t's command with the tool arguments
+func (t *mcpTool) request(arguments map[string]any) (req Request, err error) {
+	var missing string
+
+	req = Request{
+		Command:      t.path,
+		Flags:        make(map[string][]string),
+		InvocationID: cliutil.NewInvocationID(),
+	}
+	for _, name := range t.argNames {
+		value, ok := arguments[name]
+		if !ok {
+			missing = name
+			continue
+		}
+		if missing != "" {
+			err = cliutil.NewErr(ErrInvalidToolArgs, "argument", name, "missing", missing, "rule", "earlier positional arguments are required")
+			goto end
+		}
+		// A variadic arg's array gives one positional arg per element
+		if values, ok := value.([]any); ok {
+			for _, v := range values {
+				req.Args = append(req.Args, toolValue(v))
+			}
+			continue
+		}
+		req.Args = append(req.Args, toolValue(value))
+	}
+	for name, value := range arguments {
+		if slices.Contains(t.argNames, name) {
+			continue
+		}
+		if _, ok := t.InputSchema["properties"].(map[string]any)[name]; !ok {
+			err = cliutil.NewErr(ErrInvalidToolArgs, "argument", name, "rule", "unknown argument")
+			goto end
+		}
+		req.Flags[name] = []string{toolValue(value)}
+	}
+end:
+	return req, err
+}
+
+// toolValue formats a JSON tool argument as a command-line value
+func toolValue(v any) (s string) {
+	switch v := v.(type) {
+	case string:
+		s = v
+	case bool:
+		s = strconv.FormatBool(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	return s
+}
+
+// mcpTools returns a tool for each allowed command that can be run
+func mcpTools(allow []string) (tools []*mcpTool) {
+	for _, cmd := range cliutil.RegisteredCommands() {
+		if !cliutil.IsRunnable(cmd) {
+			continue
+		}
+		if _, ok := cmd.(*mcpServeCmd); ok {
+			continue
+		}
+		path := cliutil.CmdPath(cmd)
+		switch {
+		case allow != nil && !slices.Contains(allow, path):
+			continue
+		case allow == nil && cmd.IsHidden():
+			continue
+		}
+		tools = append(tools, newMCPTool(path, cmd))
+	}
+	slices.SortFunc(tools, func(a, b *mcpTool) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return tools
+}
+
+// newMCPTool returns the tool for cmd, with a JSON Schema property for each
+// of its flags and positional args
+func newMCPTool(path string, cmd cliutil.Command) (tool *mcpTool) {
+	var required []string
+
+	properties := make(map[string]any)
+	for _, fs := range cmd.FlagSets() {
+		for _, fd := range fs.FlagDefs {
+			prop := map[string]any{"description": fd.Usage}
+			switch fd.Type() {
+			case cliutil.BoolFlag:
+				prop["type"] = "boolean"
+			case cliutil.IntFlag, cliutil.Int64Flag, cliutil.CountFlag:
+				prop["type"] = "integer"
+			case cliutil.Float64Flag:
+				prop["type"] = "number"
+			default:
+				prop["type"] = "string"
+			}
+			switch def := fd.Default.(type) {
+			case nil:
+			case time.Duration:
+				prop["default"] = def.String()
+			case []string, []int, map[string]string:
+				// A tool argument gives a repeatable flag one value, so a
+				// list default would not match the "string" type
+			default:
+				prop["default"] = def
+			}
+			properties[fd.Name] = prop
+			if fd.Required {
+				required = append(required, fd.Name)
+			}
+		}
+	}
+	tool = &mcpTool{
+		Name:        strings.ReplaceAll(path, " ", "_"),
+		Description: cmd.Description(),
+		path:        path,
+	}
+	for _, ad := range cmd.ArgDefs() {
+		name := ad.Name
+		if _, ok := properties[name]; ok {
+			name += "_arg"
+		}
+		properties[name] = map[string]any{
+			"type":        "string",
+			"description": ad.Usage,
+		}
+		if ad.IsVariadic() {
+			properties[name] = map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": ad.Usage,
+			}
+		}
+		tool.argNames = append(tool.argNames, name)
+		if ad.Required {
+			required = append(required, name)
+		}
+	}
+	tool.InputSchema = map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		tool.InputSchema["required"] = required
+	}
+	return tool
+}
+
+var _ cliutil.CommandHandler = (*mcpServeCmd)(nil)
+
+// mcpCmd groups the MCP commands
+type mcpCmd struct {
+	*cliutil.CmdBase
+}
+
+// mcpServeCmd serves MCP over stdin and the Writer's stdout
+type mcpServeCmd struct {
+	*cliutil.CmdBase
+	args MCPArgs
+}
+
+// RegisterMCPCommands registers an "mcp serve" command that serves the
+// other commands to AI agents over MCP on stdin and stdout; see ServeMCP.
+// Agents are typically configured to launch "myapp mcp serve".
+func RegisterMCPCommands(args MCPArgs) (err error) {
+	group := &mcpCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "mcp",
+			Description: "Model Context Protocol commands",
+		}),
+	}
+	serve := &mcpServeCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "serve",
+			Description: "Serve commands as MCP tools over stdin and stdout",
+		}),
+		args: args,
+	}
+	err = cliutil.RegisterCommand(group)
+	if err != nil {
+		goto end
+	}
+	err = cliutil.RegisterCommand(serve, group)
+end:
+	return err
+}
+
+func (c *mcpServeCmd) Handle() error {
+	// Tools run with this command's AppInfo and Config; their output is
+	// captured, so nothing may log to stdout, which carries the protocol
+	runner := c.CmdRunnerArgs
+	runner.Logger = nil
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	server := NewServer(ServerArgs{Runner: runner})
+	return server.ServeMCP(ctx, os.Stdin, c.Writer.Writer(), c.args)
+}
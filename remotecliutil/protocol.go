@@ -0,0 +1,110 @@
+// Package remotecliutil runs cliutil commands in a long-lived daemon on behalf
+// of a thin client, for tools whose startup (loading config, warming caches,
+// opening connections) is slow compared to the commands themselves.
+//
+// The client parses its command line as usual, then sends the resolved
+// command path, its flag values, and its positional arguments to the daemon
+// over a unix socket. The daemon runs the same command and streams its
+// output back to the client's Writer:
+//
+//	// daemon
+//	err = remotecliutil.NewServer(remotecliutil.ServerArgs{
+//		Socket: socket,
+//	}).Serve(ctx)
+//
+//	// client
+//	cmd, err := runner.ParseCmd(args)
+//	if err == nil {
+//		err = remotecliutil.RunCmd(socket, runner, cmd)
+//	}
+//	os.Exit(runner.HandleErr(err))
+//
+// Both sides must register the same commands. Messages are JSON lines, so
+// the package needs nothing beyond the standard library; there is no gRPC
+// transport. Standard input is not forwarded.
+//
+// Server.HTTPHandler serves an allowlist of commands over local HTTP/JSON
+// for GUIs and editor extensions, guarded by a bearer token, and
+// Server.ServeMCP serves them to AI agents as Model Context Protocol tools.
+package remotecliutil
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	ErrConnectFailed     = errors.New("connecting to command daemon failed")
+	ErrProtocol          = errors.New("invalid message from command daemon")
+	ErrRemoteCmdFailed   = errors.New("remote command failed")
+	ErrInvalidRequest    = errors.New("invalid remote command request")
+	ErrServeFailed       = errors.New("serving remote commands failed")
+	ErrConnectionClosed  = errors.New("command daemon closed the connection")
+	ErrCommandNotAllowed = errors.New("command not allowed")
+	ErrFlagNotAllowed    = errors.New("flag not allowed in remote requests")
+)
+
+// Request is what a client sends to run a command
+type Request struct {
+	Command      string              `json:"command"`      // Command path, e.g. "db migrate"
+	GlobalFlags  map[string][]string `json:"global_flags"` // Global flags set by the client, by long name
+	Flags        map[string][]string `json:"flags"`        // Command flags set by the client, by long name, one value per time given
+	Args         []string            `json:"args"`         // Positional arguments
+	InvocationID string              `json:"invocation_id"`
+}
+
+// maxFrameSize bounds a single JSON line, which holds one write by the
+// command or the request
+const maxFrameSize = 64 << 20
+
+// Stream names used in Frame.Stream
+const (
+	StdoutStream = "stdout"
+	StderrStream = "stderr"
+)
+
+// Frame is one message of a command's response. Output frames carry a
+// Stream and Data; the final frame has Done set and the command's exit code
+// and error message, if any.
+type Frame struct {
+	Stream   string `json:"stream,omitempty"`
+	Data     string `json:"data,omitempty"`
+	Done     bool   `json:"done,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// frameEncoder writes frames to a connection; stdout and stderr frames share
+// it, so writes are serialized
+type frameEncoder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newFrameEncoder(w io.Writer) *frameEncoder {
+	return &frameEncoder{enc: json.NewEncoder(w)}
+}
+
+func (e *frameEncoder) encode(f Frame) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(f)
+}
+
+// streamWriter is an io.Writer sending each write as a frame on one stream
+type streamWriter struct {
+	stream string
+	enc    *frameEncoder
+}
+
+func (w streamWriter) Write(p []byte) (n int, err error) {
+	err = w.enc.encode(Frame{Stream: w.stream, Data: string(p)})
+	if err != nil {
+		goto end
+	}
+	n = len(p)
+end:
+	return n, err
+}
@@ -0,0 +1,282 @@
+package remotecliutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// ServerArgs configures a Server
+type ServerArgs struct {
+	Socket string // Path of the unix socket to listen on
+
+	// Runner is the template for each request's CmdRunnerArgs, e.g. with the
+	// AppInfo and Config loaded once at daemon startup. Its Writer, Options,
+	// Args, and InvocationID are set per request, and its Context defaults to
+	// the one passed to Serve.
+	Runner cliutil.CmdRunnerArgs
+
+	// Options wraps the GlobalOptions parsed for a request in the app's own
+	// Options type. OPTIONAL: defaults to using the GlobalOptions as is.
+	Options func(*cliutil.GlobalOptions) cliutil.Options
+}
+
+// Server runs commands sent by RunCmd. Global options are package-level
+// state in cliutil, so requests run one at a time.
+type Server struct {
+	args ServerArgs
+	mu   sync.Mutex // serializes requests
+}
+
+// NewServer returns a Server for args
+func NewServer(args ServerArgs) *Server {
+	if args.Options == nil {
+		args.Options = func(opts *cliutil.GlobalOptions) cliutil.Options {
+			return opts
+		}
+	}
+	return &Server{args: args}
+}
+
+// Serve listens on the socket and runs requests until ctx is done, then
+// removes the socket. A stale socket left by a previous daemon is replaced,
+// but not one a daemon still answers on, nor a file that is not a socket.
+// Only the user running the daemon may connect to the socket.
+func (s *Server) Serve(ctx context.Context) (err error) {
+	var ln net.Listener
+	var conn net.Conn
+	var wg sync.WaitGroup
+	var stop func() bool
+
+	err = removeStaleSocket(s.args.Socket)
+	if err != nil {
+		goto end
+	}
+	ln, err = net.Listen("unix", s.args.Socket)
+	if err != nil {
+		err = cliutil.NewErr(ErrServeFailed, "socket", s.args.Socket, err)
+		goto end
+	}
+	defer wg.Wait()
+	defer func() { _ = ln.Close() }()
+	err = os.Chmod(s.args.Socket, 0o600)
+	if err != nil {
+		err = cliutil.NewErr(ErrServeFailed, "socket", s.args.Socket, err)
+		goto end
+	}
+
+	stop = context.AfterFunc(ctx, func() { _ = ln.Close() })
+	defer stop()
+
+	for {
+		conn, err = ln.Accept()
+		if err != nil {
+			break
+		}
+		wg.Add(1)
+		go func(conn net.Conn) {
+			defer wg.Done()
+			s.ServeConn(ctx, conn)
+		}(conn)
+	}
+	if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+		err = nil
+		goto end
+	}
+	err = cliutil.NewErr(ErrServeFailed, "socket", s.args.Socket, err)
+end:
+	return err
+}
+
+// removeStaleSocket removes the socket at path left by a daemon that is no
+// longer running. It fails if a daemon answers on it or path is not a socket.
+func removeStaleSocket(path string) (err error) {
+	var info os.FileInfo
+	var conn net.Conn
+
+	info, err = os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+		goto end
+	}
+	if err != nil {
+		err = cliutil.NewErr(ErrServeFailed, "socket", path, err)
+		goto end
+	}
+	if info.Mode().Type() != os.ModeSocket {
+		err = cliutil.NewErr(ErrServeFailed, "socket", path, "reason", "path exists and is not a socket")
+		goto end
+	}
+	conn, err = net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		err = cliutil.NewErr(ErrServeFailed, "socket", path, "reason", "another daemon is listening on it")
+		goto end
+	}
+	err = os.Remove(path)
+	if err != nil {
+		err = cliutil.NewErr(ErrServeFailed, "socket", path, err)
+	}
+end:
+	return err
+}
+
+// ServeConn runs the request read from conn, streams the command's output
+// back, and closes conn
+func (s *Server) ServeConn(ctx context.Context, conn net.Conn) {
+	var req Request
+	var err error
+
+	defer func() { _ = conn.Close() }()
+	enc := newFrameEncoder(conn)
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, maxFrameSize)
+	if !scanner.Scan() {
+		return
+	}
+	err = json.Unmarshal(scanner.Bytes(), &req)
+	if err != nil {
+		err = cliutil.NewErr(ErrInvalidRequest, err)
+		_ = enc.encode(Frame{Done: true, ExitCode: cliutil.ExitOptionsParseError, Error: err.Error()})
+		return
+	}
+	err = s.run(ctx, req, runArgs{
+		stdout: streamWriter{stream: StdoutStream, enc: enc},
+		stderr: streamWriter{stream: StderrStream, enc: enc},
+	})
+	frame := Frame{Done: true, ExitCode: cliutil.ExitCode(err)}
+	if err != nil {
+		frame.Error = err.Error()
+	}
+	_ = enc.encode(frame)
+}
+
+// runArgs are where run writes a request's output and, optionally, which
+// commands it may run
+type runArgs struct {
+	stdout io.Writer
+	stderr io.Writer
+	allow  func(path string) bool
+}
+
+// run parses and runs req as if its command line had been given to the
+// daemon, reporting any error to the client's stderr
+func (s *Server) run(ctx context.Context, req Request, ra runArgs) (err error) {
+	var opts *cliutil.GlobalOptions
+	var args []string
+	var cmd cliutil.Command
+	var runner *cliutil.CmdRunner
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Checked before parsing, as parsing is what reads the daemon's files
+	err = checkLocalFlags(requestArgs(req))
+	if err == nil {
+		opts, args, err = cliutil.ParseGlobalOptions(requestArgs(req))
+	}
+	writerArgs := &cliutil.WriterArgs{
+		Verbosity: cliutil.Verbosity(cliutil.DefaultVerbosity),
+		Stdout:    ra.stdout,
+		Stderr:    ra.stderr,
+	}
+	if err == nil {
+		writerArgs.Quiet = opts.Quiet()
+		writerArgs.Verbosity = opts.Verbosity()
+	}
+	w := cliutil.NewWriter(writerArgs)
+
+	// Send package-level output to this client, always putting the daemon's
+	// Writer back after. A daemon without one keeps none, as SetWriter
+	// cannot unset it, so no request's Writer outlives the request.
+	prev := cliutil.GetWriter()
+	if prev != nil {
+		cliutil.SetWriter(w)
+		defer cliutil.SetWriter(prev)
+	}
+	cliutil.ResetWarnings()
+
+	runnerArgs := s.args.Runner
+	runnerArgs.Writer = w
+	runnerArgs.Options = s.args.Options(opts)
+	runnerArgs.Args = args
+	runnerArgs.InvocationID = req.InvocationID
+	if runnerArgs.Context == nil {
+		runnerArgs.Context = ctx
+	}
+	runner = cliutil.NewCmdRunner(runnerArgs)
+	if err != nil {
+		goto end
+	}
+	cmd, err = runner.ParseCmd(args)
+	if err != nil {
+		goto end
+	}
+	// Check the resolved command, since args can also select a subcommand
+	if ra.allow != nil && !ra.allow(cliutil.CmdPath(cmd)) {
+		err = cliutil.NewErr(ErrCommandNotAllowed, "command", cliutil.CmdPath(cmd))
+		goto end
+	}
+	err = runner.RunCmd(cmd)
+end:
+	runner.ReportErr(err)
+	return err
+}
+
+// localGlobalFlags are the global flags a request may not set, as they would
+// have the daemon read its own stdin or files rather than the client's
+var localGlobalFlags = []string{"args-from-stdin", "env-file", cliutil.ConfigFlagName}
+
+// checkLocalFlags returns ErrFlagNotAllowed if args, a command line from
+// requestArgs, sets any of localGlobalFlags before its ArgsTerminator
+func checkLocalFlags(args []string) (err error) {
+	for _, arg := range args {
+		if arg == cliutil.ArgsTerminator {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if slices.Contains(localGlobalFlags, name) {
+			err = cliutil.NewErr(ErrFlagNotAllowed, "flag", "--"+name)
+			break
+		}
+	}
+	return err
+}
+
+// requestArgs rebuilds a command line for req, in the form expected by
+// cliutil.ParseGlobalOptions
+func requestArgs(req Request) (args []string) {
+	args = append(args, "remote")
+	args = append(args, flagArgs(req.GlobalFlags)...)
+	args = append(args, strings.Fields(req.Command)...)
+	args = append(args, flagArgs(req.Flags)...)
+	// Args are positional even if they start with "-"
+	args = append(args, cliutil.ArgsTerminator)
+	args = append(args, req.Args...)
+	return args
+}
+
+// flagArgs returns flags as --name=value args, sorted by name, with one arg
+// per value of a flag given more than once
+func flagArgs(flags map[string][]string) (args []string) {
+	for _, name := range slices.Sorted(maps.Keys(flags)) {
+		for _, value := range flags[name] {
+			args = append(args, "--"+name+"="+value)
+		}
+	}
+	return args
+}
@@ -0,0 +1,143 @@
+package cliutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorReport is the structured form of an error emitted on stderr when a
+// machine-readable output format such as --output=json is active.
+type ErrorReport struct {
+	Code       int            `json:"code"`
+	ErrorCode  string         `json:"error_code,omitempty"`
+	Class      string         `json:"class,omitempty"`
+	Message    string         `json:"message"`
+	Suggestion string         `json:"suggestion,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+// NewErrorReport builds an ErrorReport from err, collecting its exit code,
+// stable error code, suggestions, and every key/value attached via NewErr or WithErr.
+func NewErrorReport(err error) ErrorReport {
+	report := ErrorReport{
+		Code:       ExitCode(err),
+		ErrorCode:  ErrorCodeOf(err),
+		Class:      ErrClassOf(err).String(),
+		Message:    err.Error(),
+		Suggestion: strings.Join(Suggestions(err), "; "),
+	}
+	for _, pair := range AllErrMeta(err) {
+		if report.Details == nil {
+			report.Details = make(map[string]any)
+		}
+		_, exists := report.Details[pair.Key()]
+		if exists {
+			continue
+		}
+		report.Details[pair.Key()] = jsonSafeValue(pair.Value())
+	}
+	return report
+}
+
+// ReportError writes err to the Writer's error stream followed by any
+// suggestions attached with WithSuggestion. Output depends on ErrClassOf(err):
+//   - NotifiedErr: the error text is omitted because the user was already told
+//   - SilentErr: nothing is written
+//   - RetryableErr: a hint that retrying may succeed is added
+//
+// When the default CLI's --output option selects a machine-readable format
+// the error is instead written as a single JSON object so automation can
+// parse it (see CLI.ReportError).
+func ReportError(w Writer, err error) {
+	defaultCLI.ReportError(w, err)
+}
+
+// ReportError is ReportError in the format selected by cli's --output
+// option, colored under its --no-color
+func (cli *CLI) ReportError(w Writer, err error) {
+	var class ErrClass
+
+	if err == nil {
+		goto end
+	}
+	class = ErrClassOf(err)
+	if class == SilentErr {
+		goto end
+	}
+	if cli.GlobalOptions().OutputFormat().IsMachineReadable() {
+		cli.writeErrorJSON(w, err)
+		goto end
+	}
+	switch {
+	case class == NotifiedErr:
+		// The user has already been notified
+	case errors.Is(err, ErrInvalidInvocation):
+		cli.writeProblemList(w, err)
+	default:
+		w.Errorf("%s: %v\n", cli.errorPrefix(w), err)
+	}
+	for _, s := range Suggestions(err) {
+		w.Errorf("  %s: %s\n", Msg(MsgHintPrefix), s)
+	}
+	if class == RetryableErr {
+		w.Errorf("  %s: %s\n", Msg(MsgHintPrefix), Msg(MsgRetryHint))
+	}
+end:
+	return
+}
+
+// errorPrefix returns the "Error" that starts a reported error, in the Error
+// style of the Theme when the error stream is a terminal (see CLI.Styled)
+func (cli *CLI) errorPrefix(w Writer) string {
+	return cli.Styled(w.ErrWriter(), GetTheme().Error, Msg(MsgErrorPrefix))
+}
+
+// writeProblemList writes each problem of an ErrInvalidInvocation error on
+// its own line so users can fix an entire invocation in one pass.
+func (cli *CLI) writeProblemList(w Writer, err error) {
+	problems, ok := FindErr[combined](err)
+	if !ok {
+		w.Errorf("%s: %v\n", cli.errorPrefix(w), err)
+		goto end
+	}
+	w.Errorf("%s: %s\n", cli.errorPrefix(w), Msg(MsgProblemsFound, len(problems.errs)))
+	for _, p := range problems.errs {
+		w.Errorf("  - %v\n", p)
+	}
+	if errors.Is(err, ErrShowUsage) {
+		w.Errorf("  %s: %s\n", Msg(MsgHintPrefix), ErrShowUsage.Error())
+	}
+end:
+	return
+}
+
+// writeErrorJSON writes err as a JSON ErrorReport to the Writer's error stream
+func (cli *CLI) writeErrorJSON(w Writer, err error) {
+	b, jsonErr := json.Marshal(NewErrorReport(err))
+	if jsonErr != nil {
+		// Fall back to prose rather than lose the error entirely
+		w.Errorf("%s: %v\n", cli.errorPrefix(w), err)
+		goto end
+	}
+	_, _ = fmt.Fprintf(w.ErrWriter(), "%s\n", b)
+end:
+	return
+}
+
+// jsonSafeValue returns v if it can be marshaled to JSON, otherwise its
+// string representation.
+func jsonSafeValue(v any) any {
+	switch t := v.(type) {
+	case error:
+		return t.Error()
+	case fmt.Stringer:
+		return t.String()
+	}
+	_, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return v
+}
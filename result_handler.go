@@ -0,0 +1,81 @@
+package cliutil
+
+import (
+	"context"
+	"reflect"
+	"text/template"
+)
+
+// ResultHandler is implemented by commands that return what they produce
+// rather than write it, separating their logic from its presentation. RunCmd
+// calls HandleResult instead of Handle, with a Context bound by --timeout as
+// for a ContextHandler, and writes the result in the format selected by
+// --output (see WriteResult). A nil result writes nothing.
+type ResultHandler interface {
+	Command
+	HandleResult(ctx context.Context) (result any, err error)
+}
+
+// resultTemplater is implemented by commands with a CmdArgs.ResultTemplate
+type resultTemplater interface {
+	ResultTemplate() string
+}
+
+// WriteResult writes result to w's output stream in format as Emitter writes
+// a record, except that as text it is rendered by tmplText when that is not
+// empty, with the Theme's template functions (see UsageTemplateText), and a
+// slice is written one element per line.
+func WriteResult(w Writer, result any, format OutputFormat, tmplText string) (err error) {
+	return defaultCLI.WriteResult(w, result, format, tmplText)
+}
+
+// WriteResult is WriteResult with a template styled under cli's --no-color
+func (cli *CLI) WriteResult(w Writer, result any, format OutputFormat, tmplText string) (err error) {
+	var tmpl *template.Template
+	var e *Emitter
+	var v reflect.Value
+
+	if result == nil {
+		goto end
+	}
+	e = cli.NewEmitter(w, EmitterArgs{Format: format})
+	switch {
+	case e.Format().IsMachineReadable():
+		err = e.Emit(result)
+	case tmplText != "":
+		tmpl, err = parseTemplate("result", tmplText)
+		if err != nil {
+			goto end
+		}
+		err = executeTemplate(cli, tmpl, w.Writer(), w.Writer(), result)
+	default:
+		v = reflect.ValueOf(result)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			err = e.Emit(result)
+			goto end
+		}
+		for i := 0; i < v.Len() && err == nil; i++ {
+			err = e.Emit(v.Index(i).Interface())
+		}
+	}
+end:
+	return err
+}
+
+// handleResult runs handler and writes its result, if it succeeded
+func (cr CmdRunner) handleResult(ctx context.Context, handler ResultHandler) (err error) {
+	var result any
+	var tmplText string
+
+	result, err = handler.HandleResult(ctx)
+	err = cr.timedOutErr(ctx, err)
+	if err != nil {
+		goto end
+	}
+	if rt, ok := handler.(resultTemplater); ok {
+		tmplText = rt.ResultTemplate()
+	}
+	err = cr.Args.cli().WriteResult(cr.Args.Writer, result, cr.Args.cli().GlobalOptions().OutputFormat(), tmplText)
+end:
+	return err
+}
@@ -0,0 +1,147 @@
+package cliutil
+
+import (
+	"encoding/json"
+)
+
+// CLISchema is the machine-readable definition of the CLI written by
+// ExportCLISchema, for tooling such as docs sites, IDE plugins, and
+// completion generators
+type CLISchema struct {
+	GlobalFlags []FlagSchema `json:"global_flags"`
+	Commands    []CmdSchema  `json:"commands"`
+}
+
+// CmdSchema is one visible command and its visible subcommands
+type CmdSchema struct {
+	Name        string       `json:"name"`
+	Path        string       `json:"path"` // Space-separated full name, e.g. "db migrate"
+	Usage       string       `json:"usage"`
+	Description string       `json:"description,omitempty"`
+	Deprecated  string       `json:"deprecated,omitempty"`
+	Args        []ArgSchema  `json:"args,omitempty"`
+	Flags       []FlagSchema `json:"flags,omitempty"`
+	Examples    []Example    `json:"examples,omitempty"`
+	Commands    []CmdSchema  `json:"commands,omitempty"`
+}
+
+// ArgSchema is one positional argument of a command
+type ArgSchema struct {
+	Name     string `json:"name"`
+	Usage    string `json:"usage,omitempty"`
+	Required bool   `json:"required"`
+	Default  any    `json:"default,omitempty"`
+	Example  string `json:"example,omitempty"`
+	Stdin    bool   `json:"stdin,omitempty"`
+	Variadic bool   `json:"variadic,omitempty"`
+}
+
+// FlagSchema is one flag of a command or a global option. The default of a
+// sensitive flag is replaced with RedactedValue.
+type FlagSchema struct {
+	Name       string `json:"name"`
+	Shortcut   string `json:"shortcut,omitempty"`
+	Type       string `json:"type"`
+	Usage      string `json:"usage,omitempty"`
+	Required   bool   `json:"required"`
+	Default    any    `json:"default,omitempty"`
+	Example    string `json:"example,omitempty"`
+	Deprecated string `json:"deprecated,omitempty"`
+	ReplacedBy string `json:"replaced_by,omitempty"`
+}
+
+// BuildCLISchema returns the definition of the global options and of every
+// visible command, in help order
+func BuildCLISchema() CLISchema {
+	schema := CLISchema{
+		GlobalFlags: []FlagSchema{},
+		Commands:    schemaCmds("", GetTopLevelCmds()),
+	}
+	if GetGlobalFlagSet() != nil {
+		schema.GlobalFlags = schemaFlags(GetGlobalFlagSet())
+	}
+	if schema.Commands == nil {
+		schema.Commands = []CmdSchema{}
+	}
+	return schema
+}
+
+// ExportCLISchema returns BuildCLISchema() as indented JSON
+func ExportCLISchema() ([]byte, error) {
+	return json.MarshalIndent(BuildCLISchema(), "", "  ")
+}
+
+// schemaCmds returns the visible cmds under the dot-separated parent path
+// with their subcommands
+func schemaCmds(parent string, cmds []Command) (schemas []CmdSchema) {
+	var path string
+
+	for _, cmd := range cmds {
+		if cmd.IsHidden() {
+			continue
+		}
+		path = cmd.Name()
+		if parent != "" {
+			path = parent + "." + cmd.Name()
+		}
+		schemas = append(schemas, schemaCmd(cmd, path))
+	}
+	return schemas
+}
+
+// schemaCmd returns the definition of cmd, found at the dot-separated path
+func schemaCmd(cmd Command, path string) CmdSchema {
+	usage := BuildCmdUsage(cmd)
+	schema := CmdSchema{
+		Name:        cmd.Name(),
+		Path:        CmdPath(cmd),
+		Usage:       usage.Usage,
+		Description: cmd.Description(),
+		Examples:    usage.Examples,
+		Commands:    schemaCmds(path, cliOf(cmd).GetSubCmds(path)),
+	}
+	dep, ok := cmd.(interface{ Deprecated() string })
+	if ok {
+		schema.Deprecated = dep.Deprecated()
+	}
+	for _, ad := range cmd.ArgDefs() {
+		schema.Args = append(schema.Args, ArgSchema{
+			Name:     ad.Name,
+			Usage:    ad.Usage,
+			Required: ad.Required,
+			Default:  jsonSafeValue(ad.Default),
+			Example:  ad.Example,
+			Stdin:    ad.Stdin,
+			Variadic: ad.IsVariadic(),
+		})
+	}
+	for _, fs := range cmd.FlagSets() {
+		schema.Flags = append(schema.Flags, schemaFlags(fs)...)
+	}
+	return schema
+}
+
+// schemaFlags returns the definitions of the flags in fs
+func schemaFlags(fs *FlagSet) (schemas []FlagSchema) {
+	for i := range fs.FlagDefs {
+		fd := &fs.FlagDefs[i]
+		schema := FlagSchema{
+			Name:       fd.Name,
+			Type:       fd.Type().String(),
+			Usage:      fd.Usage,
+			Required:   fd.Required,
+			Default:    jsonSafeValue(fd.Default),
+			Example:    fd.Example,
+			Deprecated: fd.deprecationNote(),
+			ReplacedBy: fd.ReplacedBy,
+		}
+		if fd.Shortcut != 0 {
+			schema.Shortcut = string(fd.Shortcut)
+		}
+		if fd.IsSensitive() {
+			schema.Default = RedactedValue
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas
+}
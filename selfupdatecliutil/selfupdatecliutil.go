@@ -0,0 +1,263 @@
+// Package selfupdatecliutil lets a cliutil app replace its own binary with
+// the latest release. A Source finds the release, such as a GitHubSource for
+// GitHub releases or a ManifestSource for a JSON manifest; Update downloads
+// the binary for the running platform, verifies its SHA-256 checksum, and
+// swaps it in for the running executable atomically, so an interrupted
+// update leaves the old binary in place.
+//
+//	err := selfupdatecliutil.Register(selfupdatecliutil.Args{
+//		Source: selfupdatecliutil.GitHubSource{Owner: "me", Repo: "myapp"},
+//	})
+package selfupdatecliutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+var (
+	ErrFetchFailed      = errors.New("fetching release failed")
+	ErrNoBinary         = errors.New("release has no binary for this platform")
+	ErrNoChecksum       = errors.New("release has no checksum for the binary")
+	ErrChecksumMismatch = errors.New("downloaded binary does not match its checksum")
+	ErrVersionUnknown   = errors.New("current version is unknown")
+	ErrReplaceFailed    = errors.New("replacing executable failed")
+)
+
+// CmdName is the name of the command Register registers
+const CmdName = "self-update"
+
+// Args configures Check, Update, and the command Register registers
+type Args struct {
+	Source     Source
+	AppInfo    appinfo.AppInfo // OPTIONAL: its Version() is the current version; defaults to the runner's AppInfo for the command
+	Executable string          // OPTIONAL: the binary to replace; defaults to os.Executable()
+	Client     *http.Client    // OPTIONAL: for downloading binaries; defaults to http.DefaultClient
+}
+
+// Register registers the CmdName command, which updates the app to the latest
+// release or, with --check or under --dry-run, only reports whether there is
+// a newer one. Call it from an init func or an initializer, like any other
+// command registration.
+func Register(args Args) error {
+	cmd := &updateCmd{args: args}
+	cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        CmdName,
+		Usage:       CmdName + " [--check]",
+		Description: "Update to the latest release",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "self-update",
+			FlagDefs: []cliutil.FlagDef{{
+				Name:  "check",
+				Usage: "Only report whether a newer release is available",
+				Bool:  &cmd.check,
+			}},
+		}},
+		NoExamples: true,
+	})
+	return cliutil.RegisterCommand(cmd)
+}
+
+var _ cliutil.CommandHandler = (*updateCmd)(nil)
+
+type updateCmd struct {
+	*cliutil.CmdBase
+	args  Args
+	check bool
+}
+
+// Handle is not bound by --timeout, which is too short for a download
+func (c *updateCmd) Handle() (err error) {
+	var release Release
+	var newer bool
+
+	args := c.args
+	if args.AppInfo == nil {
+		args.AppInfo = c.AppInfo
+	}
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	name := c.CLIName()
+	if args.AppInfo != nil {
+		name = args.AppInfo.Name()
+	}
+	current := currentVersion(args.AppInfo)
+	checkOnly := c.check || (c.Options != nil && c.Options.DryRun())
+	if checkOnly {
+		release, newer, err = Check(ctx, args)
+	} else {
+		release, newer, err = Update(ctx, args)
+	}
+	switch {
+	case err != nil:
+	case !newer:
+		c.Writer.Printf("%s %s is up to date\n", name, current)
+	case checkOnly:
+		c.Writer.Printf("%s %s is available (you have %s)\n", name, release.Version, current)
+	default:
+		c.Writer.Printf("Updated %s from %s to %s\n", name, current, release.Version)
+	}
+	return err
+}
+
+// Check returns the latest release and whether it is newer than the current
+// version (see cliutil.CompareVersions)
+func Check(ctx context.Context, args Args) (release Release, newer bool, err error) {
+	var current = currentVersion(args.AppInfo)
+
+	if current == "" {
+		err = ErrVersionUnknown
+		goto end
+	}
+	release, err = args.Source.LatestRelease(ctx, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		goto end
+	}
+	newer = cliutil.CompareVersions(release.Version, current) > 0
+end:
+	return release, newer, err
+}
+
+// Update replaces the executable with the latest release when it is newer,
+// and returns the release and whether it was installed
+func Update(ctx context.Context, args Args) (release Release, updated bool, err error) {
+	var exe string
+
+	release, updated, err = Check(ctx, args)
+	if err != nil || !updated {
+		goto end
+	}
+	exe, err = executable(args)
+	if err != nil {
+		goto end
+	}
+	err = install(ctx, args.Client, release, exe)
+	if err != nil {
+		updated = false
+	}
+end:
+	return release, updated, err
+}
+
+// currentVersion returns the version of ai, or of the running binary
+func currentVersion(ai appinfo.AppInfo) dt.Version {
+	if ai != nil {
+		return ai.Version()
+	}
+	return cliutil.ReadBuildInfo().Version
+}
+
+// executable returns the path of the binary to replace, with symlinks
+// resolved so the link itself is kept
+func executable(args Args) (exe string, err error) {
+	exe = args.Executable
+	if exe == "" {
+		exe, err = os.Executable()
+		if err != nil {
+			goto end
+		}
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+end:
+	if err != nil {
+		err = cliutil.NewErr(ErrReplaceFailed, "executable", exe, err)
+	}
+	return exe, err
+}
+
+// install downloads release's binary next to exe, verifies it, and renames
+// it over exe
+func install(ctx context.Context, client *http.Client, release Release, exe string) (err error) {
+	var resp *http.Response
+	var tmp *os.File
+	var info os.FileInfo
+	var sum string
+
+	if release.Checksum == "" {
+		err = cliutil.NewErr(ErrNoChecksum, "version", release.Version)
+		goto end
+	}
+	info, err = os.Stat(exe)
+	if err != nil {
+		err = cliutil.NewErr(ErrReplaceFailed, "executable", exe, err)
+		goto end
+	}
+	// Created in the same directory so the rename cannot cross file systems
+	tmp, err = os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+".*.new")
+	if err != nil {
+		err = cliutil.NewErr(ErrReplaceFailed, "executable", exe, err)
+		goto end
+	}
+	defer func() {
+		_ = tmp.Close()
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	resp, err = get(ctx, client, release.URL)
+	if err != nil {
+		goto end
+	}
+	defer func() { _ = resp.Body.Close() }()
+	sum, err = copyWithSHA256(tmp, resp.Body)
+	if err != nil {
+		err = cliutil.NewErr(ErrFetchFailed, "url", release.URL, err)
+		goto end
+	}
+	if !strings.EqualFold(sum, release.Checksum) {
+		err = cliutil.NewErr(ErrChecksumMismatch, "url", release.URL, "want", release.Checksum, "got", sum)
+		goto end
+	}
+	err = tmp.Chmod(info.Mode().Perm())
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err == nil {
+		err = replace(tmp.Name(), exe)
+	}
+	if err != nil {
+		err = cliutil.NewErr(ErrReplaceFailed, "executable", exe, err)
+	}
+end:
+	return err
+}
+
+// copyWithSHA256 copies src to dst and returns the hex SHA-256 of what it copied
+func copyWithSHA256(dst io.Writer, src io.Reader) (sum string, err error) {
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dst, h), src)
+	return hex.EncodeToString(h.Sum(nil)), err
+}
+
+// replace renames path over exe. Windows cannot replace a running
+// executable, but can rename it, so there it is moved aside first.
+func replace(path, exe string) (err error) {
+	if runtime.GOOS != "windows" {
+		return os.Rename(path, exe)
+	}
+	old := exe + ".old"
+	_ = os.Remove(old)
+	err = os.Rename(exe, old)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(path, exe)
+	if err != nil {
+		_ = os.Rename(old, exe)
+	}
+	return err
+}
@@ -0,0 +1,232 @@
+package selfupdatecliutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+)
+
+// Release is the latest version of the app, with its binary for one platform
+type Release struct {
+	Version  dt.Version
+	URL      string // Where the binary is downloaded from
+	Checksum string // Hex-encoded SHA-256 of the binary
+}
+
+// Source finds the latest release of the app for a platform, given as
+// runtime.GOOS and runtime.GOARCH values
+type Source interface {
+	LatestRelease(ctx context.Context, goos, goarch string) (Release, error)
+}
+
+// DefaultGitHubAPI is the API GitHubSource queries unless BaseURL is set
+const DefaultGitHubAPI = "https://api.github.com"
+
+// DefaultChecksumsAsset is the release asset GitHubSource reads checksums from
+const DefaultChecksumsAsset = "checksums.txt"
+
+// GitHubSource finds releases published on GitHub. Each release must have the
+// binary for each platform as an asset of its own, not in an archive, and an
+// asset of checksums in the format sha256sum writes, as GoReleaser does with
+// `format: binary`.
+type GitHubSource struct {
+	Owner          string
+	Repo           string
+	AssetName      func(goos, goarch string) string // OPTIONAL: defaults to "<repo>_<goos>_<goarch>", plus ".exe" on Windows
+	ChecksumsAsset string                           // OPTIONAL: defaults to DefaultChecksumsAsset
+	BaseURL        string                           // OPTIONAL: defaults to DefaultGitHubAPI; set for GitHub Enterprise
+	Client         *http.Client                     // OPTIONAL: defaults to http.DefaultClient
+}
+
+var _ Source = GitHubSource{}
+
+// LatestRelease returns the binary for goos and goarch from the repo's latest
+// release
+func (s GitHubSource) LatestRelease(ctx context.Context, goos, goarch string) (release Release, err error) {
+	var latest struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	var checksumsURL string
+	var checksums map[string]string
+
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultGitHubAPI
+	}
+	name := s.assetName(goos, goarch)
+	checksumsAsset := s.ChecksumsAsset
+	if checksumsAsset == "" {
+		checksumsAsset = DefaultChecksumsAsset
+	}
+
+	err = getJSON(ctx, s.Client, fmt.Sprintf("%s/repos/%s/%s/releases/latest", strings.TrimSuffix(baseURL, "/"), s.Owner, s.Repo), &latest)
+	if err != nil {
+		goto end
+	}
+	release.Version = dt.Version(latest.TagName)
+	for _, asset := range latest.Assets {
+		switch asset.Name {
+		case name:
+			release.URL = asset.URL
+		case checksumsAsset:
+			checksumsURL = asset.URL
+		}
+	}
+	if release.URL == "" {
+		err = cliutil.NewErr(ErrNoBinary, "version", release.Version, "asset", name)
+		goto end
+	}
+	if checksumsURL == "" {
+		err = cliutil.NewErr(ErrNoChecksum, "version", release.Version, "asset", checksumsAsset)
+		goto end
+	}
+	checksums, err = getChecksums(ctx, s.Client, checksumsURL)
+	if err != nil {
+		goto end
+	}
+	release.Checksum = checksums[name]
+end:
+	return release, err
+}
+
+// assetName returns the name of the release asset for goos and goarch
+func (s GitHubSource) assetName(goos, goarch string) (name string) {
+	if s.AssetName != nil {
+		return s.AssetName(goos, goarch)
+	}
+	name = fmt.Sprintf("%s_%s_%s", s.Repo, goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// ManifestSource finds releases from a JSON manifest at URL, for apps that
+// publish binaries on their own server:
+//
+//	{
+//	  "version": "v1.2.3",
+//	  "binaries": {
+//	    "linux/amd64": {"url": "https://example.com/myapp-linux-amd64", "sha256": "9f86d0..."}
+//	  }
+//	}
+type ManifestSource struct {
+	URL    string
+	Client *http.Client // OPTIONAL: defaults to http.DefaultClient
+}
+
+var _ Source = ManifestSource{}
+
+// manifest is the JSON document a ManifestSource reads
+type manifest struct {
+	Version  dt.Version `json:"version"`
+	Binaries map[string]struct {
+		URL    string `json:"url"`
+		SHA256 string `json:"sha256"`
+	} `json:"binaries"`
+}
+
+// LatestRelease returns the binary for goos and goarch in the manifest
+func (s ManifestSource) LatestRelease(ctx context.Context, goos, goarch string) (release Release, err error) {
+	var m manifest
+
+	err = getJSON(ctx, s.Client, s.URL, &m)
+	if err != nil {
+		goto end
+	}
+	release.Version = m.Version
+	if binary, ok := m.Binaries[goos+"/"+goarch]; ok {
+		release.URL, release.Checksum = binary.URL, binary.SHA256
+	}
+	if release.URL == "" {
+		err = cliutil.NewErr(ErrNoBinary, "version", release.Version, "platform", goos+"/"+goarch)
+	}
+end:
+	return release, err
+}
+
+// get returns the response to a GET of url, failing unless it is 200 OK
+func get(ctx context.Context, client *http.Client, url string) (resp *http.Response, err error) {
+	var req *http.Request
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		goto end
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		goto end
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+end:
+	if err != nil {
+		err = cliutil.NewErr(ErrFetchFailed, "url", url, err)
+	}
+	return resp, err
+}
+
+// getJSON decodes the JSON at url into v
+func getJSON(ctx context.Context, client *http.Client, url string, v any) (err error) {
+	var resp *http.Response
+
+	resp, err = get(ctx, client, url)
+	if err != nil {
+		goto end
+	}
+	defer func() { _ = resp.Body.Close() }()
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(v)
+	if err != nil {
+		err = cliutil.NewErr(ErrFetchFailed, "url", url, err)
+	}
+end:
+	return err
+}
+
+// getChecksums returns the checksums at url by file name, from lines of a
+// hex digest and a file name, as sha256sum writes them
+func getChecksums(ctx context.Context, client *http.Client, url string) (checksums map[string]string, err error) {
+	var resp *http.Response
+	var scanner *bufio.Scanner
+
+	resp, err = get(ctx, client, url)
+	if err != nil {
+		goto end
+	}
+	defer func() { _ = resp.Body.Close() }()
+	checksums = make(map[string]string)
+	scanner = bufio.NewScanner(io.LimitReader(resp.Body, maxMetadataSize))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks files read in binary mode with a leading "*"
+		checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+	err = scanner.Err()
+	if err != nil {
+		err = cliutil.NewErr(ErrFetchFailed, "url", url, err)
+	}
+end:
+	return checksums, err
+}
+
+// maxMetadataSize limits how much of a release listing or checksums file is read
+const maxMetadataSize = 10 << 20
@@ -0,0 +1,151 @@
+package cliutil
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SettingSource identifies where a setting's effective value came from
+type SettingSource string
+
+const (
+	DefaultSource SettingSource = "default"
+	FlagSource    SettingSource = "flag"
+	EnvSource     SettingSource = "env"
+	ConfigSource  SettingSource = "config"
+)
+
+// Setting is the effective value of one flag. Values of sensitive flags are
+// replaced with RedactedValue.
+type Setting struct {
+	Name    string        `json:"name"`
+	Command string        `json:"command,omitempty"` // Empty for a global option
+	Value   any           `json:"value"`
+	Default any           `json:"default"`
+	Source  SettingSource `json:"source"`
+}
+
+// SetSource records that the value of the flag named name was assigned from
+// source, for apps that fill the targets of flags not given on the command
+// line from the environment or a config file after parsing. A flag given on
+// the command line is reported as FlagSource regardless.
+func (fs *FlagSet) SetSource(name string, source SettingSource) {
+	if fs.sources == nil {
+		fs.sources = make(map[string]SettingSource)
+	}
+	fs.sources[name] = source
+}
+
+// Settings returns the effective value and source of each flag in fs, in
+// definition order, labeling each with the command path cmdPath
+func (fs *FlagSet) Settings(cmdPath string) (settings []Setting) {
+	set := make(map[string]bool)
+	if fs.FlagSet != nil {
+		fs.FlagSet.Visit(func(f *flag.Flag) {
+			set[f.Name] = true
+		})
+	}
+	for i := range fs.FlagDefs {
+		fd := &fs.FlagDefs[i]
+		setting := Setting{
+			Name:    fd.Name,
+			Command: cmdPath,
+			Value:   jsonSafeValue(fd.Value()),
+			Default: jsonSafeValue(fd.defaultValue()),
+			Source:  DefaultSource,
+		}
+		switch {
+		case set[fd.Name] || (fd.Shortcut != 0 && set[string(fd.Shortcut)]):
+			setting.Source = FlagSource
+		case fs.sources[fd.Name] != "":
+			setting.Source = fs.sources[fd.Name]
+		}
+		if setting.Value == nil {
+			setting.Value = setting.Default
+		}
+		if fd.IsSensitive() {
+			setting.Value = RedactedValue
+			setting.Default = RedactedValue
+		}
+		settings = append(settings, setting)
+	}
+	return settings
+}
+
+// EffectiveSettings returns the settings of the global options of cmd's CLI,
+// or of the default CLI when cmd is nil, followed by those of cmd's flags
+func EffectiveSettings(cmd Command) (settings []Setting) {
+	globalFS := cliOf(cmd).GlobalFlagSet()
+	if globalFS != nil {
+		settings = globalFS.Settings("")
+	}
+	if cmd == nil {
+		goto end
+	}
+	for _, fs := range cmd.FlagSets() {
+		settings = append(settings, fs.Settings(CmdPath(cmd))...)
+	}
+end:
+	return settings
+}
+
+// WriteSettings writes settings to w as a table, or as a JSON array when
+// format is machine readable. The table is tab-separated when w is piped
+// (see IsPipedOutput).
+func WriteSettings(w io.Writer, settings []Setting, format OutputFormat) (err error) {
+	var enc *json.Encoder
+	var rows [][]string
+
+	if format.IsMachineReadable() {
+		if settings == nil {
+			settings = []Setting{}
+		}
+		enc = json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(settings)
+		goto end
+	}
+	for _, s := range settings {
+		name := "--" + s.Name
+		if s.Command != "" {
+			name = s.Command + " " + name
+		}
+		rows = append(rows, []string{name, fmt.Sprint(s.Value), fmt.Sprint(s.Default), string(s.Source)})
+	}
+	err = writeTable(w, []string{"NAME", "VALUE", "DEFAULT", "SOURCE"}, rows)
+end:
+	return err
+}
+
+var _ CommandHandler = (*settingsCmd)(nil)
+
+// settingsCmd shows the effective settings, including those of the command
+// named by its args
+type settingsCmd struct {
+	*CmdBase
+}
+
+func (c *settingsCmd) Handle() (err error) {
+	var cmd Command
+	var format OutputFormat
+	var path string
+
+	getter, ok := c.Options.(GlobalOptionsGetter)
+	if ok && getter.GlobalOptions() != nil {
+		format = getter.GlobalOptions().OutputFormat()
+	}
+	if len(c.PositionalArgs()) > 0 {
+		path = strings.Join(c.PositionalArgs(), ".")
+		cmd = c.CmdRunnerArgs.cli().GetExactCommand(path)
+		if cmd == nil {
+			err = WithErrClass(NewErr(ErrUnknownCommand, "command", path), UsageErr)
+			goto end
+		}
+	}
+	err = WriteSettings(c.Writer.Writer(), EffectiveSettings(cmd), format)
+end:
+	return err
+}
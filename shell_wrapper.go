@@ -0,0 +1,257 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Shell identifies a shell that WriteShellWrapper can generate a wrapper for,
+// or WriteCompletion a completion script for
+type Shell string
+
+const (
+	BashShell       Shell = "bash"
+	ZshShell        Shell = "zsh"
+	FishShell       Shell = "fish"
+	PowerShellShell Shell = "powershell" // Completion only
+)
+
+var (
+	ErrInvalidShell     = errors.New("invalid shell")
+	ErrNoShellWrapper   = errors.New("not run from the shell wrapper")
+	ErrInvalidEnvName   = errors.New("invalid environment variable name")
+	ErrShellDirectiveIO = errors.New("writing shell directive failed")
+)
+
+// shells lists the shells WriteShellWrapper supports
+var shells = []Shell{BashShell, ZshShell, FishShell}
+
+// completionShells lists the shells WriteCompletion supports
+var completionShells = []Shell{BashShell, ZshShell, FishShell, PowerShellShell}
+
+// Environment variables set by the shell wrapper for the command it runs
+const (
+	ShellDirectivesEnv = "CLIUTIL_SHELL_DIRECTIVES" // File the wrapper sources after the command exits
+	ShellEnv           = "CLIUTIL_SHELL"            // Shell the directives are written for
+)
+
+// shellDirectives is the wrapper's directives file, and directivesShell its
+// shell. Both are read once and removed from the environment so processes
+// the command runs cannot write directives for it.
+var shellDirectives, directivesShell = takeShellEnv()
+
+func takeShellEnv() (path string, shell Shell) {
+	path = os.Getenv(ShellDirectivesEnv)
+	shell = Shell(os.Getenv(ShellEnv))
+	_ = os.Unsetenv(ShellDirectivesEnv)
+	_ = os.Unsetenv(ShellEnv)
+	return path, shell
+}
+
+// ParseShell validates s as one of the shells WriteShellWrapper supports
+func ParseShell(s string) (shell Shell, err error) {
+	return parseShellIn(s, shells)
+}
+
+// ParseCompletionShell validates s as one of the shells WriteCompletion
+// supports, accepting "pwsh" for PowerShellShell
+func ParseCompletionShell(s string) (shell Shell, err error) {
+	if strings.EqualFold(strings.TrimSpace(s), "pwsh") {
+		s = string(PowerShellShell)
+	}
+	return parseShellIn(s, completionShells)
+}
+
+// parseShellIn validates s as one of valid
+func parseShellIn(s string, valid []Shell) (shell Shell, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, sh := range valid {
+		if string(sh) == s {
+			shell = sh
+			goto end
+		}
+	}
+	err = NewErr(
+		ErrInvalidShell,
+		"shell", s,
+		"valid", strings.Join(shellNames(valid), "|"),
+	)
+end:
+	return shell, err
+}
+
+// ShellWrapperArgs configures WriteShellWrapper
+type ShellWrapperArgs struct {
+	Name    string // Name of the shell function, usually the CLI's name
+	Command string // OPTIONAL: command the function runs; defaults to Name
+}
+
+// WriteShellWrapper writes a shell function for shell that runs the CLI and
+// then applies what its commands asked for with ShellChdir, ShellSetenv, and
+// ShellUnsetenv, since a process cannot change its parent shell directly.
+// Users install it from their shell's startup file, e.g. for an app that
+// prints it from a "shell-init" command:
+//
+//	eval "$(myapp shell-init bash)"    # bash or zsh
+//	myapp shell-init fish | source     # fish
+func WriteShellWrapper(w io.Writer, shell Shell, args ShellWrapperArgs) (err error) {
+	var tmpl *template.Template
+	var name, text string
+
+	if args.Command == "" {
+		args.Command = args.Name
+	}
+	if !shellFuncRegex.MatchString(args.Name) {
+		err = NewErr(ErrInvalidShell, "function_name", args.Name, "rule", "may contain only letters, numbers, '-', and '_'")
+		goto end
+	}
+	switch shell {
+	case BashShell, ZshShell:
+		name, text = "posix_wrapper", posixWrapperText
+	case FishShell:
+		name, text = "fish_wrapper", fishWrapperText
+	default:
+		_, err = ParseShell(string(shell))
+		goto end
+	}
+	tmpl, err = parseTemplate(name, text)
+	if err != nil {
+		goto end
+	}
+	err = tmpl.Execute(w, map[string]string{
+		"Name":          args.Name,
+		"Command":       quoteShell(shell, args.Command),
+		"Shell":         string(shell),
+		"DirectivesEnv": ShellDirectivesEnv,
+		"ShellEnv":      ShellEnv,
+	})
+end:
+	return err
+}
+
+var shellFuncRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+const posixWrapperText = `{{.Name}}() {
+	local __cliutil_file __cliutil_status
+	__cliutil_file="$(mktemp)" || return
+	{{.DirectivesEnv}}="$__cliutil_file" {{.ShellEnv}}={{.Shell}} command {{.Command}} "$@"
+	__cliutil_status=$?
+	. "$__cliutil_file"
+	rm -f "$__cliutil_file"
+	return $__cliutil_status
+}
+`
+
+const fishWrapperText = `function {{.Name}}
+	set -l __cliutil_file (mktemp); or return
+	env {{.DirectivesEnv}}="$__cliutil_file" {{.ShellEnv}}={{.Shell}} {{.Command}} $argv
+	set -l __cliutil_status $status
+	source "$__cliutil_file"
+	rm -f "$__cliutil_file"
+	return $__cliutil_status
+end
+`
+
+// HasShellWrapper returns true if the CLI was run by the function from
+// WriteShellWrapper, so ShellChdir, ShellSetenv, and ShellUnsetenv will
+// take effect
+func HasShellWrapper() bool {
+	return shellDirectives != ""
+}
+
+// ShellChdir asks the shell wrapper to change the shell's working directory
+// to dir after the command exits. It fails with ErrNoShellWrapper when the
+// CLI was not run by the wrapper.
+func ShellChdir(dir string) error {
+	return writeShellDirective(func(shell Shell) string {
+		if shell == FishShell {
+			return "cd " + quoteShell(shell, dir)
+		}
+		return "cd -- " + quoteShell(shell, dir)
+	})
+}
+
+// ShellSetenv asks the shell wrapper to export name=value in the shell after
+// the command exits. It fails with ErrNoShellWrapper when the CLI was not
+// run by the wrapper.
+func ShellSetenv(name, value string) (err error) {
+	if !envNameRegex.MatchString(name) {
+		err = NewErr(ErrInvalidEnvName, "name", name)
+		goto end
+	}
+	err = writeShellDirective(func(shell Shell) string {
+		if shell == FishShell {
+			return "set -gx " + name + " " + quoteShell(shell, value)
+		}
+		return "export " + name + "=" + quoteShell(shell, value)
+	})
+end:
+	return err
+}
+
+// ShellUnsetenv asks the shell wrapper to remove name from the shell's
+// environment after the command exits. It fails with ErrNoShellWrapper when
+// the CLI was not run by the wrapper.
+func ShellUnsetenv(name string) (err error) {
+	if !envNameRegex.MatchString(name) {
+		err = NewErr(ErrInvalidEnvName, "name", name)
+		goto end
+	}
+	err = writeShellDirective(func(shell Shell) string {
+		if shell == FishShell {
+			return "set -e " + name
+		}
+		return "unset " + name
+	})
+end:
+	return err
+}
+
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// writeShellDirective appends the line returned by directive for the
+// wrapper's shell to its directives file
+func writeShellDirective(directive func(Shell) string) (err error) {
+	var f *os.File
+
+	if !HasShellWrapper() {
+		err = WithSuggestion(NewErr(ErrNoShellWrapper),
+			fmt.Sprintf("install the shell wrapper to let %s change your shell", filepath.Base(os.Args[0])),
+		)
+		goto end
+	}
+	f, err = os.OpenFile(shellDirectives, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		err = NewErr(ErrShellDirectiveIO, "file", shellDirectives, err)
+		goto end
+	}
+	_, err = fmt.Fprintln(f, directive(directivesShell))
+	err = CombineErrs([]error{err, f.Close()})
+	if err != nil {
+		err = NewErr(ErrShellDirectiveIO, "file", shellDirectives, err)
+	}
+end:
+	return err
+}
+
+func shellNames(list []Shell) (names []string) {
+	for _, sh := range list {
+		names = append(names, string(sh))
+	}
+	return names
+}
+
+// quoteShell quotes s as a single word for shell
+func quoteShell(shell Shell, s string) string {
+	if shell == FishShell {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
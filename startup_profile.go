@@ -0,0 +1,142 @@
+package cliutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// StartupProfileEnv enables the startup profile when set to a non-empty
+// value. The profile breaks down the time from cliutil's package init until
+// the first command runs, and is written to stderr just before it runs:
+//
+//	CLIUTIL_PROFILE_STARTUP=1 myapp status
+const StartupProfileEnv = "CLIUTIL_PROFILE_STARTUP"
+
+// startupPhase is one timed step of startup
+type startupPhase struct {
+	name    string
+	elapsed time.Duration
+	calls   int
+}
+
+// startupProfile records where startup time goes. Package init is measured
+// from cliutil's own init, which runs before the init funcs of the packages
+// that import it, until the first phase outside of RegisterCommand begins.
+type startupProfile struct {
+	mu       sync.Mutex
+	enabled  bool
+	start    time.Time
+	initEnd  time.Time // When package init was over; zero until then
+	register startupPhase
+	phases   []startupPhase
+	reported bool
+}
+
+var profile = &startupProfile{
+	enabled: os.Getenv(StartupProfileEnv) != "",
+	start:   GetClock().Now(),
+	register: startupPhase{
+		name: "RegisterCommand",
+	},
+}
+
+// begin returns the start time of a phase, or the zero time when profiling
+// is disabled
+func (p *startupProfile) begin() (start time.Time) {
+	if p.enabled {
+		start = GetClock().Now()
+	}
+	return start
+}
+
+// end records the phase named name that began at start
+func (p *startupProfile) end(name string, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	elapsed := GetClock().Now().Sub(start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reported {
+		return
+	}
+	if p.initEnd.IsZero() {
+		p.initEnd = start
+	}
+	p.phases = append(p.phases, startupPhase{name: name, elapsed: elapsed, calls: 1})
+}
+
+// endRegister records a RegisterCommand call that began at start. Commands
+// are registered from init funcs, so registrations do not end package init.
+func (p *startupProfile) endRegister(start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	elapsed := GetClock().Now().Sub(start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.register.elapsed += elapsed
+	p.register.calls++
+}
+
+// report writes the profile to w the first time it is called
+func (p *startupProfile) report(w io.Writer) (err error) {
+	var tw *tabwriter.Writer
+	var initTime time.Duration
+
+	if !p.enabled {
+		goto end
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reported {
+		goto end
+	}
+	p.reported = true
+	if p.initEnd.IsZero() {
+		p.initEnd = GetClock().Now()
+	}
+	// Registrations ran inside package init, so report them separately
+	initTime = p.initEnd.Sub(p.start) - p.register.elapsed
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "cliutil startup profile:")
+	writeStartupPhase(tw, startupPhase{name: "package init", elapsed: initTime})
+	writeStartupPhase(tw, p.register)
+	for _, phase := range p.phases {
+		writeStartupPhase(tw, phase)
+	}
+	writeStartupPhase(tw, startupPhase{name: "total", elapsed: GetClock().Now().Sub(p.start)})
+	err = tw.Flush()
+end:
+	return err
+}
+
+func writeStartupPhase(w io.Writer, phase startupPhase) {
+	calls := ""
+	if phase.calls > 1 {
+		calls = fmt.Sprintf("%d calls", phase.calls)
+	}
+	_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n", phase.name, phase.elapsed.Round(time.Microsecond), calls)
+}
+
+// initializerName returns a name for f in the profile, e.g. "initializer
+// myapp/config.init.0"
+func initializerName(f InitializerFunc) string {
+	name := "anonymous"
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn != nil {
+		name = fn.Name()
+		// Strip the module path, keeping the package and function
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	return "initializer " + name
+}
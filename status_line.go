@@ -0,0 +1,126 @@
+package cliutil
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultStatusInterval is how often a StatusLine writes a line when its
+// output is not a terminal
+const DefaultStatusInterval = 5 * time.Second
+
+// clearLine returns the cursor to the start of the line and erases it
+const clearLine = "\r\x1b[2K"
+
+// StatusLineArgs configures a StatusLine
+type StatusLineArgs struct {
+	Interval time.Duration // OPTIONAL: defaults to DefaultStatusInterval
+}
+
+// StatusLine shows live status, such as "processed 4,200/10,000", on the
+// error stream of a Writer by rewriting one line rather than scrolling. When
+// that stream is not a terminal, as in CI logs, each status is instead
+// written as a line of its own, at most once per Interval. It writes nothing
+// when the Writer is quiet.
+//
+//	status := cliutil.NewStatusLine(c.Writer, cliutil.StatusLineArgs{})
+//	for i, item := range items {
+//		status.Update("processed %s/%s", cliutil.FormatInt(int64(i)), total)
+//		process(item)
+//	}
+//	status.Clear()
+type StatusLine struct {
+	mu       sync.Mutex
+	out      io.Writer
+	interval time.Duration
+	tty      bool
+	quiet    bool
+	text     string // The status now shown
+	written  string // The status last written when not a terminal
+	lastTime time.Time
+	done     bool
+}
+
+// NewStatusLine returns a StatusLine that writes to w's error stream
+func NewStatusLine(w Writer, args StatusLineArgs) *StatusLine {
+	if args.Interval <= 0 {
+		args.Interval = DefaultStatusInterval
+	}
+	return &StatusLine{
+		out:      w.ErrWriter(),
+		interval: args.Interval,
+		tty:      IsTerminal(w.ErrWriter()),
+		quiet:    isQuiet(w),
+		lastTime: GetClock().Now(),
+	}
+}
+
+// Update replaces the status with format and args formatted as by Printf
+func (s *StatusLine) Update(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.show(fmt.Sprintf(format, args...), false)
+}
+
+// Clear erases the status, as before writing other output or when the work
+// is finished, and ends s; later calls do nothing
+func (s *StatusLine) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done && !s.quiet && s.tty && s.text != "" {
+		_, _ = fmt.Fprint(s.out, clearLine)
+	}
+	s.done = true
+}
+
+// Done leaves the status as its final line and ends s; later calls do nothing
+func (s *StatusLine) Done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.show(s.text, true)
+	if !s.done && !s.quiet && s.tty && s.text != "" {
+		_, _ = fmt.Fprintln(s.out)
+	}
+	s.done = true
+}
+
+// show writes text when it changed and, when not on a terminal, only once per
+// interval unless final
+func (s *StatusLine) show(text string, final bool) {
+	var now time.Time
+
+	if s.quiet || s.done {
+		goto end
+	}
+	if s.tty {
+		if text != s.text {
+			_, _ = fmt.Fprint(s.out, clearLine+text)
+		}
+		s.text = text
+		goto end
+	}
+	s.text = text
+	now = GetClock().Now()
+	if !final && now.Sub(s.lastTime) < s.interval {
+		goto end
+	}
+	if text != s.written {
+		_, _ = fmt.Fprintln(s.out, text)
+	}
+	s.written = text
+	s.lastTime = now
+end:
+	return
+}
+
+// isQuiet returns true when w suppresses output for --quiet. Writers that do
+// not say, such as test doubles, follow the global --quiet flag.
+func isQuiet(w Writer) bool {
+	qw, ok := w.(interface{ IsQuiet() bool })
+	if ok {
+		return qw.IsQuiet()
+	}
+	return defaultCLI.options.Quiet()
+}
@@ -0,0 +1,117 @@
+package cliutil
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// StdinArg is the positional argument that stands for stdin; see ArgDef.Stdin
+const StdinArg = "-"
+
+var (
+	ErrStdinIO    = errors.New("reading stdin failed")
+	ErrStdinInUse = errors.New("stdin already in use")
+)
+
+// isFlagArg returns true when arg is a flag: it starts with "-" but is not
+// StdinArg, which is a positional argument
+func isFlagArg(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-'
+}
+
+// stdinOf returns the stdin of args, os.Stdin unless args.Stdin is set
+func stdinOf(args CmdRunnerArgs) io.Reader {
+	if args.Stdin == nil {
+		return os.Stdin
+	}
+	return args.Stdin
+}
+
+// readStdinArg replaces the value of the ArgDef of cmd with Stdin set that was
+// given as StdinArg with the contents of stdin, less one trailing newline.
+// Only one argument may read stdin, and none when --args-from-stdin did.
+func readStdinArg(cmd Command, args CmdRunnerArgs) (err error) {
+	var data []byte
+	var reader *ArgDef
+
+	for _, argDef := range cmd.ArgDefs() {
+		if !argDef.Stdin || argDef.String == nil || *argDef.String != StdinArg {
+			continue
+		}
+		if reader != nil || args.stdinUsed {
+			err = WithErrClass(NewErr(ErrStdinInUse,
+				"argument", argDef.Name,
+				"reason", "only one flag or argument can be read from stdin",
+			), UsageErr)
+			goto end
+		}
+		reader = argDef
+	}
+	if reader == nil {
+		goto end
+	}
+	data, err = io.ReadAll(stdinOf(args))
+	if err != nil {
+		err = NewErr(ErrStdinIO, "argument", reader.Name, err)
+		goto end
+	}
+	*reader.String = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+end:
+	return err
+}
+
+// argsFromStdin returns true when --args-from-stdin was given and this run
+// has not already taken its args from stdin
+func (cr CmdRunner) argsFromStdin() bool {
+	getter, ok := cr.Args.Options.(GlobalOptionsGetter)
+	if !ok || getter.GlobalOptions() == nil || cr.Args.stdinUsed {
+		return false
+	}
+	return getter.GlobalOptions().ArgsFromStdin()
+}
+
+// runArgsFromStdin runs the command line once per non-blank line of stdin,
+// with the line appended as one more positional argument. Unlike xargs, the
+// line is not split on spaces, so names with spaces pass through intact.
+// Lines whose run fails do not stop the rest; their errors are combined.
+// Each run gets an empty stdin, so it cannot consume the remaining lines.
+func (cr CmdRunner) runArgsFromStdin() (err error) {
+	var errs []error
+	var cmd Command
+	var lineErr error
+
+	scanner := bufio.NewScanner(stdinOf(cr.Args))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineRunner := cr
+		lineRunner.Args.Args = slices.Clip(cr.Args.Args)
+		// The line is one positional arg, even if it starts with "-"
+		if !slices.Contains(lineRunner.Args.Args, ArgsTerminator) {
+			lineRunner.Args.Args = append(lineRunner.Args.Args, ArgsTerminator)
+		}
+		lineRunner.Args.Args = append(lineRunner.Args.Args, line)
+		lineRunner.Args.Stdin = strings.NewReader("")
+		lineRunner.Args.stdinUsed = true
+		cmd, lineErr = lineRunner.ParseCmd(lineRunner.Args.Args)
+		if lineErr == nil {
+			lineErr = lineRunner.RunCmd(cmd)
+		}
+		if lineErr != nil {
+			errs = append(errs, WithErr(lineErr, "stdin_line", line))
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		errs = append(errs, NewErr(ErrStdinIO, err))
+	}
+	err = CombineErrs(errs)
+	return err
+}
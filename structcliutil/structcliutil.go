@@ -0,0 +1,469 @@
+// Package structcliutil derives cliutil commands from a nested Go struct, in
+// the style of kong, as an alternative to embedding CmdBase and calling
+// RegisterCommand for each command:
+//
+//	var cli struct {
+//		Debug bool `help:"Enable debug output"`
+//		DB    struct {
+//			Migrate MigrateCmd `cmd:"" help:"Run database migrations"`
+//		} `cmd:"" help:"Database commands"`
+//	}
+//
+//	type MigrateCmd struct {
+//		Steps  int    `short:"n" help:"Number of migrations to run" default:"1"`
+//		Target string `arg:"" optional:"" help:"Version to migrate to"`
+//	}
+//
+//	func (c *MigrateCmd) Run(args cliutil.CmdRunnerArgs) error { ... }
+//
+//	_, err := structcliutil.Register(&cli)
+//
+// Fields tagged cmd become commands, named for the kebab-cased field name
+// unless tagged with name. Fields tagged arg become positional arguments,
+// which are required unless tagged optional; a []string arg must come last
+// and receives any remaining arguments. Other exported fields of type
+// string, bool, int, int64, float64, or time.Duration become flags, as do
+// []string, []int, and map[string]string fields, which become repeatable
+// flags whose default tag is comma-separated, as key=value for a map. The
+// root struct's flags are global flags, and a command's flags are inherited
+// by its subcommands.
+//
+// Flag and arg tags are help, default, short (flags only), required (flags
+// only), optional (args only), enum (comma-separated allowed values),
+// sensitive, example, count (int flags only, counting repeats as -vvv), and
+// fromfile (string flags only, reading a value given as @path from the file).
+// Commands are tagged help and, optionally, name and hidden. Fields tagged
+// name:"-" are ignored, and the exported fields of embedded structs are
+// treated as fields of the embedding struct.
+//
+// A command struct whose pointer implements Runner is run when the command
+// is invoked; other command structs only group their subcommands.
+package structcliutil
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var (
+	ErrInvalidStruct      = errors.New("invalid CLI struct")
+	ErrInvalidFlagValue   = errors.New("invalid flag value")
+	ErrRegistrationFailed = errors.New("struct command registration failed")
+)
+
+// Runner is implemented by command structs that do work when invoked
+type Runner interface {
+	Run(args cliutil.CmdRunnerArgs) error
+}
+
+var _ cliutil.CommandTyper = (*Cmd)(nil)
+var _ cliutil.CommandHandler = (*handlerCmd)(nil)
+
+// Cmd is a cliutil command declared by a struct field tagged cmd. Commands
+// whose struct implements Runner also implement cliutil.CommandHandler.
+type Cmd struct {
+	*cliutil.CmdBase
+	cmdType reflect.Type
+	runner  Runner
+}
+
+// handlerCmd is a Cmd whose struct implements Runner
+type handlerCmd struct {
+	*Cmd
+}
+
+// fields are the flags, args, and subcommands declared by a struct
+type fields struct {
+	flags   []cliutil.FlagDef
+	args    []*cliutil.ArgDef
+	rest    *[]string       // Trailing []string arg, if any
+	restDef *cliutil.ArgDef // Describes rest in help
+	cmds    []cmdField
+}
+
+// cmdField is a struct field tagged cmd
+type cmdField struct {
+	field reflect.StructField
+	value reflect.Value
+}
+
+var (
+	stringType      = reflect.TypeOf("")
+	boolType        = reflect.TypeOf(false)
+	intType         = reflect.TypeOf(0)
+	int64Type       = reflect.TypeOf(int64(0))
+	durationType    = reflect.TypeOf(time.Duration(0))
+	float64Type     = reflect.TypeOf(float64(0))
+	stringSliceType = reflect.TypeOf([]string(nil))
+	intSliceType    = reflect.TypeOf([]int(nil))
+	stringMapType   = reflect.TypeOf(map[string]string(nil))
+)
+
+// Register registers the commands declared by cli, which must be a pointer
+// to a struct, and returns them. Flags declared directly on cli are added as
+// global flags.
+func Register(cli any) (cmds []cliutil.Command, err error) {
+	var errs []error
+	var root fields
+
+	v := reflect.ValueOf(cli)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		err = cliutil.NewErr(ErrInvalidStruct, "type", reflect.TypeOf(cli), "rule", "must be a non-nil pointer to a struct")
+		goto end
+	}
+	root, err = parseFields(v.Elem())
+	if err != nil {
+		goto end
+	}
+	if len(root.args) > 0 || root.rest != nil {
+		err = cliutil.NewErr(ErrInvalidStruct, "rule", "the root struct cannot declare args")
+		goto end
+	}
+	for _, fd := range root.flags {
+		errs = cliutil.AppendErr(errs, cliutil.AddCLIOption(fd))
+	}
+	for _, cf := range root.cmds {
+		cmds, err = register(cf, nil, nil, cmds)
+		errs = cliutil.AppendErr(errs, err)
+	}
+	err = cliutil.CombineErrs(errs)
+end:
+	if err != nil {
+		err = cliutil.WithErr(err, ErrRegistrationFailed)
+	}
+	return cmds, err
+}
+
+// register registers the command for cf under parent, then its subcommands,
+// appending them to cmds
+func register(cf cmdField, parent cliutil.Command, inherited []cliutil.FlagDef, cmds []cliutil.Command) (_ []cliutil.Command, err error) {
+	var errs []error
+	var c *Cmd
+	var sf fields
+	var cmd cliutil.Command
+
+	c, sf, err = newCmd(cf, inherited)
+	if err != nil {
+		goto end
+	}
+	cmd = c
+	if c.runner != nil {
+		cmd = &handlerCmd{Cmd: c}
+	}
+	if parent == nil {
+		err = cliutil.RegisterCommand(cmd)
+	} else {
+		err = cliutil.RegisterCommand(cmd, parent)
+	}
+	if err != nil {
+		goto end
+	}
+	cmds = append(cmds, cmd)
+
+	inherited = append(inherited[:len(inherited):len(inherited)], sf.flags...)
+	for _, sub := range sf.cmds {
+		cmds, err = register(sub, cmd, inherited, cmds)
+		errs = cliutil.AppendErr(errs, err)
+	}
+	err = cliutil.CombineErrs(errs)
+end:
+	return cmds, err
+}
+
+// newCmd builds the command declared by cf, with inherited flags added to
+// its own, and returns it with the fields of its struct
+func newCmd(cf cmdField, inherited []cliutil.FlagDef) (c *Cmd, sf fields, err error) {
+	var runner Runner
+	var ok bool
+	var flagDefs []cliutil.FlagDef
+	var args cliutil.CmdArgs
+
+	v := cf.value
+	if v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		err = cliutil.NewErr(ErrInvalidStruct, "field", cf.field.Name, "rule", "cmd fields must be structs")
+		goto end
+	}
+	sf, err = parseFields(v)
+	if err != nil {
+		goto end
+	}
+	runner, ok = v.Addr().Interface().(Runner)
+	if !ok && len(sf.cmds) == 0 {
+		err = cliutil.NewErr(ErrInvalidStruct, "field", cf.field.Name, "rule", "commands need a Run method or subcommands")
+		goto end
+	}
+
+	c = &Cmd{
+		cmdType: cliutil.NewCommandType(),
+		runner:  runner,
+	}
+	if sf.rest != nil {
+		sf.args = append(sf.args, sf.restDef)
+	}
+	flagDefs = append(inherited[:len(inherited):len(inherited)], sf.flags...)
+	args = cliutil.CmdArgs{
+		Name:        fieldName(cf.field),
+		Description: cf.field.Tag.Get("help"),
+		ArgDefs:     sf.args,
+		Hide:        hasTag(cf.field, "hidden"),
+	}
+	if len(flagDefs) > 0 {
+		args.FlagSets = []*cliutil.FlagSet{{Name: args.Name, FlagDefs: flagDefs}}
+	}
+	c.CmdBase = cliutil.NewCmdBase(args)
+end:
+	return c, sf, err
+}
+
+// CommandType identifies c in the cliutil registry
+func (c *Cmd) CommandType() reflect.Type {
+	return c.cmdType
+}
+
+// Handle runs the command
+func (c *handlerCmd) Handle() error {
+	return c.runner.Run(c.CmdRunnerArgs)
+}
+
+// parseFields returns the flags, args, and subcommands declared by the
+// fields of v, a struct
+func parseFields(v reflect.Value) (sf fields, err error) {
+	var errs []error
+	var fd cliutil.FlagDef
+	var ad *cliutil.ArgDef
+	var embedded fields
+
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fv := v.Field(i)
+		switch {
+		case !field.IsExported() || field.Tag.Get("name") == "-":
+			continue
+		case hasTag(field, "cmd"):
+			sf.cmds = append(sf.cmds, cmdField{field: field, value: fv})
+		case hasTag(field, "arg"):
+			if sf.rest != nil {
+				errs = append(errs, cliutil.NewErr(ErrInvalidStruct, "field", field.Name, "rule", "a []string arg must be the last arg"))
+				continue
+			}
+			if field.Type == stringSliceType {
+				sf.rest = fv.Addr().Interface().(*[]string)
+				sf.restDef = &cliutil.ArgDef{
+					Name:      fieldName(field),
+					Usage:     field.Tag.Get("help"),
+					Required:  !hasTag(field, "optional"),
+					Remainder: sf.rest,
+				}
+				continue
+			}
+			ad, err = newArgDef(field, fv)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sf.args = append(sf.args, ad)
+		case field.Anonymous && field.Type.Kind() == reflect.Struct:
+			embedded, err = parseFields(fv)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sf.flags = append(sf.flags, embedded.flags...)
+			sf.args = append(sf.args, embedded.args...)
+			sf.cmds = append(sf.cmds, embedded.cmds...)
+			if embedded.rest != nil {
+				sf.rest, sf.restDef = embedded.rest, embedded.restDef
+			}
+		default:
+			fd, err = newFlagDef(field, fv)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sf.flags = append(sf.flags, fd)
+		}
+	}
+	err = cliutil.CombineErrs(errs)
+	return sf, err
+}
+
+// newArgDef returns the positional argument declared by field, a string
+func newArgDef(field reflect.StructField, fv reflect.Value) (ad *cliutil.ArgDef, err error) {
+	if field.Type != stringType {
+		err = cliutil.NewErr(ErrInvalidStruct, "field", field.Name, "type", field.Type, "rule", "args must be string or []string")
+		goto end
+	}
+	ad = &cliutil.ArgDef{
+		Name:     fieldName(field),
+		Usage:    field.Tag.Get("help"),
+		Required: !hasTag(field, "optional"),
+		String:   fv.Addr().Interface().(*string),
+		Example:  field.Tag.Get("example"),
+	}
+	if def, ok := field.Tag.Lookup("default"); ok {
+		ad.Default = def
+		*ad.String = def
+	}
+end:
+	return ad, err
+}
+
+// newFlagDef returns the flag declared by field
+func newFlagDef(field reflect.StructField, fv reflect.Value) (fd cliutil.FlagDef, err error) {
+	var def any
+
+	fd = cliutil.FlagDef{
+		Name:      fieldName(field),
+		Usage:     field.Tag.Get("help"),
+		Required:  hasTag(field, "required"),
+		Example:   field.Tag.Get("example"),
+		Sensitive: hasTag(field, "sensitive"),
+		FromFile:  hasTag(field, "fromfile"),
+	}
+	if short := field.Tag.Get("short"); short != "" {
+		fd.Shortcut = short[0]
+	}
+	if enum := field.Tag.Get("enum"); enum != "" {
+		fd.ValidationFunc = enumValidator(strings.Split(enum, ","))
+	}
+	tag, hasDefault := field.Tag.Lookup("default")
+
+	switch field.Type {
+	case stringType:
+		fd.String = fv.Addr().Interface().(*string)
+		def = tag
+	case boolType:
+		fd.Bool = fv.Addr().Interface().(*bool)
+		if hasDefault {
+			def, err = strconv.ParseBool(tag)
+		}
+	case intType:
+		if hasTag(field, "count") {
+			fd.Count = fv.Addr().Interface().(*int)
+		} else {
+			fd.Int = fv.Addr().Interface().(*int)
+		}
+		if hasDefault {
+			def, err = strconv.Atoi(tag)
+		}
+	case int64Type:
+		fd.Int64 = fv.Addr().Interface().(*int64)
+		if hasDefault {
+			def, err = strconv.ParseInt(tag, 10, 64)
+		}
+	case durationType:
+		fd.Duration = fv.Addr().Interface().(*time.Duration)
+		if hasDefault {
+			def, err = time.ParseDuration(tag)
+		}
+	case float64Type:
+		fd.Float64 = fv.Addr().Interface().(*float64)
+		if hasDefault {
+			def, err = strconv.ParseFloat(tag, 64)
+		}
+	case stringSliceType:
+		fd.Strings = fv.Addr().Interface().(*[]string)
+		if hasDefault {
+			def = strings.Split(tag, ",")
+		}
+	case stringMapType:
+		fd.Map = fv.Addr().Interface().(*map[string]string)
+		if hasDefault {
+			values := make(map[string]string)
+			for pair := range strings.SplitSeq(tag, ",") {
+				key, value, ok := strings.Cut(pair, "=")
+				if !ok {
+					err = errors.New("expected key=value pairs")
+					break
+				}
+				values[key] = value
+			}
+			def = values
+		}
+	case intSliceType:
+		fd.Ints = fv.Addr().Interface().(*[]int)
+		if hasDefault {
+			var values []int
+			for item := range strings.SplitSeq(tag, ",") {
+				var n int
+				n, err = strconv.Atoi(strings.TrimSpace(item))
+				if err != nil {
+					break
+				}
+				values = append(values, n)
+			}
+			def = values
+		}
+	default:
+		err = cliutil.NewErr(ErrInvalidStruct, "field", field.Name, "type", field.Type, "rule", "flags must be string, bool, int, int64, float64, time.Duration, []string, []int, or map[string]string")
+		goto end
+	}
+	if err != nil {
+		err = cliutil.NewErr(ErrInvalidStruct, "field", field.Name, "default", tag, err)
+		goto end
+	}
+	if hasDefault {
+		fd.Default = def
+	}
+end:
+	return fd, err
+}
+
+// enumValidator returns a ValidationFunc that accepts only values in enum
+func enumValidator(enum []string) cliutil.ValidationFunc {
+	return func(value any) error {
+		s, _ := value.(string)
+		for _, e := range enum {
+			if s == strings.TrimSpace(e) {
+				return nil
+			}
+		}
+		return cliutil.NewErr(ErrInvalidFlagValue, "value", s, "allowed", strings.Join(enum, ", "))
+	}
+}
+
+// hasTag returns true if field has key in its tag, with any value
+func hasTag(field reflect.StructField, key string) bool {
+	_, ok := field.Tag.Lookup(key)
+	return ok
+}
+
+// fieldName returns field's name tag, or its kebab-cased Go name
+func fieldName(field reflect.StructField) string {
+	name := field.Tag.Get("name")
+	if name != "" {
+		return name
+	}
+	return kebab(field.Name)
+}
+
+// kebab converts a Go identifier such as DryRun or APIKey to kebab case,
+// e.g. dry-run or api-key
+func kebab(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				sb.WriteByte('-')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
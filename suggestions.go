@@ -0,0 +1,153 @@
+package cliutil
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// WithSuggestion attaches a user-facing suggestion to err, such as
+// "try running 'myapp login' first". Suggestions are printed beneath the
+// error by ReportError so commands can guide users without formatting
+// multi-line errors themselves. Returns nil if err is nil.
+func WithSuggestion(err error, suggestion string) error {
+	if err == nil {
+		return nil
+	}
+	return &suggestionErr{
+		err:        err,
+		suggestion: suggestion,
+	}
+}
+
+// Suggestions returns every suggestion attached anywhere in err's tree,
+// outermost first, with duplicates removed.
+func Suggestions(err error) (suggestions []string) {
+	seen := make(map[string]struct{})
+	walkErrTree(err, func(e error) {
+		var se *suggestionErr
+		var ok bool
+
+		//goland:noinspection GoTypeAssertionOnErrors
+		se, ok = e.(*suggestionErr)
+		if !ok || se.suggestion == "" {
+			return
+		}
+		_, ok = seen[se.suggestion]
+		if ok {
+			return
+		}
+		seen[se.suggestion] = NULL{}
+		suggestions = append(suggestions, se.suggestion)
+	})
+	return suggestions
+}
+
+var _ error = (*suggestionErr)(nil)
+
+type suggestionErr struct {
+	err        error
+	suggestion string
+}
+
+func (e *suggestionErr) Error() string {
+	return e.err.Error()
+}
+
+func (e *suggestionErr) Unwrap() error {
+	return e.err
+}
+
+// walkErrTree visits err and every error reachable from it via Unwrap() error
+// or Unwrap() []error, depth-first and left-to-right.
+func walkErrTree(err error, visit func(error)) {
+	if err == nil {
+		return
+	}
+	visit(err)
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		walkErrTree(u.Unwrap(), visit)
+	case interface{ Unwrap() []error }:
+		for _, child := range u.Unwrap() {
+			walkErrTree(child, visit)
+		}
+	}
+}
+
+// maxSuggestions limits how many near matches a suggestion lists
+const maxSuggestions = 3
+
+// closestNames returns the candidates nearest to name by edit distance,
+// nearest first, leaving out any too far from name to be a likely typo
+func closestNames(name string, candidates []string) (names []string) {
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+
+	limit := max(2, len(name)/3)
+	for _, c := range candidates {
+		d := editDistance(name, c)
+		if d > limit && !strings.HasPrefix(c, name) {
+			continue
+		}
+		matches = append(matches, match{name: c, distance: d})
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return cmp.Or(a.distance-b.distance, strings.Compare(a.name, b.name))
+	})
+	for _, m := range matches {
+		if slices.Contains(names, m.name) {
+			continue
+		}
+		names = append(names, m.name)
+		if len(names) == maxSuggestions {
+			break
+		}
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// withFlagSuggestion attaches to err the known flags of flagSets nearest to
+// the unknown flag, if any are close
+func withFlagSuggestion(err error, flag string, flagSets []*FlagSet) error {
+	var known, names []string
+
+	flag, _, _ = strings.Cut(flag, "=")
+	name := strings.TrimLeft(flag, "-")
+	for _, fs := range flagSets {
+		for _, fd := range fs.FlagDefs {
+			known = append(known, fd.Name)
+		}
+	}
+	for _, n := range closestNames(name, known) {
+		names = append(names, "--"+n)
+	}
+	if len(names) == 0 {
+		return err
+	}
+	return WithSuggestion(err, Msg(MsgDidYouMean, flag, strings.Join(names, ", ")))
+}
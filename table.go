@@ -0,0 +1,208 @@
+package cliutil
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Align is how a Column's cells are aligned within it
+type Align int
+
+const (
+	LeftAlign Align = iota
+	RightAlign
+)
+
+// Column describes one column of a Table
+type Column struct {
+	Header   string
+	Align    Align // OPTIONAL: defaults to LeftAlign
+	MaxWidth int   // OPTIONAL: longer cells are truncated with an ellipsis; 0 for no limit
+}
+
+// Table renders rows as columns aligned with spaces, so commands need not pad
+// columns by hand. When output is piped (see IsPiped) it renders them instead
+// as tab-separated values, untruncated and one row per line, for other
+// programs to parse:
+//
+//	t := cliutil.NewTable(
+//		cliutil.Column{Header: "NAME"},
+//		cliutil.Column{Header: "SIZE", Align: cliutil.RightAlign},
+//	)
+//	for _, f := range files {
+//		t.AddRow(f.Name, f.Size)
+//	}
+//	t.Write(c.Writer)
+type Table struct {
+	Columns  []Column
+	MaxWidth int // OPTIONAL: longer lines are truncated, e.g. to GetTerminal().Width(); 0 for no limit
+	rows     [][]string
+}
+
+// NewTable returns a Table with columns and no rows
+func NewTable(columns ...Column) *Table {
+	return &Table{Columns: columns}
+}
+
+// AddRow adds a row of cells, each formatted as by fmt.Sprint, and returns t
+// so rows can be chained
+func (t *Table) AddRow(cells ...any) *Table {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = fmt.Sprint(cell)
+	}
+	t.rows = append(t.rows, row)
+	return t
+}
+
+// Write writes t to w's output, respecting Quiet and verbosity as Printf does
+func (t *Table) Write(w Writer) {
+	w.Printf("%s", t.render(IsPiped(w)))
+}
+
+// render returns t as aligned columns or, when piped, as tab-separated values
+func (t *Table) render(piped bool) string {
+	var b strings.Builder
+	var widths []int
+
+	rows := t.rows
+	if t.hasHeader() {
+		header := make([]string, len(t.Columns))
+		for i, col := range t.Columns {
+			header[i] = col.Header
+		}
+		rows = append([][]string{header}, rows...)
+	}
+	if piped {
+		for _, row := range rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = cellReplacer.Replace(cell)
+			}
+			b.WriteString(strings.Join(cells, "\t"))
+			b.WriteByte('\n')
+		}
+		goto end
+	}
+	rows = t.fitCells(rows)
+	widths = t.columnWidths(rows)
+	for _, row := range rows {
+		b.WriteString(truncateText(t.alignRow(row, widths), t.MaxWidth))
+		b.WriteByte('\n')
+	}
+end:
+	return b.String()
+}
+
+// hasHeader returns true when any Column has a Header
+func (t *Table) hasHeader() bool {
+	for _, col := range t.Columns {
+		if col.Header != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// column returns the Column for cells at index i; rows may have more cells
+// than t has Columns
+func (t *Table) column(i int) (col Column) {
+	if i < len(t.Columns) {
+		col = t.Columns[i]
+	}
+	return col
+}
+
+// fitCells returns rows with each cell on one line and cut to its Column's
+// MaxWidth, so every row stays aligned
+func (t *Table) fitCells(rows [][]string) [][]string {
+	fitted := make([][]string, len(rows))
+	for r, row := range rows {
+		fitted[r] = make([]string, len(row))
+		for i, cell := range row {
+			fitted[r][i] = truncateText(cellReplacer.Replace(cell), t.column(i).MaxWidth)
+		}
+	}
+	return fitted
+}
+
+// columnWidths returns the display width of the widest cell of each column
+func (t *Table) columnWidths(rows [][]string) (widths []int) {
+	widths = make([]int, len(t.Columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	return widths
+}
+
+// alignRow returns row's cells padded to widths and separated by two spaces.
+// The last cell is not padded unless it is aligned right.
+func (t *Table) alignRow(row []string, widths []int) string {
+	var b strings.Builder
+
+	for i := range widths {
+		var cell string
+		if i < len(row) {
+			cell = row[i]
+		}
+		pad := strings.Repeat(" ", widths[i]-displayWidth(cell))
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		switch {
+		case t.column(i).Align == RightAlign:
+			b.WriteString(pad + cell)
+		case i == len(widths)-1:
+			b.WriteString(cell)
+		default:
+			b.WriteString(cell + pad)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// Ellipsis ends text that Table truncated
+const Ellipsis = "…"
+
+// truncateText returns text cut to width with an Ellipsis as its last
+// character, or text as is when it fits or width is 0. Styles (see Styled)
+// are dropped from text that is cut.
+func truncateText(text string, width int) string {
+	if width <= 0 || displayWidth(text) <= width {
+		return text
+	}
+	runes := []rune(ansiSequence.ReplaceAllString(text, ""))
+	return string(runes[:width-1]) + Ellipsis
+}
+
+// cellReplacer keeps a cell on one line and in one column
+var cellReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ")
+
+// ansiSequence matches the escape sequences Styled adds
+var ansiSequence = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// displayWidth returns the number of characters text shows, not counting the
+// escape sequences of Styles, so styled cells align with plain ones
+func displayWidth(text string) int {
+	return utf8.RuneCountInString(ansiSequence.ReplaceAllString(text, ""))
+}
+
+// writeTable writes header and rows to w as a Table with a left-aligned
+// column for each header (see Table)
+func writeTable(w io.Writer, header []string, rows [][]string) (err error) {
+	t := NewTable()
+	for _, h := range header {
+		t.Columns = append(t.Columns, Column{Header: h})
+	}
+	t.rows = rows
+	_, err = io.WriteString(w, t.render(IsPipedOutput(w)))
+	return err
+}
@@ -2,15 +2,196 @@ package cliutil
 
 import (
 	_ "embed"
+	"errors"
+	"io"
+	"sync"
 	"text/template"
+	"text/template/parse"
 )
 
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// UsageTemplateText renders the main help screen from a UsageStream, so help
+// is written as its rows are rendered, or from a Usage when the template
+// calls len, index, or slice. Assign a custom template before help is shown
+// to replace it. Templates can style
+// text with the current Theme using heading, command, flag, and example, as
+// in {{heading "COMMANDS:"}}, and call funcs added by RegisterTemplateFuncs.
+//
 //go:embed templates/usage.gotmpl
 var UsageTemplateText string
 
-var UsageTemplate = template.Must(template.New("usage").Parse(UsageTemplateText))
-
+// CmdUsageTemplateText renders a command's help. Assign a custom template
+// before help is shown to replace it.
+//
 //go:embed templates/cmd_usage.gotmpl
 var CmdUsageTemplateText string
 
-var CmdUsageTemplate = template.Must(template.New("cmd_usage").Parse(CmdUsageTemplateText))
+// UsageTemplate, when assigned, renders the main help screen instead of
+// UsageTemplateText. It is nil unless assigned, as help templates are parsed
+// on first use.
+//
+// Deprecated: Assign UsageTemplateText instead, or use GetUsageTemplate to
+// get the compiled template.
+var UsageTemplate *template.Template
+
+// CmdUsageTemplate, when assigned, renders a command's help instead of
+// CmdUsageTemplateText. It is nil unless assigned.
+//
+// Deprecated: Assign CmdUsageTemplateText instead, or use GetCmdUsageTemplate
+// to get the compiled template.
+var CmdUsageTemplate *template.Template
+
+// GetUsageTemplate returns UsageTemplate, if assigned, or else
+// UsageTemplateText compiled, parsing it on first use
+func GetUsageTemplate() (*template.Template, error) {
+	if UsageTemplate != nil {
+		return UsageTemplate, nil
+	}
+	return parseTemplate("usage", UsageTemplateText)
+}
+
+// GetCmdUsageTemplate returns CmdUsageTemplate, if assigned, or else
+// CmdUsageTemplateText compiled, parsing it on first use
+func GetCmdUsageTemplate() (*template.Template, error) {
+	if CmdUsageTemplate != nil {
+		return CmdUsageTemplate, nil
+	}
+	return parseTemplate("cmd_usage", CmdUsageTemplateText)
+}
+
+// GetCmdHelpTemplate returns the template for cmd's help compiled: its
+// CmdArgs.HelpTemplate, if any, or else CmdUsageTemplateText
+func GetCmdHelpTemplate(cmd Command) (*template.Template, error) {
+	ht, ok := cmd.(interface{ HelpTemplate() string })
+	if !ok || ht.HelpTemplate() == "" {
+		return GetCmdUsageTemplate()
+	}
+	return parseTemplate("help:"+CmdPath(cmd), ht.HelpTemplate())
+}
+
+type templateKey struct {
+	name string
+	text string
+}
+
+var (
+	templatesMu   sync.Mutex
+	templates     = make(map[templateKey]*template.Template)
+	templateFuncs = template.FuncMap{} // Added by RegisterTemplateFuncs
+)
+
+// RegisterTemplateFuncs makes funcs, such as "upper" or "wrap", available to
+// the help templates, including custom UsageTemplateText and
+// CmdUsageTemplateText. A func named like one already registered, or like a
+// Theme function such as "heading", replaces it.
+func RegisterTemplateFuncs(funcs template.FuncMap) {
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	for name, fn := range funcs {
+		templateFuncs[name] = fn
+	}
+	// Templates parsed before could not call funcs
+	clear(templates)
+}
+
+// templateFuncMap returns the Theme functions for out and cli merged with
+// the registered funcs; the caller holds templatesMu
+func templateFuncMap(cli *CLI, out io.Writer) template.FuncMap {
+	funcs := themeFuncs(cli, out)
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// parseTemplate returns text compiled as a template named name. Compiled
+// templates are cached per name and text, so nothing is parsed until help is
+// shown, and each custom text is parsed only once however often it renders.
+func parseTemplate(name, text string) (tmpl *template.Template, err error) {
+	var ok bool
+
+	key := templateKey{name: name, text: text}
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	tmpl, ok = templates[key]
+	if ok {
+		goto end
+	}
+	tmpl, err = template.New(name).Funcs(templateFuncMap(defaultCLI, io.Discard)).Parse(text)
+	if err != nil {
+		err = NewErr(ErrInvalidTemplate, "template", name, err)
+		goto end
+	}
+	templates[key] = tmpl
+end:
+	return tmpl, err
+}
+
+// usesIndexing reports whether tmpl, or a template associated with it, calls
+// len, index, or slice, which need the rows of a Usage rather than the
+// iterators of a UsageStream
+func usesIndexing(tmpl *template.Template) bool {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && nodeUsesIndexing(t.Tree.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeUsesIndexing(node parse.Node) (uses bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			break
+		}
+		for _, child := range n.Nodes {
+			if nodeUsesIndexing(child) {
+				uses = true
+				break
+			}
+		}
+	case *parse.ActionNode:
+		uses = nodeUsesIndexing(n.Pipe)
+	case *parse.IfNode:
+		uses = branchUsesIndexing(&n.BranchNode)
+	case *parse.RangeNode:
+		uses = branchUsesIndexing(&n.BranchNode)
+	case *parse.WithNode:
+		uses = branchUsesIndexing(&n.BranchNode)
+	case *parse.TemplateNode:
+		uses = n.Pipe != nil && nodeUsesIndexing(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			break
+		}
+		for _, cmd := range n.Cmds {
+			if nodeUsesIndexing(cmd) {
+				uses = true
+				break
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if nodeUsesIndexing(arg) {
+				uses = true
+				break
+			}
+		}
+	case *parse.ChainNode:
+		uses = nodeUsesIndexing(n.Node)
+	case *parse.IdentifierNode:
+		switch n.Ident {
+		case "len", "index", "slice":
+			uses = true
+		}
+	}
+	return uses
+}
+
+func branchUsesIndexing(n *parse.BranchNode) bool {
+	return nodeUsesIndexing(n.Pipe) ||
+		nodeUsesIndexing(n.List) ||
+		(n.ElseList != nil && nodeUsesIndexing(n.ElseList))
+}
@@ -3,9 +3,93 @@ package cliutil
 import (
 	"errors"
 	"os"
+	"strconv"
+	"sync"
 	"syscall"
 )
 
+// DefaultTerminalWidth is used when the terminal width cannot be determined
+const DefaultTerminalWidth = 80
+
+var ErrTerminalUnsupported = errors.New("terminal operation not supported")
+
+// Terminal abstracts the terminal capabilities that interactive features such
+// as prompts, spinners, progress bars, and color detection depend on, so they
+// can be unit-tested with a fake (see clitest.FakeTerminal) instead of a PTY.
+type Terminal interface {
+	// IsTTY reports whether output goes to an interactive terminal
+	IsTTY() bool
+	// Width returns the terminal width in columns, or DefaultTerminalWidth
+	Width() int
+	// MakeRaw puts input into raw mode and returns a func to restore it
+	MakeRaw() (restore func() error, err error)
+	// SetEcho enables or disables echoing of typed input
+	SetEcho(enabled bool) error
+}
+
+// Package-level terminal instance
+var (
+	terminal   Terminal     = NewTerminal(os.Stdin, os.Stdout)
+	terminalMu sync.RWMutex // synchronizes access to terminal
+)
+
+// SetTerminal replaces the Terminal used by interactive features (primarily
+// for testing) and returns the previous one so it can be restored.
+func SetTerminal(t Terminal) (prev Terminal) {
+	terminalMu.Lock()
+	defer terminalMu.Unlock()
+	prev = terminal
+	terminal = t
+	return prev
+}
+
+// GetTerminal returns the Terminal used by interactive features
+func GetTerminal() Terminal {
+	terminalMu.RLock()
+	defer terminalMu.RUnlock()
+	return terminal
+}
+
+// NewTerminal returns a Terminal that reads from in and writes to out
+func NewTerminal(in, out *os.File) Terminal {
+	return &osTerminal{in: in, out: out}
+}
+
+var _ Terminal = (*osTerminal)(nil)
+
+// osTerminal implements Terminal for real file descriptors
+type osTerminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func (t *osTerminal) IsTTY() bool {
+	return isTerminalFile(t.out)
+}
+
+func (t *osTerminal) Width() (width int) {
+	width = terminalWidth(t.out)
+	if width > 0 {
+		goto end
+	}
+	// Fall back to $COLUMNS, which many shells export
+	width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	if width > 0 {
+		goto end
+	}
+	width = DefaultTerminalWidth
+end:
+	return width
+}
+
+func (t *osTerminal) MakeRaw() (restore func() error, err error) {
+	return makeRaw(t.in)
+}
+
+func (t *osTerminal) SetEcho(enabled bool) error {
+	return setEcho(t.in, enabled)
+}
+
 // IsTerminalError checks if an error is related to terminal/input operations
 // These errors should abort the entire operation rather than continue
 func IsTerminalError(err error) (isTermErr bool) {
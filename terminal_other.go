@@ -0,0 +1,30 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package cliutil
+
+import (
+	"os"
+)
+
+func isTerminalFile(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func terminalWidth(*os.File) int {
+	return 0
+}
+
+func makeRaw(*os.File) (func() error, error) {
+	return nil, ErrTerminalUnsupported
+}
+
+func setEcho(*os.File, bool) error {
+	return ErrTerminalUnsupported
+}
@@ -0,0 +1,93 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package cliutil
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// getTermios reads the terminal attributes of f
+func getTermios(f *os.File) (t syscall.Termios, err error) {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		err = errno
+	}
+	return t, err
+}
+
+// setTermios writes the terminal attributes of f
+func setTermios(f *os.File, t *syscall.Termios) (err error) {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		err = errno
+	}
+	return err
+}
+
+func isTerminalFile(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	_, err := getTermios(f)
+	return err == nil
+}
+
+func terminalWidth(f *os.File) int {
+	var ws struct {
+		Row, Col, XPixel, YPixel uint16
+	}
+	if f == nil {
+		return 0
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.Col)
+}
+
+func makeRaw(f *os.File) (restore func() error, err error) {
+	var orig, raw syscall.Termios
+
+	orig, err = getTermios(f)
+	if err != nil {
+		goto end
+	}
+	// Same flags as cfmakeraw(3)
+	raw = orig
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	err = setTermios(f, &raw)
+	if err != nil {
+		goto end
+	}
+	restore = func() error {
+		return setTermios(f, &orig)
+	}
+end:
+	return restore, err
+}
+
+func setEcho(f *os.File, enabled bool) (err error) {
+	var t syscall.Termios
+
+	t, err = getTermios(f)
+	if err != nil {
+		goto end
+	}
+	if enabled {
+		t.Lflag |= syscall.ECHO
+	} else {
+		t.Lflag &^= syscall.ECHO
+	}
+	err = setTermios(f, &t)
+end:
+	return err
+}
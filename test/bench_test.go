@@ -0,0 +1,129 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// benchSizes are the registry sizes each benchmark is run against
+var benchSizes = []clitest.FixtureArgs{
+	{Commands: 10, FlagsPerCommand: 5, Prefix: "bench"},
+	{Commands: 100, FlagsPerCommand: 10, Prefix: "bench"},
+	{Commands: 1000, FlagsPerCommand: 10, Prefix: "bench"},
+}
+
+func benchName(args clitest.FixtureArgs) string {
+	return fmt.Sprintf("cmds=%d/flags=%d", args.Commands, args.FlagsPerCommand)
+}
+
+func BenchmarkRegisterCommand(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(benchName(size), func(b *testing.B) {
+			clitest.IsolateRegistry(b)
+			snap := cliutil.SnapshotRegistry()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				cliutil.RestoreRegistry(snap)
+				cmds := clitest.GenerateCommands(size)
+				b.StartTimer()
+				for _, cmd := range cmds {
+					err := cliutil.RegisterCommand(cmd)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBuildCommandTree(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(benchName(size), func(b *testing.B) {
+			clitest.RegisterFixtures(b, size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := cliutil.BuildCommandTree()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseCmd(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(benchName(size), func(b *testing.B) {
+			clitest.RegisterFixtures(b, size)
+
+			// Resolve the last-registered command with every one of its flags set
+			argv := []string{"app", clitest.FixtureCmdName(size.Prefix, size.Commands-1)}
+			for j := 0; j < size.FlagsPerCommand; j++ {
+				argv = append(argv, fmt.Sprintf("--%s=v%d", clitest.FixtureFlagName(j), j))
+			}
+			argv = append(argv, "item")
+			opts, args, err := cliutil.ParseGlobalOptions(argv)
+			if err != nil {
+				b.Fatal(err)
+			}
+			runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+				Context: context.Background(),
+				Writer:  clitest.NewBufferedWriter(),
+				Options: opts,
+				Args:    args,
+			})
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err = runner.ParseCmd(args)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBuildUsage(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(benchName(size), func(b *testing.B) {
+			clitest.RegisterFixtures(b, size)
+			args := cliutil.UsageArgs{
+				AppInfo: appinfo.New(appinfo.Args{Name: "bench", ExeName: "bench"}),
+				Writer:  clitest.NewBufferedWriter(),
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = cliutil.BuildUsage(args)
+			}
+		})
+	}
+}
+
+func BenchmarkStreamUsage(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(benchName(size), func(b *testing.B) {
+			clitest.RegisterFixtures(b, size)
+			args := cliutil.UsageArgs{
+				AppInfo: appinfo.New(appinfo.Args{Name: "bench", ExeName: "bench"}),
+				Writer:  clitest.NewBufferedWriter(),
+			}
+			tmpl, err := cliutil.GetUsageTemplate()
+			if err != nil {
+				b.Fatalf("GetUsageTemplate() failed: %v", err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = tmpl.Execute(io.Discard, cliutil.StreamUsage(args))
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
 	"github.com/mikeschinkel/go-testutil"
 )
 
@@ -192,3 +193,31 @@ func TestBufferedWriter_ConcurrentAccess(t *testing.T) {
 		t.Error("Expected some doterr lines from concurrent writes")
 	}
 }
+
+func TestClitestBufferedWriter_LinesAndRelease(t *testing.T) {
+	writer := clitest.NewBufferedWriter()
+	writer.GrowHint(1<<16, 0)
+	writer.Printf("one\r\ntwo\n")
+	writer.Errorf("three")
+
+	var lines []string
+	for line := range writer.StdoutLines() {
+		lines = append(lines, line)
+	}
+	if strings.Join(lines, "|") != "one|two" {
+		t.Errorf("Expected stdout lines [one two], got %q", lines)
+	}
+	lines = lines[:0]
+	for line := range writer.StderrLines() {
+		lines = append(lines, line)
+	}
+	if strings.Join(lines, "|") != "three" {
+		t.Errorf("Expected stderr lines [three], got %q", lines)
+	}
+
+	writer.Release()
+	reused := clitest.NewBufferedWriter()
+	if reused.Stdout() != "" || reused.Stderr() != "" {
+		t.Error("Expected a new writer to start empty even with pooled buffers")
+	}
+}
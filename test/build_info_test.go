@@ -0,0 +1,70 @@
+package test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// setBuildVars sets the link-time build variables for t
+func setBuildVars(t *testing.T, version, commit, dirty, date string) {
+	t.Helper()
+	saved := []string{cliutil.BuildVersion, cliutil.BuildCommit, cliutil.BuildDirty, cliutil.BuildDate}
+	t.Cleanup(func() {
+		cliutil.BuildVersion, cliutil.BuildCommit, cliutil.BuildDirty, cliutil.BuildDate = saved[0], saved[1], saved[2], saved[3]
+	})
+	cliutil.BuildVersion, cliutil.BuildCommit, cliutil.BuildDirty, cliutil.BuildDate = version, commit, dirty, date
+}
+
+func TestWithBuildInfo_FillsAppInfo(t *testing.T) {
+	setBuildVars(t, "v1.2.3", "0123456789abcdef", "true", "2026-01-02T15:04:05Z")
+
+	info := appinfo.New(cliutil.WithBuildInfo(appinfo.Args{Name: "app"}))
+	build := cliutil.BuildInfoOf(info)
+	if info.Version() != "v1.2.3" || build.Commit != "0123456789abcdef" || !build.Dirty {
+		t.Errorf("Expected the link-time values, got %+v", build)
+	}
+	if !build.Date.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected the build date, got %v", build.Date)
+	}
+	if build.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %s, got %q", runtime.Version(), build.GoVersion)
+	}
+	want := "v1.2.3 (commit 0123456789ab, dirty, built 2026-01-02T15:04:05Z, " + runtime.Version() + ")"
+	if got := build.String(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	info = appinfo.New(cliutil.WithBuildInfo(appinfo.Args{
+		Name:      "app",
+		Version:   "v9",
+		ExtraInfo: map[string]any{cliutil.BuildCommitKey: "mine"},
+	}))
+	build = cliutil.BuildInfoOf(info)
+	if build.Version != "v9" || build.Commit != "mine" {
+		t.Errorf("Expected explicit values to be kept, got %+v", build)
+	}
+}
+
+func TestVersionCmd_ShowsBuildInfo(t *testing.T) {
+	setBuildVars(t, "v1.2.3", "abc", "", "")
+	newTestRunner(t)
+	enableTestBuiltins(t)
+
+	runner, args, writer := newTestRunner(t, "version")
+	runner.Args.AppInfo = appinfo.New(cliutil.WithBuildInfo(appinfo.Args{Name: "app"}))
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if err := runner.RunCmd(cmd); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got := writer.GetStdout(); !strings.HasPrefix(got, "app v1.2.3 (commit abc") {
+		t.Errorf("Expected the version and commit, got %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestOptionsWith(t *testing.T) {
+	opts := clitest.OptionsWith(
+		clitest.WithDryRun(true),
+		clitest.WithVerbosity(cliutil.HighVerbosity),
+		clitest.WithTimeout(30*time.Second),
+		clitest.WithOutput(cliutil.JSONOutput),
+	)
+	if !opts.DryRun() {
+		t.Error("Expected DryRun() to be true")
+	}
+	if opts.Verbosity() != cliutil.HighVerbosity {
+		t.Errorf("Expected HighVerbosity, got %v", opts.Verbosity())
+	}
+	if opts.Timeout() != 30*time.Second {
+		t.Errorf("Expected 30s timeout, got %v", opts.Timeout())
+	}
+	if opts.OutputFormat() != cliutil.JSONOutput {
+		t.Errorf("Expected JSON output, got %v", opts.OutputFormat())
+	}
+	if opts.Quiet() || opts.Force() || opts.Strict() {
+		t.Error("Expected unset options to take their defaults")
+	}
+}
+
+func TestOptionsWith_PanicsOnInvalidValue(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected OptionsWith() to panic on an invalid verbosity")
+		}
+	}()
+	clitest.OptionsWith(clitest.WithVerbosity(99))
+}
+
+func TestConfigFromMap(t *testing.T) {
+	var cfg cliutil.Config = clitest.ConfigFromMap(map[string]any{
+		"name":    "svc",
+		"debug":   true,
+		"retries": float64(3),
+		"db":      map[string]any{"host": "localhost", "port": 5432},
+	})
+	mc := cfg.(*clitest.MapConfig)
+
+	if got := mc.String("name"); got != "svc" {
+		t.Errorf("Expected name=svc, got %q", got)
+	}
+	if !mc.Bool("debug") {
+		t.Error("Expected debug=true")
+	}
+	if got := mc.Int("retries"); got != 3 {
+		t.Errorf("Expected retries=3, got %d", got)
+	}
+	if got := mc.String("db.host"); got != "localhost" {
+		t.Errorf("Expected db.host=localhost, got %q", got)
+	}
+	if got := mc.Int("db.port"); got != 5432 {
+		t.Errorf("Expected db.port=5432, got %d", got)
+	}
+	if _, ok := mc.Get("db.missing"); ok {
+		t.Error("Expected db.missing to be absent")
+	}
+	if _, ok := mc.Get("name.nested"); ok {
+		t.Error("Expected a path through a non-map value to be absent")
+	}
+}
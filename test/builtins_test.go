@@ -0,0 +1,216 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+func enableTestBuiltins(t *testing.T) {
+	t.Helper()
+	useTestCmds(t)
+	err := cliutil.EnableBuiltins(cliutil.Builtins{Docs: true, Completion: true, Version: true, Man: true})
+	if err != nil {
+		t.Fatalf("EnableBuiltins() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+}
+
+// runBuiltin parses and runs args as a command and returns its stdout
+func runBuiltin(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	return runTestCmd(t, func(runner *cliutil.CmdRunner) {
+		runner.Args.AppInfo = appinfo.New(appinfo.Args{Name: "app", ExeName: "app", Version: "1.2.3"})
+	}, args...)
+}
+
+func TestEnableBuiltins_RegistersCommands(t *testing.T) {
+	newTestRunner(t)
+	enableTestBuiltins(t)
+
+	names := cliutil.Complete(nil, "")
+	for _, want := range []string{"completion", "docs", "parsetest", "version"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("Expected %q among top-level completions, got %v", want, names)
+		}
+	}
+	if slices.Contains(names, cliutil.CompleteCmdName) {
+		t.Errorf("Expected %s to be hidden, got %v", cliutil.CompleteCmdName, names)
+	}
+
+	out, err := runBuiltin(t, "version")
+	if err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+	if out != "app 1.2.3\n" {
+		t.Errorf("Expected the name and version, got %q", out)
+	}
+
+	out, err = runBuiltin(t, "version", "--verbose")
+	if err != nil {
+		t.Fatalf("version --verbose failed: %v", err)
+	}
+	if !strings.HasPrefix(out, "app 1.2.3\n") || !strings.Contains(out, "  Platform: "+runtime.GOOS+"/"+runtime.GOARCH+"\n") {
+		t.Errorf("Expected the version and build details one per line, got %q", out)
+	}
+
+	out, err = runBuiltin(t, "docs")
+	if err != nil {
+		t.Fatalf("docs failed: %v", err)
+	}
+	for _, want := range []string{"# app\n", "## app parsetest\n", "- `--count`: How many", "- `name`: Name to use (required)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected docs to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, cliutil.CompleteCmdName) {
+		t.Errorf("Expected docs to omit hidden commands, got:\n%s", out)
+	}
+}
+
+func TestEnableBuiltins_Completion(t *testing.T) {
+	newTestRunner(t)
+	enableTestBuiltins(t)
+
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell", "pwsh"} {
+		out, err := runBuiltin(t, "completion", shell)
+		if err != nil {
+			t.Fatalf("completion %s failed: %v", shell, err)
+		}
+		if !strings.Contains(out, cliutil.CompleteCmdName) {
+			t.Errorf("Expected the %s script to call %s, got:\n%s", shell, cliutil.CompleteCmdName, out)
+		}
+	}
+	_, err := runBuiltin(t, "completion", "tcsh")
+	if cliutil.ErrClassOf(err) != cliutil.UsageErr {
+		t.Errorf("Expected a usage error for an unknown shell, got %v", err)
+	}
+
+	got := cliutil.Complete([]string{"parsetest"}, "--co")
+	if !slices.Contains(got, "--count") {
+		t.Errorf("Expected parsetest's flags, got %v", got)
+	}
+	got = cliutil.Complete(nil, "ver")
+	if !slices.Equal(got, []string{"version"}) {
+		t.Errorf("Expected only version, got %v", got)
+	}
+	if got = cliutil.Complete([]string{"--output"}, ""); got != nil {
+		t.Errorf("Expected no candidates for a flag's value, got %v", got)
+	}
+	if got = cliutil.Complete([]string{"--quiet"}, "ver"); !slices.Equal(got, []string{"version"}) {
+		t.Errorf("Expected commands after a bool flag, got %v", got)
+	}
+
+	t.Setenv(cliutil.CompleteArgsEnv, "")
+	t.Setenv(cliutil.CompleteWordEnv, "doc")
+	out, err := runBuiltin(t, cliutil.CompleteCmdName)
+	if err != nil {
+		t.Fatalf("%s failed: %v", cliutil.CompleteCmdName, err)
+	}
+	if out != "docs\n" {
+		t.Errorf("Expected one candidate per line, got %q", out)
+	}
+}
+
+// deployCmd has a flag and an argument with dynamic completion
+type deployCmd struct {
+	*cliutil.CmdBase
+	env, job string
+}
+
+func (c *deployCmd) Handle() error { return nil }
+
+func TestComplete_CallsCompleteFuncs(t *testing.T) {
+	cmd := &deployCmd{}
+	envs := func(string) []string { return []string{"prod", "preview", "staging"} }
+	cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name: "deploy",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "deploy",
+			FlagDefs: []cliutil.FlagDef{{
+				Name:     "env",
+				Shortcut: 'e',
+				Default:  "",
+				String:   &cmd.env,
+				Complete: envs,
+			}},
+		}},
+		ArgDefs: []*cliutil.ArgDef{{
+			Name:   "job",
+			String: &cmd.job,
+			Complete: func(prefix string) []string {
+				return []string{prefix + "-1", prefix + "-2"}
+			},
+		}},
+	})
+	useTestCmds(t, cmd)
+
+	tests := []struct {
+		args []string
+		word string
+		want []string
+	}{
+		{args: []string{"deploy", "--env"}, word: "pr", want: []string{"prod", "preview"}},
+		{args: []string{"deploy", "-e"}, word: "s", want: []string{"staging"}},
+		{args: []string{"deploy"}, word: "--env=pro", want: []string{"--env=prod"}},
+		{args: []string{"deploy", "--env", "prod"}, word: "web", want: []string{"web-1", "web-2"}},
+		{args: []string{"deploy", "web-1"}, word: "", want: nil},
+	}
+	for _, tt := range tests {
+		if got := cliutil.Complete(tt.args, tt.word); !slices.Equal(got, tt.want) {
+			t.Errorf("Complete(%q, %q) = %q, want %q", tt.args, tt.word, got, tt.want)
+		}
+	}
+
+	// The hidden command answers even though EnableBuiltins was not called
+	t.Setenv(cliutil.CompleteArgsEnv, "deploy\n--env")
+	t.Setenv(cliutil.CompleteWordEnv, "st")
+	out, err := runTestCmd(t, nil, cliutil.CompleteCmdName)
+	if err != nil {
+		t.Fatalf("%s failed: %v", cliutil.CompleteCmdName, err)
+	}
+	if out != "staging\n" {
+		t.Errorf("Expected the flag's candidates, got %q", out)
+	}
+}
+
+func TestEnableBuiltins_ManPages(t *testing.T) {
+	newTestRunner(t)
+	enableTestBuiltins(t)
+	dir := t.TempDir()
+
+	if _, err := runBuiltin(t, cliutil.ManCmdName, dir); err != nil {
+		t.Fatalf("%s failed: %v", cliutil.ManCmdName, err)
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Expected man page %s: %v", name, err)
+		}
+		return string(data)
+	}
+	app := read("app.1")
+	for _, want := range []string{`.TH "APP" "1"`, `"app 1.2.3"`, ".SH COMMANDS", ".SH GLOBAL OPTIONS", "app\\-parsetest(1)"} {
+		if !strings.Contains(app, want) {
+			t.Errorf("Expected %q in app.1, got:\n%s", want, app)
+		}
+	}
+	page := read("app-parsetest.1")
+	for _, want := range []string{".SH SYNOPSIS", ".SH ARGUMENTS", ".SH OPTIONS", "\\-\\-count value", "SEE ALSO\napp(1)"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected %q in app-parsetest.1, got:\n%s", want, page)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app-gen-man.1")); err == nil {
+		t.Errorf("Expected no page for the hidden %s command", cliutil.ManCmdName)
+	}
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt/appinfo"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+// greetCmd prints its greeting
+type greetCmd struct {
+	*cliutil.CmdBase
+	greeting string
+}
+
+func (c *greetCmd) Handle() error {
+	c.Writer.Printf("%s\n", c.greeting)
+	return nil
+}
+
+// newGreetCLI returns a CLI whose "greet" command prints greeting
+func newGreetCLI(t *testing.T, greeting string) *cliutil.CLI {
+	t.Helper()
+	cli := cliutil.NewCLI()
+	cmd := &greetCmd{greeting: greeting}
+	cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{Name: "greet", Description: "Print a greeting"})
+	if err := cli.RegisterCommand(cmd); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cli.Initialize(testutil.NewBufferedWriter()); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+	return cli
+}
+
+// runCLI runs osArgs in cli and returns stdout
+func runCLI(t *testing.T, cli *cliutil.CLI, osArgs ...string) string {
+	t.Helper()
+	writer := testutil.NewBufferedWriter()
+	opts, args, err := cli.ParseGlobalOptions(append([]string{"app"}, osArgs...))
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Writer:  writer,
+		Options: testOptions{globalOptions: opts},
+		Args:    args,
+		CLI:     cli,
+	})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if err := runner.RunCmd(cmd); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	return writer.GetStdout()
+}
+
+func TestCLI_IndependentRegistries(t *testing.T) {
+	t.Parallel()
+	hello := newGreetCLI(t, "hello")
+	hola := newGreetCLI(t, "hola")
+
+	if out := runCLI(t, hello, "greet"); out != "hello\n" {
+		t.Errorf("hello greet = %q, want %q", out, "hello\n")
+	}
+	if out := runCLI(t, hola, "greet"); out != "hola\n" {
+		t.Errorf("hola greet = %q, want %q", out, "hola\n")
+	}
+	if cliutil.GetExactCommand("greet") != nil {
+		t.Error("greet was registered in the default CLI")
+	}
+	if got := hello.Complete(nil, "gr"); !slices.Equal(got, []string{"greet"}) {
+		t.Errorf("Complete() = %v, want [greet]", got)
+	}
+}
+
+func TestCLI_IndependentGlobalOptions(t *testing.T) {
+	t.Parallel()
+	quiet := cliutil.NewCLI()
+	loud := cliutil.NewCLI()
+
+	if _, _, err := quiet.ParseGlobalOptions([]string{"app", "--quiet", "help"}); err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	if _, _, err := loud.ParseGlobalOptions([]string{"app", "help"}); err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	if !quiet.GlobalOptions().Quiet() {
+		t.Error("--quiet was not set for the CLI given it")
+	}
+	if loud.GlobalOptions().Quiet() {
+		t.Error("--quiet leaked into another CLI")
+	}
+	if quiet.GlobalFlagSet() == loud.GlobalFlagSet() {
+		t.Error("CLIs share a global FlagSet")
+	}
+}
+
+func TestCLI_ShowsItsOwnHelp(t *testing.T) {
+	t.Parallel()
+	cli := newGreetCLI(t, "hello")
+	writer := testutil.NewBufferedWriter()
+	args := cliutil.UsageArgs{
+		AppInfo: appinfo.New(appinfo.Args{Name: "greeter", ExeName: "greeter"}),
+		Writer:  writer,
+		CLI:     cli,
+	}
+
+	if err := cliutil.ShowMainHelp(args); err != nil {
+		t.Fatalf("ShowMainHelp() failed: %v", err)
+	}
+	if !writer.ContainsStdout("Print a greeting") {
+		t.Errorf("Expected main help to list greet, got %q", writer.GetStdout())
+	}
+	if err := cliutil.ShowCmdHelp([]string{"greet"}, args); err != nil {
+		t.Errorf("ShowCmdHelp() failed for a command of UsageArgs.CLI: %v", err)
+	}
+}
+
+func TestCLI_FollowsItsOwnOptions(t *testing.T) {
+	t.Parallel()
+	cli := cliutil.NewCLI()
+	if _, _, err := cli.ParseGlobalOptions([]string{"app", "--strict", "--utc", "--output=json", "help"}); err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+
+	if !cli.IsStrict() {
+		t.Error("Expected --strict to make the CLI strict")
+	}
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+	if got, want := cli.FormatTime(at), "2026-01-02 20:04:05 UTC"; got != want {
+		t.Errorf("FormatTime() = %q, want %q", got, want)
+	}
+	writer := testutil.NewBufferedWriter()
+	cli.ReportError(writer, errors.New("boom"))
+	if !strings.HasPrefix(writer.GetStderr(), "{") {
+		t.Errorf("Expected ReportError() to write JSON under --output=json, got %q", writer.GetStderr())
+	}
+}
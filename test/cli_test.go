@@ -0,0 +1,365 @@
+package test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+// globalOptions aliases cliutil.GlobalOptions so testOptions can embed it
+// while still providing a GlobalOptions() method
+type globalOptions = cliutil.GlobalOptions
+
+// testOptions satisfies cliutil.Options and exposes the parsed GlobalOptions
+type testOptions struct {
+	*globalOptions
+}
+
+func (o testOptions) GlobalOptions() *cliutil.GlobalOptions {
+	return o.globalOptions
+}
+
+// parseTestCmd is a throwaway command used to exercise the parse pipeline
+type parseTestCmd struct {
+	*cliutil.CmdBase
+}
+
+var parseTestOpts = struct {
+	count int
+	name  string
+}{}
+
+func (c *parseTestCmd) Handle() error {
+	return nil
+}
+
+func init() {
+	err := cliutil.RegisterCommand(&parseTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "parsetest",
+			Description: "Exercise the parse pipeline",
+			FlagSets: []*cliutil.FlagSet{{
+				Name: "parsetest",
+				FlagDefs: []cliutil.FlagDef{{
+					Name:  "count",
+					Usage: "How many",
+					Int:   &parseTestOpts.count,
+				}},
+			}},
+			ArgDefs: []*cliutil.ArgDef{{
+				Name:     "name",
+				Usage:    "Name to use",
+				Required: true,
+				String:   &parseTestOpts.name,
+			}},
+		}),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+var initCLIOnce sync.Once
+
+// newTestRunner initializes cliutil once and returns a runner whose options
+// reflect the given command line (which must not include the program name).
+func newTestRunner(t *testing.T, args ...string) (*cliutil.CmdRunner, []string, *testutil.BufferedWriter) {
+	t.Helper()
+	writer := testutil.NewBufferedWriter()
+	initCLIOnce.Do(func() {
+		err := cliutil.Initialize(writer)
+		if err != nil {
+			t.Fatalf("Initialize() failed: %v", err)
+		}
+		// Help shows the same name however the test binary is built
+		cliutil.SetCLIName("app")
+	})
+	opts, args, err := cliutil.ParseGlobalOptions(append([]string{"app"}, args...))
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Writer:  writer,
+		Options: testOptions{globalOptions: opts},
+		Args:    args,
+	})
+	return runner, args, writer
+}
+
+// runTestCmd parses and runs args like newTestRunner, after setup, if not
+// nil, adjusts the runner, and returns stdout
+func runTestCmd(t *testing.T, setup func(*cliutil.CmdRunner), args ...string) (string, error) {
+	t.Helper()
+	runner, args, writer := newTestRunner(t, args...)
+	if setup != nil {
+		setup(runner)
+	}
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		return "", err
+	}
+	err = runner.RunCmd(cmd)
+	return writer.GetStdout(), err
+}
+
+// useTestCmds gives the rest of the test a registry holding only cmds, with
+// the command tree built. The test CLI is initialized first, as Initialize
+// must not run on a test's isolated registry. Subcommands follow their
+// parents and name them with AddParent.
+func useTestCmds(t *testing.T, cmds ...cliutil.Command) {
+	t.Helper()
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	for _, cmd := range cmds {
+		if err := cliutil.RegisterCommand(cmd); err != nil {
+			t.Fatalf("RegisterCommand() failed: %v", err)
+		}
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+}
+
+func TestParseCmd_ReportsAllProblems(t *testing.T) {
+	runner, args, writer := newTestRunner(t, "parsetest", "--count=abc", "--bogus")
+
+	_, err := runner.ParseCmd(args)
+	if err == nil {
+		t.Fatal("Expected ParseCmd() to fail")
+	}
+	runner.ReportErr(err)
+
+	if !writer.ContainsStderr("3 problems found") {
+		t.Errorf("Expected all three problems to be reported, got: %q", writer.GetStderr())
+	}
+	for _, want := range []string{"flags parsing failed", "unknown flag(s)", "name"} {
+		if !writer.ContainsStderr(want) {
+			t.Errorf("Expected stderr to mention %q, got: %q", want, writer.GetStderr())
+		}
+	}
+	if code := cliutil.ExitCode(err); code != cliutil.ExitOptionsParseError {
+		t.Errorf("Expected ExitOptionsParseError, got %d", code)
+	}
+}
+
+func TestParseCmd_SuggestsNearestFlag(t *testing.T) {
+	runner, args, writer := newTestRunner(t, "parsetest", "--cuont=3", "bob")
+
+	_, err := runner.ParseCmd(args)
+	if !errors.Is(err, cliutil.ErrUnknownFlags) {
+		t.Fatalf("Expected ErrUnknownFlags, got %v", err)
+	}
+	if got := cliutil.Suggestions(err); !slices.Equal(got, []string{"--cuont: did you mean --count?"}) {
+		t.Errorf("Suggestions() = %v, want a suggestion of --count", got)
+	}
+	runner.ReportErr(err)
+	if !writer.ContainsStderr("hint: --cuont: did you mean --count?") {
+		t.Errorf("Expected the suggestion beneath the error, got: %q", writer.GetStderr())
+	}
+}
+
+// warnTestCmd records a warning during Handle
+type warnTestCmd struct {
+	*cliutil.CmdBase
+}
+
+func (c *warnTestCmd) Handle() error {
+	c.Warnf("disk is %d%% full", 91)
+	return nil
+}
+
+func init() {
+	err := cliutil.RegisterCommand(&warnTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "warntest",
+			Description: "Record a warning",
+		}),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestRunCmd_WarningSummary(t *testing.T) {
+	runner, args, writer := newTestRunner(t, "warntest")
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	err = runner.RunCmd(cmd)
+	if err != nil {
+		t.Errorf("Expected warnings not to fail the run, got: %v", err)
+	}
+	if !writer.ContainsStderr("Completed with 1 warning") {
+		t.Errorf("Expected warning summary, got: %q", writer.GetStderr())
+	}
+}
+
+func TestRunCmd_WarningsArePerRun(t *testing.T) {
+	global := testutil.NewBufferedWriter()
+	prev := cliutil.GetWriter()
+	cliutil.SetWriter(global)
+	t.Cleanup(func() { cliutil.SetWriter(prev) })
+
+	for i := 0; i < 2; i++ {
+		runner, args, writer := newTestRunner(t, "warntest")
+		cmd, err := runner.ParseCmd(args)
+		if err != nil {
+			t.Fatalf("ParseCmd() failed: %v", err)
+		}
+		if err = runner.RunCmd(cmd); err != nil {
+			t.Fatalf("RunCmd() failed: %v", err)
+		}
+		want := "Warning: disk is 91% full\nCompleted with 1 warning\n"
+		if got := writer.GetStderr(); got != want {
+			t.Errorf("Run %d: expected %q on the runner's Writer, got %q", i+1, want, got)
+		}
+	}
+	if global.GetStderr() != "" {
+		t.Errorf("Expected nothing on the global Writer, got %q", global.GetStderr())
+	}
+}
+
+func TestRunCmd_StrictWarnings(t *testing.T) {
+	runner, args, _ := newTestRunner(t, "--strict", "warntest")
+	clitest.ResetGlobalOptions(t)
+
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	err = runner.RunCmd(cmd)
+	if !errors.Is(err, cliutil.ErrWarningsAsErrors) {
+		t.Errorf("Expected ErrWarningsAsErrors with --strict, got: %v", err)
+	}
+}
+
+// registerRunCmd registers a "run" command taking a script and the args to
+// pass it
+func registerRunCmd(t *testing.T) (script *string, scriptArgs *[]string) {
+	t.Helper()
+	script, scriptArgs = new(string), new([]string)
+	useTestCmds(t, &parseTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "run",
+			Description: "Run a script",
+			ArgDefs: []*cliutil.ArgDef{
+				{Name: "script", Usage: "Script to run", Required: true, String: script},
+				{Name: "args", Usage: "Args to pass the script", Remainder: scriptArgs},
+			},
+		}),
+	})
+	return script, scriptArgs
+}
+
+func TestAssignArgs_Remainder(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "none", args: []string{"build.sh"}},
+		{name: "several", args: []string{"build.sh", "a", "b"}, want: []string{"a", "b"}},
+		{name: "flag-like", args: []string{"build.sh", "--", "-v", "x"}, want: []string{"-v", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, scriptArgs := registerRunCmd(t)
+			runner, args, _ := newTestRunner(t, append([]string{"run"}, tt.args...)...)
+			if _, err := runner.ParseCmd(args); err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if *script != "build.sh" || !slices.Equal(*scriptArgs, tt.want) {
+				t.Errorf("Expected build.sh %q, got %s %q", tt.want, *script, *scriptArgs)
+			}
+		})
+	}
+	t.Run("help", func(t *testing.T) {
+		registerRunCmd(t)
+		usage := cliutil.BuildCmdUsage(cliutil.GetExactCommand("run")).Usage
+		if !strings.Contains(usage, "<script> [<args>...]") {
+			t.Errorf("Expected usage to show args as variadic, got %q", usage)
+		}
+	})
+}
+
+func TestRegisterCommand_RejectsRemainderBeforeLastArg(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	err := cliutil.RegisterCommand(&parseTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name: "copy",
+			ArgDefs: []*cliutil.ArgDef{
+				{Name: "sources", Remainder: new([]string)},
+				{Name: "dest", String: new(string)},
+			},
+		}),
+	})
+	if !errors.Is(err, cliutil.ErrRemainderNotLast) {
+		t.Errorf("Expected ErrRemainderNotLast, got %v", err)
+	}
+}
+
+func TestRegisterCommand_RejectsFlagsClashingWithGlobalFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flagDef cliutil.FlagDef
+		clash   bool
+	}{
+		{name: "name", flagDef: cliutil.FlagDef{Name: "force", Usage: "Force", Bool: new(bool)}, clash: true},
+		{name: "shortcut", flagDef: cliutil.FlagDef{Name: "query", Shortcut: 'q', Usage: "Query", String: new(string)}, clash: true},
+		{name: "none", flagDef: cliutil.FlagDef{Name: "assume", Shortcut: 'y', Usage: "Assume", Bool: new(bool)}},
+	}
+	newTestRunner(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clitest.IsolateRegistry(t)
+			err := cliutil.RegisterCommand(&parseTestCmd{
+				CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+					Name: "clash",
+					FlagSets: []*cliutil.FlagSet{{
+						Name:     "clash",
+						FlagDefs: []cliutil.FlagDef{tt.flagDef},
+					}},
+				}),
+			})
+			if tt.clash != errors.Is(err, cliutil.ErrCommandRegistrationFailed) {
+				t.Errorf("Expected a clash %t, got %v", tt.clash, err)
+			}
+		})
+	}
+}
+
+func TestAssignArgs_MinMaxArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmdArgs    cliutil.CmdArgs
+		args       []string
+		wantErr    error
+		unexpected string
+	}{
+		{name: "within", cmdArgs: cliutil.CmdArgs{MinArgs: 1, MaxArgs: 2}, args: []string{"a", "b"}},
+		{name: "too few", cmdArgs: cliutil.CmdArgs{MinArgs: 1, MaxArgs: 2}, wantErr: cliutil.ErrTooFewArgs},
+		{name: "too many", cmdArgs: cliutil.CmdArgs{MinArgs: 1, MaxArgs: 2}, args: []string{"a", "b", "c", "d"}, wantErr: cliutil.ErrTooManyArgs, unexpected: "c d"},
+		{name: "no limit", cmdArgs: cliutil.CmdArgs{}, args: []string{"a", "b", "c"}},
+		{name: "no args", cmdArgs: cliutil.CmdArgs{MaxArgs: cliutil.NoArgs}, args: []string{"a"}, wantErr: cliutil.ErrTooManyArgs, unexpected: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cmdArgs.Name = "pick"
+			err := cliutil.NewCmdBase(tt.cmdArgs).AssignArgs(tt.args)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if got, _ := cliutil.ErrValue[string](err, "unexpected"); got != tt.unexpected {
+				t.Errorf("Expected unexpected args %q, got %q", tt.unexpected, got)
+			}
+		})
+	}
+}
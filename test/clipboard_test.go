@@ -0,0 +1,64 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestToClipboard_UsesInstalledClipboard(t *testing.T) {
+	fake := &clitest.FakeClipboard{}
+	clitest.UseClipboard(t, fake)
+
+	if err := cliutil.ToClipboard("token-123"); err != nil {
+		t.Fatalf("ToClipboard() failed: %v", err)
+	}
+	if fake.Text() != "token-123" {
+		t.Errorf("Expected the text to be copied, got %q", fake.Text())
+	}
+	fake.Err = cliutil.ErrClipboardUnsupported
+	if err := cliutil.ToClipboard("x"); !errors.Is(err, cliutil.ErrClipboardUnsupported) {
+		t.Errorf("Expected the clipboard's error, got %v", err)
+	}
+}
+
+func TestToClipboard_ReportsMissingTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("clip.exe is always present on Windows")
+	}
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("WAYLAND_DISPLAY", "")
+	err := cliutil.ToClipboard("x")
+	if !errors.Is(err, cliutil.ErrClipboardUnsupported) {
+		t.Fatalf("Expected ErrClipboardUnsupported without clipboard tools, got %v", err)
+	}
+	if len(cliutil.Suggestions(err)) == 0 {
+		t.Error("Expected a suggestion of what to install")
+	}
+}
+
+func TestToClipboard_PipesTextToTool(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses a fake xclip on Linux")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "copied")
+	script := "#!/bin/sh\ncat > " + out + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "xclip"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if err := cliutil.ToClipboard("https://example.com"); err != nil {
+		t.Fatalf("ToClipboard() failed: %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil || string(got) != "https://example.com" {
+		t.Errorf("Expected xclip to receive the text, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestFakeClock_AdvanceFiresDueWaiters(t *testing.T) {
+	fc := clitest.NewFakeClock(time.Time{})
+	start := fc.Now()
+	short := fc.After(time.Second)
+	long := fc.After(time.Minute)
+
+	fc.Advance(2 * time.Second)
+	select {
+	case got := <-short:
+		if !got.Equal(start.Add(2 * time.Second)) {
+			t.Errorf("Expected fire time %v, got %v", start.Add(2*time.Second), got)
+		}
+	default:
+		t.Fatal("Expected the 1s waiter to fire")
+	}
+	select {
+	case <-long:
+		t.Fatal("Expected the 1m waiter not to fire yet")
+	default:
+	}
+	if fc.Waiters() != 1 {
+		t.Errorf("Expected 1 pending waiter, got %d", fc.Waiters())
+	}
+}
+
+func TestSleep_UsesClock(t *testing.T) {
+	fc := clitest.NewFakeClock(time.Time{})
+	clitest.UseClock(t, fc)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cliutil.Sleep(context.Background(), time.Hour)
+	}()
+	fc.BlockUntil(1)
+	fc.Advance(time.Hour)
+	if err := <-done; err != nil {
+		t.Errorf("Expected Sleep() to return nil, got %v", err)
+	}
+}
+
+func TestSleep_ReturnsOnCancel(t *testing.T) {
+	clitest.UseClock(t, clitest.NewFakeClock(time.Time{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := cliutil.Sleep(ctx, time.Hour)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTimeoutContext(t *testing.T) {
+	fc := clitest.NewFakeClock(time.Time{})
+	clitest.UseClock(t, fc)
+
+	start := fc.Now()
+	// A cancelable parent, whose own cause must not hide the timeout
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	ctx, cancel := cliutil.TimeoutContext(parent, 30*time.Second)
+	defer cancel()
+	child, cancelChild := context.WithCancel(ctx)
+	defer cancelChild()
+
+	if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(start.Add(30*time.Second)) {
+		t.Errorf("Expected a deadline 30s after the fake clock's start, got %v (%t)", deadline, ok)
+	}
+
+	fc.Advance(29 * time.Second)
+	if ctx.Err() != nil {
+		t.Fatalf("Expected context to be live before the timeout, got %v", ctx.Err())
+	}
+	fc.Advance(time.Second)
+	<-ctx.Done()
+	if !errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		t.Errorf("Expected cause DeadlineExceeded, got %v", context.Cause(ctx))
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("Expected Err() DeadlineExceeded, got %v", ctx.Err())
+	}
+	<-child.Done()
+	if child.Err() != context.DeadlineExceeded {
+		t.Errorf("Expected a derived context's Err() to be DeadlineExceeded, got %v", child.Err())
+	}
+	if !errors.Is(context.Cause(child), context.DeadlineExceeded) {
+		t.Errorf("Expected a derived context's cause DeadlineExceeded, got %v", context.Cause(child))
+	}
+}
+
+func TestTimeoutContext_RealClock(t *testing.T) {
+	start := time.Now()
+	ctx, cancel := cliutil.TimeoutContext(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if deadline, ok := ctx.Deadline(); !ok || deadline.Before(start.Add(time.Millisecond)) {
+		t.Errorf("Expected a deadline at least 1ms from the start, got %v (%t)", deadline, ok)
+	}
+	<-ctx.Done()
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("Expected Err() DeadlineExceeded, got %v", ctx.Err())
+	}
+}
+
+func TestTimeoutContext_CancelReportsCanceled(t *testing.T) {
+	clitest.UseClock(t, clitest.NewFakeClock(time.Time{}))
+
+	ctx, cancel := cliutil.TimeoutContext(context.Background(), time.Hour)
+	cancel()
+	<-ctx.Done()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Expected Err() Canceled, got %v", ctx.Err())
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/cobracliutil"
+	"github.com/spf13/cobra"
+)
+
+// cobraRun records what a converted cobra command was run with
+type cobraRun struct {
+	hooks  []string
+	args   []string
+	env    string
+	count  int
+	config string
+}
+
+// newCobraTree returns a cobra tree with a runnable command, an alias, a
+// group command, and a persistent root flag
+func newCobraTree(run *cobraRun) *cobra.Command {
+	root := &cobra.Command{
+		Use: "app",
+		PersistentPreRun: func(*cobra.Command, []string) {
+			run.hooks = append(run.hooks, "persistent-pre")
+		},
+	}
+	root.PersistentFlags().StringVar(&run.config, "config", "app.yaml", "Config file")
+
+	deploy := &cobra.Command{
+		Use:     "deploy <service>",
+		Short:   "Deploy a service",
+		Aliases: []string{"ship"},
+		Args:    cobra.ExactArgs(1),
+		Example: "# Deploy to production\napp deploy --env=prod web",
+		PreRun: func(*cobra.Command, []string) {
+			run.hooks = append(run.hooks, "pre")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run.hooks = append(run.hooks, "run")
+			run.args = args
+			if !cmd.Flags().Changed("env") {
+				return errors.New("env not marked changed")
+			}
+			return nil
+		},
+	}
+	deploy.Flags().StringVar(&run.env, "env", "dev", "Target environment")
+	deploy.Flags().IntVarP(&run.count, "count", "n", 1, "Instances")
+
+	db := &cobra.Command{Use: "db", Short: "Database commands"}
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Run migrations",
+		Run: func(*cobra.Command, []string) {
+			run.hooks = append(run.hooks, "migrate")
+		},
+	}
+	db.AddCommand(migrate)
+	root.AddCommand(deploy, db)
+	return root
+}
+
+// runCobraArgs converts a fresh cobra tree and runs args through cliutil
+func runCobraArgs(t *testing.T, args ...string) (*cobraRun, error) {
+	t.Helper()
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	run := &cobraRun{}
+	_, err := cobracliutil.FromCobra(newCobraTree(run))
+	if err != nil {
+		t.Fatalf("FromCobra() failed: %v", err)
+	}
+	err = cliutil.BuildCommandTree()
+	if err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	opts, args, err := cliutil.ParseGlobalOptions(append([]string{"app"}, args...))
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context: context.Background(),
+		Writer:  clitest.NewBufferedWriter(),
+		Options: opts,
+		Args:    args,
+	})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		return run, err
+	}
+	return run, runner.RunCmd(cmd)
+}
+
+func TestFromCobra_RunsCommandWithFlagsAndArgs(t *testing.T) {
+	run, err := runCobraArgs(t, "deploy", "--env=prod", "-n", "3", "--config=prod.yaml", "web")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got := strings.Join(run.hooks, ","); got != "persistent-pre,pre,run" {
+		t.Errorf("Expected hooks persistent-pre,pre,run, got %s", got)
+	}
+	if run.env != "prod" || run.count != 3 || run.config != "prod.yaml" {
+		t.Errorf("Expected env=prod count=3 config=prod.yaml, got %+v", run)
+	}
+	if len(run.args) != 1 || run.args[0] != "web" {
+		t.Errorf("Expected args [web], got %v", run.args)
+	}
+}
+
+func TestFromCobra_Alias(t *testing.T) {
+	run, err := runCobraArgs(t, "ship", "--env=prod", "api")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if len(run.args) != 1 || run.args[0] != "api" {
+		t.Errorf("Expected args [api], got %v", run.args)
+	}
+}
+
+func TestFromCobra_Subcommand(t *testing.T) {
+	run, err := runCobraArgs(t, "db", "migrate")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got := strings.Join(run.hooks, ","); got != "persistent-pre,migrate" {
+		t.Errorf("Expected hooks persistent-pre,migrate, got %s", got)
+	}
+}
+
+func TestFromCobra_ArgsValidationIsUsageError(t *testing.T) {
+	_, err := runCobraArgs(t, "deploy", "--env=prod")
+	if cliutil.ErrClassOf(err) != cliutil.UsageErr {
+		t.Errorf("Expected a usage error for missing args, got %v", err)
+	}
+}
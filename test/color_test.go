@@ -0,0 +1,98 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestStyled_OnlyWhenColorEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	bold := cliutil.Styles(cliutil.BoldStyle, cliutil.RedStyle)
+	if bold != "1;31" {
+		t.Errorf("Styles() = %q, want %q", bold, "1;31")
+	}
+
+	tests := []struct {
+		name    string
+		tty     bool
+		noColor bool
+		want    string
+	}{
+		{name: "terminal", tty: true, want: "\x1b[1;31mfail\x1b[0m"},
+		{name: "piped", tty: false, want: "fail"},
+		{name: "NO_COLOR", tty: true, noColor: true, want: "fail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: tt.tty}))
+			clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{NoColor: tt.noColor}))
+			if got := cliutil.Styled(&buf, bold, "fail"); got != tt.want {
+				t.Errorf("Styled() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyled_NoColorFlag(t *testing.T) {
+	var buf bytes.Buffer
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+
+	registerTypesCmd(t)
+	clitest.ResetGlobalOptions(t)
+	if err := parseTypes(t, "--no-color"); err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if !cliutil.GetGlobalOptions().NoColor() {
+		t.Fatal("Expected --no-color to set NoColor()")
+	}
+	if got := cliutil.Styled(&buf, cliutil.GreenStyle, "ok"); got != "ok" {
+		t.Errorf("Styled() = %q, want text without color", got)
+	}
+}
+
+func TestStyled_FollowsRunningCLINoColor(t *testing.T) {
+	var buf bytes.Buffer
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	newTestRunner(t)
+	cli := cliutil.NewCLI()
+	if _, _, err := cli.ParseGlobalOptions([]string{"app", "--no-color", "help"}); err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+
+	args := cliutil.CmdRunnerArgs{CLI: cli}
+	if got := args.Styled(&buf, cliutil.GreenStyle, "ok"); got != "ok" {
+		t.Errorf("Styled() = %q, want text without color under the running CLI's --no-color", got)
+	}
+	if got := cliutil.Styled(&buf, cliutil.GreenStyle, "ok"); got == "ok" {
+		t.Error("Expected --no-color given to another CLI not to affect the default CLI")
+	}
+	writer := testutil.NewBufferedWriter()
+	cli.ReportError(writer, errTest)
+	if writer.ContainsStderr("\x1b[") {
+		t.Errorf("Expected an uncolored error prefix under --no-color, got %q", writer.GetStderr())
+	}
+}
+
+func TestReportError_ColorsPrefixOnTerminal(t *testing.T) {
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	writer := testutil.NewBufferedWriter()
+	cliutil.ReportError(writer, errTest)
+	if !writer.ContainsStderr("\x1b[1;31mError\x1b[0m: test failure") {
+		t.Errorf("Expected colored error prefix on a terminal, got: %q", writer.GetStderr())
+	}
+
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	writer = testutil.NewBufferedWriter()
+	cliutil.ReportError(writer, errTest)
+	if !writer.ContainsStderr("Error: test failure") {
+		t.Errorf("Expected plain error prefix when piped, got: %q", writer.GetStderr())
+	}
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+// loggingCmd logs one record from Handle so decoration can be inspected
+type loggingCmd struct {
+	*cliutil.CmdBase
+}
+
+func (c *loggingCmd) Handle() error {
+	c.Logger.Info("handled")
+	return nil
+}
+
+func TestRunCmd_DecoratesLogger(t *testing.T) {
+	var region, apiToken, pin string
+	useTestCmds(t, &loggingCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "logtest",
+			Description: "Log from a handler",
+			FlagSets: []*cliutil.FlagSet{{
+				Name: "logtest",
+				FlagDefs: []cliutil.FlagDef{
+					{Name: "region", Usage: "Region", String: &region},
+					{Name: "api-token", Usage: "API token", String: &apiToken},
+					{Name: "pin", Usage: "PIN", String: &pin, Sensitive: true},
+				},
+			}},
+		}),
+	})
+
+	opts, args, err := cliutil.ParseGlobalOptions([]string{"app", "logtest", "--region=us", "--api-token=abc", "--pin=1234"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context:      context.Background(),
+		Writer:       clitest.NewBufferedWriter(),
+		Logger:       slog.New(slog.NewJSONHandler(&buf, nil)),
+		Options:      opts,
+		Args:         args,
+		InvocationID: "run-1",
+	})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if err = runner.RunCmd(cmd); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+
+	var rec struct {
+		Msg          string            `json:"msg"`
+		Command      string            `json:"command"`
+		InvocationID string            `json:"invocation_id"`
+		Flags        map[string]string `json:"flags"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("Expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	if rec.Command != "logtest" || rec.InvocationID != "run-1" {
+		t.Errorf("Expected command=logtest invocation_id=run-1, got %+v", rec)
+	}
+	want := map[string]string{
+		"region":    "us",
+		"api-token": cliutil.RedactedValue,
+		"pin":       cliutil.RedactedValue,
+	}
+	for k, v := range want {
+		if rec.Flags[k] != v {
+			t.Errorf("Expected flags.%s=%q, got %q", k, v, rec.Flags[k])
+		}
+	}
+	if len(rec.Flags) != len(want) {
+		t.Errorf("Expected only explicitly set flags, got %v", rec.Flags)
+	}
+}
@@ -0,0 +1,192 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/tomlcliutil"
+	"github.com/mikeschinkel/go-cliutil/yamlcliutil"
+)
+
+const serveHostEnv = "CLIUTIL_TEST_SERVE_HOST"
+
+// serveCmd prints the host and port its flags were given
+type serveCmd struct {
+	*cliutil.CmdBase
+	host string
+	port int
+}
+
+func (c *serveCmd) Handle() error {
+	c.Writer.Printf("%s:%d\n", c.host, c.port)
+	return nil
+}
+
+// registerServeCmd registers "serve" with a --host flag bound to
+// serveHostEnv and a --port flag, and enables config files
+func registerServeCmd(t *testing.T, args cliutil.ConfigArgs) {
+	t.Helper()
+	tomlcliutil.Register()
+	cmd := &serveCmd{}
+	cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name: "serve",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "serve",
+			FlagDefs: []cliutil.FlagDef{
+				{Name: "host", Default: "localhost", Usage: "Host to serve", String: &cmd.host, EnvVar: serveHostEnv},
+				{Name: "port", Default: 80, Usage: "Port to serve", Int: &cmd.port},
+			},
+		}},
+	})
+	useTestCmds(t, cmd)
+	if err := cliutil.EnableConfig(args); err != nil {
+		t.Fatalf("EnableConfig() failed: %v", err)
+	}
+}
+
+// writeConfig writes content to a file named name in a temp dir
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	return file
+}
+
+func TestConfig_Precedence(t *testing.T) {
+	registerServeCmd(t, cliutil.ConfigArgs{})
+	file := writeConfig(t, "app.toml", `
+verbosity = 2 # a global option
+
+[serve]
+host = "config.example"
+port = 8_080
+`)
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{name: "defaults", args: []string{"serve"}, want: "localhost:80\n"},
+		{name: "config", args: []string{"--config", file, "serve"}, want: "config.example:8080\n"},
+		{name: "env over config", env: "env.example", args: []string{"--config", file, "serve"}, want: "env.example:8080\n"},
+		{name: "flag over env", env: "env.example", args: []string{"--config", file, "serve", "--host=flag.example", "--port=9"}, want: "flag.example:9\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv(serveHostEnv, tt.env)
+			}
+			out, err := runTestCmd(t, nil, tt.args...)
+			if err != nil {
+				t.Fatalf("RunCmd() failed: %v", err)
+			}
+			if out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+	runTestCmd(t, nil, "--config", file, "serve")
+	if v := cliutil.GetGlobalOptions().Verbosity(); v != 2 {
+		t.Errorf("Verbosity() = %d, want 2 from the config file", v)
+	}
+}
+
+func TestConfig_SettingSources(t *testing.T) {
+	registerServeCmd(t, cliutil.ConfigArgs{})
+	t.Setenv(serveHostEnv, "env.example")
+	file := writeConfig(t, "app.json", `{"serve": {"port": 8080}}`)
+
+	if _, err := runTestCmd(t, nil, "--config", file, "serve"); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	sources := make(map[string]cliutil.SettingSource)
+	for _, s := range cliutil.EffectiveSettings(cliutil.GetExactCommand("serve")) {
+		sources[s.Name] = s.Source
+	}
+	want := map[string]cliutil.SettingSource{
+		"config": cliutil.FlagSource,
+		"host":   cliutil.EnvSource,
+		"port":   cliutil.ConfigSource,
+		"quiet":  cliutil.DefaultSource,
+	}
+	for name, source := range want {
+		if sources[name] != source {
+			t.Errorf("source of --%s = %q, want %q", name, sources[name], source)
+		}
+	}
+}
+
+func TestConfig_FilesUsesFirstFound(t *testing.T) {
+	yamlcliutil.Register()
+	file := writeConfig(t, "app.yaml", "serve:\n  host: yaml.example\n")
+	registerServeCmd(t, cliutil.ConfigArgs{Files: []string{filepath.Join(t.TempDir(), "missing.toml"), file}})
+
+	out, err := runTestCmd(t, nil, "serve")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if out != "yaml.example:80\n" {
+		t.Errorf("stdout = %q, want the host from %s", out, file)
+	}
+	if got := cliutil.ConfigFile(); got != file {
+		t.Errorf("ConfigFile() = %q, want %q", got, file)
+	}
+}
+
+func TestConfig_NotEnabled(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	if cliutil.GetGlobalFlagSet().HasFlag(cliutil.ConfigFlagName) {
+		t.Fatal("--config is a global flag before EnableConfig()")
+	}
+	if err := cliutil.EnableConfig(cliutil.ConfigArgs{}); err != nil {
+		t.Fatalf("EnableConfig() failed: %v", err)
+	}
+	if err := cliutil.EnableConfig(cliutil.ConfigArgs{}); err != nil {
+		t.Errorf("EnableConfig() failed when called again: %v", err)
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	registerServeCmd(t, cliutil.ConfigArgs{})
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    error
+	}{
+		{name: "syntax", file: "app.toml", content: "[serve\n", want: cliutil.ErrLoadingConfigFile},
+		{name: "leading zero", file: "app.toml", content: "[serve]\nport = 010\n", want: cliutil.ErrLoadingConfigFile},
+		{name: "underscores", file: "app.toml", content: "[serve]\nport = 8__080\n", want: cliutil.ErrLoadingConfigFile},
+		{name: "hex float", file: "app.toml", content: "rate = 0x1p4\n", want: cliutil.ErrLoadingConfigFile},
+		{name: "infinity", file: "app.toml", content: "rate = Infinity\n", want: cliutil.ErrLoadingConfigFile},
+		{name: "format", file: "app.ini", content: "port=1\n", want: cliutil.ErrUnsupportedConfigFormat},
+		{name: "type", file: "app.json", content: `{"serve": {"port": "many"}}`, want: cliutil.ErrFlagsParsingFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeConfig(t, tt.file, tt.content)
+			opts, args, err := cliutil.ParseGlobalOptions([]string{"app", "--config", file, "serve"})
+			if err == nil {
+				runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{Options: testOptions{globalOptions: opts}, Args: args})
+				_, err = runner.ParseCmd(args)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			if code := cliutil.ExitCode(err); code != cliutil.ExitOptionsParseError {
+				t.Errorf("ExitCode() = %d, want %d", code, cliutil.ExitOptionsParseError)
+			}
+		})
+	}
+	if _, _, err := cliutil.ParseGlobalOptions([]string{"app", "--config", "missing.toml", "serve"}); !errors.Is(err, cliutil.ErrLoadingConfigFile) {
+		t.Errorf("missing --config file error = %v, want %v", err, cliutil.ErrLoadingConfigFile)
+	}
+}
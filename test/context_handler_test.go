@@ -0,0 +1,126 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+// waitCmd signals started then blocks until its Context is done, or
+// returns at once when fast
+type waitCmd struct {
+	*cliutil.CmdBase
+	fast    bool
+	ctx     context.Context
+	started chan struct{}
+}
+
+func (c *waitCmd) HandleContext(ctx context.Context) error {
+	c.ctx = ctx
+	if c.fast {
+		return nil
+	}
+	close(c.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func registerWaitCmd(t *testing.T) *waitCmd {
+	t.Helper()
+	cmd := &waitCmd{}
+	cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        "wait",
+		Description: "Wait until canceled",
+	})
+	useTestCmds(t, cmd)
+	return cmd
+}
+
+// runWait runs args and returns RunCmd's error
+func runWait(t *testing.T, args ...string) error {
+	t.Helper()
+	_, err := runTestCmd(t, nil, args...)
+	return err
+}
+
+func TestContextHandler_CanceledOnTimeout(t *testing.T) {
+	var err error
+
+	cmd := registerWaitCmd(t)
+	cmd.started = make(chan struct{})
+	fc := clitest.NewFakeClock(time.Time{})
+	clitest.UseClock(t, fc)
+
+	done := make(chan struct{})
+	go func() {
+		err = runWait(t, "--timeout=30", "wait")
+		close(done)
+	}()
+	<-cmd.started
+	fc.Advance(29 * time.Second)
+	if cmd.ctx.Err() != nil {
+		t.Fatalf("Context canceled before the timeout: %v", cmd.ctx.Err())
+	}
+	fc.Advance(time.Second)
+	<-done
+
+	if !errors.Is(err, cliutil.ErrCommandTimedOut) {
+		t.Fatalf("RunCmd() error = %v, want ErrCommandTimedOut", err)
+	}
+	if code := cliutil.ExitCode(err); code != cliutil.ExitKnownRuntimeError {
+		t.Errorf("ExitCode() = %d, want %d", code, cliutil.ExitKnownRuntimeError)
+	}
+	if code := cliutil.ErrorCodeOf(err); code != "CLI403" {
+		t.Errorf("ErrorCodeOf() = %q, want %q", code, "CLI403")
+	}
+}
+
+func TestContextHandler_TimesOutUnderCancelableContext(t *testing.T) {
+	var err error
+
+	cmd := registerWaitCmd(t)
+	cmd.started = make(chan struct{})
+	fc := clitest.NewFakeClock(time.Time{})
+	clitest.UseClock(t, fc)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	done := make(chan struct{})
+	go func() {
+		_, err = runTestCmd(t, func(runner *cliutil.CmdRunner) {
+			runner.Args.Context = ctx
+		}, "--timeout=30", "wait")
+		close(done)
+	}()
+	<-cmd.started
+	fc.Advance(30 * time.Second)
+	<-done
+
+	if !errors.Is(err, cliutil.ErrCommandTimedOut) {
+		t.Fatalf("RunCmd() error = %v, want ErrCommandTimedOut", err)
+	}
+}
+
+func TestContextHandler_CanceledOnReturn(t *testing.T) {
+	cmd := registerWaitCmd(t)
+	cmd.fast = true
+	t.Cleanup(func() { cmd.fast = false })
+
+	err := runWait(t, "--timeout=0", "wait")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if _, ok := cmd.ctx.Deadline(); ok {
+		t.Error("Context has a deadline with no timeout")
+	}
+	if cmd.ctx.Err() == nil {
+		t.Error("Context was not canceled after HandleContext returned")
+	}
+	if cmd.Context != cmd.ctx {
+		t.Error("CmdBase.Context is not the Context passed to HandleContext")
+	}
+}
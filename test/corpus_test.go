@@ -44,10 +44,11 @@ func TestFuzzCorpus(t *testing.T) {
 		dryRun := parts[3] == "true"
 		force := parts[4] == "true"
 
+		timeoutSecs := time.Duration(timeout) * time.Second
 		_, _ = cliutil.NewGlobalOptions(cliutil.GlobalOptionsArgs{
 			Quiet:     &quiet,
 			Verbosity: &verbosity,
-			Timeout:   &timeout,
+			Timeout:   &timeoutSecs,
 			DryRun:    &dryRun,
 			Force:     &force,
 		})
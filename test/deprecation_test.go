@@ -0,0 +1,155 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// deprecatedCmd records whether its handler ran
+type deprecatedCmd struct {
+	*cliutil.CmdBase
+	old string
+	ran bool
+}
+
+func (c *deprecatedCmd) Handle() error {
+	c.ran = true
+	return nil
+}
+
+// registerDeprecatedCmds registers "sync" with a deprecated --old flag and a
+// --src flag replaced by --source, a deprecated "fetch" command, and a "legacy" command using CmdArgs.FlagDefs
+func registerDeprecatedCmds(t *testing.T) (sync, fetch, legacy *deprecatedCmd) {
+	t.Helper()
+	sync, fetch, legacy = &deprecatedCmd{}, &deprecatedCmd{}, &deprecatedCmd{}
+	sync.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name: "sync",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "sync",
+			FlagDefs: []cliutil.FlagDef{{
+				Name:       "old",
+				Usage:      "Old name for --source",
+				Default:    "",
+				String:     &sync.old,
+				Deprecated: "use --source",
+			}, {
+				Name:    "source",
+				Usage:   "Where to sync from",
+				Default: "",
+				String:  new(string),
+			}, {
+				Name:       "src",
+				Usage:      "Short name for --source",
+				Default:    "",
+				String:     new(string),
+				Deprecated: "ambiguous with --srcdir",
+				ReplacedBy: "source",
+			}},
+		}},
+	})
+	fetch.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:       "fetch",
+		Deprecated: "use sync",
+	})
+	legacy.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name: "legacy",
+		FlagDefs: []cliutil.FlagDef{{
+			Name:    "unused",
+			Default: "",
+			String:  new(string),
+		}},
+	})
+	useTestCmds(t, sync, fetch, legacy)
+	return sync, fetch, legacy
+}
+
+// runWithStderr runs args and returns the runner's stderr
+func runWithStderr(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var writer interface{ GetStderr() string }
+	_, err := runTestCmd(t, func(runner *cliutil.CmdRunner) {
+		writer = runner.Args.Writer.(interface{ GetStderr() string })
+	}, args...)
+	return writer.GetStderr(), err
+}
+
+func TestDeprecations_WarnUnlessStrict(t *testing.T) {
+	sync, fetch, legacy := registerDeprecatedCmds(t)
+
+	stderr, err := runWithStderr(t, "sync", "--old=x")
+	if err != nil {
+		t.Fatalf("Expected a deprecated flag only to warn, got %v", err)
+	}
+	if want := "flag --old is deprecated: use --source"; !strings.Contains(stderr, want) {
+		t.Errorf("Expected %q, got %q", want, stderr)
+	}
+	stderr, err = runWithStderr(t, "fetch")
+	if err != nil || !fetch.ran {
+		t.Fatalf("Expected a deprecated command only to warn, got %v", err)
+	}
+	if want := "command 'fetch' is deprecated: use sync"; !strings.Contains(stderr, want) {
+		t.Errorf("Expected %q, got %q", want, stderr)
+	}
+	if _, err = runWithStderr(t, "legacy"); err != nil {
+		t.Errorf("Expected legacy FlagDefs not to be reported without --strict, got %v", err)
+	}
+
+	sync.ran, fetch.ran, legacy.ran = false, false, false
+	for _, args := range [][]string{
+		{"--strict", "sync", "--old=x"},
+		{"--strict", "fetch"},
+		{"--strict", "legacy"},
+	} {
+		_, err = runWithStderr(t, args...)
+		if !errors.Is(err, cliutil.ErrDeprecatedUsage) {
+			t.Errorf("%v: expected ErrDeprecatedUsage, got %v", args, err)
+		}
+		if code := cliutil.ExitCode(err); code != cliutil.ExitDeprecatedUsage {
+			t.Errorf("%v: expected exit code %d, got %d", args, cliutil.ExitDeprecatedUsage, code)
+		}
+	}
+	if sync.ran || fetch.ran || legacy.ran {
+		t.Errorf("Expected handlers not to run under --strict")
+	}
+
+	if _, err = runWithStderr(t, "--strict", "sync"); err != nil {
+		t.Errorf("Expected no error without deprecated usage, got %v", err)
+	}
+}
+
+func TestDeprecations_SetStrict(t *testing.T) {
+	registerDeprecatedCmds(t)
+	prev := cliutil.SetStrict(true)
+	t.Cleanup(func() { cliutil.SetStrict(prev) })
+
+	_, err := runWithStderr(t, "fetch")
+	if !errors.Is(err, cliutil.ErrDeprecatedUsage) {
+		t.Errorf("Expected SetStrict(true) to act like --strict, got %v", err)
+	}
+}
+
+func TestDeprecations_ReplacedBy(t *testing.T) {
+	registerDeprecatedCmds(t)
+
+	stderr, err := runWithStderr(t, "sync", "--src=x")
+	if err != nil {
+		t.Fatalf("Expected a replaced flag only to warn, got %v", err)
+	}
+	want := "flag --src is deprecated: ambiguous with --srcdir; use --source instead"
+	if !strings.Contains(stderr, want) {
+		t.Errorf("Expected %q, got %q", want, stderr)
+	}
+
+	var row cliutil.FlagRow
+	for _, fr := range cliutil.BuildCmdUsage(cliutil.GetExactCommand("sync")).FlagRows {
+		if fr.Name == "src" {
+			row = fr
+		}
+	}
+	if !strings.Contains(row.Descr, "[deprecated: ambiguous with --srcdir; use --source instead]") {
+		t.Errorf("src row = %+v, want it marked deprecated", row)
+	}
+}
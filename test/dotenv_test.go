@@ -0,0 +1,94 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// unsetAfter unsets the environment variable name when t ends, since an env
+// file sets variables with os.Setenv rather than t.Setenv
+func unsetAfter(t *testing.T, name string) {
+	t.Helper()
+	t.Cleanup(func() { os.Unsetenv(name) })
+}
+
+func TestEnvFile_FeedsFlagEnvVars(t *testing.T) {
+	registerServeCmd(t, cliutil.ConfigArgs{})
+	unsetAfter(t, serveHostEnv)
+	file := writeConfig(t, "app.env", `
+# Local overrides
+export `+serveHostEnv+`="dotenv.example" # quoted
+CLIUTIL_TEST_DOTENV_RAW='a "raw" value'
+CLIUTIL_TEST_DOTENV_PLAIN=plain value # comment
+`)
+	unsetAfter(t, "CLIUTIL_TEST_DOTENV_RAW")
+	unsetAfter(t, "CLIUTIL_TEST_DOTENV_PLAIN")
+
+	out, err := runTestCmd(t, nil, "--env-file", file, "serve")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if out != "dotenv.example:80\n" {
+		t.Errorf("stdout = %q, want the host from the env file", out)
+	}
+	want := map[string]string{
+		"CLIUTIL_TEST_DOTENV_RAW":   `a "raw" value`,
+		"CLIUTIL_TEST_DOTENV_PLAIN": "plain value",
+	}
+	for name, value := range want {
+		if got := os.Getenv(name); got != value {
+			t.Errorf("$%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestEnvFile_EnvironmentWins(t *testing.T) {
+	registerServeCmd(t, cliutil.ConfigArgs{})
+	t.Setenv(serveHostEnv, "env.example")
+	file := writeConfig(t, "app.env", serveHostEnv+"=dotenv.example\n")
+
+	out, err := runTestCmd(t, nil, "--env-file", file, "serve")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if out != "env.example:80\n" {
+		t.Errorf("stdout = %q, want the host from the environment", out)
+	}
+}
+
+func TestEnvFile_DefaultDotEnv(t *testing.T) {
+	registerServeCmd(t, cliutil.ConfigArgs{})
+	unsetAfter(t, serveHostEnv)
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(cliutil.DotEnvFile, []byte(serveHostEnv+"=default.example\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	out, err := runTestCmd(t, nil, "serve")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if out != "default.example:80\n" {
+		t.Errorf("stdout = %q, want the host from %s", out, cliutil.DotEnvFile)
+	}
+}
+
+func TestEnvFile_Errors(t *testing.T) {
+	registerServeCmd(t, cliutil.ConfigArgs{})
+	tests := map[string]string{
+		"missing":  "missing.env",
+		"no value": writeConfig(t, "bad.env", "JUST_A_NAME\n"),
+		"trailing": writeConfig(t, "bad.env", `NAME="value" extra`+"\n"),
+	}
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := cliutil.ParseGlobalOptions([]string{"app", "--env-file", file, "serve"})
+			if !errors.Is(err, cliutil.ErrLoadingEnvFile) {
+				t.Errorf("error = %v, want %v", err, cliutil.ErrLoadingEnvFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/yamlcliutil"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+type fileRecord struct {
+	Name string `json:"name" yaml:"name"`
+	Size int    `json:"size" yaml:"size"`
+}
+
+func (r fileRecord) String() string {
+	return fmt.Sprintf("%s (%d bytes)", r.Name, r.Size)
+}
+
+func emitFiles(t *testing.T, args cliutil.EmitterArgs) (string, error) {
+	t.Helper()
+	writer := clitest.NewBufferedWriter()
+	e := cliutil.NewEmitter(writer, args)
+	for _, r := range []fileRecord{{Name: "a.txt", Size: 5}, {Name: "b.txt", Size: 7}} {
+		if err := e.Emit(r); err != nil {
+			return writer.Stdout(), err
+		}
+	}
+	return writer.Stdout(), nil
+}
+
+func TestEmitter_Formats(t *testing.T) {
+	yamlcliutil.Register()
+	tests := []struct {
+		format cliutil.OutputFormat
+		want   string
+	}{
+		{format: cliutil.TextOutput, want: "a.txt (5 bytes)\nb.txt (7 bytes)\n"},
+		{format: cliutil.JSONOutput, want: `{"name":"a.txt","size":5}` + "\n" + `{"name":"b.txt","size":7}` + "\n"},
+		{format: cliutil.YAMLOutput, want: "---\nname: a.txt\nsize: 5\n---\nname: b.txt\nsize: 7\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := emitFiles(t, cliutil.EmitterArgs{Format: tt.format})
+			if err != nil {
+				t.Fatalf("Emit() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Emit() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmitter_FollowsOutputFlag(t *testing.T) {
+	yamlcliutil.Register()
+	newTestRunner(t, "--output=yaml")
+	clitest.ResetGlobalOptions(t)
+
+	got, err := emitFiles(t, cliutil.EmitterArgs{})
+	if err != nil {
+		t.Fatalf("Emit() failed: %v", err)
+	}
+	if !strings.HasPrefix(got, "---\nname: a.txt\n") {
+		t.Errorf("Expected YAML for --output=yaml, got %q", got)
+	}
+	if usage := outputUsage(cliutil.GetGlobalFlagSet().FlagDefs); !strings.Contains(usage, "yaml") {
+		t.Errorf("Expected --output usage to list yaml once registered, got %q", usage)
+	}
+}
+
+func TestEmitter_FollowsRunningCLIOutput(t *testing.T) {
+	newTestRunner(t)
+	cli := cliutil.NewCLI()
+	if _, _, err := cli.ParseGlobalOptions([]string{"app", "--output=json", "help"}); err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+
+	args := cliutil.CmdRunnerArgs{Writer: testutil.NewBufferedWriter(), CLI: cli}
+	if got := args.NewEmitter(cliutil.EmitterArgs{}).Format(); got != cliutil.JSONOutput {
+		t.Errorf("Format() = %q, want %q from the running CLI's --output", got, cliutil.JSONOutput)
+	}
+	if got := cliutil.NewEmitter(args.Writer, cliutil.EmitterArgs{}).Format(); got != cliutil.TextOutput {
+		t.Errorf("Format() = %q, want --output given to another CLI not to affect the default CLI", got)
+	}
+}
+
+func outputUsage(fds []cliutil.FlagDef) string {
+	for _, fd := range fds {
+		if fd.Name == "output" {
+			return fd.Usage
+		}
+	}
+	return ""
+}
+
+func TestEmitter_CustomTextAndUnsupportedFormat(t *testing.T) {
+	got, err := emitFiles(t, cliutil.EmitterArgs{
+		Format: cliutil.TextOutput,
+		Text: func(w io.Writer, record any) error {
+			_, err := fmt.Fprintf(w, "- %s\n", record.(fileRecord).Name)
+			return err
+		},
+	})
+	if err != nil || got != "- a.txt\n- b.txt\n" {
+		t.Errorf("Emit() wrote %q, %v; want custom text", got, err)
+	}
+
+	_, err = emitFiles(t, cliutil.EmitterArgs{Format: "xml"})
+	if !errors.Is(err, cliutil.ErrUnsupportedOutputFormat) {
+		t.Errorf("Expected ErrUnsupportedOutputFormat, got %v", err)
+	}
+}
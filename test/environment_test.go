@@ -0,0 +1,100 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+// ciEnvs are the variables cliutil.IsCI checks
+var ciEnvs = []string{
+	"CI",
+	"CONTINUOUS_INTEGRATION",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"BUILDKITE",
+	"TF_BUILD",
+	"CIRCLECI",
+	"TRAVIS",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"CODEBUILD_BUILD_ID",
+}
+
+// clearCIEnv unsets the CI variables for the duration of the test
+func clearCIEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range ciEnvs {
+		t.Setenv(env, "")
+	}
+}
+
+func TestEnvironment_DetectsFromEnv(t *testing.T) {
+	clearCIEnv(t)
+	tests := []struct {
+		shell string
+		want  cliutil.Shell
+	}{
+		{shell: "/bin/bash", want: cliutil.BashShell},
+		{shell: "/usr/local/bin/zsh", want: cliutil.ZshShell},
+		{shell: "/opt/homebrew/bin/fish", want: cliutil.FishShell},
+		{shell: "/usr/bin/pwsh", want: cliutil.PowerShellShell},
+		{shell: "/bin/tcsh", want: ""},
+		{shell: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Setenv("SHELL", tt.shell)
+		if got := cliutil.DetectShell(); got != tt.want {
+			t.Errorf("DetectShell() with SHELL=%q = %q, want %q", tt.shell, got, tt.want)
+		}
+	}
+
+	if cliutil.IsCI() {
+		t.Errorf("Expected IsCI() false with no CI variables set")
+	}
+	t.Setenv("BUILD_NUMBER", "42")
+	t.Setenv("RUN_ID", "7")
+	if cliutil.IsCI() {
+		t.Errorf("Expected IsCI() false with only BUILD_NUMBER and RUN_ID set")
+	}
+	t.Setenv("GITHUB_ACTIONS", "true")
+	if !cliutil.IsCI() {
+		t.Errorf("Expected IsCI() true with GITHUB_ACTIONS set")
+	}
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	if cliutil.IsInteractive() {
+		t.Errorf("Expected IsInteractive() false under CI")
+	}
+}
+
+func TestEnvironment_FakeOverridesDetection(t *testing.T) {
+	t.Setenv("CI", "true")
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{
+		Shell:       cliutil.ZshShell,
+		Interactive: true,
+	}))
+	if got := cliutil.DetectShell(); got != cliutil.ZshShell {
+		t.Errorf("DetectShell() = %q, want zsh", got)
+	}
+	if cliutil.IsCI() || !cliutil.IsInteractive() {
+		t.Errorf("Expected the fake's answers, got IsCI()=%t IsInteractive()=%t", cliutil.IsCI(), cliutil.IsInteractive())
+	}
+
+	newTestRunner(t)
+	enableTestBuiltins(t)
+	out, err := runBuiltin(t, "completion")
+	if err != nil {
+		t.Fatalf("completion without a shell failed: %v", err)
+	}
+	if !strings.HasPrefix(out, "#compdef") {
+		t.Errorf("Expected a zsh script for the detected shell, got:\n%s", out)
+	}
+
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	_, err = runBuiltin(t, "completion")
+	if cliutil.ErrClassOf(err) != cliutil.UsageErr {
+		t.Errorf("Expected a usage error when the shell is unknown, got %v", err)
+	}
+}
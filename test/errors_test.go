@@ -0,0 +1,245 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+var errTest = errors.New("test failure")
+
+func TestWithSuggestion_ReportError(t *testing.T) {
+	writer := testutil.NewBufferedWriter()
+
+	err := cliutil.WithSuggestion(errTest, "try running 'app login' first")
+	if !errors.Is(err, errTest) {
+		t.Error("WithSuggestion() should preserve the wrapped error")
+	}
+
+	cliutil.ReportError(writer, err)
+	if !writer.ContainsStderr("Error: test failure") {
+		t.Errorf("Expected error message in stderr, got: %q", writer.GetStderr())
+	}
+	if !writer.ContainsStderr("hint: try running 'app login' first") {
+		t.Errorf("Expected suggestion beneath error, got: %q", writer.GetStderr())
+	}
+}
+
+func TestSuggestions_JoinedErrors(t *testing.T) {
+	err := errors.Join(
+		cliutil.WithSuggestion(errTest, "first"),
+		cliutil.WithSuggestion(errTest, "second"),
+		cliutil.WithSuggestion(errTest, "first"),
+	)
+	got := cliutil.Suggestions(err)
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("Expected [first second], got: %v", got)
+	}
+}
+
+func TestReportError_OmitUserNotify(t *testing.T) {
+	writer := testutil.NewBufferedWriter()
+
+	err := cliutil.WithSuggestion(cliutil.NewErr(cliutil.ErrOmitUserNotify, errTest), "check the docs")
+	cliutil.ReportError(writer, err)
+	if writer.ContainsStderr("Error:") {
+		t.Errorf("Expected error text to be omitted, got: %q", writer.GetStderr())
+	}
+	if !writer.ContainsStderr("hint: check the docs") {
+		t.Errorf("Expected suggestion to still be shown, got: %q", writer.GetStderr())
+	}
+}
+
+func TestExitCode_Registry(t *testing.T) {
+	errAuth := errors.New("auth failed")
+	cliutil.RegisterExitCode(errAuth, 41)
+
+	if code := cliutil.ExitCode(nil); code != cliutil.ExitSuccess {
+		t.Errorf("Expected ExitSuccess for nil error, got %d", code)
+	}
+	if code := cliutil.ExitCode(cliutil.NewErr(errAuth, "user", "bob")); code != 41 {
+		t.Errorf("Expected 41 for registered sentinel, got %d", code)
+	}
+	if code := cliutil.ExitCode(cliutil.NewErr(cliutil.ErrUnknownCommand)); code != cliutil.ExitOptionsParseError {
+		t.Errorf("Expected ExitOptionsParseError for unknown command, got %d", code)
+	}
+	if code := cliutil.ExitCode(errTest); code != cliutil.ExitUnknownRuntimeError {
+		t.Errorf("Expected ExitUnknownRuntimeError for unregistered error, got %d", code)
+	}
+}
+
+func TestReportError_JSONOutput(t *testing.T) {
+	_, _, err := cliutil.ParseGlobalOptions([]string{"app", "--output=json", "help"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _, _ = cliutil.ParseGlobalOptions([]string{"app", "--output=text", "help"})
+	})
+
+	writer := testutil.NewBufferedWriter()
+	err = cliutil.WithSuggestion(cliutil.NewErr(errTest, "user", "bob"), "log in first")
+	cliutil.ReportError(writer, err)
+
+	var report cliutil.ErrorReport
+	if jsonErr := json.Unmarshal([]byte(writer.GetStderr()), &report); jsonErr != nil {
+		t.Fatalf("Expected JSON on stderr, got %q: %v", writer.GetStderr(), jsonErr)
+	}
+	if report.Code != cliutil.ExitUnknownRuntimeError {
+		t.Errorf("Expected code %d, got %d", cliutil.ExitUnknownRuntimeError, report.Code)
+	}
+	if report.Suggestion != "log in first" {
+		t.Errorf("Expected suggestion 'log in first', got %q", report.Suggestion)
+	}
+	if report.Details["user"] != "bob" {
+		t.Errorf("Expected details user=bob, got %v", report.Details)
+	}
+}
+
+func TestRegisterCommand_RejectsOwnOutputFlag(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	err := cliutil.RegisterCommand(&parseTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name: "export",
+			FlagSets: []*cliutil.FlagSet{{
+				Name: "export",
+				FlagDefs: []cliutil.FlagDef{
+					{Name: "output", Usage: "File to export to", String: new(string)},
+				},
+			}},
+		}),
+	})
+	if !errors.Is(err, cliutil.ErrCommandRegistrationFailed) {
+		t.Fatalf("Expected ErrCommandRegistrationFailed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "--output") {
+		t.Errorf("Expected the error to name --output, got %v", err)
+	}
+}
+
+func TestFindErrValue_DeepChain(t *testing.T) {
+	inner := cliutil.NewErr(errTest, "path", "/tmp/x")
+	outer := cliutil.NewErr(cliutil.ErrCommandNotFound, cliutil.NewKV("command", "list"), inner)
+
+	path, ok := cliutil.FindErrValue[string](outer, "path")
+	if !ok || path != "/tmp/x" {
+		t.Errorf("Expected path=/tmp/x from inner entry, got %q (found=%v)", path, ok)
+	}
+	if _, ok = cliutil.FindErrValue[int](outer, "command"); ok {
+		t.Error("FindErrValue() should not match a value of the wrong type")
+	}
+	if !cliutil.HasErrKey(outer, "command") {
+		t.Error("HasErrKey() should find a key passed via NewKV()")
+	}
+	if len(cliutil.AllErrMeta(outer)) != 2 {
+		t.Errorf("Expected 2 metadata pairs, got %v", cliutil.AllErrMeta(outer))
+	}
+}
+
+func TestMessageCatalog_LocalizedSentinels(t *testing.T) {
+	cliutil.RegisterMessages("de", map[cliutil.MessageID]string{
+		cliutil.MsgUnknownCommand: "unbekannter Befehl",
+	})
+	prev := cliutil.Language()
+	cliutil.SetLanguage("de_AT")
+	t.Cleanup(func() { cliutil.SetLanguage(prev) })
+
+	if got := cliutil.ErrUnknownCommand.Error(); got != "unbekannter Befehl" {
+		t.Errorf("Expected localized message, got %q", got)
+	}
+	if got := cliutil.ErrCommandNotFound.Error(); got != "command not found" {
+		t.Errorf("Expected English fallback, got %q", got)
+	}
+	if !errors.Is(cliutil.NewErr(cliutil.ErrFlagRequired), dt.ErrFlagIsRequired) {
+		t.Error("ErrFlagRequired should match dt.ErrFlagIsRequired")
+	}
+}
+
+// errQuota is registered once, since the error code table is global
+var errQuota = errors.New("quota exceeded")
+
+func init() {
+	cliutil.RegisterErrorCode("APP501", errQuota, "Quota exceeded")
+}
+
+func TestErrorCodeOf_MostSpecific(t *testing.T) {
+	runner, args, _ := newTestRunner(t, "parsetest", "--count=1")
+	_, err := runner.ParseCmd(args)
+	if code := cliutil.ErrorCodeOf(err); code != "CLI302" {
+		t.Errorf("Expected CLI302 for missing argument, got %q (err: %v)", code, err)
+	}
+
+	if code := cliutil.ErrorCodeOf(cliutil.NewErr(errQuota, cliutil.ErrShowUsage)); code != "APP501" {
+		t.Errorf("Expected application code to take precedence, got %q", code)
+	}
+	// Registering the same pair again, as a second test run does, is harmless
+	cliutil.RegisterErrorCode("APP501", errQuota, "Quota exceeded")
+
+	catalog := cliutil.ErrorCatalog()
+	for i := 1; i < len(catalog); i++ {
+		if catalog[i-1].Code > catalog[i].Code {
+			t.Fatalf("Expected ErrorCatalog() sorted by code, got %s before %s", catalog[i-1].Code, catalog[i].Code)
+		}
+	}
+}
+
+func TestErrClass_ReportAndExitCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		class      cliutil.ErrClass
+		exitCode   int
+		wantStderr string
+	}{
+		{
+			name:     "silent",
+			err:      cliutil.WithErrClass(errTest, cliutil.SilentErr),
+			class:    cliutil.SilentErr,
+			exitCode: cliutil.ExitUnknownRuntimeError,
+		},
+		{
+			name:     "notified",
+			err:      cliutil.WithErrClass(errTest, cliutil.NotifiedErr),
+			class:    cliutil.NotifiedErr,
+			exitCode: cliutil.ExitUnknownRuntimeError,
+		},
+		{
+			name:       "retryable",
+			err:        cliutil.WithErrClass(errTest, cliutil.RetryableErr),
+			class:      cliutil.RetryableErr,
+			exitCode:   cliutil.ExitKnownRuntimeError,
+			wantStderr: "retrying may succeed",
+		},
+		{
+			name:       "usage",
+			err:        cliutil.WithErrClass(errTest, cliutil.UsageErr),
+			class:      cliutil.UsageErr,
+			exitCode:   cliutil.ExitOptionsParseError,
+			wantStderr: "test failure",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _, writer := newTestRunner(t)
+			if class := cliutil.ErrClassOf(tt.err); class != tt.class {
+				t.Errorf("Expected class %v, got %v", tt.class, class)
+			}
+			if code := runner.HandleErr(tt.err); code != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, code)
+			}
+			switch {
+			case tt.wantStderr == "" && writer.GetStderr() != "":
+				t.Errorf("Expected no output, got: %q", writer.GetStderr())
+			case !writer.ContainsStderr(tt.wantStderr):
+				t.Errorf("Expected stderr to contain %q, got: %q", tt.wantStderr, writer.GetStderr())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestVerifyExamples(t *testing.T) {
+	newTestRunner(t)
+	clitest.VerifyExamples(t, clitest.ExamplesArgs{
+		Placeholders: map[string]string{"name": "widget"},
+	})
+}
+
+func TestCheckExamples_ReportsStaleExamples(t *testing.T) {
+	useTestCmds(t, &throwawayCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "stale",
+			Description: "Command with outdated examples",
+			Examples: []cliutil.Example{
+				{Descr: "Renamed flag", Cmd: "app stale --old-flag=1"},
+				{Descr: "Removed command", Cmd: "app help gone"},
+				{Descr: "Quoted arg", Cmd: `app stale "two words"`},
+			},
+		}),
+	})
+
+	problems := clitest.CheckExamples(clitest.ExamplesArgs{
+		Placeholders: map[string]string{"name": "widget"},
+	})
+	got := make(map[string]error)
+	for _, p := range problems {
+		got[p.Example.Descr] = p.Err
+	}
+	if !errors.Is(got["Renamed flag"], cliutil.ErrUnknownFlags) {
+		t.Errorf("Expected the renamed flag example to fail with ErrUnknownFlags, got %v", got["Renamed flag"])
+	}
+	if !errors.Is(got["Removed command"], cliutil.ErrUnknownCommand) {
+		t.Errorf("Expected the removed command example to fail with ErrUnknownCommand, got %v", got["Removed command"])
+	}
+	if len(problems) != 2 {
+		t.Errorf("Expected 2 problems, got %d: %v", len(problems), problems)
+	}
+}
@@ -0,0 +1,20 @@
+package test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestParseGlobalOptions_BoolFlagKeepsNextArg(t *testing.T) {
+	runner, args, _ := newTestRunner(t, "--dry-run", "parsetest", "x")
+	clitest.ResetGlobalOptions(t)
+
+	if !slices.Equal(args, []string{"parsetest", "x"}) {
+		t.Errorf("Expected the command after a bool flag to be kept, got %v", args)
+	}
+	if !runner.Args.Options.DryRun() {
+		t.Error("Expected --dry-run to be set")
+	}
+}
@@ -0,0 +1,258 @@
+package test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// syntaxOpts receives the flags of the command registered by registerSyntaxCmd
+type syntaxOpts struct {
+	all   bool
+	brief bool
+	count int
+	name  string
+}
+
+// registerSyntaxCmd registers a "syntax" command with bool shortcuts -a and
+// -b, an int shortcut -n, and a string shortcut -N
+func registerSyntaxCmd(t *testing.T) *syntaxOpts {
+	t.Helper()
+	opts := &syntaxOpts{}
+	useTestCmds(t, &parseTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "syntax",
+			Description: "Exercise flag syntax",
+			FlagSets: []*cliutil.FlagSet{{
+				Name: "syntax",
+				FlagDefs: []cliutil.FlagDef{
+					{Name: "all", Shortcut: 'a', Usage: "All", Bool: &opts.all, Default: true},
+					{Name: "brief", Shortcut: 'b', Usage: "Brief", Bool: &opts.brief},
+					{Name: "count", Shortcut: 'n', Usage: "Count", Int: &opts.count},
+					{Name: "name", Shortcut: 'N', Usage: "Name", String: &opts.name},
+				},
+			}},
+		}),
+	})
+	return opts
+}
+
+// usePFlagSyntax selects PFlagSyntax for the rest of the test
+func usePFlagSyntax(t *testing.T) {
+	prev := cliutil.SetFlagSyntax(cliutil.PFlagSyntax)
+	t.Cleanup(func() { cliutil.SetFlagSyntax(prev) })
+}
+
+func TestPFlagSyntax_ParsesLikePFlag(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		want  syntaxOpts
+		quiet bool
+	}{
+		{name: "bundled bools", args: []string{"-b"}, want: syntaxOpts{all: true, brief: true}},
+		{name: "bundled with global", args: []string{"-qb"}, want: syntaxOpts{all: true, brief: true}, quiet: true},
+		{name: "bundle ending in value", args: []string{"-bn3"}, want: syntaxOpts{all: true, brief: true, count: 3}},
+		{name: "attached value", args: []string{"-n3"}, want: syntaxOpts{all: true, count: 3}},
+		{name: "equals value", args: []string{"-n=3"}, want: syntaxOpts{all: true, count: 3}},
+		{name: "separate value", args: []string{"-n", "3"}, want: syntaxOpts{all: true, count: 3}},
+		{name: "long separate value", args: []string{"--count", "3"}, want: syntaxOpts{all: true, count: 3}},
+		{name: "long equals value", args: []string{"--count=3"}, want: syntaxOpts{all: true, count: 3}},
+		{name: "bool shortcut off", args: []string{"-a=false"}, want: syntaxOpts{}},
+		{name: "bool does not take value", args: []string{"-b", "x"}, want: syntaxOpts{all: true, brief: true}},
+		{name: "string value with dash letters", args: []string{"-Nabc"}, want: syntaxOpts{all: true, name: "abc"}},
+		{name: "attached string value", args: []string{"-Nfile.txt"}, want: syntaxOpts{all: true, name: "file.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePFlagSyntax(t)
+			opts := registerSyntaxCmd(t)
+			runner, args, _ := newTestRunner(t, append([]string{"syntax"}, tt.args...)...)
+
+			_, err := runner.ParseCmd(args)
+			if err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if *opts != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *opts)
+			}
+			if got := cliutil.GetGlobalOptions().Quiet(); got != tt.quiet {
+				t.Errorf("Expected Quiet() %t, got %t", tt.quiet, got)
+			}
+		})
+	}
+}
+
+func TestPFlagSyntax_RejectsSingleDashLongNames(t *testing.T) {
+	usePFlagSyntax(t)
+	registerSyntaxCmd(t)
+	runner, args, _ := newTestRunner(t, "syntax", "-count=3")
+
+	_, err := runner.ParseCmd(args)
+	if err == nil {
+		t.Fatal("Expected -count to be rejected as an unknown shortcut bundle")
+	}
+}
+
+func TestPFlagSyntax_RejectsGlobalShortcutAfterCommandShortcut(t *testing.T) {
+	usePFlagSyntax(t)
+	registerSyntaxCmd(t)
+	runner, args, _ := newTestRunner(t, "syntax", "-bq")
+
+	_, err := runner.ParseCmd(args)
+	if err == nil {
+		t.Fatal("Expected -bq to be rejected since global shortcuts are parsed first")
+	}
+}
+
+func TestGoFlagSyntax_ParsesBundles(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want syntaxOpts
+	}{
+		{name: "bundled bools", args: []string{"-ab"}, want: syntaxOpts{all: true, brief: true}},
+		{name: "bundle ending in value", args: []string{"-bn3"}, want: syntaxOpts{all: true, brief: true, count: 3}},
+		{name: "bundle before value", args: []string{"-bn", "3"}, want: syntaxOpts{all: true, brief: true, count: 3}},
+		{name: "attached value", args: []string{"-n3"}, want: syntaxOpts{all: true, count: 3}},
+		{name: "attached negative value", args: []string{"-n-1"}, want: syntaxOpts{all: true, count: -1}},
+		{name: "attached string value", args: []string{"-Nfile.txt"}, want: syntaxOpts{all: true, name: "file.txt"}},
+		{name: "equals value", args: []string{"-n=3"}, want: syntaxOpts{all: true, count: 3}},
+		{name: "single-dash long name", args: []string{"-count=3"}, want: syntaxOpts{all: true, count: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := registerSyntaxCmd(t)
+			runner, args, _ := newTestRunner(t, append([]string{"syntax"}, tt.args...)...)
+
+			_, err := runner.ParseCmd(args)
+			if err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if *opts != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *opts)
+			}
+		})
+	}
+}
+
+func TestGoFlagSyntax_ParsesGlobalBundles(t *testing.T) {
+	registerSyntaxCmd(t)
+	_, _, _ = newTestRunner(t, "-qf", "-vt", "30", "syntax")
+
+	opts := cliutil.GetGlobalOptions()
+	if !opts.Quiet() || !opts.Force() || opts.Verbosity() != 1 || opts.Timeout() != 30*time.Second {
+		t.Errorf("Expected -qf -vt 30 to set quiet, force, verbosity 1, and a 30s timeout, got %v, %v, %v, %v",
+			opts.Quiet(), opts.Force(), opts.Verbosity(), opts.Timeout())
+	}
+}
+
+func TestGoFlagSyntax_RejectsBundlesAcrossFlagSets(t *testing.T) {
+	registerSyntaxCmd(t)
+	runner, args, _ := newTestRunner(t, "syntax", "-qb")
+
+	_, err := runner.ParseCmd(args)
+	if err == nil {
+		t.Fatal("Expected -qb to be rejected since its shortcuts belong to different FlagSets")
+	}
+}
+
+func TestFlagSet_HasFlagTracksFlagDefs(t *testing.T) {
+	fs := &cliutil.FlagSet{
+		Name: "index",
+		FlagDefs: []cliutil.FlagDef{
+			{Name: "count", Shortcut: 'n', Usage: "Count", Int: new(int)},
+		},
+	}
+	for _, name := range []string{"count", "n"} {
+		if !fs.HasFlag(name) {
+			t.Errorf("Expected %s to be a flag", name)
+		}
+	}
+	if fs.HasFlag("name") || fs.HasFlag("c") {
+		t.Error("Expected only defined names and shortcuts to be flags")
+	}
+
+	fs.FlagDefs = append(fs.FlagDefs, cliutil.FlagDef{Name: "name", Usage: "Name", String: new(string)})
+	if !fs.HasFlag("name") {
+		t.Error("Expected an appended FlagDef to be found")
+	}
+	args, err := fs.Parse([]string{"-n", "3", "--name=x", "rest"})
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(args) != 1 || args[0] != "rest" {
+		t.Errorf("Expected [rest], got %v", args)
+	}
+}
+
+func TestFlagSet_ParseStopsAtTerminator(t *testing.T) {
+	var brief bool
+	fs := &cliutil.FlagSet{
+		Name: "terminator",
+		FlagDefs: []cliutil.FlagDef{
+			{Name: "brief", Shortcut: 'b', Usage: "Brief", Bool: &brief},
+		},
+	}
+	args, err := fs.Parse([]string{"-b", "x", "--", "-b", "--brief=false"})
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if !brief {
+		t.Error("Expected -b before -- to be parsed")
+	}
+	want := []string{"x", "--", "-b", "--brief=false"}
+	if !slices.Equal(args, want) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+}
+
+func TestParseCmd_TreatsArgsAfterTerminatorAsPositional(t *testing.T) {
+	tests := []struct {
+		name   string
+		syntax cliutil.FlagSyntax
+	}{
+		{name: "go syntax", syntax: cliutil.GoFlagSyntax},
+		{name: "pflag syntax", syntax: cliutil.PFlagSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := cliutil.SetFlagSyntax(tt.syntax)
+			t.Cleanup(func() { cliutil.SetFlagSyntax(prev) })
+			var brief bool
+			var target string
+			useTestCmds(t, &parseTestCmd{
+				CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+					Name:        "wrap",
+					Description: "Forward an argument",
+					FlagSets: []*cliutil.FlagSet{{
+						Name: "wrap",
+						FlagDefs: []cliutil.FlagDef{
+							{Name: "brief", Shortcut: 'b', Usage: "Brief", Bool: &brief},
+						},
+					}},
+					ArgDefs: []*cliutil.ArgDef{{
+						Name:     "target",
+						Usage:    "Argument to forward",
+						Required: true,
+						String:   &target,
+					}},
+				}),
+			})
+			runner, args, _ := newTestRunner(t, "wrap", "--", "-b")
+
+			_, err := runner.ParseCmd(args)
+			if err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if brief {
+				t.Error("Expected -b after -- not to set brief")
+			}
+			if target != "-b" {
+				t.Errorf("Expected target %q, got %q", "-b", target)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package test
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// typesOpts receives the flags of the command registered by registerTypesCmd
+type typesOpts struct {
+	interval time.Duration
+	ratio    float64
+	tags     []string
+	ports    []int
+	labels   map[string]string
+	notes    map[string]string
+	wrap     bool
+	dir      string
+	dirCalls int // how often the "dir" flag's DefaultFunc ran
+}
+
+// errPortRange is returned by validPort
+var errPortRange = errors.New("port out of range")
+
+// validPort validates one value of the "port" flag
+func validPort(value any) (err error) {
+	if n := value.(int); n < 1 || n > 65535 {
+		err = errPortRange
+	}
+	return err
+}
+
+// registerTypesCmd registers a "types" command with a flag of each type
+// beyond the string, bool, and int ones registerSyntaxCmd covers
+func registerTypesCmd(t *testing.T) *typesOpts {
+	t.Helper()
+	opts := &typesOpts{}
+	useTestCmds(t, &parseTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "types",
+			Description: "Exercise flag types",
+			FlagSets: []*cliutil.FlagSet{{
+				Name: "types",
+				FlagDefs: []cliutil.FlagDef{
+					{Name: "interval", Shortcut: 'i', Usage: "Interval", Duration: &opts.interval, Default: time.Minute},
+					{Name: "ratio", Shortcut: 'r', Usage: "Ratio", Float64: &opts.ratio, Default: 0.5},
+					{Name: "tag", Shortcut: 'g', Usage: "Tag", Strings: &opts.tags, Default: []string{"dev", "ops"}},
+					{Name: "port", Shortcut: 'p', Usage: "Port", Ints: &opts.ports, Default: []int{8080}, ValidationFunc: validPort},
+					{Name: "label", Shortcut: 'l', Usage: "Label", Map: &opts.labels, Default: map[string]string{"env": "dev"}},
+					{Name: "note", Usage: "Note", Map: &opts.notes, UniqueKeys: true},
+					{Name: "wrap", Usage: "Wrap long lines", Bool: &opts.wrap, Default: true},
+					{Name: "dir", Usage: "Directory", String: &opts.dir, DefaultText: "working directory", DefaultFunc: func() any {
+						opts.dirCalls++
+						return "/work"
+					}},
+				},
+			}},
+		}),
+	})
+	return opts
+}
+
+// parseTypes parses args with the command registered by registerTypesCmd
+func parseTypes(t *testing.T, args ...string) error {
+	t.Helper()
+	runner, args, _ := newTestRunner(t, append([]string{"types"}, args...)...)
+	_, err := runner.ParseCmd(args)
+	return err
+}
+
+func TestDurationFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want time.Duration
+	}{
+		{name: "default", want: time.Minute},
+		{name: "duration", args: []string{"--interval=90s"}, want: 90 * time.Second},
+		{name: "shortcut", args: []string{"-i", "5m"}, want: 5 * time.Minute},
+		{name: "seconds", args: []string{"--interval=30"}, want: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := registerTypesCmd(t)
+			if err := parseTypes(t, tt.args...); err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if opts.interval != tt.want {
+				t.Errorf("interval = %v, want %v", opts.interval, tt.want)
+			}
+		})
+	}
+
+	registerTypesCmd(t)
+	if err := parseTypes(t, "--interval=soon"); !errors.Is(err, cliutil.ErrFlagsParsingFailed) {
+		t.Errorf("ParseCmd() error = %v, want %v", err, cliutil.ErrFlagsParsingFailed)
+	}
+}
+
+func TestTimeoutFlag_TakesDuration(t *testing.T) {
+	for arg, want := range map[string]time.Duration{
+		"--timeout=2m": 2 * time.Minute,
+		"--timeout=45": 45 * time.Second,
+	} {
+		opts, _, err := cliutil.ParseGlobalOptions([]string{"app", arg, "help"})
+		if err != nil {
+			t.Fatalf("ParseGlobalOptions(%s) failed: %v", arg, err)
+		}
+		if opts.Timeout() != want {
+			t.Errorf("%s: Timeout() = %v, want %v", arg, opts.Timeout(), want)
+		}
+	}
+	opts, _, err := cliutil.ParseGlobalOptions([]string{"app", "help"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	if opts.Timeout() != cliutil.DefaultTimeout {
+		t.Errorf("Timeout() = %v, want %v", opts.Timeout(), cliutil.DefaultTimeout)
+	}
+}
+
+func TestFloat64Flag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want float64
+	}{
+		{name: "default", want: 0.5},
+		{name: "flag", args: []string{"--ratio=0.75"}, want: 0.75},
+		{name: "shortcut", args: []string{"-r", "2"}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := registerTypesCmd(t)
+			if err := parseTypes(t, tt.args...); err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if opts.ratio != tt.want {
+				t.Errorf("ratio = %v, want %v", opts.ratio, tt.want)
+			}
+		})
+	}
+
+	registerTypesCmd(t)
+	if err := parseTypes(t, "--ratio=most"); !errors.Is(err, cliutil.ErrFlagsParsingFailed) {
+		t.Errorf("ParseCmd() error = %v, want %v", err, cliutil.ErrFlagsParsingFailed)
+	}
+}
+
+func TestStringsFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "default", want: []string{"dev", "ops"}},
+		{name: "once", args: []string{"--tag=a"}, want: []string{"a"}},
+		{name: "repeated", args: []string{"--tag=a", "--tag", "b"}, want: []string{"a", "b"}},
+		{name: "shortcut", args: []string{"-g", "a", "--tag=b", "-g=c"}, want: []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := registerTypesCmd(t)
+			if err := parseTypes(t, tt.args...); err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if !slices.Equal(opts.tags, tt.want) {
+				t.Errorf("tags = %q, want %q", opts.tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringsFlag_HelpAndExamples(t *testing.T) {
+	registerTypesCmd(t)
+	var row cliutil.FlagRow
+	for _, fr := range cliutil.BuildCmdUsage(cliutil.GetExactCommand("types")).FlagRows {
+		if fr.Name == "tag" {
+			row = fr
+		}
+	}
+	if !row.Repeatable || !strings.Contains(row.Descr, "[default=dev,ops] [repeatable]") {
+		t.Errorf("tag row = %+v, want repeatable with default dev,ops", row)
+	}
+
+	args := cliutil.UsageArgs{AppInfo: appinfo.New(appinfo.Args{Name: "app", ExeName: "app"})}
+	var found bool
+	for _, example := range cliutil.BuildUsage(args).Examples {
+		found = found || strings.Contains(example.Cmd, "--tag=dev --tag=ops")
+	}
+	if !found {
+		t.Error("Expected an example giving --tag once per default value")
+	}
+}
+
+func TestIntsFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []int
+	}{
+		{name: "default", want: []int{8080}},
+		{name: "repeated", args: []string{"--port=80", "-p", "443"}, want: []int{80, 443}},
+		{name: "comma-separated", args: []string{"--port=80,443"}, want: []int{80, 443}},
+		{name: "both", args: []string{"--port=80,443", "--port=8443"}, want: []int{80, 443, 8443}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := registerTypesCmd(t)
+			if err := parseTypes(t, tt.args...); err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if !slices.Equal(opts.ports, tt.want) {
+				t.Errorf("ports = %v, want %v", opts.ports, tt.want)
+			}
+		})
+	}
+
+	for arg, want := range map[string]error{
+		"--port=80,http":  cliutil.ErrFlagsParsingFailed,
+		"--port=80,70000": errPortRange,
+	} {
+		t.Run(arg, func(t *testing.T) {
+			registerTypesCmd(t)
+			if err := parseTypes(t, arg); !errors.Is(err, want) {
+				t.Errorf("ParseCmd() error = %v, want %v", err, want)
+			}
+		})
+	}
+}
+
+func TestMapFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want map[string]string
+	}{
+		{name: "default", want: map[string]string{"env": "dev"}},
+		{name: "repeated", args: []string{"--label", "env=prod", "-l", "team=core"}, want: map[string]string{"env": "prod", "team": "core"}},
+		{name: "last wins", args: []string{"--label=env=prod", "--label=env=test"}, want: map[string]string{"env": "test"}},
+		{name: "empty value", args: []string{"--label=env="}, want: map[string]string{"env": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := registerTypesCmd(t)
+			if err := parseTypes(t, tt.args...); err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if !maps.Equal(opts.labels, tt.want) {
+				t.Errorf("labels = %v, want %v", opts.labels, tt.want)
+			}
+		})
+	}
+
+	for name, args := range map[string][]string{
+		"not key=value": {"--label=prod"},
+		"duplicate key": {"--note=a=1", "--note=a=2"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			registerTypesCmd(t)
+			if err := parseTypes(t, args...); !errors.Is(err, cliutil.ErrFlagsParsingFailed) {
+				t.Errorf("ParseCmd() error = %v, want %v", err, cliutil.ErrFlagsParsingFailed)
+			}
+		})
+	}
+
+	t.Run("help", func(t *testing.T) {
+		registerTypesCmd(t)
+		for _, row := range cliutil.BuildCmdUsage(cliutil.GetExactCommand("types")).FlagRows {
+			if row.Name == "label" && row.Flag != "-l, --label key=value" {
+				t.Errorf("Flag = %q, want %q", row.Flag, "-l, --label key=value")
+			}
+		}
+	})
+}
+
+func TestCountFlag_RaisesVerbosity(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want cliutil.Verbosity
+	}{
+		{name: "default", want: cliutil.Verbosity(cliutil.DefaultVerbosity)},
+		{name: "once", args: []string{"-v"}, want: 1},
+		{name: "bundled", args: []string{"-vv"}, want: 2},
+		{name: "repeated", args: []string{"-v", "-vv"}, want: 3},
+		{name: "long and shortcut", args: []string{"--verbosity", "-v"}, want: 2},
+		{name: "equals count", args: []string{"--verbosity=3"}, want: 3},
+		{name: "separate count", args: []string{"-v", "2"}, want: 2},
+	}
+	for _, syntax := range []cliutil.FlagSyntax{cliutil.GoFlagSyntax, cliutil.PFlagSyntax} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				prev := cliutil.SetFlagSyntax(syntax)
+				t.Cleanup(func() { cliutil.SetFlagSyntax(prev) })
+				registerTypesCmd(t)
+				if err := parseTypes(t, tt.args...); err != nil {
+					t.Fatalf("ParseCmd() failed: %v", err)
+				}
+				if got := cliutil.GetGlobalOptions().Verbosity(); got != tt.want {
+					t.Errorf("Verbosity() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+
+	_, _, err := cliutil.ParseGlobalOptions([]string{"app", "-vvvv", "help"})
+	if !errors.Is(err, cliutil.ErrInvalidateVerbosity) {
+		t.Errorf("ParseGlobalOptions() error = %v, want %v", err, cliutil.ErrInvalidateVerbosity)
+	}
+}
+
+func TestBoolFlag_Negated(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{name: "default", want: true},
+		{name: "negated", args: []string{"--no-wrap"}, want: false},
+		{name: "negated false", args: []string{"--no-wrap=false"}, want: true},
+		{name: "last wins", args: []string{"--no-wrap", "--wrap"}, want: true},
+	}
+	for _, syntax := range []cliutil.FlagSyntax{cliutil.GoFlagSyntax, cliutil.PFlagSyntax} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				prev := cliutil.SetFlagSyntax(syntax)
+				t.Cleanup(func() { cliutil.SetFlagSyntax(prev) })
+				opts := registerTypesCmd(t)
+				if err := parseTypes(t, tt.args...); err != nil {
+					t.Fatalf("ParseCmd() failed: %v", err)
+				}
+				if opts.wrap != tt.want {
+					t.Errorf("wrap = %v, want %v", opts.wrap, tt.want)
+				}
+			})
+		}
+	}
+
+	t.Run("global", func(t *testing.T) {
+		registerTypesCmd(t)
+		if err := parseTypes(t, "-q", "--no-quiet"); err != nil {
+			t.Fatalf("ParseCmd() failed: %v", err)
+		}
+		if cliutil.GetGlobalOptions().Quiet() {
+			t.Error("Expected --no-quiet to turn off -q")
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		registerTypesCmd(t)
+		for _, row := range cliutil.BuildCmdUsage(cliutil.GetExactCommand("types")).FlagRows {
+			if row.Name == "wrap" && row.Flag != "--wrap, --no-wrap" {
+				t.Errorf("Flag = %q, want %q", row.Flag, "--wrap, --no-wrap")
+			}
+		}
+	})
+}
+
+func TestFlagDef_DefaultFunc(t *testing.T) {
+	t.Run("help", func(t *testing.T) {
+		opts := registerTypesCmd(t)
+		var row cliutil.FlagRow
+		for _, fr := range cliutil.BuildCmdUsage(cliutil.GetExactCommand("types")).FlagRows {
+			if fr.Name == "dir" {
+				row = fr
+			}
+		}
+		if row.Default != "working directory" || !strings.Contains(row.Descr, "[default=working directory]") {
+			t.Errorf("dir row = %+v, want DefaultText as its default", row)
+		}
+		if opts.dirCalls != 0 {
+			t.Errorf("Expected help not to call DefaultFunc, got %d calls", opts.dirCalls)
+		}
+	})
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "default", want: "/work"},
+		{name: "given", args: []string{"--dir=/tmp"}, want: "/tmp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := registerTypesCmd(t)
+			if err := parseTypes(t, tt.args...); err != nil {
+				t.Fatalf("ParseCmd() failed: %v", err)
+			}
+			if opts.dir != tt.want {
+				t.Errorf("dir = %q, want %q", opts.dir, tt.want)
+			}
+			if opts.dirCalls == 0 {
+				t.Error("Expected parsing to call DefaultFunc")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+// useLocale installs the locale named name for the duration of the test
+func useLocale(t *testing.T, name string) {
+	t.Helper()
+	prev := cliutil.SetLocale(cliutil.ParseLocale(name))
+	t.Cleanup(func() { cliutil.SetLocale(prev) })
+}
+
+func TestFormat_FollowsLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		int    string
+		bytes  string
+	}{
+		{locale: "en_US.UTF-8", int: "-1,234,567", bytes: "1.5 MiB"},
+		{locale: "de_DE.UTF-8", int: "-1.234.567", bytes: "1,5 MiB"},
+		{locale: "de_CH", int: "-1'234'567", bytes: "1.5 MiB"},
+		{locale: "fr-CA", int: "-1\u00a0234\u00a0567", bytes: "1,5 MiB"},
+		{locale: "C", int: "-1234567", bytes: "1.5 MiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			useLocale(t, tt.locale)
+			if got := cliutil.FormatInt(-1234567); got != tt.int {
+				t.Errorf("FormatInt(): expected %q, got %q", tt.int, got)
+			}
+			if got := cliutil.FormatBytes(3 << 19); got != tt.bytes {
+				t.Errorf("FormatBytes(): expected %q, got %q", tt.bytes, got)
+			}
+		})
+	}
+}
+
+func TestFormatBytesAndDuration(t *testing.T) {
+	useLocale(t, "en_US")
+	for n, want := range map[int64]string{0: "0 B", 512: "512 B", 1024: "1.0 KiB", 5 << 30: "5.0 GiB", -2048: "-2.0 KiB"} {
+		if got := cliutil.FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d): expected %q, got %q", n, want, got)
+		}
+	}
+	for d, want := range map[time.Duration]string{
+		450 * time.Millisecond:                 "450ms",
+		3200 * time.Millisecond:                "3.2s",
+		4*time.Minute + 5*time.Second:          "4m 05s",
+		2*time.Hour + 10*time.Minute:           "2h 10m",
+		3*24*time.Hour + 4*time.Hour:           "3d 4h",
+		-(90 * time.Second):                    "-1m 30s",
+		time.Duration(1500) * 24 * time.Hour:   "1,500d 0h",
+		59*time.Minute + 59*time.Second + 1e8:  "59m 59s",
+		23*time.Hour + 59*time.Minute + 29*1e9: "23h 59m",
+	} {
+		if got := cliutil.FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v): expected %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestFormatTimeAgoAndTime(t *testing.T) {
+	clock := clitest.NewFakeClock(time.Time{})
+	clitest.UseClock(t, clock)
+	now := clock.Now()
+	for offset, want := range map[time.Duration]string{
+		-3 * time.Second:     "just now",
+		-5 * time.Minute:     "5 minutes ago",
+		-time.Hour:           "1 hour ago",
+		-50 * 24 * time.Hour: "1 month ago",
+		48 * time.Hour:       "in 2 days",
+	} {
+		if got := cliutil.FormatTimeAgo(now.Add(offset)); got != want {
+			t.Errorf("FormatTimeAgo(%v): expected %q, got %q", offset, want, got)
+		}
+	}
+
+	newTestRunner(t, "--utc", "parsetest")
+	if got := cliutil.FormatTime(time.Date(2026, 1, 2, 15, 4, 5, 0, time.FixedZone("X", 3600))); got != "2026-01-02 14:04:05 UTC" {
+		t.Errorf("Expected UTC with --utc, got %q", got)
+	}
+	newTestRunner(t, "parsetest")
+	if got, want := cliutil.FormatTime(now), now.Local().Format("2006-01-02 15:04:05 MST"); got != want {
+		t.Errorf("Expected local time %q without --utc, got %q", want, got)
+	}
+}
@@ -1,7 +1,9 @@
 package test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mikeschinkel/go-cliutil"
 )
@@ -53,12 +55,121 @@ func FuzzNewGlobalOptions(f *testing.F) {
 
 	f.Fuzz(func(t *testing.T, quiet bool, verbosity, timeout int, dryRun, force bool) {
 		// Just ensure it doesn't panic
+		timeoutSecs := time.Duration(timeout) * time.Second
 		_, _ = cliutil.NewGlobalOptions(cliutil.GlobalOptionsArgs{
 			Quiet:     &quiet,
 			Verbosity: &verbosity,
-			Timeout:   &timeout,
+			Timeout:   &timeoutSecs,
 			DryRun:    &dryRun,
 			Force:     &force,
 		})
 	})
 }
+
+// fuzzTimeout bounds each argv pipeline run so infinite loops surface as failures
+const fuzzTimeout = 2 * time.Second
+
+// splitFuzzArgs turns fuzzer input into an argv slice; NUL separates
+// arguments so the fuzzer can produce empty and space-containing args
+func splitFuzzArgs(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
+
+// runBounded runs fn and fails t if it does not return within fuzzTimeout
+func runBounded(t *testing.T, argv []string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(fuzzTimeout):
+		t.Fatalf("did not return within %v for argv %q", fuzzTimeout, argv)
+	}
+}
+
+// argvSeeds are shared seed inputs for the argument pipeline fuzzers
+var argvSeeds = []string{
+	"",
+	"help",
+	"parsetest\x00--count=2\x00widget",
+	"parsetest\x00--count\x002\x00widget",
+	"--strict\x00warntest",
+	"-v\x003\x00-q\x00parsetest",
+	"--\x00parsetest",
+	"-\x00--\x00---\x00=\x00--=",
+	"--count=\x00--count",
+	"--help\x00parsetest",
+	"parsetest\x00help\x00--bogus=1\x00-x",
+	"--output=json\x00--timeout=-1",
+	"parsetest\x00\x00\x00widget",
+}
+
+// FuzzParseGlobalOptions feeds arbitrary argv through global option parsing,
+// which includes flag-command transformation and --help extraction
+func FuzzParseGlobalOptions(f *testing.F) {
+	for _, seed := range argvSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		argv := splitFuzzArgs(input)
+		runBounded(t, argv, func() {
+			_, _, _ = cliutil.ParseGlobalOptions(append([]string{"app"}, argv...))
+		})
+	})
+}
+
+// FuzzParseCmd feeds arbitrary argv through the full resolution pipeline:
+// global options, longest command-path matching, flag parsing, and argument
+// assignment
+func FuzzParseCmd(f *testing.F) {
+	for _, seed := range argvSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		argv := splitFuzzArgs(input)
+		runner, _, _ := newTestRunner(t)
+		runBounded(t, argv, func() {
+			opts, rest, err := cliutil.ParseGlobalOptions(append([]string{"app"}, argv...))
+			if err != nil {
+				return
+			}
+			runner.Args.Options = testOptions{globalOptions: opts}
+			runner.Args.Args = rest
+			_, _ = runner.ParseCmd(rest)
+		})
+	})
+}
+
+// FuzzFlagSetParse feeds arbitrary argv to a FlagSet with every flag type
+// and shortcuts, which exercises the flag/non-flag classification code
+func FuzzFlagSetParse(f *testing.F) {
+	for _, seed := range argvSeeds {
+		f.Add(seed)
+	}
+	f.Add("-n\x005\x00-b\x00-s\x00x\x00--big=9223372036854775808")
+	f.Fuzz(func(t *testing.T, input string) {
+		var s string
+		var b bool
+		var n int
+		var big int64
+		fs := &cliutil.FlagSet{
+			Name: "fuzz",
+			FlagDefs: []cliutil.FlagDef{
+				{Name: "str", Shortcut: 's', Usage: "A string", String: &s},
+				{Name: "bool", Shortcut: 'b', Usage: "A bool", Bool: &b},
+				{Name: "num", Shortcut: 'n', Usage: "An int", Int: &n},
+				{Name: "big", Usage: "An int64", Int64: &big},
+			},
+		}
+		argv := splitFuzzArgs(input)
+		runBounded(t, argv, func() {
+			_, _ = fs.Parse(argv)
+		})
+	})
+}
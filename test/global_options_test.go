@@ -0,0 +1,46 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestGlobalOptions_UsableAsOptions(t *testing.T) {
+	opts, _, err := cliutil.ParseGlobalOptions([]string{"app", "--force"})
+	clitest.ResetGlobalOptions(t)
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	var options cliutil.Options = opts
+	getter, ok := options.(cliutil.GlobalOptionsGetter)
+	if !ok || getter.GlobalOptions() != opts {
+		t.Fatal("Expected *GlobalOptions to provide itself as GlobalOptions()")
+	}
+	if !getter.GlobalOptions().Force() {
+		t.Error("Expected --force to be set")
+	}
+}
+
+func TestResetGlobalOptions_RestoresDefaults(t *testing.T) {
+	_, _, _ = newTestRunner(t)
+	opts, _, err := cliutil.ParseGlobalOptions([]string{"app", "--force", "--output=json", "-v", "3"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+
+	err = cliutil.ResetGlobalOptions()
+	if err != nil {
+		t.Fatalf("ResetGlobalOptions() failed: %v", err)
+	}
+	if opts.Force() {
+		t.Error("Expected --force to be reset")
+	}
+	if got := opts.OutputFormat(); got != cliutil.TextOutput {
+		t.Errorf("Expected output format to be reset to text, got %q", got)
+	}
+	if got := opts.Verbosity(); got != cliutil.LowVerbosity {
+		t.Errorf("Expected verbosity to be reset, got %v", got)
+	}
+}
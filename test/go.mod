@@ -4,13 +4,46 @@ go 1.25.3
 
 require (
 	github.com/mikeschinkel/go-cliutil v0.3.0
+	github.com/mikeschinkel/go-cliutil/cobracliutil v0.0.0
+	github.com/mikeschinkel/go-cliutil/otelcliutil v0.0.0
+	github.com/mikeschinkel/go-cliutil/tomlcliutil v0.0.0
+	github.com/mikeschinkel/go-cliutil/urfavecliutil v0.0.0
+	github.com/mikeschinkel/go-cliutil/yamlcliutil v0.0.0
+	github.com/mikeschinkel/go-dt v0.3.3
+	github.com/mikeschinkel/go-dt/appinfo v0.2.1
 	github.com/mikeschinkel/go-testutil v0.2.1
+	github.com/spf13/cobra v1.10.2
+	github.com/urfave/cli/v2 v2.27.7
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/sdk v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
 )
 
 require (
-	github.com/mikeschinkel/go-dt v0.3.3 // indirect
-	github.com/mikeschinkel/go-dt/appinfo v0.2.1 // indirect
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/cpuguy83/go-md2man/v2 v2.0.7 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mikeschinkel/go-dt/dtx v0.2.1 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/spf13/pflag v1.0.9 // indirect
+	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.38.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace github.com/mikeschinkel/go-cliutil => ..
+
+replace github.com/mikeschinkel/go-cliutil/cobracliutil => ../cobracliutil
+
+replace github.com/mikeschinkel/go-cliutil/otelcliutil => ../otelcliutil
+
+replace github.com/mikeschinkel/go-cliutil/tomlcliutil => ../tomlcliutil
+
+replace github.com/mikeschinkel/go-cliutil/urfavecliutil => ../urfavecliutil
+
+replace github.com/mikeschinkel/go-cliutil/yamlcliutil => ../yamlcliutil
@@ -0,0 +1,312 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+func TestGolden_CmdHelp(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	err := cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer})
+	if err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	clitest.Golden(t, writer.GetStdout(), "testdata/parsetest_help.golden")
+}
+
+func TestNormalizeOutput(t *testing.T) {
+	got := clitest.NormalizeOutput("\r\nUSAGE:   \r\n   --count      How many  \n\n")
+	want := "USAGE:\n   --count How many\n"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if !strings.HasSuffix(got, "\n") {
+		t.Error("Expected normalized output to end with a newline")
+	}
+}
+
+func TestHelpSnapshots(t *testing.T) {
+	newTestRunner(t)
+	clitest.HelpSnapshots(t, "testdata/help")
+}
+
+func TestShowCmdHelp_UsesCustomTemplateText(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	saved := cliutil.CmdUsageTemplateText
+	t.Cleanup(func() { cliutil.CmdUsageTemplateText = saved })
+
+	cliutil.CmdUsageTemplateText = "custom: {{.CmdName}}\n"
+	err := cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer})
+	if err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	if got := writer.GetStdout(); got != "custom: parsetest\n" {
+		t.Errorf("Expected the custom template's output, got %q", got)
+	}
+
+	cliutil.CmdUsageTemplateText = "{{.CmdName"
+	err = cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer})
+	if !errors.Is(err, cliutil.ErrInvalidTemplate) {
+		t.Errorf("Expected ErrInvalidTemplate, got %v", err)
+	}
+}
+
+func TestShowCmdHelp_UsesDeprecatedTemplateVars(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	t.Cleanup(func() {
+		cliutil.UsageTemplate = nil
+		cliutil.CmdUsageTemplate = nil
+	})
+
+	cliutil.CmdUsageTemplate = template.Must(template.New("cmd").Parse("assigned: {{.CmdName}}\n"))
+	err := cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer})
+	if err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	if got := writer.GetStdout(); got != "assigned: parsetest\n" {
+		t.Errorf("Expected the assigned CmdUsageTemplate's output, got %q", got)
+	}
+
+	cliutil.UsageTemplate = template.Must(template.New("main").Parse("main help\n"))
+	if tmpl, err := cliutil.GetUsageTemplate(); err != nil || tmpl != cliutil.UsageTemplate {
+		t.Errorf("Expected GetUsageTemplate() to return the assigned UsageTemplate, got %v (%v)", tmpl, err)
+	}
+}
+
+func TestBuildUsage_ExamplesTrackRegistration(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	args := cliutil.UsageArgs{
+		AppInfo: appinfo.New(appinfo.Args{Name: "app", ExeName: "app"}),
+		Writer:  writer,
+	}
+	hasExample := func(cmd string) bool {
+		for _, example := range cliutil.BuildUsage(args).Examples {
+			if example.Cmd == cmd {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasExample("app help parsetest") || hasExample("app help examplecache") {
+		t.Fatal("Expected examples for registered commands only")
+	}
+
+	err := cliutil.RegisterCommand(&throwawayCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{Name: "examplecache", Description: "Cached examples"}),
+	})
+	if err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	if !hasExample("app help examplecache") {
+		t.Error("Expected examples for a newly registered command")
+	}
+
+	examples := cliutil.BuildUsage(args).Examples
+	examples[0].Cmd = "changed"
+	if cliutil.BuildUsage(args).Examples[0].Cmd == "changed" {
+		t.Error("Expected each BuildUsage() to get its own examples")
+	}
+}
+
+func TestShowMainHelp_RerendersAfterRegistration(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	args := cliutil.UsageArgs{
+		AppInfo: appinfo.New(appinfo.Args{Name: "app", ExeName: "app"}),
+		Writer:  writer,
+	}
+	for i := 0; i < 2; i++ {
+		if err := cliutil.ShowMainHelp(args); err != nil {
+			t.Fatalf("ShowMainHelp() failed: %v", err)
+		}
+	}
+	first, _, _ := strings.Cut(writer.GetStdout(), "For more information")
+	if strings.Contains(first, "helpcache") {
+		t.Fatal("Expected no unregistered command in help")
+	}
+
+	err := cliutil.RegisterCommand(&throwawayCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{Name: "helpcache", Description: "Cached help"}),
+	})
+	if err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	writer.Reset()
+	if err := cliutil.ShowMainHelp(args); err != nil {
+		t.Fatalf("ShowMainHelp() failed: %v", err)
+	}
+	if !strings.Contains(writer.GetStdout(), "helpcache") {
+		t.Errorf("Expected help to list a newly registered command, got:\n%s", writer.GetStdout())
+	}
+}
+
+func TestShowMainHelp_TemplateCanUseLenAndIndex(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	saved := cliutil.UsageTemplateText
+	t.Cleanup(func() { cliutil.UsageTemplateText = saved })
+	args := cliutil.UsageArgs{
+		AppInfo: appinfo.New(appinfo.Args{Name: "app", ExeName: "app"}),
+		Writer:  writer,
+	}
+
+	cliutil.UsageTemplateText = "{{len .TopCmdRows}} {{(index .TopCmdRows 0).Display}}\n"
+	if err := cliutil.ShowMainHelp(args); err != nil {
+		t.Fatalf("ShowMainHelp() failed: %v", err)
+	}
+	rows := cliutil.BuildUsage(args).TopCmdRows
+	want := fmt.Sprintf("%d %s\n", len(rows), rows[0].Display)
+	if got := writer.GetStdout(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+// describeCmd calls onDescribe each time help asks for its description
+type describeCmd struct {
+	*cliutil.CmdBase
+	onDescribe func()
+}
+
+func (c *describeCmd) Description() string {
+	c.onDescribe()
+	return c.CmdBase.Description()
+}
+
+func (c *describeCmd) Handle() error {
+	return nil
+}
+
+func TestShowMainHelp_WritesRowsAsTheyRender(t *testing.T) {
+	var written string
+
+	_, _, writer := newTestRunner(t)
+	useTestCmds(t,
+		&throwawayCmd{
+			CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{Name: "alpha", Description: "First"}),
+		},
+		&describeCmd{
+			CmdBase:    cliutil.NewCmdBase(cliutil.CmdArgs{Name: "beta", Description: "Second"}),
+			onDescribe: func() { written = writer.GetStdout() },
+		},
+	)
+
+	args := cliutil.UsageArgs{
+		AppInfo: appinfo.New(appinfo.Args{Name: "app", ExeName: "app"}),
+		Writer:  writer,
+	}
+	if err := cliutil.ShowMainHelp(args); err != nil {
+		t.Fatalf("ShowMainHelp() failed: %v", err)
+	}
+	if !strings.Contains(written, "alpha") {
+		t.Errorf("Expected alpha's row to be written before beta's renders, got:\n%s", written)
+	}
+	if strings.Contains(written, "For more information") {
+		t.Errorf("Expected help to be unfinished when beta's row renders, got:\n%s", written)
+	}
+	if !strings.Contains(writer.GetStdout(), "beta") {
+		t.Errorf("Expected help to list beta, got:\n%s", writer.GetStdout())
+	}
+}
+
+func TestRegisterTemplateFuncs_UsableInHelpTemplates(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	saved := cliutil.CmdUsageTemplateText
+	t.Cleanup(func() { cliutil.CmdUsageTemplateText = saved })
+
+	// Parsed before the func exists, so the cached template must be dropped
+	cliutil.CmdUsageTemplateText = "{{.CmdName}}\n"
+	if err := cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer}); err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+
+	cliutil.RegisterTemplateFuncs(template.FuncMap{
+		"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+	})
+	cliutil.CmdUsageTemplateText = "{{shout .CmdName}} {{example \"go\"}}\n"
+	writer.Reset()
+	if err := cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer}); err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	if got := writer.GetStdout(); got != "PARSETEST! go\n" {
+		t.Errorf("Expected registered funcs in the template, got %q", got)
+	}
+}
+
+func TestShowCmdHelp_UsesCommandHelpTemplate(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	useTestCmds(t, &parseTestCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:         "tutorial",
+			Description:  "Learn the basics",
+			HelpTemplate: "{{heading \"TUTORIAL\"}}\n\n{{.Description}}, step by step.\n",
+		}),
+	})
+
+	err := cliutil.ShowCmdHelp([]string{"tutorial"}, cliutil.UsageArgs{Writer: writer})
+	if err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	if got := writer.GetStdout(); got != "TUTORIAL\n\nLearn the basics, step by step.\n" {
+		t.Errorf("Expected the command's own help template, got %q", got)
+	}
+
+	writer.Reset()
+	err = cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer})
+	if err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	if got := writer.GetStdout(); !strings.Contains(got, "USAGE:") {
+		t.Errorf("Expected other commands to use CmdUsageTemplateText, got %q", got)
+	}
+}
+
+func TestShowMainHelp_GroupsCommandsByCategory(t *testing.T) {
+	var cmds []cliutil.Command
+
+	_, _, writer := newTestRunner(t)
+	for _, args := range []cliutil.CmdArgs{
+		{Name: "users", Description: "Manage users", Category: "Admin Commands", Order: 1},
+		{Name: "serve", Description: "Start the server", Order: 2},
+		{Name: "backup", Description: "Back up data", Category: "Admin Commands", Order: 3},
+		{Name: "config", Description: "Edit settings", Category: "Setup Commands", Order: 4},
+	} {
+		cmds = append(cmds, &throwawayCmd{CmdBase: cliutil.NewCmdBase(args)})
+	}
+	useTestCmds(t, cmds...)
+
+	err := cliutil.ShowMainHelp(cliutil.UsageArgs{
+		AppInfo: appinfo.New(appinfo.Args{Name: "app", ExeName: "app"}),
+		Writer:  writer,
+	})
+	if err != nil {
+		t.Fatalf("ShowMainHelp() failed: %v", err)
+	}
+	got := writer.GetStdout()
+	uncategorized, categorized, _ := strings.Cut(got, "\n\nADMIN COMMANDS:\n")
+	if !strings.Contains(uncategorized, "COMMANDS:\n    serve ") {
+		t.Errorf("Expected commands without a category first, got:\n%s", got)
+	}
+	want := "    users               Manage users\n" +
+		"    backup              Back up data\n" +
+		"\n" +
+		"SETUP COMMANDS:\n" +
+		"    config              Edit settings\n"
+	if !strings.HasPrefix(categorized, want) {
+		t.Errorf("Expected commands grouped by category, got:\n%s", got)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestHistory_RecordsListsAndReruns(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := cliutil.EnableHistory(cliutil.HistoryArgs{File: file}); err != nil {
+		t.Fatalf("EnableHistory() failed: %v", err)
+	}
+	t.Cleanup(cliutil.DisableHistory)
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	if _, err := runTestCmd(t, nil, "parsetest", "--count=3", "bob"); err != nil {
+		t.Fatalf("parsetest failed: %v", err)
+	}
+	out, err := runTestCmd(t, nil, "history")
+	if err != nil {
+		t.Fatalf("history failed: %v", err)
+	}
+	if !strings.Contains(out, "    1  ") || !strings.Contains(out, "parsetest --count=3 bob  (exit 0,") {
+		t.Errorf("Expected the recorded invocation, got %q", out)
+	}
+
+	out, err = runTestCmd(t, nil, "history", "1")
+	if err != nil {
+		t.Fatalf("history 1 failed: %v", err)
+	}
+	if !strings.Contains(out, "Running: parsetest --count=3 bob") {
+		t.Errorf("Expected the re-run to be announced, got %q", out)
+	}
+	entries, err := cliutil.ReadHistory(file)
+	if err != nil {
+		t.Fatalf("ReadHistory() failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected the re-run but not the history commands to be recorded, got %+v", entries)
+	}
+	if entries[1].Command != "parsetest" || entries[1].Args[0] != "bob" || entries[1].InvocationID == entries[0].InvocationID {
+		t.Errorf("Expected a separately recorded re-run, got %+v", entries[1])
+	}
+
+	_, err = runTestCmd(t, nil, "history", "9")
+	if !errors.Is(err, cliutil.ErrHistoryNotFound) {
+		t.Errorf("Expected ErrHistoryNotFound, got %v", err)
+	}
+	_, err = cliutil.HistoryEntry{Command: "login", Flags: map[string]any{"token": cliutil.RedactedValue}}.CommandLine()
+	if !errors.Is(err, cliutil.ErrHistoryNotReusable) {
+		t.Errorf("Expected ErrHistoryNotReusable for a redacted flag, got %v", err)
+	}
+	args, err := cliutil.HistoryEntry{Command: "deploy", Flags: map[string]any{"tag": []any{"a", "b"}}}.CommandLine()
+	if err != nil || !slices.Equal(args, []string{"deploy", "--tag=a", "--tag=b"}) {
+		t.Errorf("Expected a repeated flag for a list value, got %q, %v", args, err)
+	}
+	args, err = cliutil.HistoryEntry{Command: "deploy", Flags: map[string]any{"label": map[string]any{"team": "core", "env": "prod"}}}.CommandLine()
+	if err != nil || !slices.Equal(args, []string{"deploy", "--label=env=prod", "--label=team=core"}) {
+		t.Errorf("Expected a repeated flag for a map value, got %q, %v", args, err)
+	}
+	args, err = cliutil.HistoryEntry{Command: "wrap", Args: []string{"-b"}}.CommandLine()
+	if err != nil || !slices.Equal(args, []string{"wrap", "--", "-b"}) {
+		t.Errorf("Expected -- before a dash-prefixed arg, got %q, %v", args, err)
+	}
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// hookParentCmd groups hookChildCmd
+type hookParentCmd struct {
+	*cliutil.CmdBase
+}
+
+func (c *hookParentCmd) Handle() error {
+	return nil
+}
+
+// hookChildCmd records that its handler ran
+type hookChildCmd struct {
+	*cliutil.CmdBase
+	calls *[]string
+}
+
+func (c *hookChildCmd) Handle() error {
+	*c.calls = append(*c.calls, "handle")
+	return nil
+}
+
+// registerHookCmds registers "parent child" with hooks that record their
+// calls, the parent's persistent pre-run hook failing with preErr
+func registerHookCmds(t *testing.T, preErr error) (calls *[]string) {
+	t.Helper()
+	calls = new([]string)
+	record := func(name string, err error) cliutil.HookFunc {
+		return func(cmd cliutil.Command) error {
+			*calls = append(*calls, name+":"+cmd.Name())
+			return err
+		}
+	}
+	parent := &hookParentCmd{CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:              "parent",
+		PersistentPreRun:  record("parent-pre", preErr),
+		PersistentPostRun: record("parent-post", nil),
+	})}
+	child := &hookChildCmd{calls: calls}
+	child.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:    "child",
+		PreRun:  record("pre", nil),
+		PostRun: record("post", nil),
+	})
+	child.AddParent(cliutil.CommandTypeOf(parent))
+	useTestCmds(t, parent, child)
+	return calls
+}
+
+func TestHooks_RunAroundHandler(t *testing.T) {
+	calls := registerHookCmds(t, nil)
+
+	if _, err := runTestCmd(t, nil, "parent", "child"); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	want := []string{"parent-pre:child", "pre:child", "handle", "post:child", "parent-post:child"}
+	if !slices.Equal(*calls, want) {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+}
+
+func TestHooks_PreRunErrorSkipsHandler(t *testing.T) {
+	errDenied := errors.New("denied")
+	calls := registerHookCmds(t, errDenied)
+
+	_, err := runTestCmd(t, nil, "parent", "child")
+	if !errors.Is(err, errDenied) {
+		t.Fatalf("RunCmd() error = %v, want %v", err, errDenied)
+	}
+	want := []string{"parent-pre:child"}
+	if !slices.Equal(*calls, want) {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+}
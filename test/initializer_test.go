@@ -0,0 +1,114 @@
+package test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestCallInitializerFuncs_HonorsDependencies(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, name)
+	}
+	failed := errors.New("plugin scan failed")
+	warmed := make(chan struct{})
+	for _, ini := range []cliutil.Initializer{
+		{Name: "cache", DependsOn: []string{"config"}, Func: func(cliutil.InitializerArgs) error {
+			record("cache")
+			return nil
+		}},
+		{Name: "config", Func: func(cliutil.InitializerArgs) error {
+			// Blocks until warm runs, which only works if they run concurrently
+			select {
+			case <-warmed:
+			case <-time.After(5 * time.Second):
+				t.Error("Expected independent initializers to run concurrently")
+			}
+			record("config")
+			return nil
+		}},
+		{Name: "warm", Func: func(cliutil.InitializerArgs) error {
+			close(warmed)
+			record("warm")
+			return nil
+		}},
+		{Name: "plugins", Func: func(cliutil.InitializerArgs) error {
+			return failed
+		}},
+		{Name: "extensions", DependsOn: []string{"plugins"}, Func: func(cliutil.InitializerArgs) error {
+			record("extensions")
+			return nil
+		}},
+	} {
+		if err := cliutil.RegisterInitializer(ini); err != nil {
+			t.Fatalf("RegisterInitializer() failed: %v", err)
+		}
+	}
+
+	err := cliutil.CallInitializerFuncs(cliutil.InitializerArgs{Writer: cliutil.GetWriter()})
+	if !errors.Is(err, failed) || !errors.Is(err, cliutil.ErrInitializerSkipped) {
+		t.Errorf("Expected the failure and the skipped dependent, got %v", err)
+	}
+	if slices.Contains(ran, "extensions") {
+		t.Error("Expected a dependent of a failed initializer not to run")
+	}
+	if slices.Index(ran, "config") > slices.Index(ran, "cache") || !slices.Contains(ran, "cache") {
+		t.Errorf("Expected config to run before cache, got %v", ran)
+	}
+}
+
+func TestCallInitializerFuncs_RejectsBadDependencies(t *testing.T) {
+	newTestRunner(t)
+	noop := func(cliutil.InitializerArgs) error { return nil }
+	for name, tt := range map[string]struct {
+		inits []cliutil.Initializer
+		want  error
+	}{
+		"unknown": {
+			inits: []cliutil.Initializer{{Name: "a", DependsOn: []string{"missing"}, Func: noop}},
+			want:  cliutil.ErrUnknownInitializer,
+		},
+		"cycle": {
+			inits: []cliutil.Initializer{
+				{Name: "a", DependsOn: []string{"b"}, Func: noop},
+				{Name: "b", DependsOn: []string{"a"}, Func: noop},
+			},
+			want: cliutil.ErrInitializerCycle,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clitest.IsolateRegistry(t)
+			for _, ini := range tt.inits {
+				if err := cliutil.RegisterInitializer(ini); err != nil {
+					t.Fatalf("RegisterInitializer() failed: %v", err)
+				}
+			}
+			err := cliutil.CallInitializerFuncs(cliutil.InitializerArgs{Writer: cliutil.GetWriter()})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	t.Run("duplicate", func(t *testing.T) {
+		clitest.IsolateRegistry(t)
+		if err := cliutil.RegisterInitializer(cliutil.Initializer{Name: "a", Func: noop}); err != nil {
+			t.Fatalf("RegisterInitializer() failed: %v", err)
+		}
+		err := cliutil.RegisterInitializer(cliutil.Initializer{Name: "a", Func: noop})
+		if !errors.Is(err, cliutil.ErrDuplicateInitializer) {
+			t.Errorf("Expected ErrDuplicateInitializer, got %v", err)
+		}
+	})
+}
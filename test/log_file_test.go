@@ -0,0 +1,95 @@
+package test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+func TestLogFile_RotatesAndRetains(t *testing.T) {
+	dir := t.TempDir()
+	lf, err := cliutil.OpenLogFile(cliutil.LogFileArgs{
+		Dir:        dir,
+		Name:       "app.log",
+		MaxSize:    10,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatalf("OpenLogFile() failed: %v", err)
+	}
+	defer lf.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = lf.Write([]byte(line))
+		if err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		"app.log":   "fourth\n",
+		"app.log.1": "third\n",
+		"app.log.2": "second\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, got)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(dir, "app.log.3")); err == nil {
+		t.Error("Expected backups beyond MaxBackups to be removed")
+	}
+}
+
+func TestLogFile_Handler(t *testing.T) {
+	dir := t.TempDir()
+	lf, err := cliutil.OpenLogFile(cliutil.LogFileArgs{
+		AppInfo: appinfo.New(appinfo.Args{ExeName: "tool"}),
+		Dir:     dir,
+	})
+	if err != nil {
+		t.Fatalf("OpenLogFile() failed: %v", err)
+	}
+	slog.New(lf.Handler(nil)).Info("started", "pid", 42)
+	if err = lf.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if filepath.Base(lf.Path()) != "tool.log" {
+		t.Errorf("Expected log named after the executable, got %s", lf.Path())
+	}
+	got, err := os.ReadFile(lf.Path())
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	if !strings.Contains(string(got), `"msg":"started"`) || !strings.Contains(string(got), `"pid":42`) {
+		t.Errorf("Expected a JSON record, got %q", got)
+	}
+	if _, err = lf.Write([]byte("late")); err == nil {
+		t.Error("Expected Write() after Close() to fail")
+	}
+}
+
+func TestLogDir_UsesXDGStateHome(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG_STATE_HOME applies to Unix-like platforms other than macOS")
+	}
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+	dir, err := cliutil.LogDir(appinfo.New(appinfo.Args{AppSlug: "myapp"}))
+	if err != nil {
+		t.Fatalf("LogDir() failed: %v", err)
+	}
+	if want := filepath.Join("/tmp/state", "myapp", "logs"); dir != want {
+		t.Errorf("Expected %s, got %s", want, dir)
+	}
+}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestLogLevelForVerbosity(t *testing.T) {
+	tests := []struct {
+		verbosity cliutil.Verbosity
+		quiet     bool
+		want      slog.Level
+	}{
+		{cliutil.LowVerbosity, false, slog.LevelWarn},
+		{cliutil.MediumVerbosity, false, slog.LevelInfo},
+		{cliutil.HighVerbosity, false, slog.LevelDebug},
+		{cliutil.HighVerbosity, true, slog.LevelError},
+	}
+	for _, tt := range tests {
+		got := cliutil.LogLevelForVerbosity(tt.verbosity, tt.quiet)
+		if got != tt.want {
+			t.Errorf("LogLevelForVerbosity(%d, %v): expected %v, got %v", tt.verbosity, tt.quiet, tt.want, got)
+		}
+	}
+}
+
+func TestGlobalOptions_LogLevel(t *testing.T) {
+	_, _, _ = newTestRunner(t)
+	clitest.ResetGlobalOptions(t)
+
+	opts, _, err := cliutil.ParseGlobalOptions([]string{"app", "-v", "3", "warntest"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	if got := opts.LogLevel(); got != slog.LevelDebug {
+		t.Errorf("Expected -v 3 to select Debug, got %v", got)
+	}
+
+	opts, _, err = cliutil.ParseGlobalOptions([]string{"app", "-v", "3", "--log-level=error", "warntest"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	if got := opts.LogLevel(); got != slog.LevelError {
+		t.Errorf("Expected --log-level to override verbosity, got %v", got)
+	}
+
+	_, _, err = cliutil.ParseGlobalOptions([]string{"app", "--log-level=loud", "warntest"})
+	if !errors.Is(err, cliutil.ErrInvalidLogLevel) {
+		t.Errorf("Expected ErrInvalidLogLevel, got %v", err)
+	}
+	if code := cliutil.ErrorCodeOf(err); code != "CLI206" {
+		t.Errorf("Expected error code CLI206, got %q", code)
+	}
+}
+
+func TestNewCmdRunner_LogLevelShowsDebugAtDefaultVerbosity(t *testing.T) {
+	_, _, _ = newTestRunner(t)
+	clitest.ResetGlobalOptions(t)
+
+	opts, _, err := cliutil.ParseGlobalOptions([]string{"app", "--log-level=debug", "warntest"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	w := clitest.NewBufferedWriter()
+	w.SetVerbosity(cliutil.LowVerbosity)
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{Writer: w, Options: opts})
+	runner.Args.Logger.Debug("cache miss", "key", "k1")
+	runner.Args.Logger.Info("synced")
+	if got := w.Stdout(); got != "cache miss key=k1\nsynced\n" {
+		t.Errorf("Expected --log-level=debug to show debug and info output, got %q", got)
+	}
+}
+
+func TestNewCmdRunner_DerivesLogger(t *testing.T) {
+	w := clitest.NewBufferedWriter()
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Writer:  w,
+		Options: clitest.OptionsWith(clitest.WithVerbosity(cliutil.MediumVerbosity)),
+	})
+	logger := runner.Args.Logger
+	if logger == nil {
+		t.Fatal("Expected NewCmdRunner() to derive a Logger")
+	}
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Expected Debug to be disabled at medium verbosity")
+	}
+	logger.Info("synced")
+	if w.Stdout() != "synced\n" {
+		t.Errorf("Expected Info to reach the Writer, got %q", w.Stdout())
+	}
+}
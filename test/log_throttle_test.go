@@ -0,0 +1,78 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestLogThrottle_Allow(t *testing.T) {
+	fc := clitest.NewFakeClock(time.Time{})
+	clitest.UseClock(t, fc)
+	lt := cliutil.NewLogThrottle(cliutil.LogThrottleArgs{Limit: 2, Interval: time.Minute})
+
+	var allowed int
+	for i := 0; i < 5; i++ {
+		ok, n := lt.Allow("retrying")
+		if n != 0 {
+			t.Errorf("Expected no summary within the first window, got %d", n)
+		}
+		if ok {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Expected 2 allowed messages, got %d", allowed)
+	}
+	if ok, _ := lt.Allow("other"); !ok {
+		t.Error("Expected a different key to be allowed")
+	}
+
+	fc.Advance(time.Minute)
+	ok, n := lt.Allow("retrying")
+	if !ok || n != 3 {
+		t.Errorf("Expected a new window reporting 3 suppressed, got ok=%v n=%d", ok, n)
+	}
+}
+
+func TestWriterLogger_WithThrottle(t *testing.T) {
+	fc := clitest.NewFakeClock(time.Time{})
+	clitest.UseClock(t, fc)
+	w := clitest.NewBufferedWriter()
+	wl := cliutil.NewWriterLogger(w, slog.New(slog.NewTextHandler(io.Discard, nil))).
+		WithThrottle(cliutil.NewLogThrottle(cliutil.LogThrottleArgs{Limit: 1, Interval: time.Minute}))
+
+	for i := 0; i < 4; i++ {
+		wl.WarnError("connection refused", "attempt", i)
+	}
+	if got := strings.Count(w.Stderr(), "connection refused; attempt="); got != 1 {
+		t.Fatalf("Expected 1 warning in the first window, got %d: %q", got, w.Stderr())
+	}
+
+	fc.Advance(time.Minute)
+	wl.WarnError("connection refused", "attempt", 4)
+	if !strings.Contains(w.Stderr(), "suppressed 3 similar messages: connection refused\n") {
+		t.Errorf("Expected a summary for the previous window, got %q", w.Stderr())
+	}
+
+	wl.WarnError("connection refused", "attempt", 5)
+	wl.FlushThrottle()
+	if !strings.Contains(w.Stderr(), "suppressed 1 similar message: connection refused\n") {
+		t.Errorf("Expected FlushThrottle() to report remaining suppressions, got %q", w.Stderr())
+	}
+
+	err := wl.ErrorError("upload failed", errors.New("timeout"))
+	err2 := wl.ErrorError("upload failed", errors.New("timeout"))
+	if err == nil || err2 == nil {
+		t.Error("Expected ErrorError() to return an error even when throttled")
+	}
+	if got := strings.Count(w.Stderr(), "upload failed"); got != 1 {
+		t.Errorf("Expected 1 printed error, got %d", got)
+	}
+}
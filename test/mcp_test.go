@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/remotecliutil"
+)
+
+func TestServeMCP_ExposesCommandsAsTools(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	greet := &remoteTestCmd{}
+	greet.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        "greet",
+		Description: "Greet someone",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "greet",
+			FlagDefs: []cliutil.FlagDef{
+				{Name: "greeting", Usage: "Greeting", Default: "Hello", String: &greet.greeting},
+				{Name: "fail", Usage: "Fail", Bool: &greet.fail},
+			},
+		}},
+		ArgDefs: []*cliutil.ArgDef{
+			{Name: "name", Usage: "Who to greet", Required: true, String: &greet.name},
+		},
+	})
+	hidden := &remoteTestCmd{}
+	hidden.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{Name: "internal", Description: "Hidden", Hide: true})
+	for _, cmd := range []cliutil.Command{greet, hidden} {
+		if err := cliutil.RegisterCommand(cmd); err != nil {
+			t.Fatalf("RegisterCommand() failed: %v", err)
+		}
+	}
+	if err := remotecliutil.RegisterMCPCommands(remotecliutil.MCPArgs{}); err != nil {
+		t.Fatalf("RegisterMCPCommands() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"greet","arguments":{"name":"agent","greeting":"Hey"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"greet","arguments":{"name":"agent","fail":true}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"internal"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"bogus"}`,
+	}, "\n")
+	var out strings.Builder
+	server := remotecliutil.NewServer(remotecliutil.ServerArgs{})
+	err := server.ServeMCP(context.Background(), strings.NewReader(in), &out, remotecliutil.MCPArgs{Name: "app", Version: "1.0"})
+	if err != nil {
+		t.Fatalf("ServeMCP() failed: %v", err)
+	}
+
+	type response struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	responses := make(map[int]response)
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var r response
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("Invalid response %q: %v", scanner.Text(), err)
+		}
+		responses[r.ID] = r
+	}
+	if len(responses) != 6 {
+		t.Fatalf("Expected 6 responses (none for the notification), got %d:\n%s", len(responses), out.String())
+	}
+
+	if !strings.Contains(string(responses[1].Result), `"protocolVersion":"2025-03-26"`) {
+		t.Errorf("Expected initialize to echo the protocol version, got %s", responses[1].Result)
+	}
+
+	var list struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			InputSchema struct {
+				Properties map[string]struct {
+					Type string `json:"type"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(responses[2].Result, &list); err != nil {
+		t.Fatalf("Invalid tools/list result: %v", err)
+	}
+	tools := make(map[string]int)
+	for i, tool := range list.Tools {
+		tools[tool.Name] = i
+	}
+	i, ok := tools["greet"]
+	if !ok {
+		t.Fatalf("Expected a greet tool, got %+v", list.Tools)
+	}
+	if _, ok := tools["internal"]; ok {
+		t.Error("Expected hidden commands not to be tools")
+	}
+	if _, ok := tools["mcp_serve"]; ok {
+		t.Error("Expected mcp serve not to be a tool")
+	}
+	schema := list.Tools[i].InputSchema
+	if schema.Properties["fail"].Type != "boolean" || schema.Properties["name"].Type != "string" {
+		t.Errorf("Expected typed properties, got %+v", schema.Properties)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "name" {
+		t.Errorf("Expected name to be required, got %v", schema.Required)
+	}
+
+	var call struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(responses[3].Result, &call); err != nil {
+		t.Fatalf("Invalid tools/call result: %v", err)
+	}
+	if call.IsError || call.Content[0].Text != "Hey, agent!\n" {
+		t.Errorf("Expected the command's output, got %+v", call)
+	}
+	call.IsError = false
+	if err := json.Unmarshal(responses[4].Result, &call); err != nil {
+		t.Fatalf("Invalid tools/call result: %v", err)
+	}
+	if !call.IsError {
+		t.Errorf("Expected a failing command to be reported as a tool error, got %+v", call)
+	}
+	if responses[5].Error == nil || responses[6].Error == nil {
+		t.Error("Expected errors for a hidden tool and an unknown method")
+	}
+	if hidden.ran != 0 {
+		t.Error("Expected the hidden command not to run")
+	}
+}
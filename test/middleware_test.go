@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// panicCmd panics in its handler
+type panicCmd struct {
+	*cliutil.CmdBase
+}
+
+func (c *panicCmd) Handle() error {
+	panic("boom")
+}
+
+// runWithMiddleware runs args with middleware added to the runner
+func runWithMiddleware(t *testing.T, middleware []cliutil.Middleware, args ...string) error {
+	t.Helper()
+	_, err := runTestCmd(t, func(runner *cliutil.CmdRunner) {
+		runner.Use(middleware...)
+	}, args...)
+	return err
+}
+
+func TestCmdRunner_UseWrapsInOrder(t *testing.T) {
+	calls := registerHookCmds(t, nil)
+	record := func(name string) cliutil.Middleware {
+		return func(next cliutil.HandlerFunc) cliutil.HandlerFunc {
+			return func(ctx context.Context, cmd cliutil.Command) error {
+				*calls = append(*calls, name+":"+cmd.Name())
+				err := next(ctx, cmd)
+				*calls = append(*calls, name+":done")
+				return err
+			}
+		}
+	}
+
+	err := runWithMiddleware(t, []cliutil.Middleware{record("outer"), record("inner")}, "parent", "child")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	want := []string{
+		"outer:child", "inner:child",
+		"parent-pre:child", "pre:child", "handle", "post:child", "parent-post:child",
+		"inner:done", "outer:done",
+	}
+	if !slices.Equal(*calls, want) {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+}
+
+func TestCmdRunner_UseRecoversPanics(t *testing.T) {
+	var errPanicked = errors.New("command panicked")
+
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	if err := cliutil.RegisterCommand(&panicCmd{CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{Name: "panic"})}); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	recoverer := func(next cliutil.HandlerFunc) cliutil.HandlerFunc {
+		return func(ctx context.Context, cmd cliutil.Command) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%w: %v", errPanicked, r)
+				}
+			}()
+			return next(ctx, cmd)
+		}
+	}
+
+	err := runWithMiddleware(t, []cliutil.Middleware{recoverer}, "panic")
+	if !errors.Is(err, errPanicked) {
+		t.Fatalf("RunCmd() error = %v, want %v", err, errPanicked)
+	}
+}
+
+func TestRecoverPanics_WritesCrashReport(t *testing.T) {
+	setBuildVars(t, "", "", "", "")
+	runner, args, writer := newTestRunner(t, "panic")
+	clitest.IsolateRegistry(t)
+	if err := cliutil.RegisterCommand(&panicCmd{CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{Name: "panic"})}); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	runner.Use(cliutil.RecoverPanics(cliutil.CrashReportArgs{
+		AppInfo: appinfo.New(cliutil.WithBuildInfo(appinfo.Args{
+			Name:      "app",
+			Version:   "v1.2.3",
+			ExtraInfo: map[string]any{cliutil.BuildCommitKey: "0123456789abcdef"},
+		})),
+		Writer:   writer,
+		IssueURL: "https://example.com/issues",
+	}))
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+
+	err = runner.RunCmd(cmd)
+	if !errors.Is(err, cliutil.ErrPanic) || cliutil.ErrClassOf(err) != cliutil.NotifiedErr {
+		t.Fatalf("RunCmd() error = %v, want a notified %v", err, cliutil.ErrPanic)
+	}
+	for _, want := range []string{
+		"app crashed running \"panic\": boom\n",
+		"Please report this at https://example.com/issues",
+		"app v1.2.3\n",
+		"  Commit:   0123456789abcdef\n",
+		"goroutine ",
+	} {
+		if !writer.ContainsStderr(want) {
+			t.Errorf("Expected crash report to contain %q, got:\n%s", want, writer.GetStderr())
+		}
+	}
+	if strings.Contains(writer.GetStdout(), "boom") {
+		t.Errorf("Expected the crash report on stderr only, got stdout:\n%s", writer.GetStdout())
+	}
+}
@@ -0,0 +1,314 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/openapicliutil"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+const petstoreSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Petstore", "version": "1.0.0"},
+  "tags": [{"name": "pets", "description": "Manage pets"}],
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "summary": "List all pets",
+        "tags": ["pets"],
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer"}},
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["available", "sold"]}}
+        ]
+      },
+      "post": {
+        "operationId": "createPet",
+        "summary": "Create a pet",
+        "tags": ["pets"],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}
+        }
+      }
+    },
+    "/pets/{petId}": {
+      "parameters": [{"$ref": "#/components/parameters/PetId"}],
+      "get": {
+        "operationId": "showPetById",
+        "summary": "Info for a pet",
+        "tags": ["pets"],
+        "parameters": [{"name": "X-Request-ID", "in": "header", "schema": {"type": "string"}}]
+      }
+    },
+    "/health": {
+      "get": {"summary": "Health check"}
+    }
+  },
+  "components": {
+    "parameters": {
+      "PetId": {"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}
+    },
+    "schemas": {
+      "Pet": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string"},
+          "age": {"type": "integer"},
+          "vaccinated": {"type": "boolean"},
+          "tags": {"type": "array", "items": {"type": "string"}}
+        }
+      }
+    }
+  }
+}`
+
+// apiRequest is a request received by the test API server
+type apiRequest struct {
+	method string
+	uri    string
+	header http.Header
+	body   string
+}
+
+// itemsSpec has numeric query and body values and a path parameter with a
+// default
+const itemsSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Items", "version": "1.0.0"},
+  "paths": {
+    "/items/{version}": {
+      "post": {
+        "operationId": "findItems",
+        "parameters": [
+          {"name": "version", "in": "path", "required": true, "schema": {"type": "string", "default": "latest"}},
+          {"name": "ids", "in": "query", "schema": {"type": "array", "items": {"type": "integer"}}}
+        ],
+        "requestBody": {
+          "content": {"application/json": {"schema": {"type": "object", "properties": {
+            "weight": {"type": "number"},
+            "sizes": {"type": "array", "items": {"type": "number"}}
+          }}}}
+        }
+      }
+    }
+  }
+}`
+
+// runOpenAPIArgs registers the petstore commands against a test server and
+// runs args, returning the request the server received, if any
+func runOpenAPIArgs(t *testing.T, args ...string) (*apiRequest, *testutil.BufferedWriter, error) {
+	t.Helper()
+	return runOpenAPISpec(t, petstoreSpec, args...)
+}
+
+// runOpenAPISpec is runOpenAPIArgs for the commands of spec
+func runOpenAPISpec(t *testing.T, spec string, args ...string) (*apiRequest, *testutil.BufferedWriter, error) {
+	t.Helper()
+	var got *apiRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = &apiRequest{method: r.Method, uri: r.URL.RequestURI(), header: r.Header, body: string(body)}
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	parsed, err := openapicliutil.ParseSpec([]byte(spec))
+	if err != nil {
+		t.Fatalf("ParseSpec() failed: %v", err)
+	}
+	_, err = openapicliutil.Register(openapicliutil.Args{
+		Spec:    parsed,
+		BaseURL: server.URL,
+		Prepare: func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer test")
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+	err = cliutil.BuildCommandTree()
+	if err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	runner, args, writer := newTestRunner(t, args...)
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		return got, writer, err
+	}
+	return got, writer, runner.RunCmd(cmd)
+}
+
+func TestOpenAPI_QueryParameters(t *testing.T) {
+	got, writer, err := runOpenAPIArgs(t, "pets", "list-pets", "--limit=2", "--status=sold")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got.method != http.MethodGet || got.uri != "/pets?limit=2&status=sold" {
+		t.Errorf("Expected GET /pets?limit=2&status=sold, got %s %s", got.method, got.uri)
+	}
+	if got.header.Get("Authorization") != "Bearer test" {
+		t.Error("Expected Prepare to add the Authorization header")
+	}
+	if !writer.ContainsStdout(`"ok": true`) {
+		t.Errorf("Expected the indented response on stdout, got %q", writer.GetStdout())
+	}
+}
+
+func TestOpenAPI_EnumIsValidated(t *testing.T) {
+	got, _, err := runOpenAPIArgs(t, "pets", "list-pets", "--status=lost")
+	if err == nil {
+		t.Fatal("Expected a value outside the enum to be rejected")
+	}
+	if got != nil {
+		t.Error("Expected no request to be sent")
+	}
+}
+
+func TestOpenAPI_PathAndHeaderParameters(t *testing.T) {
+	got, _, err := runOpenAPIArgs(t, "pets", "show-pet-by-id", "--pet-id=a b", "--x-request-id=r1")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got.uri != "/pets/a%20b" || got.header.Get("X-Request-ID") != "r1" {
+		t.Errorf("Expected /pets/a%%20b with X-Request-ID r1, got %s %v", got.uri, got.header)
+	}
+}
+
+func TestOpenAPI_BodyFromFlags(t *testing.T) {
+	got, _, err := runOpenAPIArgs(t, "pets", "create-pet", `--body={"name":"Tom","age":1}`,
+		"--name=Rex", "--age=3", "--vaccinated", "--tags=good,dog")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	var body map[string]any
+	err = json.Unmarshal([]byte(got.body), &body)
+	if err != nil {
+		t.Fatalf("Expected a JSON body, got %q", got.body)
+	}
+	if body["name"] != "Rex" || body["age"] != float64(3) || body["vaccinated"] != true {
+		t.Errorf("Expected flags to override --body, got %v", body)
+	}
+	if tags, _ := body["tags"].([]any); len(tags) != 2 {
+		t.Errorf("Expected 2 tags, got %v", body["tags"])
+	}
+	if got.header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON Content-Type, got %q", got.header.Get("Content-Type"))
+	}
+}
+
+func TestOpenAPI_NumbersAreValidatedWhenParsed(t *testing.T) {
+	for _, arg := range []string{"--ids=1,abc", "--ids=1, 2", "--ids=1.5", "--weight=NaN",
+		"--weight=0x1p4", "--weight=Infinity", "--sizes=1,1e", "--sizes=+1"} {
+		t.Run(arg, func(t *testing.T) {
+			got, _, err := runOpenAPISpec(t, itemsSpec, "find-items", arg)
+			if !errors.Is(err, openapicliutil.ErrInvalidFlagValue) {
+				t.Errorf("Expected %v, got %v", openapicliutil.ErrInvalidFlagValue, err)
+			}
+			if got != nil {
+				t.Error("Expected no request to be sent")
+			}
+		})
+	}
+}
+
+func TestOpenAPI_NumbersAndPathDefault(t *testing.T) {
+	got, _, err := runOpenAPISpec(t, itemsSpec, "find-items", "--ids=1,2", "--weight=-2.5e3", "--sizes=0.5,10")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got.uri != "/items/latest?ids=1&ids=2" {
+		t.Errorf("Expected the path parameter's default in /items/latest?ids=1&ids=2, got %s", got.uri)
+	}
+	if got.body != `{"sizes":[0.5,10],"weight":-2.5e3}` {
+		t.Errorf("Expected numeric body values, got %s", got.body)
+	}
+
+}
+
+func TestOpenAPI_PathParameterOverridesDefault(t *testing.T) {
+	got, _, err := runOpenAPISpec(t, itemsSpec, "find-items", "--version=v2")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got.uri != "/items/v2" {
+		t.Errorf("Expected /items/v2, got %s", got.uri)
+	}
+}
+
+func TestOpenAPI_UntaggedOperationWithoutID(t *testing.T) {
+	got, _, err := runOpenAPIArgs(t, "get-health")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got.uri != "/health" {
+		t.Errorf("Expected /health, got %s", got.uri)
+	}
+}
+
+func TestOpenAPI_DryRunPrintsRequest(t *testing.T) {
+	got, writer, err := runOpenAPIArgs(t, "--dry-run", "pets", "create-pet", "--name=Rex")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got != nil {
+		t.Error("Expected --dry-run not to send the request")
+	}
+	if !writer.ContainsStdout("POST ") || !writer.ContainsStdout(`"name": "Rex"`) {
+		t.Errorf("Expected the request to be printed, got %q", writer.GetStdout())
+	}
+}
+
+func TestOpenAPI_ErrorStatus(t *testing.T) {
+	_, _, err := runOpenAPIArgs(t, "pets", "show-pet-by-id", "--pet-id=missing")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a 404 error, got %v", err)
+	}
+}
+
+func TestOpenAPI_Generate(t *testing.T) {
+	spec, err := openapicliutil.ParseSpec([]byte(petstoreSpec))
+	if err != nil {
+		t.Fatalf("ParseSpec() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	err = openapicliutil.Generate(&buf, spec, openapicliutil.GenerateArgs{
+		Package:  "petcmds",
+		SpecFile: "petstore.json",
+	})
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	src := buf.String()
+	for _, want := range []string{"//go:embed petstore.json", "func RegisterCommands(", "//   - pets create-pet"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("Expected generated source to contain %q:\n%s", want, src)
+		}
+	}
+	_, err = parser.ParseFile(token.NewFileSet(), "gen.go", src, parser.ParseComments)
+	if err != nil {
+		t.Errorf("Generated source does not parse: %v", err)
+	}
+}
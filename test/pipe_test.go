@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestIsPiped_FollowsTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	w := cliutil.NewWriter(&cliutil.WriterArgs{Verbosity: 1, Stdout: &buf})
+
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	if cliutil.IsPiped(w) {
+		t.Errorf("Expected output to a terminal not to be piped")
+	}
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	if !cliutil.IsPiped(w) {
+		t.Errorf("Expected output to a non-terminal to be piped")
+	}
+
+	// A file is never a terminal, whatever the Terminal says about stdout
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	if !cliutil.IsPipedOutput(f) {
+		t.Errorf("Expected output redirected to a file to be piped")
+	}
+}
+
+func TestCmdRunnerArgs_DetectsTerminalAndInteractivity(t *testing.T) {
+	var buf bytes.Buffer
+	args := cliutil.CmdRunnerArgs{
+		Writer: cliutil.NewWriter(&cliutil.WriterArgs{Verbosity: 1, Stdout: &buf}),
+	}
+
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	if !args.IsTerminal() || !cliutil.IsTerminal(&buf) {
+		t.Errorf("Expected output to a terminal to be a terminal")
+	}
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	if args.IsTerminal() || cliutil.IsTerminal(&buf) {
+		t.Errorf("Expected piped output not to be a terminal")
+	}
+
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{Interactive: true}))
+	if !args.IsInteractive() {
+		t.Errorf("Expected IsInteractive() to follow the Environment")
+	}
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{Interactive: false}))
+	if args.IsInteractive() {
+		t.Errorf("Expected IsInteractive() to follow the Environment")
+	}
+}
+
+func TestWriteSettings_TabSeparatedWhenPiped(t *testing.T) {
+	settings := []cliutil.Setting{
+		{Name: "timeout", Value: 9, Default: 3, Source: cliutil.FlagSource},
+		{Name: "label", Command: "db migrate", Value: "a\tb", Default: "", Source: cliutil.DefaultSource},
+	}
+
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	var buf bytes.Buffer
+	if err := cliutil.WriteSettings(&buf, settings, cliutil.TextOutput); err != nil {
+		t.Fatalf("WriteSettings() failed: %v", err)
+	}
+	want := "NAME\tVALUE\tDEFAULT\tSOURCE\n" +
+		"--timeout\t9\t3\tflag\n" +
+		"db migrate --label\ta b\t\tdefault\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Expected TSV when piped, got %q, want %q", got, want)
+	}
+
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	buf.Reset()
+	if err := cliutil.WriteSettings(&buf, settings, cliutil.TextOutput); err != nil {
+		t.Fatalf("WriteSettings() failed: %v", err)
+	}
+	if got := buf.String(); strings.Contains(got, "\t") || !strings.HasPrefix(got, "NAME                VALUE") {
+		t.Errorf("Expected an aligned table on a terminal, got:\n%s", got)
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestProgress_RedrawsBarOnTerminal(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	writer := clitest.NewBufferedWriter()
+
+	p := cliutil.NewProgress(writer, cliutil.ProgressArgs{Label: "Copying", Total: 4, Width: 4})
+	p.Add(1)
+	p.Add(0) // Unchanged, so not redrawn
+	p.Set(4)
+	p.Done()
+	p.Done()
+
+	want := "\r\x1b[2KCopying [=   ]  25% 1/4" +
+		"\r\x1b[2KCopying [====] 100% 4/4\n"
+	if got := writer.Stderr(); got != want {
+		t.Errorf("Stderr() = %q, want %q", got, want)
+	}
+	if writer.Stdout() != "" {
+		t.Errorf("Expected nothing on stdout, got %q", writer.Stdout())
+	}
+}
+
+func TestProgress_WritesLinesPeriodicallyWhenPiped(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	clock := clitest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	clitest.UseClock(t, clock)
+	writer := clitest.NewBufferedWriter()
+
+	p := cliutil.NewProgress(writer, cliutil.ProgressArgs{
+		Total:    2048,
+		Interval: time.Second,
+		Format:   cliutil.FormatBytes,
+	})
+	p.Set(512) // Too soon after starting
+	clock.Advance(time.Second)
+	p.Set(1024)
+	p.Set(1536) // Too soon after the last line
+	p.Set(2048)
+	p.Done()
+
+	want := " 50% 1.0 KiB/2.0 KiB\n" +
+		"100% 2.0 KiB/2.0 KiB\n"
+	if got := writer.Stderr(); got != want {
+		t.Errorf("Stderr() = %q, want %q", got, want)
+	}
+}
+
+func TestProgress_UnknownTotalAndQuiet(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	writer := clitest.NewBufferedWriter()
+
+	p := cliutil.NewProgress(writer, cliutil.ProgressArgs{Label: "Scanned"})
+	p.Add(9)
+	p.Add(1)
+	p.Done()
+	if got := writer.Stderr(); got != "\r\x1b[2KScanned 9\r\x1b[2KScanned 10\n" {
+		t.Errorf("Stderr() = %q, want counts without a bar", got)
+	}
+
+	quiet := clitest.NewBufferedWriter()
+	quiet.SetQuiet(true)
+	p = cliutil.NewProgress(quiet, cliutil.ProgressArgs{Total: 10})
+	p.Add(5)
+	p.Done()
+	if got := quiet.Stderr(); strings.TrimSpace(got) != "" {
+		t.Errorf("Expected no progress when quiet, got %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestConfirm_AsksWhenInteractive(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{Interactive: true}))
+	clitest.UsePromptInput(t, "y\nno\n YES \n")
+
+	for i, want := range []bool{true, false, true, false} {
+		if got := cliutil.Confirm(writer, "Delete 3 backups?"); got != want {
+			t.Errorf("Confirm() #%d = %t, want %t", i+1, got, want)
+		}
+	}
+	if !writer.ContainsStderr("Delete 3 backups? [y/N] ") {
+		t.Errorf("Expected the prompt on stderr, got %q", writer.GetStderr())
+	}
+}
+
+func TestConfirm_RefusesWhenNotInteractive(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	clitest.UsePromptInput(t, "y\n")
+
+	if cliutil.Confirm(writer, "Delete 3 backups?") {
+		t.Error("Expected Confirm() to refuse with no one at the terminal")
+	}
+	if writer.GetStderr() != "" {
+		t.Errorf("Expected no prompt, got %q", writer.GetStderr())
+	}
+}
+
+func TestConfirm_YesSkipsPrompt(t *testing.T) {
+	_, _, writer := newTestRunner(t, "--yes")
+	clitest.ResetGlobalOptions(t)
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+
+	if !cliutil.Confirm(writer, "Delete 3 backups?") {
+		t.Error("Expected --yes to confirm without asking")
+	}
+	if writer.GetStderr() != "" {
+		t.Errorf("Expected no prompt, got %q", writer.GetStderr())
+	}
+}
+
+func TestConfirm_FollowsRunningCLIYes(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	cli := cliutil.NewCLI()
+	if _, _, err := cli.ParseGlobalOptions([]string{"app", "--yes", "help"}); err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+
+	args := cliutil.CmdRunnerArgs{Writer: writer, CLI: cli}
+	if !args.Confirm("Delete 3 backups?") {
+		t.Error("Expected --yes given to the running CLI to confirm")
+	}
+	if cliutil.Confirm(writer, "Delete 3 backups?") {
+		t.Error("Expected --yes given to another CLI not to confirm for the default CLI")
+	}
+}
+
+// echoCheckReader records whether the terminal echoed input while it was read
+type echoCheckReader struct {
+	io.Reader
+	term   *clitest.FakeTerminal
+	echoed bool
+}
+
+func (r *echoCheckReader) Read(p []byte) (int, error) {
+	r.echoed = r.echoed || r.term.EchoEnabled()
+	return r.Reader.Read(p)
+}
+
+// usePromptWriter makes writer the Writer PromptSecret prompts on
+func usePromptWriter(t *testing.T) *testutil.BufferedWriter {
+	t.Helper()
+	_, _, writer := newTestRunner(t)
+	prev := cliutil.GetWriter()
+	cliutil.SetWriter(writer)
+	t.Cleanup(func() { cliutil.SetWriter(prev) })
+	return writer
+}
+
+func TestPromptSecret_HidesInputOnTTY(t *testing.T) {
+	writer := usePromptWriter(t)
+	term := clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true})
+	clitest.UseTerminal(t, term)
+	input := &echoCheckReader{Reader: strings.NewReader("s3cret\r\n"), term: term}
+	prev := cliutil.SetPromptInput(input)
+	t.Cleanup(func() { cliutil.SetPromptInput(prev) })
+
+	secret, err := cliutil.PromptSecret("Password: ")
+	if err != nil || secret != "s3cret" {
+		t.Fatalf("PromptSecret() = %q, %v, want s3cret", secret, err)
+	}
+	if input.echoed {
+		t.Error("Expected echo to be off while the secret was read")
+	}
+	if !term.EchoEnabled() {
+		t.Error("Expected echo to be restored")
+	}
+	if got := writer.GetStderr(); got != "Password: \n" {
+		t.Errorf("Expected the prompt and a newline on stderr, got %q", got)
+	}
+}
+
+func TestPromptSecret_ReadsPipedInput(t *testing.T) {
+	usePromptWriter(t)
+	term := clitest.NewFakeTerminal(clitest.FakeTerminalArgs{EchoErr: errors.New("not a terminal")})
+	clitest.UseTerminal(t, term)
+	clitest.UsePromptInput(t, "s3cret")
+
+	secret, err := cliutil.PromptSecret("Password: ")
+	if err != nil || secret != "s3cret" {
+		t.Errorf("PromptSecret() = %q, %v, want s3cret without touching echo", secret, err)
+	}
+}
+
+func TestPromptSecret_Fails(t *testing.T) {
+	t.Run("echo", func(t *testing.T) {
+		usePromptWriter(t)
+		clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true, EchoErr: errors.New("no echo control")}))
+		clitest.UsePromptInput(t, "s3cret\n")
+
+		_, err := cliutil.PromptSecret("Password: ")
+		if !errors.Is(err, cliutil.ErrPromptIO) {
+			t.Errorf("Expected ErrPromptIO when echo cannot be turned off, got %v", err)
+		}
+	})
+	t.Run("no input", func(t *testing.T) {
+		usePromptWriter(t)
+		clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{}))
+		clitest.UsePromptInput(t, "")
+
+		_, err := cliutil.PromptSecret("Password: ")
+		if !errors.Is(err, cliutil.ErrPromptIO) || !errors.Is(err, io.EOF) {
+			t.Errorf("Expected ErrPromptIO wrapping io.EOF, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,330 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+// throwawayCmd is registered by isolated tests and must not leak out of them
+type throwawayCmd struct {
+	*cliutil.CmdBase
+}
+
+func (c *throwawayCmd) Handle() error {
+	return nil
+}
+
+func TestIsolateRegistry(t *testing.T) {
+	before := len(cliutil.RegisteredCommands())
+	flagCount := len(cliutil.GetGlobalFlagSet().FlagDefs)
+
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"throwaway-a", "throwaway-b", "throwaway-c"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				clitest.IsolateRegistry(t)
+
+				err := cliutil.RegisterCommand(&throwawayCmd{
+					CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+						Name:        name,
+						Description: "Throwaway command",
+					}),
+				})
+				if err != nil {
+					t.Fatalf("RegisterCommand() failed: %v", err)
+				}
+				err = cliutil.AddCLIOption(cliutil.FlagDef{
+					Name:  name + "-flag",
+					Usage: "Throwaway flag",
+					Bool:  new(bool),
+				})
+				if err != nil {
+					t.Fatalf("AddCLIOption() failed: %v", err)
+				}
+				err = cliutil.BuildCommandTree()
+				if err != nil {
+					t.Fatalf("BuildCommandTree() failed: %v", err)
+				}
+				if cliutil.GetExactCommand(name) == nil {
+					t.Errorf("Expected %s to be registered", name)
+				}
+				if got := len(cliutil.RegisteredCommands()); got != before+1 {
+					t.Errorf("Expected %d commands while isolated, got %d", before+1, got)
+				}
+			})
+		}
+	})
+
+	if got := len(cliutil.RegisteredCommands()); got != before {
+		t.Errorf("Expected %d commands after restore, got %d", before, got)
+	}
+	if got := len(cliutil.GetGlobalFlagSet().FlagDefs); got != flagCount {
+		t.Errorf("Expected %d global flags after restore, got %d", flagCount, got)
+	}
+	if cliutil.GetExactCommand("throwaway-a") != nil {
+		t.Error("Expected throwaway-a to be removed after restore")
+	}
+}
+
+// treeCmd is a runnable command with its own registry type, so several can
+// be nested in one test
+type treeCmd struct {
+	*cliutil.CmdBase
+	cmdType reflect.Type
+	ran     bool
+}
+
+func newTreeCmd(name string) *treeCmd {
+	return &treeCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{Name: name, Description: "Tree command"}),
+		cmdType: cliutil.NewCommandType(),
+	}
+}
+
+func (c *treeCmd) CommandType() reflect.Type {
+	return c.cmdType
+}
+
+func (c *treeCmd) Handle() error {
+	c.ran = true
+	return nil
+}
+
+func TestCommandTree_ResolvesNestedPaths(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	root := newTreeCmd("tree")
+	beta := newTreeCmd("beta")
+	alpha := newTreeCmd("alpha")
+	deep := newTreeCmd("deep")
+	for _, reg := range []struct {
+		cmd    cliutil.Command
+		parent cliutil.Command
+	}{{root, nil}, {beta, root}, {alpha, root}, {deep, alpha}} {
+		var err error
+		if reg.parent == nil {
+			err = cliutil.RegisterCommand(reg.cmd)
+		} else {
+			err = cliutil.RegisterCommand(reg.cmd, reg.parent)
+		}
+		if err != nil {
+			t.Fatalf("RegisterCommand() failed: %v", err)
+		}
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	var names []string
+	for _, cmd := range cliutil.GetSubCmds("tree") {
+		names = append(names, cmd.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"alpha", "beta"}) {
+		t.Errorf("Expected subcommands [alpha beta], got %v", names)
+	}
+	if subCmds := cliutil.GetSubCmds("tree.alpha"); len(subCmds) != 1 || subCmds[0] != deep {
+		t.Errorf("Expected deep as the only subcommand of tree.alpha, got %v", subCmds)
+	}
+	if cliutil.GetSubCmds("tree.missing") != nil {
+		t.Error("Expected no subcommands for an unknown path")
+	}
+	subCmds := cliutil.GetSubCmds("tree")
+	subCmds[0] = deep
+	if cliutil.GetSubCmds("tree")[0] != alpha {
+		t.Error("Expected modifying the returned slice to leave the subcommands alone")
+	}
+
+	opts, args, err := cliutil.ParseGlobalOptions([]string{"app", "tree", "alpha", "deep", "extra"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context: context.Background(),
+		Writer:  clitest.NewBufferedWriter(),
+		Options: opts,
+		Args:    args,
+	})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if cmd != deep {
+		t.Errorf("Expected the deepest matching command, got %s", cliutil.CmdPath(cmd))
+	}
+	if got := deep.PositionalArgs(); !reflect.DeepEqual(got, []string{"extra"}) {
+		t.Errorf("Expected [extra] as positional args, got %v", got)
+	}
+}
+
+func TestGetTopLevelCmds_OrdersStablyAndTracksRegistration(t *testing.T) {
+	clitest.IsolateRegistry(t)
+
+	register := func(name string, order int) {
+		t.Helper()
+		cmd := newTreeCmd(name)
+		cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{Name: name, Description: "Tree command", Order: order})
+		if err := cliutil.RegisterCommand(cmd); err != nil {
+			t.Fatalf("RegisterCommand() failed: %v", err)
+		}
+		if err := cliutil.BuildCommandTree(); err != nil {
+			t.Fatalf("BuildCommandTree() failed: %v", err)
+		}
+	}
+	position := func(name string) int {
+		for i, cmd := range cliutil.GetTopLevelCmds() {
+			if cmd.Name() == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	register("order-zz", 1)
+	register("order-aa", 0)
+	register("order-mm", 0)
+	if !(position("order-zz") < position("order-aa") && position("order-aa") < position("order-mm")) {
+		t.Errorf("Expected ordered commands first, then by name; got zz=%d aa=%d mm=%d",
+			position("order-zz"), position("order-aa"), position("order-mm"))
+	}
+	first := cliutil.GetTopLevelCmds()
+	if second := cliutil.GetTopLevelCmds(); !reflect.DeepEqual(first, second) {
+		t.Error("Expected repeated calls to return the same order")
+	}
+
+	register("order-bb", 0)
+	if position("order-bb") != position("order-aa")+1 {
+		t.Error("Expected a newly registered command to appear in sorted position")
+	}
+
+	cmds := cliutil.GetTopLevelCmds()
+	first = slices.Clone(cmds)
+	slices.Reverse(cmds)
+	if !reflect.DeepEqual(cliutil.GetTopLevelCmds(), first) {
+		t.Error("Expected modifying the returned slice to leave the command order alone")
+	}
+}
+
+func TestRestoreRegistry_UndoesSubcommandsAddedToExistingParents(t *testing.T) {
+	clitest.IsolateRegistry(t)
+
+	root := newTreeCmd("restore")
+	if err := cliutil.RegisterCommand(root); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	snap := cliutil.SnapshotRegistry()
+
+	if err := cliutil.RegisterCommand(newTreeCmd("child"), root); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	if len(cliutil.GetSubCmds("restore")) != 1 {
+		t.Fatal("Expected child to be added to restore")
+	}
+
+	cliutil.RestoreRegistry(snap)
+	if subCmds := cliutil.GetSubCmds("restore"); len(subCmds) != 0 {
+		t.Errorf("Expected no subcommands after restore, got %v", subCmds)
+	}
+	if cliutil.GetExactCommand("restore.child") != nil {
+		t.Error("Expected restore.child to be removed after restore")
+	}
+	if cliutil.GetExactCommand("restore") != root {
+		t.Error("Expected restore to survive the restore")
+	}
+}
+
+// TestRegistry_ConcurrentAccess is meaningful under -race: it registers and
+// builds commands while other goroutines look them up and parse arguments
+func TestRegistry_ConcurrentAccess(t *testing.T) {
+	clitest.IsolateRegistry(t)
+
+	parent := newTreeCmd("concurrent")
+	if err := cliutil.RegisterCommand(parent); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				child := newTreeCmd(fmt.Sprintf("child-%d-%d", i, j))
+				if err := cliutil.RegisterCommand(child, parent); err != nil {
+					t.Errorf("RegisterCommand() failed: %v", err)
+					return
+				}
+				if err := cliutil.BuildCommandTree(); err != nil {
+					t.Errorf("BuildCommandTree() failed: %v", err)
+					return
+				}
+				_ = child.FullNames()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = cliutil.GetTopLevelCmds()
+				_ = cliutil.GetSubCmds("concurrent")
+				_ = cliutil.GetExactCommand("concurrent")
+				_ = cliutil.RegisteredCommands()
+				if _, _, err := cliutil.ParseGlobalOptions([]string{"app", "concurrent"}); err != nil {
+					t.Errorf("ParseGlobalOptions() failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(cliutil.GetSubCmds("concurrent")); got != 80 {
+		t.Errorf("Expected 80 subcommands, got %d", got)
+	}
+}
+
+func TestFullNames_CachedByBuildCommandTree(t *testing.T) {
+	clitest.IsolateRegistry(t)
+
+	root := newTreeCmd("names")
+	child := newTreeCmd("child")
+	leaf := newTreeCmd("leaf")
+	if err := cliutil.RegisterCommand(root); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.RegisterCommand(child, root); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.RegisterCommand(leaf, child); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if got := leaf.FullNames(); !reflect.DeepEqual(got, []string{"names.child.leaf"}) {
+		t.Errorf("Expected names resolved before BuildCommandTree, got %v", got)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	names := leaf.FullNames()
+	if !reflect.DeepEqual(names, []string{"names.child.leaf"}) {
+		t.Fatalf("Expected [names.child.leaf], got %v", names)
+	}
+	names[0] = "changed"
+	if got := leaf.FullNames(); got[0] != "names.child.leaf" {
+		t.Errorf("Expected FullNames() to return a copy, got %v", got)
+	}
+	if cliutil.CommandTypeOf(leaf) != leaf.CommandType() {
+		t.Error("Expected the registered type to be the command's own")
+	}
+}
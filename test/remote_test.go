@@ -0,0 +1,285 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/remotecliutil"
+)
+
+// remoteTestCmd greets its argument and fails when --fail is set
+type remoteTestCmd struct {
+	*cliutil.CmdBase
+	name     string
+	greeting string
+	tags     []string
+	fail     bool
+	ran      int // Times Handle was called
+}
+
+func (c *remoteTestCmd) Handle() error {
+	c.ran++
+	c.Writer.Printf("%s, %s!\n", c.greeting, c.name)
+	if c.fail {
+		return cliutil.WithErrClass(errors.New("greeting failed"), cliutil.RetryableErr)
+	}
+	return nil
+}
+
+// startRemoteServer serves remote commands on a temporary socket until the
+// test ends
+func startRemoteServer(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "cli.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- remotecliutil.NewServer(remotecliutil.ServerArgs{Socket: socket}).Serve(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() failed: %v", err)
+		}
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the server socket")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return socket
+}
+
+// runRemote parses args locally and runs the resulting command via socket
+func runRemote(t *testing.T, socket string, args ...string) (*clitest.BufferedWriter, remotecliutil.Request, error) {
+	t.Helper()
+	opts, args, err := cliutil.ParseGlobalOptions(append([]string{"app"}, args...))
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	writer := clitest.NewBufferedWriter()
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context: context.Background(),
+		Writer:  writer,
+		Options: opts,
+		Args:    args,
+	})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	req, err := remotecliutil.NewRequest(runner, cmd)
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+	return writer, req, remotecliutil.RunCmd(socket, runner, cmd)
+}
+
+func TestRemote_RunsCommandInServer(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	cmd := &remoteTestCmd{}
+	cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        "greet",
+		Description: "Greet someone",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "greet",
+			FlagDefs: []cliutil.FlagDef{
+				{Name: "greeting", Shortcut: 'g', Usage: "Greeting", Default: "Hello", String: &cmd.greeting},
+				{Name: "fail", Usage: "Fail", Bool: &cmd.fail},
+				{Name: "tag", Usage: "Tag", Strings: &cmd.tags},
+			},
+		}},
+		ArgDefs: []*cliutil.ArgDef{
+			{Name: "name", Usage: "Who to greet", Required: true, String: &cmd.name},
+		},
+	})
+	if err := cliutil.RegisterCommand(cmd); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	socket := startRemoteServer(t)
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the socket to be private to the user, got %v (%v)", info.Mode(), err)
+	}
+
+	writer, req, err := runRemote(t, socket, "--quiet=false", "greet", "-g", "Howdy", "world")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if req.Command != "greet" || !slices.Equal(req.Flags["greeting"], []string{"Howdy"}) || len(req.Args) != 1 || req.Args[0] != "world" {
+		t.Errorf("Expected the request to carry the parsed invocation, got %+v", req)
+	}
+	if !slices.Equal(req.GlobalFlags["quiet"], []string{"false"}) {
+		t.Errorf("Expected the request to carry global flags, got %v", req.GlobalFlags)
+	}
+	if cmd.ran != 1 {
+		t.Errorf("Expected the command to run once, ran %d times", cmd.ran)
+	}
+	if writer.Stdout() != "Howdy, world!\n" {
+		t.Errorf("Expected streamed stdout, got %q", writer.Stdout())
+	}
+
+	writer, _, err = runRemote(t, socket, "greet", "--tag=a", "--tag=b,c", "--", "--world")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if !slices.Equal(cmd.tags, []string{"a", "b,c"}) || cmd.name != "--world" {
+		t.Errorf("Expected tags [a b,c] and name --world, got %q and %q", cmd.tags, cmd.name)
+	}
+	cmd.tags = nil
+
+	writer, _, err = runRemote(t, socket, "greet", "--fail", "world")
+	if err == nil {
+		t.Fatal("Expected RunCmd() to fail")
+	}
+	if !errors.Is(err, remotecliutil.ErrRemoteCmdFailed) {
+		t.Errorf("Expected ErrRemoteCmdFailed, got %v", err)
+	}
+	if code := cliutil.ExitCode(err); code != cliutil.ExitKnownRuntimeError {
+		t.Errorf("Expected the daemon's exit code %d, got %d", cliutil.ExitKnownRuntimeError, code)
+	}
+	if cliutil.ErrClassOf(err) != cliutil.NotifiedErr {
+		t.Errorf("Expected a NotifiedErr, got %v", cliutil.ErrClassOf(err))
+	}
+	if !strings.Contains(writer.Stderr(), "greeting failed") {
+		t.Errorf("Expected the daemon's error report on stderr, got %q", writer.Stderr())
+	}
+}
+
+func TestRemote_ServeKeepsLiveSocketsAndOtherFiles(t *testing.T) {
+	socket := startRemoteServer(t)
+	err := remotecliutil.NewServer(remotecliutil.ServerArgs{Socket: socket}).Serve(context.Background())
+	if !errors.Is(err, remotecliutil.ErrServeFailed) {
+		t.Errorf("Expected %v for a socket a daemon answers on, got %v", remotecliutil.ErrServeFailed, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err = os.WriteFile(file, []byte("keep"), 0o644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	err = remotecliutil.NewServer(remotecliutil.ServerArgs{Socket: file}).Serve(context.Background())
+	if !errors.Is(err, remotecliutil.ErrServeFailed) {
+		t.Errorf("Expected %v for a file that is not a socket, got %v", remotecliutil.ErrServeFailed, err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "keep" {
+		t.Error("Expected the file to be left alone")
+	}
+}
+
+func TestRemote_HTTPHandlerRunsAllowedCommands(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	greet := &remoteTestCmd{greeting: "Hi"}
+	greet.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        "greet",
+		Description: "Greet someone",
+		ArgDefs: []*cliutil.ArgDef{
+			{Name: "name", Usage: "Who to greet", Required: true, String: &greet.name},
+		},
+	})
+	secret := &remoteTestCmd{}
+	secret.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{Name: "secret", Description: "Not for GUIs"})
+	for _, cmd := range []cliutil.Command{greet, secret} {
+		if err := cliutil.RegisterCommand(cmd); err != nil {
+			t.Fatalf("RegisterCommand() failed: %v", err)
+		}
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	handler, err := remotecliutil.NewServer(remotecliutil.ServerArgs{}).HTTPHandler(remotecliutil.HTTPArgs{
+		Token: "s3cret",
+		Allow: []string{"greet"},
+	})
+	if err != nil {
+		t.Fatalf("HTTPHandler() failed: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	post := func(token string, req remotecliutil.Request) *http.Response {
+		t.Helper()
+		body, _ := json.Marshal(req)
+		r, _ := http.NewRequest(http.MethodPost, server.URL+"/run", bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		resp, err := server.Client().Do(r)
+		if err != nil {
+			t.Fatalf("POST /run failed: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	if resp := post("wrong", remotecliutil.Request{Command: "greet"}); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a bad token, got %d", resp.StatusCode)
+	}
+	if resp := post("s3cret", remotecliutil.Request{Command: "secret"}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a command not in the allowlist, got %d", resp.StatusCode)
+	}
+	if secret.ran != 0 {
+		t.Error("Expected the disallowed command not to run")
+	}
+
+	resp := post("s3cret", remotecliutil.Request{Command: "greet", Args: []string{"editor"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	var result remotecliutil.Response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Decoding the response failed: %v", err)
+	}
+	if result.ExitCode != 0 || result.Stdout != "Hi, editor!\n" {
+		t.Errorf("Expected the command's output, got %+v", result)
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, server.URL+"/commands", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	listResp, err := server.Client().Do(r)
+	if err != nil {
+		t.Fatalf("GET /commands failed: %v", err)
+	}
+	defer func() { _ = listResp.Body.Close() }()
+	var infos []remotecliutil.CommandInfo
+	if err := json.NewDecoder(listResp.Body).Decode(&infos); err != nil {
+		t.Fatalf("Decoding the command list failed: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Command != "greet" || infos[0].Description != "Greet someone" {
+		t.Errorf("Expected only the allowed command, got %+v", infos)
+	}
+
+	greet.ran = 0
+	for _, req := range []remotecliutil.Request{
+		{Command: "greet", GlobalFlags: map[string][]string{"env-file": {"/etc/passwd"}}, Args: []string{"editor"}},
+		{Command: "greet", Flags: map[string][]string{"config": {"/etc/app.toml"}}, Args: []string{"editor"}},
+		{Command: "greet --args-from-stdin", Args: []string{"editor"}},
+	} {
+		if resp := post("s3cret", req); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%+v: expected 403 for a flag reading the daemon's files, got %d", req, resp.StatusCode)
+		}
+	}
+	if greet.ran != 0 {
+		t.Error("Expected no command to run with a disallowed flag")
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+var _ cliutil.ResultHandler = (*listCmd)(nil)
+
+// listCmd returns files as its result rather than writing them
+type listCmd struct {
+	*cliutil.CmdBase
+	files []fileRecord
+	err   error
+	ctx   context.Context
+}
+
+func (c *listCmd) HandleResult(ctx context.Context) (any, error) {
+	c.ctx = ctx
+	return c.files, c.err
+}
+
+func registerListCmd(t *testing.T, tmpl string) *listCmd {
+	t.Helper()
+	cmd := &listCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:           "list",
+			Description:    "List files",
+			ResultTemplate: tmpl,
+		}),
+		files: []fileRecord{{Name: "a.txt", Size: 5}, {Name: "b.txt", Size: 7}},
+	}
+	useTestCmds(t, cmd)
+	return cmd
+}
+
+func runList(t *testing.T, flags ...string) (stdout string, err error) {
+	t.Helper()
+	clitest.ResetGlobalOptions(t)
+	return runTestCmd(t, nil, append(flags, "list")...)
+}
+
+func TestResultHandler_RendersResultInOutputFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		tmpl  string
+		flags []string
+		want  string
+	}{
+		{name: "text", want: "a.txt (5 bytes)\nb.txt (7 bytes)\n"},
+		{name: "template", tmpl: "{{range .}}{{.Name}}={{.Size}}\n{{end}}", want: "a.txt=5\nb.txt=7\n"},
+		{name: "json", tmpl: "ignored", flags: []string{"--output=json"}, want: `[{"name":"a.txt","size":5},{"name":"b.txt","size":7}]` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := registerListCmd(t, tt.tmpl)
+			got, err := runList(t, tt.flags...)
+			if err != nil {
+				t.Fatalf("RunCmd() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RunCmd() wrote %q, want %q", got, tt.want)
+			}
+			if cmd.ctx.Err() == nil {
+				t.Error("Context was not canceled after HandleResult returned")
+			}
+		})
+	}
+}
+
+func TestResultHandler_ErrorWritesNothing(t *testing.T) {
+	cmd := registerListCmd(t, "")
+	cmd.err = errTest
+	got, err := runList(t)
+	if !errors.Is(err, errTest) {
+		t.Errorf("Expected the handler's error, got %v", err)
+	}
+	if got != "" {
+		t.Errorf("Expected no result written on error, got %q", got)
+	}
+	if !cliutil.IsRunnable(cmd) {
+		t.Error("Expected a ResultHandler to be runnable")
+	}
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestExportCLISchema(t *testing.T) {
+	var schema cliutil.CLISchema
+	var echo *cliutil.CmdSchema
+	var verbosity *cliutil.FlagSchema
+
+	registerEchoCmd(t)
+
+	data, err := cliutil.ExportCLISchema()
+	if err != nil {
+		t.Fatalf("ExportCLISchema() failed: %v", err)
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v\n%s", err, data)
+	}
+	for i := range schema.Commands {
+		if schema.Commands[i].Path == "echo" {
+			echo = &schema.Commands[i]
+		}
+	}
+	if echo == nil {
+		t.Fatalf("schema has no echo command:\n%s", data)
+	}
+	if echo.Description != "Print text" {
+		t.Errorf("echo description = %q, want %q", echo.Description, "Print text")
+	}
+	if len(echo.Args) != 1 || echo.Args[0].Name != "text" || !echo.Args[0].Required || !echo.Args[0].Stdin {
+		t.Errorf("echo args = %+v, want one required stdin arg named text", echo.Args)
+	}
+
+	for i := range schema.GlobalFlags {
+		if schema.GlobalFlags[i].Name == "verbosity" {
+			verbosity = &schema.GlobalFlags[i]
+		}
+	}
+	if verbosity == nil {
+		t.Fatalf("schema has no verbosity global flag:\n%s", data)
+	}
+	if verbosity.Shortcut != "v" || verbosity.Type != "count" {
+		t.Errorf("verbosity = %+v, want shortcut v and type count", *verbosity)
+	}
+}
+
+func TestExportCLISchema_SubcommandsAndHidden(t *testing.T) {
+	var schema cliutil.CLISchema
+	var subCmds []cliutil.CmdSchema
+
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	db, migrate, secret := &echoCmd{}, &deprecatedCmd{}, &parseTestCmd{}
+	db.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{Name: "db", Description: "Manage the database"})
+	migrate.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:       "migrate",
+		Deprecated: "use db upgrade",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "migrate",
+			FlagDefs: []cliutil.FlagDef{{
+				Name:      "password",
+				Default:   "hunter2",
+				String:    new(string),
+				Sensitive: true,
+			}},
+		}},
+	})
+	secret.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{Name: "secret", Hide: true})
+	if err := cliutil.RegisterCommand(db); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.RegisterCommand(migrate, db); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.RegisterCommand(secret); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	data, err := cliutil.ExportCLISchema()
+	if err != nil {
+		t.Fatalf("ExportCLISchema() failed: %v", err)
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v\n%s", err, data)
+	}
+	for _, cmd := range schema.Commands {
+		switch cmd.Name {
+		case "secret":
+			t.Errorf("schema includes hidden command secret")
+		case "db":
+			subCmds = cmd.Commands
+		}
+	}
+	if len(subCmds) != 1 || subCmds[0].Path != "db migrate" {
+		t.Fatalf("db subcommands = %+v, want db migrate", subCmds)
+	}
+	if subCmds[0].Deprecated != "use db upgrade" {
+		t.Errorf("migrate deprecated = %q, want %q", subCmds[0].Deprecated, "use db upgrade")
+	}
+	if len(subCmds[0].Flags) != 1 || subCmds[0].Flags[0].Default != cliutil.RedactedValue {
+		t.Errorf("migrate flags = %+v, want the password default redacted", subCmds[0].Flags)
+	}
+}
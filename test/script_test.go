@@ -0,0 +1,13 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestScripts(t *testing.T) {
+	// Ensure the shared fixtures are initialized before scripts run them
+	newTestRunner(t)
+	clitest.RunScripts(t, "testdata/scripts", clitest.DefaultRun)
+}
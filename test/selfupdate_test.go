@@ -0,0 +1,168 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/selfupdatecliutil"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// newReleaseServer serves a GitHub latest release of v1.1.0 with binary for
+// the running platform, and a checksums.txt listing checksum for it
+func newReleaseServer(t *testing.T, binary, checksum string) *httptest.Server {
+	t.Helper()
+	asset := fmt.Sprintf("myapp_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		asset += ".exe"
+	}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/repos/me/myapp/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"tag_name":"v1.1.0","assets":[
+			{"name":%q,"browser_download_url":"%s/download/bin"},
+			{"name":"checksums.txt","browser_download_url":"%s/download/checksums.txt"}]}`,
+			asset, server.URL, server.URL)
+	})
+	mux.HandleFunc("/download/bin", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, binary)
+	})
+	mux.HandleFunc("/download/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s  %s\n", checksum, asset)
+	})
+	return server
+}
+
+// writeExecutable writes an executable standing in for the running app
+func writeExecutable(t *testing.T) string {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "myapp")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	return exe
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSelfUpdate_ReplacesExecutable(t *testing.T) {
+	server := newReleaseServer(t, "new", sha256Hex("new"))
+	exe := writeExecutable(t)
+	args := selfupdatecliutil.Args{
+		Source:     selfupdatecliutil.GitHubSource{Owner: "me", Repo: "myapp", BaseURL: server.URL},
+		AppInfo:    appinfo.New(appinfo.Args{Name: "myapp", Version: "v1.0.0"}),
+		Executable: exe,
+	}
+
+	release, updated, err := selfupdatecliutil.Update(context.Background(), args)
+	if err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if !updated || release.Version != "v1.1.0" {
+		t.Errorf("Expected an update to v1.1.0, got %v (updated %t)", release.Version, updated)
+	}
+	data, _ := os.ReadFile(exe)
+	if string(data) != "new" {
+		t.Errorf("Expected the new binary, got %q", data)
+	}
+	if info, _ := os.Stat(exe); runtime.GOOS != "windows" && info.Mode().Perm() != 0o755 {
+		t.Errorf("Expected the executable's mode to be kept, got %v", info.Mode())
+	}
+
+	args.AppInfo = appinfo.New(appinfo.Args{Name: "myapp", Version: "v1.1.0"})
+	if _, updated, err = selfupdatecliutil.Update(context.Background(), args); err != nil || updated {
+		t.Errorf("Expected no update when current, got updated %t, err %v", updated, err)
+	}
+}
+
+func TestSelfUpdate_RejectsChecksumMismatch(t *testing.T) {
+	server := newReleaseServer(t, "tampered", sha256Hex("new"))
+	exe := writeExecutable(t)
+
+	_, updated, err := selfupdatecliutil.Update(context.Background(), selfupdatecliutil.Args{
+		Source:     selfupdatecliutil.GitHubSource{Owner: "me", Repo: "myapp", BaseURL: server.URL},
+		AppInfo:    appinfo.New(appinfo.Args{Name: "myapp", Version: "v1.0.0"}),
+		Executable: exe,
+	})
+	if !errors.Is(err, selfupdatecliutil.ErrChecksumMismatch) || updated {
+		t.Fatalf("Update() = updated %t, err %v; want %v", updated, err, selfupdatecliutil.ErrChecksumMismatch)
+	}
+	data, _ := os.ReadFile(exe)
+	if string(data) != "old" {
+		t.Errorf("Expected the executable to be left alone, got %q", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Errorf("Expected the download to be removed, got %d files", len(entries))
+	}
+}
+
+func TestSelfUpdate_ManifestSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"version":"v2.0.0","binaries":{"%s/%s":{"url":"https://example.com/myapp","sha256":"abc"}}}`,
+			runtime.GOOS, runtime.GOARCH)
+	}))
+	t.Cleanup(server.Close)
+
+	release, newer, err := selfupdatecliutil.Check(context.Background(), selfupdatecliutil.Args{
+		Source:  selfupdatecliutil.ManifestSource{URL: server.URL},
+		AppInfo: appinfo.New(appinfo.Args{Name: "myapp", Version: "v1.9.0"}),
+	})
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if !newer || release.URL != "https://example.com/myapp" || release.Checksum != "abc" {
+		t.Errorf("Expected the manifest's newer release, got %+v (newer %t)", release, newer)
+	}
+
+	_, err = selfupdatecliutil.ManifestSource{URL: server.URL}.LatestRelease(context.Background(), "plan9", "mips")
+	if !errors.Is(err, selfupdatecliutil.ErrNoBinary) {
+		t.Errorf("Expected %v for a platform without a binary, got %v", selfupdatecliutil.ErrNoBinary, err)
+	}
+}
+
+func TestSelfUpdate_CommandChecks(t *testing.T) {
+	server := newReleaseServer(t, "new", sha256Hex("new"))
+	exe := writeExecutable(t)
+	runner, args, writer := newTestRunner(t, selfupdatecliutil.CmdName, "--check")
+	runner.Args.AppInfo = appinfo.New(appinfo.Args{Name: "myapp", Version: "v1.0.0"})
+	clitest.IsolateRegistry(t)
+	err := selfupdatecliutil.Register(selfupdatecliutil.Args{
+		Source:     selfupdatecliutil.GitHubSource{Owner: "me", Repo: "myapp", BaseURL: server.URL},
+		Executable: exe,
+	})
+	if err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+	if err = cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if err = runner.RunCmd(cmd); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got := writer.GetStdout(); got != "myapp v1.1.0 is available (you have v1.0.0)\n" {
+		t.Errorf("Expected the available release, got %q", got)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Errorf("Expected --check to leave the executable alone, got %q", data)
+	}
+}
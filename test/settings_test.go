@@ -0,0 +1,63 @@
+package test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestSettingsCmd_ShowsValuesAndSources(t *testing.T) {
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+	if err := cliutil.EnableBuiltins(cliutil.Builtins{Settings: true}); err != nil {
+		t.Fatalf("EnableBuiltins() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	out, err := runTestCmd(t, nil, "--timeout=9", "settings", "parsetest")
+	if err != nil {
+		t.Fatalf("settings failed: %v", err)
+	}
+	for _, want := range []string{"NAME", "--timeout", "parsetest --count"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the table, got:\n%s", want, out)
+		}
+	}
+
+	out, err = runTestCmd(t, nil, "--timeout=9", "--output=json", "settings")
+	if err != nil {
+		t.Fatalf("settings --output=json failed: %v", err)
+	}
+	var settings []cliutil.Setting
+	if err := json.Unmarshal([]byte(out), &settings); err != nil {
+		t.Fatalf("Expected JSON, got %q: %v", out, err)
+	}
+	sources := make(map[string]cliutil.SettingSource)
+	for _, s := range settings {
+		sources[s.Name] = s.Source
+		if s.Name == "timeout" && s.Value != "9s" {
+			t.Errorf("Expected the effective timeout, got %v", s.Value)
+		}
+	}
+	if sources["timeout"] != cliutil.FlagSource || sources["quiet"] != cliutil.DefaultSource {
+		t.Errorf("Expected flag and default sources, got %v", sources)
+	}
+
+	cliutil.GetGlobalFlagSet().SetSource("quiet", cliutil.EnvSource)
+	t.Cleanup(func() { cliutil.GetGlobalFlagSet().SetSource("quiet", cliutil.DefaultSource) })
+	for _, s := range cliutil.EffectiveSettings(nil) {
+		if s.Name == "quiet" && s.Source != cliutil.EnvSource {
+			t.Errorf("Expected the recorded source, got %s", s.Source)
+		}
+	}
+
+	_, err = runTestCmd(t, nil, "settings", "bogus")
+	if cliutil.ErrClassOf(err) != cliutil.UsageErr {
+		t.Errorf("Expected a usage error for an unknown command, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// TestShellWrapperHelperProcess acts as the wrapped CLI when run by the
+// wrapper in TestWriteShellWrapper_AppliesDirectives
+func TestShellWrapperHelperProcess(t *testing.T) {
+	if !cliutil.HasShellWrapper() {
+		return
+	}
+	dir := os.Getenv("WRAPPER_TEST_DIR")
+	if err := cliutil.ShellChdir(dir); err != nil {
+		t.Fatalf("ShellChdir() failed: %v", err)
+	}
+	if err := cliutil.ShellSetenv("WRAPPER_TEST_VALUE", "it's set"); err != nil {
+		t.Fatalf("ShellSetenv() failed: %v", err)
+	}
+	if err := cliutil.ShellUnsetenv("WRAPPER_TEST_GONE"); err != nil {
+		t.Fatalf("ShellUnsetenv() failed: %v", err)
+	}
+	if os.Getenv(cliutil.ShellDirectivesEnv) != "" {
+		t.Error("Expected the directives variable to be removed from the environment")
+	}
+}
+
+func TestWriteShellWrapper_AppliesDirectives(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+	var script strings.Builder
+	err = cliutil.WriteShellWrapper(&script, cliutil.BashShell, cliutil.ShellWrapperArgs{
+		Name:    "wraptest",
+		Command: os.Args[0],
+	})
+	if err != nil {
+		t.Fatalf("WriteShellWrapper() failed: %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "it's here")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script.WriteString(`export WRAPPER_TEST_GONE=1
+wraptest -test.run='^TestShellWrapperHelperProcess$' >/dev/null || exit 1
+echo "$PWD"
+echo "$WRAPPER_TEST_VALUE"
+echo "${WRAPPER_TEST_GONE-unset}"
+`)
+	cmd := exec.Command(bash, "-c", script.String())
+	cmd.Env = append(os.Environ(), "WRAPPER_TEST_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Running the wrapper failed: %v\n%s", err, out)
+	}
+	want := dir + "\nit's set\nunset\n"
+	if string(out) != want {
+		t.Errorf("Expected output %q, got %q", want, out)
+	}
+}
+
+func TestShellChdir_FailsWithoutWrapper(t *testing.T) {
+	err := cliutil.ShellChdir(t.TempDir())
+	if !errors.Is(err, cliutil.ErrNoShellWrapper) {
+		t.Errorf("Expected ErrNoShellWrapper, got %v", err)
+	}
+	if len(cliutil.Suggestions(err)) == 0 {
+		t.Error("Expected a suggestion to install the wrapper")
+	}
+}
+
+func TestParseShell_RejectsUnknownShells(t *testing.T) {
+	if _, err := cliutil.ParseShell("tcsh"); !errors.Is(err, cliutil.ErrInvalidShell) {
+		t.Errorf("Expected ErrInvalidShell, got %v", err)
+	}
+	if shell, err := cliutil.ParseShell("Fish"); err != nil || shell != cliutil.FishShell {
+		t.Errorf("Expected fish, got %q, %v", shell, err)
+	}
+}
@@ -0,0 +1,46 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// TestStartupProfileHelperProcess runs a command with the startup profile
+// enabled for TestStartupProfile_ReportsPhases
+func TestStartupProfileHelperProcess(t *testing.T) {
+	if os.Getenv(cliutil.StartupProfileEnv) == "" {
+		return
+	}
+	runner, args, _ := newTestRunner(t, "parsetest", "item")
+	if err := cliutil.CallInitializerFuncs(cliutil.InitializerArgs{Writer: cliutil.GetWriter()}); err != nil {
+		t.Fatalf("CallInitializerFuncs() failed: %v", err)
+	}
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if err := runner.RunCmd(cmd); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+}
+
+func TestStartupProfile_ReportsPhases(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStartupProfileHelperProcess$")
+	cmd.Env = append(os.Environ(), cliutil.StartupProfileEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Helper process failed: %v\n%s", err, out)
+	}
+	for _, phase := range []string{"package init", "RegisterCommand", "BuildCommandTree", "initializer ", "ParseCmd", "total"} {
+		if !strings.Contains(string(out), phase) {
+			t.Errorf("Expected the profile to include %q, got:\n%s", phase, out)
+		}
+	}
+	if strings.Count(string(out), "cliutil startup profile:") != 1 {
+		t.Errorf("Expected the profile to be reported once, got:\n%s", out)
+	}
+}
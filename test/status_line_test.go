@@ -0,0 +1,59 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestStatusLine_RewritesLineOnTerminal(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	writer := clitest.NewBufferedWriter()
+
+	status := cliutil.NewStatusLine(writer, cliutil.StatusLineArgs{})
+	status.Update("processed %d/%d", 1, 3)
+	status.Update("processed %d/%d", 1, 3) // Unchanged, so not rewritten
+	status.Update("processed %d/%d", 3, 3)
+	status.Clear()
+	status.Update("ignored after Clear")
+
+	want := "\r\x1b[2Kprocessed 1/3" +
+		"\r\x1b[2Kprocessed 3/3" +
+		"\r\x1b[2K"
+	if got := writer.Stderr(); got != want {
+		t.Errorf("Stderr() = %q, want %q", got, want)
+	}
+}
+
+func TestStatusLine_DoneKeepsFinalLine(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	writer := clitest.NewBufferedWriter()
+
+	status := cliutil.NewStatusLine(writer, cliutil.StatusLineArgs{})
+	status.Update("indexed 42 files")
+	status.Done()
+	status.Done()
+	if got := writer.Stderr(); got != "\r\x1b[2Kindexed 42 files\n" {
+		t.Errorf("Stderr() = %q, want the final status on its own line", got)
+	}
+}
+
+func TestStatusLine_WritesLinesPeriodicallyWhenPiped(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	clock := clitest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	clitest.UseClock(t, clock)
+	writer := clitest.NewBufferedWriter()
+
+	status := cliutil.NewStatusLine(writer, cliutil.StatusLineArgs{Interval: time.Second})
+	status.Update("step 1") // Too soon after starting
+	clock.Advance(time.Second)
+	status.Update("step 2")
+	status.Update("step 3") // Too soon after the last line
+	status.Clear()
+
+	if got := writer.Stderr(); got != "step 2\n" {
+		t.Errorf("Stderr() = %q, want %q", got, "step 2\n")
+	}
+}
@@ -0,0 +1,174 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// echoCmd prints its "text" argument, which may be read from stdin, after
+// its --prefix flag, which may be read from a file
+type echoCmd struct {
+	*cliutil.CmdBase
+	text   string
+	prefix string
+}
+
+func (c *echoCmd) Handle() error {
+	if c.text == "fail" {
+		return errors.New("failed on purpose")
+	}
+	c.Writer.Printf("[%s%s]\n", c.prefix, c.text)
+	return nil
+}
+
+// registerEchoCmd registers echoCmd as "echo" for the duration of the test
+func registerEchoCmd(t *testing.T) {
+	t.Helper()
+	cmd := &echoCmd{}
+	cmd.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        "echo",
+		Description: "Print text",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "echo",
+			FlagDefs: []cliutil.FlagDef{{
+				Name:     "prefix",
+				Usage:    "Text to print first, or @file to read it from file",
+				Default:  "",
+				String:   &cmd.prefix,
+				FromFile: true,
+				ValidationFunc: func(value any) error {
+					if value == "bad" {
+						return errors.New("bad prefix")
+					}
+					return nil
+				},
+			}},
+		}},
+		ArgDefs: []*cliutil.ArgDef{{
+			Name:     "text",
+			Usage:    "Text to print, or - to read it from stdin",
+			Required: true,
+			String:   &cmd.text,
+			Stdin:    true,
+		}},
+	})
+	useTestCmds(t, cmd)
+}
+
+// runWithStdin runs args with stdin and returns stdout
+func runWithStdin(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	return runTestCmd(t, func(runner *cliutil.CmdRunner) {
+		runner.Args.Stdin = strings.NewReader(stdin)
+	}, args...)
+}
+
+func TestStdin_DashReadsArgument(t *testing.T) {
+	registerEchoCmd(t)
+
+	out, err := runWithStdin(t, "hello world\n", "echo", "-")
+	if err != nil {
+		t.Fatalf("echo - failed: %v", err)
+	}
+	if out != "[hello world]\n" {
+		t.Errorf("Expected the argument from stdin, got %q", out)
+	}
+
+	out, err = runWithStdin(t, "ignored", "echo", "literal")
+	if err != nil {
+		t.Fatalf("echo literal failed: %v", err)
+	}
+	if out != "[literal]\n" {
+		t.Errorf("Expected stdin to be left alone, got %q", out)
+	}
+}
+
+func TestStdin_ArgsFromStdinRunsPerLine(t *testing.T) {
+	registerEchoCmd(t)
+
+	out, err := runWithStdin(t, "one\n\ntwo words\r\nfail\nthree\n", "--args-from-stdin", "echo")
+	if err == nil {
+		t.Fatal("Expected the failing line's error")
+	}
+	if got, _ := cliutil.ErrValue[string](err, "stdin_line"); got != "fail" {
+		t.Errorf("Expected the error to name the failing line, got %q in %v", got, err)
+	}
+	if want := "[one]\n[two words]\n[three]\n"; out != want {
+		t.Errorf("Expected one run per line, got %q, want %q", out, want)
+	}
+
+	_, err = runWithStdin(t, "one\n", "--args-from-stdin", "echo", "-")
+	if !errors.Is(err, cliutil.ErrStdinInUse) {
+		t.Errorf("Expected ErrStdinInUse when - is combined with --args-from-stdin, got %v", err)
+	}
+}
+
+func TestStdin_FlagFromFile(t *testing.T) {
+	registerEchoCmd(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prefix")
+	if err := os.WriteFile(file, []byte("from-file:\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad")
+	if err := os.WriteFile(bad, []byte("bad"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "file", args: []string{"echo", "--prefix=@" + file, "x"}, want: "[from-file:x]\n"},
+		{name: "stdin", args: []string{"echo", "--prefix=@-", "x"}, want: "[from-stdin:x]\n"},
+		{name: "escaped", args: []string{"echo", "--prefix=@@at:", "x"}, want: "[@at:x]\n"},
+		{name: "plain", args: []string{"echo", "--prefix=plain:", "x"}, want: "[plain:x]\n"},
+	}
+	for _, tt := range tests {
+		out, err := runWithStdin(t, "from-stdin:\n", tt.args...)
+		if err != nil {
+			t.Errorf("%s: %v failed: %v", tt.name, tt.args, err)
+		}
+		if out != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, out)
+		}
+	}
+
+	_, err := runWithStdin(t, "x", "echo", "--prefix=@"+filepath.Join(dir, "missing"), "x")
+	if !errors.Is(err, cliutil.ErrFlagFileIO) {
+		t.Errorf("Expected ErrFlagFileIO for a missing file, got %v", err)
+	}
+	_, err = runWithStdin(t, "x", "echo", "--prefix=@"+bad, "x")
+	if err == nil || !strings.Contains(err.Error(), "bad prefix") {
+		t.Errorf("Expected the file's contents to be validated, got %v", err)
+	}
+	_, err = runWithStdin(t, "x", "echo", "--prefix=@-", "-")
+	if !errors.Is(err, cliutil.ErrStdinInUse) {
+		t.Errorf("Expected ErrStdinInUse when @- is combined with -, got %v", err)
+	}
+}
+
+func TestStdin_FlagFromFileIsLoggedAsPath(t *testing.T) {
+	registerEchoCmd(t)
+	file := filepath.Join(t.TempDir(), "prefix")
+	if err := os.WriteFile(file, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runner, args, _ := newTestRunner(t, "echo", "--prefix=@"+file, "x")
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if err = runner.RunCmd(cmd); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got := cliutil.SanitizedFlags(cmd)["prefix"]; got != "@"+file {
+		t.Errorf("Expected the flag to be logged as %q, got %v", "@"+file, got)
+	}
+}
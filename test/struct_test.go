@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/structcliutil"
+)
+
+// structMigrateCmd is a leaf command declared by struct tags
+type structMigrateCmd struct {
+	Steps  int      `short:"n" help:"Number of migrations" default:"1"`
+	Env    string   `help:"Environment" enum:"dev,prod" default:"dev"`
+	Target string   `arg:"" help:"Version to migrate to"`
+	Extra  []string `arg:"" optional:"" help:"Extra options"`
+	ran    bool
+}
+
+func (c *structMigrateCmd) Run(args cliutil.CmdRunnerArgs) error {
+	c.ran = true
+	args.Writer.Printf("migrating to %s\n", c.Target)
+	return nil
+}
+
+type structTestCLI struct {
+	Trace bool `help:"Trace SQL"`
+	DB    struct {
+		DSN     string           `name:"dsn" help:"Database DSN" sensitive:""`
+		Migrate structMigrateCmd `cmd:"" help:"Run migrations"`
+	} `cmd:"" name:"db" help:"Database commands"`
+}
+
+// runStructCmd parses and runs args against the registered commands
+func runStructCmd(t *testing.T, args ...string) (*clitest.BufferedWriter, error) {
+	t.Helper()
+	opts, args, err := cliutil.ParseGlobalOptions(append([]string{"app"}, args...))
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	writer := clitest.NewBufferedWriter()
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context: context.Background(),
+		Writer:  writer,
+		Options: opts,
+		Args:    args,
+	})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		return writer, err
+	}
+	return writer, runner.RunCmd(cmd)
+}
+
+func TestStruct_RegistersAndRunsCommands(t *testing.T) {
+	var cli structTestCLI
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	cmds, err := structcliutil.Register(&cli)
+	if err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+	if len(cmds) != 2 {
+		t.Fatalf("Expected 2 commands, got %d", len(cmds))
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	writer, err := runStructCmd(t, "--trace", "db", "migrate", "--dsn=pg://x", "-n", "3", "v42", "a", "b")
+	if err != nil {
+		t.Fatalf("Running db migrate failed: %v", err)
+	}
+	m := &cli.DB.Migrate
+	if !m.ran {
+		t.Fatal("Expected Run to be called")
+	}
+	if !cli.Trace || cli.DB.DSN != "pg://x" || m.Steps != 3 || m.Env != "dev" || m.Target != "v42" {
+		t.Errorf("Expected flags and args to be bound, got %+v", cli)
+	}
+	if !slices.Equal(m.Extra, []string{"a", "b"}) {
+		t.Errorf("Expected remaining args in Extra, got %v", m.Extra)
+	}
+	if writer.Stdout() != "migrating to v42\n" {
+		t.Errorf("Expected command output, got %q", writer.Stdout())
+	}
+
+	_, err = runStructCmd(t, "db", "migrate", "--env=staging", "v1")
+	if err == nil {
+		t.Error("Expected an enum violation to fail")
+	}
+	_, err = runStructCmd(t, "db", "migrate")
+	if err == nil {
+		t.Error("Expected a missing required arg to fail")
+	}
+}
+
+func TestStruct_RejectsInvalidStructs(t *testing.T) {
+	clitest.IsolateRegistry(t)
+
+	var noRun struct {
+		Empty struct {
+			Name string
+		} `cmd:""`
+	}
+	var badFlag struct {
+		Ratio complex128
+	}
+	for name, cli := range map[string]any{
+		"not a pointer":     struct{}{},
+		"no Run or subcmds": &noRun,
+		"unsupported flag":  &badFlag,
+	} {
+		_, err := structcliutil.Register(cli)
+		if !errors.Is(err, structcliutil.ErrInvalidStruct) {
+			t.Errorf("%s: expected ErrInvalidStruct, got %v", name, err)
+		}
+	}
+}
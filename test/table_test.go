@@ -0,0 +1,69 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func newFilesTable() *cliutil.Table {
+	return cliutil.NewTable(
+		cliutil.Column{Header: "NAME", MaxWidth: 10},
+		cliutil.Column{Header: "SIZE", Align: cliutil.RightAlign},
+		cliutil.Column{Header: "NOTE"},
+	).
+		AddRow("a.txt", 5, "new").
+		AddRow("a-very-long-name.txt", 1024, "").
+		AddRow("b\tc", 42, "two\nlines")
+}
+
+func TestTable_AlignsOnTerminal(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	writer := testutil.NewBufferedWriter()
+
+	newFilesTable().Write(writer)
+	want := "" +
+		"NAME        SIZE  NOTE\n" +
+		"a.txt          5  new\n" +
+		"a-very-lo…  1024\n" +
+		"b c           42  two lines\n"
+	if got := writer.GetStdout(); got != want {
+		t.Errorf("Write() got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTable_TabSeparatedWhenPiped(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	writer := testutil.NewBufferedWriter()
+
+	newFilesTable().Write(writer)
+	want := "" +
+		"NAME\tSIZE\tNOTE\n" +
+		"a.txt\t5\tnew\n" +
+		"a-very-long-name.txt\t1024\t\n" +
+		"b c\t42\ttwo lines\n"
+	if got := writer.GetStdout(); got != want {
+		t.Errorf("Write() got %q, want %q", got, want)
+	}
+}
+
+func TestTable_MaxWidthAndStyledCells(t *testing.T) {
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	writer := testutil.NewBufferedWriter()
+
+	table := cliutil.NewTable(cliutil.Column{}, cliutil.Column{})
+	table.MaxWidth = 12
+	table.AddRow(cliutil.Styled(writer.Writer(), cliutil.GreenStyle, "ok"), "fine").
+		AddRow("failed", "see the log for details")
+	table.Write(writer)
+	want := "" +
+		"\x1b[32mok\x1b[0m      fine\n" +
+		"failed  see…\n"
+	if got := writer.GetStdout(); got != want {
+		t.Errorf("Write() got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestFakeTerminal(t *testing.T) {
+	fake := clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true, Width: 120})
+	clitest.UseTerminal(t, fake)
+
+	term := cliutil.GetTerminal()
+	if !term.IsTTY() || term.Width() != 120 {
+		t.Errorf("Expected fake TTY of width 120, got tty=%v width=%d", term.IsTTY(), term.Width())
+	}
+
+	restore, err := term.MakeRaw()
+	if err != nil {
+		t.Fatalf("MakeRaw() failed: %v", err)
+	}
+	if !fake.IsRaw() {
+		t.Error("Expected raw mode after MakeRaw()")
+	}
+	_ = restore()
+	if fake.IsRaw() {
+		t.Error("Expected raw mode to be restored")
+	}
+
+	_ = term.SetEcho(false)
+	if fake.EchoEnabled() {
+		t.Error("Expected echo to be disabled")
+	}
+}
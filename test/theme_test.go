@@ -0,0 +1,54 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestTheme_StylesHelpAndErrors(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	clitest.UseTheme(t, cliutil.Theme{
+		Heading: cliutil.MagentaStyle,
+		Flag:    cliutil.CyanStyle,
+		Error:   cliutil.BlueStyle,
+	})
+
+	err := cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer})
+	if err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	help := writer.GetStdout()
+	if !strings.Contains(help, "\x1b[35mOPTIONS:\x1b[0m") {
+		t.Errorf("Expected headings in the theme's Heading style, got %q", help)
+	}
+	if !strings.Contains(help, "\x1b[36m") {
+		t.Errorf("Expected flags in the theme's Flag style, got %q", help)
+	}
+
+	errWriter := testutil.NewBufferedWriter()
+	cliutil.ReportError(errWriter, errTest)
+	if !errWriter.ContainsStderr("\x1b[34mError\x1b[0m: test failure") {
+		t.Errorf("Expected the theme's Error style, got %q", errWriter.GetStderr())
+	}
+}
+
+func TestTheme_NotUsedWhenPiped(t *testing.T) {
+	_, _, writer := newTestRunner(t)
+	clitest.UseEnvironment(t, clitest.NewFakeEnvironment(clitest.FakeEnvironmentArgs{}))
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	clitest.UseTheme(t, cliutil.Theme{Heading: cliutil.MagentaStyle})
+
+	err := cliutil.ShowCmdHelp([]string{"parsetest"}, cliutil.UsageArgs{Writer: writer})
+	if err != nil {
+		t.Fatalf("ShowCmdHelp() failed: %v", err)
+	}
+	if help := writer.GetStdout(); strings.Contains(help, "\x1b[") {
+		t.Errorf("Expected help without color when piped, got %q", help)
+	}
+}
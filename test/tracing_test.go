@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/otelcliutil"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanCheckCmd records whether its Context carries a valid span
+type spanCheckCmd struct {
+	*cliutil.CmdBase
+	sawSpan bool
+	err     error
+}
+
+func (c *spanCheckCmd) Handle() error {
+	c.sawSpan = trace.SpanContextFromContext(c.Context).IsValid()
+	return c.err
+}
+
+func TestRunCmd_StartsCommandSpan(t *testing.T) {
+	var env, password string
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := cliutil.SetTracer(otelcliutil.NewTracer(provider.Tracer("test")))
+	t.Cleanup(func() { cliutil.SetTracer(prev) })
+
+	cmd := &spanCheckCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "spantest",
+			Description: "Check tracing",
+			FlagSets: []*cliutil.FlagSet{{
+				Name: "spantest",
+				FlagDefs: []cliutil.FlagDef{
+					{Name: "env", Usage: "Environment", String: &env},
+					{Name: "password", Usage: "Password", String: &password},
+				},
+			}},
+		}),
+		err: errors.New("deploy failed"),
+	}
+	if err := cliutil.RegisterCommand(cmd); err != nil {
+		t.Fatalf("RegisterCommand() failed: %v", err)
+	}
+	if err := cliutil.BuildCommandTree(); err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	opts, args, err := cliutil.ParseGlobalOptions([]string{"app", "spantest", "--env=prod", "--password=hunter2"})
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context:      context.Background(),
+		Writer:       clitest.NewBufferedWriter(),
+		Options:      opts,
+		Args:         args,
+		InvocationID: "run-7",
+	})
+	parsed, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	_ = runner.RunCmd(parsed)
+
+	if !cmd.sawSpan {
+		t.Error("Expected the handler's Context to carry the command span")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 ended span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "spantest" {
+		t.Errorf("Expected span named spantest, got %q", span.Name())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("Expected Error status, got %v", span.Status())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["cli.flag.env"] != "prod" || attrs[cliutil.SpanAttrInvocationID] != "run-7" {
+		t.Errorf("Expected env and invocation ID attributes, got %v", attrs)
+	}
+	if _, ok := attrs["cli.flag.password"]; ok {
+		t.Error("Expected sensitive flags to be omitted from span attributes")
+	}
+}
+
+func TestStartCommandSpan_ChildOfIncomingSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "ci-job")
+
+	_, end := otelcliutil.NewTracer(provider.Tracer("test")).StartCommandSpan(ctx, "deploy", nil)
+	end(nil)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 ended spans, got %d", len(spans))
+	}
+	cmdSpan := spans[0]
+	if cmdSpan.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected the command span's parent to be %v, got %v",
+			parent.SpanContext().SpanID(), cmdSpan.Parent().SpanID())
+	}
+	if cmdSpan.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Error("Expected the command span to share the incoming trace")
+	}
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+// runWithUpdateCheck runs parsetest with globalArgs as version v1.0.0 and
+// returns stderr
+func runWithUpdateCheck(t *testing.T, globalArgs ...string) string {
+	t.Helper()
+	runner, args, writer := newTestRunner(t, append(globalArgs, "parsetest", "x")...)
+	runner.Args.AppInfo = appinfo.New(appinfo.Args{Name: "app", Version: "v1.0.0"})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		t.Fatalf("ParseCmd() failed: %v", err)
+	}
+	if err := runner.RunCmd(cmd); err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	return writer.GetStderr()
+}
+
+// roundTripFunc is a fake update source for an http.Client
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestUpdateCheck_NotifiesOncePerInterval(t *testing.T) {
+	clearCIEnv(t)
+	t.Setenv(cliutil.NoUpdateCheckEnv, "")
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = fmt.Fprint(w, `{"tag_name":"v1.1.0"}`)
+	}))
+	t.Cleanup(server.Close)
+	cacheDir := t.TempDir()
+	cliutil.EnableUpdateCheck(cliutil.UpdateCheckArgs{URL: server.URL, CacheDir: cacheDir})
+	t.Cleanup(cliutil.DisableUpdateCheck)
+
+	for i := 0; i < 3; i++ {
+		stderr := runWithUpdateCheck(t)
+		if !strings.Contains(stderr, "A newer version v1.1.0 is available (you have v1.0.0)") {
+			t.Errorf("Run %d: expected an update notice, got %q", i+1, stderr)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Expected the endpoint to be queried once, got %d", got)
+	}
+
+	t.Setenv("CI", "true")
+	if stderr := runWithUpdateCheck(t); strings.Contains(stderr, "newer version") {
+		t.Errorf("Expected no notice under CI, got %q", stderr)
+	}
+	t.Setenv("CI", "")
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: false}))
+	if stderr := runWithUpdateCheck(t); strings.Contains(stderr, "newer version") {
+		t.Errorf("Expected no notice without a terminal, got %q", stderr)
+	}
+}
+
+func TestUpdateCheck_NotifiesWhenQuiet(t *testing.T) {
+	clearCIEnv(t)
+	t.Setenv(cliutil.NoUpdateCheckEnv, "")
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "v2.0.0\n")
+	}))
+	t.Cleanup(server.Close)
+	cacheDir := t.TempDir()
+	cliutil.EnableUpdateCheck(cliutil.UpdateCheckArgs{
+		URL:      server.URL,
+		CacheDir: cacheDir,
+		Command:  "app self-update",
+	})
+	t.Cleanup(cliutil.DisableUpdateCheck)
+	clitest.ResetGlobalOptions(t)
+
+	stderr := runWithUpdateCheck(t, "--quiet")
+	want := "A newer version v2.0.0 is available (you have v1.0.0); run 'app self-update' to update\n"
+	if stderr != want {
+		t.Errorf("Expected %q under --quiet, got %q", want, stderr)
+	}
+}
+
+func TestUpdateCheck_CachesResultOfQuickCommand(t *testing.T) {
+	clearCIEnv(t)
+	t.Setenv(cliutil.NoUpdateCheckEnv, "")
+	clitest.UseTerminal(t, clitest.NewFakeTerminal(clitest.FakeTerminalArgs{TTY: true}))
+	// Slower than parsetest, so the check is still running when it completes
+	source := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		time.Sleep(50 * time.Millisecond)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Body:       io.NopCloser(strings.NewReader(`{"version":"v1.2.0"}`)),
+			Request:    r,
+		}, nil
+	})
+	cacheDir := t.TempDir()
+	cliutil.EnableUpdateCheck(cliutil.UpdateCheckArgs{
+		URL:      "https://example.com/releases/latest",
+		CacheDir: cacheDir,
+		Client:   &http.Client{Transport: source},
+	})
+	t.Cleanup(cliutil.DisableUpdateCheck)
+
+	stderr := runWithUpdateCheck(t)
+	if !strings.Contains(stderr, "A newer version v1.2.0 is available") {
+		t.Errorf("Expected an update notice, got %q", stderr)
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, "update-check.json"))
+	if err != nil {
+		t.Fatalf("Expected the check to be cached when the command completes: %v", err)
+	}
+	if !strings.Contains(string(data), "v1.2.0") {
+		t.Errorf("Expected v1.2.0 cached, got %s", data)
+	}
+}
+
+func TestCompareVersions_FollowsSemVerPrecedence(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "v1.2.3", b: "1.2.3", want: 0},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{a: "1.0.0-rc.2", b: "1.0.0-rc.10", want: -1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{a: "1.0.0-beta.11", b: "1.0.0-rc.1", want: -1},
+		{a: "1.0.0+build.5", b: "1.0.0", want: 0},
+		{a: "1.0.0-rc.1+build.1", b: "1.0.0-rc.1+build.2", want: 0},
+		{a: "", b: "0.0.1", want: -1},
+	}
+	for _, tt := range tests {
+		got := cliutil.CompareVersions(dt.Version(tt.a), dt.Version(tt.b))
+		if got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if back := cliutil.CompareVersions(dt.Version(tt.b), dt.Version(tt.a)); back != -tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, back, -tt.want)
+		}
+	}
+}
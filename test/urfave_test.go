@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+	"github.com/mikeschinkel/go-cliutil/urfavecliutil"
+	"github.com/urfave/cli/v2"
+)
+
+// urfaveRun records what a converted urfave/cli Action was run with
+type urfaveRun struct {
+	hooks   []string
+	args    []string
+	env     string
+	count   int
+	wait    time.Duration
+	tags    []string
+	verbose bool
+}
+
+// newURFaveApp returns an app with a global flag, an aliased command with
+// several flag types, and a command with a subcommand
+func newURFaveApp(run *urfaveRun) *cli.App {
+	return &cli.App{
+		Name: "app",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "debug", Usage: "Debug output"},
+		},
+		Before: func(*cli.Context) error {
+			run.hooks = append(run.hooks, "app-before")
+			return nil
+		},
+		After: func(*cli.Context) error {
+			run.hooks = append(run.hooks, "app-after")
+			return nil
+		},
+		Commands: []*cli.Command{
+			{
+				Name:      "deploy",
+				Aliases:   []string{"d"},
+				Usage:     "Deploy a service",
+				ArgsUsage: "<service>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "env", Aliases: []string{"e", "environment"}, Value: "dev", Usage: "Target environment"},
+					&cli.IntFlag{Name: "count", Value: 1, Usage: "Instances"},
+					&cli.DurationFlag{Name: "wait", Value: time.Second, Usage: "Wait between instances"},
+					&cli.StringSliceFlag{Name: "tag", Usage: "Tags"},
+				},
+				Action: func(c *cli.Context) error {
+					run.hooks = append(run.hooks, "action")
+					run.args = c.Args().Slice()
+					run.env = c.String("env")
+					run.count = c.Int("count")
+					run.wait = c.Duration("wait")
+					run.tags = c.StringSlice("tag")
+					run.verbose = c.Bool("debug")
+					if c.Context == nil {
+						return errors.New("no context")
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "db",
+				Usage: "Database commands",
+				Before: func(*cli.Context) error {
+					run.hooks = append(run.hooks, "db-before")
+					return nil
+				},
+				Subcommands: []*cli.Command{{
+					Name:  "migrate",
+					Usage: "Run migrations",
+					Action: func(*cli.Context) error {
+						run.hooks = append(run.hooks, "migrate")
+						return errors.New("migration failed")
+					},
+				}},
+			},
+		},
+	}
+}
+
+// runURFaveArgs converts a fresh app and runs args through cliutil
+func runURFaveArgs(t *testing.T, args ...string) (*urfaveRun, error) {
+	t.Helper()
+	newTestRunner(t)
+	clitest.IsolateRegistry(t)
+
+	run := &urfaveRun{}
+	_, err := urfavecliutil.FromApp(newURFaveApp(run))
+	if err != nil {
+		t.Fatalf("FromApp() failed: %v", err)
+	}
+	err = cliutil.BuildCommandTree()
+	if err != nil {
+		t.Fatalf("BuildCommandTree() failed: %v", err)
+	}
+
+	opts, args, err := cliutil.ParseGlobalOptions(append([]string{"app"}, args...))
+	if err != nil {
+		t.Fatalf("ParseGlobalOptions() failed: %v", err)
+	}
+	runner := cliutil.NewCmdRunner(cliutil.CmdRunnerArgs{
+		Context: context.Background(),
+		Writer:  clitest.NewBufferedWriter(),
+		Options: opts,
+		Args:    args,
+	})
+	cmd, err := runner.ParseCmd(args)
+	if err != nil {
+		return run, err
+	}
+	return run, runner.RunCmd(cmd)
+}
+
+func TestFromApp_RunsActionWithFlagsAndArgs(t *testing.T) {
+	run, err := runURFaveArgs(t, "deploy", "-e", "prod", "--count=3", "--wait=5s", "--tag=a", "--debug", "web")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if got := strings.Join(run.hooks, ","); got != "app-before,action,app-after" {
+		t.Errorf("Expected hooks app-before,action,app-after, got %s", got)
+	}
+	if run.env != "prod" || run.count != 3 || run.wait != 5*time.Second || !run.verbose {
+		t.Errorf("Expected env=prod count=3 wait=5s debug, got %+v", run)
+	}
+	if len(run.tags) != 1 || run.tags[0] != "a" {
+		t.Errorf("Expected tags [a], got %v", run.tags)
+	}
+	if len(run.args) != 1 || run.args[0] != "web" {
+		t.Errorf("Expected args [web], got %v", run.args)
+	}
+}
+
+func TestFromApp_Aliases(t *testing.T) {
+	run, err := runURFaveArgs(t, "d", "--environment=staging", "api")
+	if err != nil {
+		t.Fatalf("RunCmd() failed: %v", err)
+	}
+	if run.env != "staging" {
+		t.Errorf("Expected env=staging via the long flag alias, got %q", run.env)
+	}
+	if len(run.args) != 1 || run.args[0] != "api" {
+		t.Errorf("Expected args [api], got %v", run.args)
+	}
+}
+
+func TestFromApp_SubcommandRunsAfterFuncsOnError(t *testing.T) {
+	run, err := runURFaveArgs(t, "db", "migrate")
+	if err == nil || !strings.Contains(err.Error(), "migration failed") {
+		t.Errorf("Expected the Action's error, got %v", err)
+	}
+	if got := strings.Join(run.hooks, ","); got != "app-before,db-before,migrate,app-after" {
+		t.Errorf("Expected hooks app-before,db-before,migrate,app-after, got %s", got)
+	}
+}
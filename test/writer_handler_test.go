@@ -0,0 +1,90 @@
+package test
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestWriterHandler_RoutesByLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		verbosity  cliutil.Verbosity
+		quiet      bool
+		wantStdout []string
+		skipStdout []string
+	}{
+		{
+			name:       "low verbosity shows neither debug nor info",
+			verbosity:  cliutil.LowVerbosity,
+			skipStdout: []string{"debug msg", "info msg"},
+		},
+		{
+			name:       "medium verbosity shows info",
+			verbosity:  cliutil.MediumVerbosity,
+			wantStdout: []string{"info msg"},
+			skipStdout: []string{"debug msg"},
+		},
+		{
+			name:       "high verbosity shows debug and info",
+			verbosity:  cliutil.HighVerbosity,
+			wantStdout: []string{"debug msg", "info msg"},
+		},
+		{
+			name:       "quiet hides info even at high verbosity",
+			verbosity:  cliutil.HighVerbosity,
+			quiet:      true,
+			skipStdout: []string{"debug msg", "info msg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := clitest.NewBufferedWriter()
+			w.SetVerbosity(tt.verbosity)
+			w.SetQuiet(tt.quiet)
+			logger := slog.New(cliutil.NewWriterHandler(w, nil))
+
+			logger.Debug("debug msg")
+			logger.Info("info msg")
+			logger.Warn("warn msg", "disk", "91%")
+			logger.Error("error msg", "err", errors.New("boom"))
+
+			for _, s := range tt.wantStdout {
+				if !strings.Contains(w.Stdout(), s) {
+					t.Errorf("Expected stdout to contain %q, got %q", s, w.Stdout())
+				}
+			}
+			for _, s := range tt.skipStdout {
+				if strings.Contains(w.Stdout(), s) {
+					t.Errorf("Expected stdout not to contain %q, got %q", s, w.Stdout())
+				}
+			}
+			// Warnings and errors are always shown
+			if !strings.Contains(w.Stderr(), "Warning: warn msg disk=91%") {
+				t.Errorf("Expected warning on stderr, got %q", w.Stderr())
+			}
+			if !strings.Contains(w.Stderr(), "Error: error msg err=boom") {
+				t.Errorf("Expected error on stderr, got %q", w.Stderr())
+			}
+		})
+	}
+}
+
+func TestWriterHandler_AttrsAndGroups(t *testing.T) {
+	w := clitest.NewBufferedWriter()
+	logger := slog.New(cliutil.NewWriterHandler(w, &cliutil.WriterHandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	logger.Debug("filtered by level")
+	logger.With("cmd", "deploy").WithGroup("req").Info("sent", "id", 7, slog.Group("peer", "host", "a b"))
+
+	want := `sent cmd=deploy req.id=7 req.peer.host="a b"` + "\n"
+	if w.Stdout() != want {
+		t.Errorf("Expected %q, got %q", want, w.Stdout())
+	}
+}
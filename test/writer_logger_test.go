@@ -0,0 +1,67 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-cliutil/clitest"
+)
+
+func TestWriterLogger_JSONOutput(t *testing.T) {
+	w := clitest.NewBufferedWriter()
+	wl := cliutil.NewWriterLogger(w, slog.New(slog.NewTextHandler(io.Discard, nil))).WithJSONOutput(true)
+
+	wl.InfoPrint("synced", "files", 3)
+	wl.WarnError("slow disk", "latency_ms", 250)
+	err := wl.ErrorError("upload failed", "bucket", "b1", errors.New("timeout"))
+	if err == nil {
+		t.Fatal("Expected ErrorError() to return an error")
+	}
+
+	if w.Stdout() != "" {
+		t.Errorf("Expected nothing on stdout in JSON mode, got %q", w.Stdout())
+	}
+	lines := strings.Split(strings.TrimSpace(w.Stderr()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 JSON records, got %d: %q", len(lines), w.Stderr())
+	}
+	want := []map[string]any{
+		{"level": "INFO", "msg": "synced", "files": float64(3)},
+		{"level": "WARN", "msg": "slow disk", "latency_ms": float64(250)},
+		{"level": "ERROR", "msg": "upload failed", "bucket": "b1", "error": "timeout"},
+	}
+	for i, line := range lines {
+		var got map[string]any
+		if err = json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("Record %d is not JSON: %v: %q", i, err, line)
+		}
+		if len(got) != len(want[i]) {
+			t.Errorf("Record %d: expected keys %v, got %v", i, want[i], got)
+		}
+		for k, v := range want[i] {
+			if got[k] != v {
+				t.Errorf("Record %d: expected %s=%v, got %v", i, k, v, got[k])
+			}
+		}
+	}
+}
+
+func TestWriterLogger_TextOutputByDefault(t *testing.T) {
+	w := clitest.NewBufferedWriter()
+	wl := cliutil.NewWriterLogger(w, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if wl.IsJSONOutput() || wl.V2().IsJSONOutput() {
+		t.Error("Expected text output by default")
+	}
+	if !wl.WithJSONOutput(true).V3().IsJSONOutput() {
+		t.Error("Expected V3() to inherit JSON output")
+	}
+	wl.InfoPrint("synced", "files", 3)
+	if w.Stdout() != "synced; files=3\n" {
+		t.Errorf("Expected text record on stdout, got %q", w.Stdout())
+	}
+}
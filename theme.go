@@ -0,0 +1,86 @@
+package cliutil
+
+import (
+	"io"
+	"sync"
+	"text/template"
+)
+
+// Theme gives the Style of each part of help and reported errors, so a CLI
+// can use its own colors without replacing the usage templates. Parts left
+// as NoStyle are not colored. Colors are only used when ColorEnabled.
+type Theme struct {
+	Heading Style // Section headings such as USAGE: and OPTIONS:
+	Command Style // Command names in lists of commands
+	Flag    Style // Flags and args in lists of options
+	Example Style // Example command lines
+	Error   Style // The "Error" that starts a reported error
+}
+
+// DefaultTheme is the Theme used until SetTheme is called
+var DefaultTheme = Theme{
+	Heading: BoldStyle,
+	Command: CyanStyle,
+	Flag:    YellowStyle,
+	Example: GreenStyle,
+	Error:   Styles(BoldStyle, RedStyle),
+}
+
+// Package-level theme instance
+var (
+	theme   = DefaultTheme
+	themeMu sync.RWMutex // synchronizes access to theme
+)
+
+// SetTheme replaces the Theme used for help and reported errors and returns
+// the previous one so it can be restored. Use Theme{} for no color at all.
+func SetTheme(t Theme) (prev Theme) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+	prev = theme
+	theme = t
+	return prev
+}
+
+// GetTheme returns the Theme used for help and reported errors
+func GetTheme() Theme {
+	themeMu.RLock()
+	defer themeMu.RUnlock()
+	return theme
+}
+
+// themeFuncs returns the template functions that style text with the parts
+// of the current Theme, as in {{heading "OPTIONS:"}}, for writing to out
+// under cli's --no-color
+func themeFuncs(cli *CLI, out io.Writer) template.FuncMap {
+	t := GetTheme()
+	styler := func(style Style) func(string) string {
+		return func(text string) string {
+			return cli.Styled(out, style, text)
+		}
+	}
+	return template.FuncMap{
+		"heading": styler(t.Heading),
+		"command": styler(t.Command),
+		"flag":    styler(t.Flag),
+		"example": styler(t.Example),
+	}
+}
+
+// executeTemplate executes tmpl with data into w, styling with the current
+// Theme as suits out, the writer the result is finally written to, under
+// cli's --no-color
+func executeTemplate(cli *CLI, tmpl *template.Template, w, out io.Writer, data any) (err error) {
+	var funcs template.FuncMap
+
+	tmpl, err = tmpl.Clone()
+	if err != nil {
+		goto end
+	}
+	templatesMu.Lock()
+	funcs = templateFuncMap(cli, out)
+	templatesMu.Unlock()
+	err = tmpl.Funcs(funcs).Execute(w, data)
+end:
+	return err
+}
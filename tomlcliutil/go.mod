@@ -0,0 +1,16 @@
+module github.com/mikeschinkel/go-cliutil/tomlcliutil
+
+go 1.25.3
+
+replace github.com/mikeschinkel/go-cliutil => ..
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/mikeschinkel/go-cliutil v0.3.0
+)
+
+require (
+	github.com/mikeschinkel/go-dt v0.3.3 // indirect
+	github.com/mikeschinkel/go-dt/appinfo v0.2.1 // indirect
+	github.com/mikeschinkel/go-dt/dtx v0.2.1 // indirect
+)
@@ -0,0 +1,26 @@
+// Package tomlcliutil lets cliutil load TOML config files, which cliutil
+// cannot do itself without depending on a TOML package:
+//
+//	tomlcliutil.Register()
+//	err := cliutil.EnableConfig(cliutil.ConfigArgs{Files: []string{"myapp.toml"}})
+package tomlcliutil
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+var _ cliutil.ConfigDecoder = Decode
+
+// Register makes cliutil decode config files ending in .toml with Decode
+func Register() {
+	cliutil.RegisterConfigFormat(".toml", Decode)
+}
+
+// Decode decodes a TOML document into the keys and values of a config file,
+// rejecting anything outside the TOML 1.0 grammar, such as integers with
+// leading zeros or misplaced underscores
+func Decode(data []byte) (values map[string]any, err error) {
+	err = toml.Unmarshal(data, &values)
+	return values, err
+}
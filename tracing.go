@@ -0,0 +1,68 @@
+package cliutil
+
+import (
+	"context"
+	"sync"
+)
+
+// SpanEndFunc ends a span started by a Tracer, recording err if non-nil
+type SpanEndFunc func(err error)
+
+// Tracer starts a span for each command run by CmdRunner, so CLI
+// actions appear in existing observability pipelines. Implementations adapt
+// a tracing library; see the otelcliutil module for OpenTelemetry.
+type Tracer interface {
+	// StartCommandSpan starts a span named name (the command path) with the
+	// given attributes, as a child of any span in ctx, and returns a context
+	// carrying the span
+	StartCommandSpan(ctx context.Context, name string, attrs map[string]any) (context.Context, SpanEndFunc)
+}
+
+// Package-level tracer instance; nil disables tracing
+var (
+	tracer   Tracer
+	tracerMu sync.RWMutex // synchronizes access to tracer
+)
+
+// SetTracer installs the Tracer used by CmdRunner.RunCmd, returning the
+// previous one. Pass nil to disable tracing.
+func SetTracer(t Tracer) (prev Tracer) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	prev = tracer
+	tracer = t
+	return prev
+}
+
+// GetTracer returns the Tracer used by CmdRunner.RunCmd, or nil
+func GetTracer() Tracer {
+	tracerMu.RLock()
+	defer tracerMu.RUnlock()
+	return tracer
+}
+
+// Span attribute keys CmdRunner passes to Tracer.StartCommandSpan
+const (
+	SpanAttrInvocationID = "cli.invocation_id"
+	SpanAttrFlagPrefix   = "cli.flag."
+)
+
+// startCommandSpan starts a span for cmd when a Tracer is installed. Only
+// flags that are not sensitive become attributes.
+func startCommandSpan(ctx context.Context, cmd Command, invocationID string) (context.Context, SpanEndFunc) {
+	t := GetTracer()
+	if t == nil {
+		return ctx, func(error) {}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs := map[string]any{SpanAttrInvocationID: invocationID}
+	for name, value := range SanitizedFlags(cmd) {
+		if value == RedactedValue {
+			continue
+		}
+		attrs[SpanAttrFlagPrefix+name] = value
+	}
+	return t.StartCommandSpan(ctx, CmdPath(cmd), attrs)
+}
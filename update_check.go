@@ -0,0 +1,377 @@
+package cliutil
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/appinfo"
+)
+
+const (
+	DefaultUpdateInterval = 24 * time.Hour  // Query the release endpoint at most once a day
+	DefaultUpdateTimeout  = 2 * time.Second // Give up on the release endpoint after this long
+
+	// NoUpdateCheckEnv disables the update check when set to a non-empty value
+	NoUpdateCheckEnv = "CLIUTIL_NO_UPDATE_CHECK"
+)
+
+var ErrCacheDirUnknown = errors.New("unable to determine cache directory")
+
+// UpdateCheckArgs configures EnableUpdateCheck
+type UpdateCheckArgs struct {
+	URL      string        // Release endpoint returning JSON with "tag_name" or "version", or a bare version
+	Interval time.Duration // How often to query URL; defaults to DefaultUpdateInterval
+	Timeout  time.Duration // How long to wait for URL; defaults to DefaultUpdateTimeout
+	CacheDir string        // Where to remember the last check; defaults to CacheDir(AppInfo)
+	Client   *http.Client  // OPTIONAL: defaults to http.DefaultClient
+	Command  string        // OPTIONAL: how to update, e.g. "myapp self-update", added to the notice
+}
+
+var (
+	updateCheckMu sync.Mutex
+	updateCheck   *UpdateCheckArgs
+)
+
+// EnableUpdateCheck makes RunCmd check args.URL for a newer release of the
+// app and, after the command completes, print one line to stderr when there
+// is one, even under --quiet (see Writer.Loud). The endpoint is queried in
+// the background while the command runs, at most once per Interval, with the
+// result cached in CacheDir. Nothing is checked when stdout is not a
+// terminal, under CI (see IsCI), when NoUpdateCheckEnv is set, or when the
+// app's version is unknown. A query still running when the command completes
+// is waited for up to Timeout, so its result is cached even for quick
+// commands.
+func EnableUpdateCheck(args UpdateCheckArgs) {
+	updateCheckMu.Lock()
+	defer updateCheckMu.Unlock()
+	updateCheck = &args
+}
+
+// DisableUpdateCheck turns off the check enabled by EnableUpdateCheck
+func DisableUpdateCheck() {
+	updateCheckMu.Lock()
+	defer updateCheckMu.Unlock()
+	updateCheck = nil
+}
+
+// CacheDir returns the platform's per-user cache directory for the app (see
+// os.UserCacheDir) joined with AppInfo.AppSlug(), or the executable name.
+func CacheDir(ai appinfo.AppInfo) (dir string, err error) {
+	var app string
+
+	if ai != nil {
+		app = string(ai.AppSlug())
+	}
+	if app == "" {
+		app = filepath.Base(os.Args[0])
+	}
+	dir, err = os.UserCacheDir()
+	if err != nil {
+		err = NewErr(ErrCacheDirUnknown, err)
+		goto end
+	}
+	dir = filepath.Join(dir, app)
+end:
+	return dir, err
+}
+
+// updateCheckState is the cached result of the last update check
+type updateCheckState struct {
+	CheckedAt time.Time  `json:"checked_at"`
+	Latest    dt.Version `json:"latest,omitempty"`
+}
+
+const updateCheckFile = "update-check.json"
+
+// startUpdateCheck starts checking for a newer version than ai's if the
+// check is enabled and applies, and returns a func that reports the result
+// to w once the command completes; the func does nothing otherwise
+func startUpdateCheck(ctx context.Context, ai appinfo.AppInfo) (notify func(w Writer)) {
+	var args UpdateCheckArgs
+	var current dt.Version
+	var result chan dt.Version
+
+	notify = func(Writer) {}
+	updateCheckMu.Lock()
+	if updateCheck != nil {
+		args = *updateCheck
+	}
+	updateCheckMu.Unlock()
+	if args.URL == "" || ai == nil || !updateCheckApplies() {
+		goto end
+	}
+	current = ai.Version()
+	if current == "" {
+		goto end
+	}
+	if args.Interval <= 0 {
+		args.Interval = DefaultUpdateInterval
+	}
+	if args.Timeout <= 0 {
+		args.Timeout = DefaultUpdateTimeout
+	}
+	if args.CacheDir == "" {
+		dir, err := CacheDir(ai)
+		if err != nil {
+			goto end
+		}
+		args.CacheDir = dir
+	}
+	if args.Client == nil {
+		args.Client = http.DefaultClient
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	result = make(chan dt.Version, 1)
+	if latest, fresh := cachedVersion(args); fresh {
+		result <- latest
+	} else {
+		// The context is detached so a cancelled command does not leave a
+		// check half-written
+		go func() {
+			result <- fetchAndCacheVersion(context.WithoutCancel(ctx), args, latest)
+		}()
+	}
+	notify = func(w Writer) {
+		var latest dt.Version
+		// A check still running when the command completes is waited for,
+		// else a quick command would exit before its result is cached
+		timer := time.NewTimer(args.Timeout)
+		defer timer.Stop()
+		select {
+		case latest = <-result:
+		case <-timer.C:
+			return
+		}
+		if CompareVersions(latest, current) <= 0 {
+			return
+		}
+		notice := Msg(MsgUpdateAvailable, latest, current)
+		if args.Command != "" {
+			notice = Msg(MsgUpdateCommand, notice, args.Command)
+		}
+		w.Loud().Errorf("%s\n", notice)
+	}
+end:
+	return notify
+}
+
+// updateCheckApplies returns false where a notice would be unwanted
+func updateCheckApplies() bool {
+	return os.Getenv(NoUpdateCheckEnv) == "" && !IsCI() && GetTerminal().IsTTY()
+}
+
+// cachedVersion returns the latest version cached in args.CacheDir, with
+// fresh false when it was not checked within args.Interval
+func cachedVersion(args UpdateCheckArgs) (latest dt.Version, fresh bool) {
+	var state updateCheckState
+
+	data, err := os.ReadFile(filepath.Join(args.CacheDir, updateCheckFile))
+	if err != nil {
+		goto end
+	}
+	if json.Unmarshal(data, &state) != nil {
+		goto end
+	}
+	latest = state.Latest
+	fresh = GetClock().Now().Sub(state.CheckedAt) < args.Interval
+end:
+	return latest, fresh
+}
+
+// fetchAndCacheVersion returns the latest version from args.URL, caching
+// the result, or the previously cached version when it is unavailable
+func fetchAndCacheVersion(ctx context.Context, args UpdateCheckArgs, cached dt.Version) (latest dt.Version) {
+	var state updateCheckState
+	var data []byte
+	var tmp *os.File
+	var cancel context.CancelFunc
+	var err error
+
+	now := GetClock().Now()
+	ctx, cancel = context.WithTimeout(ctx, args.Timeout)
+	defer cancel()
+	latest, err = fetchLatestVersion(ctx, args)
+	if err != nil {
+		// Failures also wait out the interval, so an unreachable endpoint
+		// is not queried on every run
+		latest = cached
+	}
+	state = updateCheckState{CheckedAt: now, Latest: latest}
+	data, err = json.Marshal(state)
+	if err != nil {
+		goto end
+	}
+	err = os.MkdirAll(args.CacheDir, 0o755)
+	if err != nil {
+		goto end
+	}
+	// Written then renamed so a concurrent run never reads a partial file
+	tmp, err = os.CreateTemp(args.CacheDir, updateCheckFile+".*")
+	if err != nil {
+		goto end
+	}
+	_, err = tmp.Write(data)
+	err = CombineErrs([]error{err, tmp.Close()})
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(args.CacheDir, updateCheckFile))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+end:
+	return latest
+}
+
+// fetchLatestVersion queries args.URL for the latest version
+func fetchLatestVersion(ctx context.Context, args UpdateCheckArgs) (latest dt.Version, err error) {
+	var req *http.Request
+	var resp *http.Response
+	var body []byte
+	var release struct {
+		TagName string `json:"tag_name"`
+		Version string `json:"version"`
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, args.URL, nil)
+	if err != nil {
+		goto end
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err = args.Client.Do(req)
+	if err != nil {
+		goto end
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+		goto end
+	}
+	body, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		goto end
+	}
+	switch {
+	case json.Unmarshal(body, &release) != nil:
+		latest = dt.Version(strings.TrimSpace(string(body)))
+	case release.TagName != "":
+		latest = dt.Version(release.TagName)
+	default:
+		latest = dt.Version(release.Version)
+	}
+end:
+	return latest, err
+}
+
+// CompareVersions compares semantic versions like "v1.2.3" and "1.3.0-rc.1"
+// by the precedence of SemVer 2.0.0: numeric parts compare numerically, a
+// pre-release is below its release, and build metadata after "+" is
+// ignored. It returns -1, 0, or +1; an empty version is lowest.
+func CompareVersions(a, b dt.Version) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	case b == "":
+		return 1
+	}
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := range max(len(aParts), len(bParts)) {
+		an, bn := "0", "0"
+		if i < len(aParts) {
+			an = aParts[i]
+		}
+		if i < len(bParts) {
+			bn = bParts[i]
+		}
+		if c := compareNumeric(an, bn); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return comparePreReleases(aPre, bPre)
+}
+
+// splitVersion returns v's dot-separated core and pre-release, without any
+// leading "v" or "+" build metadata
+func splitVersion(v dt.Version) (core, pre string) {
+	s, _, _ := strings.Cut(strings.TrimPrefix(string(v), "v"), "+")
+	core, pre, _ = strings.Cut(s, "-")
+	return core, pre
+}
+
+// comparePreReleases compares pre-releases identifier by identifier: numeric
+// identifiers numerically and below alphanumeric ones, which compare in
+// ASCII order, with a longer pre-release higher when all else is equal
+func comparePreReleases(a, b string) int {
+	aIDs, bIDs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(aIDs), len(bIDs)) {
+		aNum, bNum := isNumeric(aIDs[i]), isNumeric(bIDs[i])
+		var c int
+		switch {
+		case aNum && bNum:
+			c = compareNumeric(aIDs[i], bIDs[i])
+		case aNum:
+			c = -1
+		case bNum:
+			c = 1
+		default:
+			c = strings.Compare(aIDs[i], bIDs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(aIDs), len(bIDs))
+}
+
+// compareNumeric compares strings of digits by their value, however long;
+// anything that is not digits counts as zero
+func compareNumeric(a, b string) int {
+	if !isNumeric(a) {
+		a = "0"
+	}
+	if !isNumeric(b) {
+		b = "0"
+	}
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if c := cmp.Compare(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// isNumeric returns true if s is a non-empty string of ASCII digits
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,19 @@
+module github.com/mikeschinkel/go-cliutil/urfavecliutil
+
+go 1.25.3
+
+replace github.com/mikeschinkel/go-cliutil => ..
+
+require (
+	github.com/mikeschinkel/go-cliutil v0.3.0
+	github.com/urfave/cli/v2 v2.27.7
+)
+
+require (
+	github.com/cpuguy83/go-md2man/v2 v2.0.7 // indirect
+	github.com/mikeschinkel/go-dt v0.3.3 // indirect
+	github.com/mikeschinkel/go-dt/appinfo v0.2.1 // indirect
+	github.com/mikeschinkel/go-dt/dtx v0.2.1 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
+)
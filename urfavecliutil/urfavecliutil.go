@@ -0,0 +1,349 @@
+// Package urfavecliutil converts urfave/cli v2 App and Command definitions
+// into cliutil commands, lowering the cost of switching an existing app to
+// cliutil:
+//
+//	_, err := urfavecliutil.FromApp(app)
+//	if err != nil {
+//		return err
+//	}
+//	err = cliutil.Initialize(writer)
+//
+// Each converted command keeps its Before, Action, and After funcs, which
+// receive a *cli.Context holding the flags and args parsed by cliutil.
+package urfavecliutil
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrConversionFailed = errors.New("urfave/cli command conversion failed")
+
+var _ cliutil.CommandTyper = (*Cmd)(nil)
+var _ cliutil.CommandHandler = (*handlerCmd)(nil)
+
+// Cmd is a cliutil.Command converted from a cli.Command. Commands that have
+// an Action also implement cliutil.CommandHandler.
+type Cmd struct {
+	*cliutil.CmdBase
+	app       *cli.App
+	command   *cli.Command
+	ancestors []*cli.Command // Parent commands, outermost first
+	cmdType   reflect.Type
+	flagSet   *cliutil.FlagSet
+	aliases   map[string]string // Flag alias to the flag's first name
+	args      []string
+}
+
+// handlerCmd is a Cmd whose cli.Command has an Action
+type handlerCmd struct {
+	*Cmd
+}
+
+// FromApp registers app's commands, recursively, as cliutil commands and
+// returns them. The app's own Action is not converted because cliutil owns
+// the top level of the CLI, but the app's flags are added to every converted
+// command and its Before and After funcs run around every Action. Command
+// aliases are registered as hidden commands.
+func FromApp(app *cli.App) (cmds []cliutil.Command, err error) {
+	var errs []error
+
+	for _, command := range app.Commands {
+		cmds, err = convert(app, command, nil, nil, cmds)
+		errs = cliutil.AppendErr(errs, err)
+	}
+	err = cliutil.CombineErrs(errs)
+	if err != nil {
+		err = cliutil.WithErr(err, ErrConversionFailed, "app", app.Name)
+	}
+	return cmds, err
+}
+
+// convert registers command and its subcommands as children of parent,
+// which is nil for top-level commands, appending them to cmds
+func convert(app *cli.App, command *cli.Command, ancestors []*cli.Command, parent cliutil.Command, cmds []cliutil.Command) (_ []cliutil.Command, err error) {
+	var errs []error
+	var cmd cliutil.Command
+	var parents []cliutil.Command
+
+	if command.Name == "help" {
+		goto end
+	}
+	if parent != nil {
+		parents = []cliutil.Command{parent}
+	}
+
+	for i, name := range command.Names() {
+		cmd = newCmd(app, command, ancestors, name, i > 0)
+		err = cliutil.RegisterCommand(cmd, parents...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cmds = append(cmds, cmd)
+		if i > 0 {
+			// Aliases run the command but don't repeat its subcommands
+			continue
+		}
+		for _, sub := range command.Subcommands {
+			cmds, err = convert(app, sub, append(ancestors[:len(ancestors):len(ancestors)], command), cmd, cmds)
+			errs = cliutil.AppendErr(errs, err)
+		}
+	}
+end:
+	return cmds, cliutil.CombineErrs(errs)
+}
+
+// newCmd converts command to a cliutil.Command named name
+func newCmd(app *cli.App, command *cli.Command, ancestors []*cli.Command, name string, alias bool) (cmd cliutil.Command) {
+	c := &Cmd{
+		app:       app,
+		command:   command,
+		ancestors: ancestors,
+		cmdType:   cliutil.NewCommandType(),
+		flagSet:   &cliutil.FlagSet{Name: name},
+		aliases:   make(map[string]string),
+	}
+	for _, f := range c.flags() {
+		c.flagSet.FlagDefs = appendFlagDefs(c.flagSet.FlagDefs, f, c.aliases)
+	}
+
+	usage := name
+	if command.ArgsUsage != "" {
+		usage += " " + command.ArgsUsage
+	}
+	description := command.Usage
+	if description == "" {
+		description = command.Description
+	}
+	c.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        name,
+		Usage:       usage,
+		Description: description,
+		FlagSets:    []*cliutil.FlagSet{c.flagSet},
+		NoExamples:  alias,
+		Hide:        alias || command.Hidden,
+	})
+
+	cmd = c
+	if command.Action != nil {
+		cmd = &handlerCmd{Cmd: c}
+	}
+	return cmd
+}
+
+// CommandType identifies c in the cliutil registry, since every converted
+// command shares the Go type *Cmd
+func (c *Cmd) CommandType() reflect.Type {
+	return c.cmdType
+}
+
+// Command returns the cli.Command c was converted from
+func (c *Cmd) Command() *cli.Command {
+	return c.command
+}
+
+// AssignArgs records the positional args to pass to the Action
+func (c *Cmd) AssignArgs(args []string) error {
+	c.args = args
+	return c.CmdBase.AssignArgs(args)
+}
+
+// flags returns the app's flags followed by those of c's ancestors and c,
+// as urfave/cli makes them all visible to an Action
+func (c *Cmd) flags() (flags []cli.Flag) {
+	flags = append(flags, c.app.Flags...)
+	for _, ancestor := range c.ancestors {
+		flags = append(flags, ancestor.Flags...)
+	}
+	return append(flags, c.command.Flags...)
+}
+
+// Handle builds a *cli.Context from the flags and args parsed by cliutil,
+// then runs the Before funcs, the Action, and the After funcs in the order
+// urfave/cli does. After funcs run even when the Action fails.
+func (c *handlerCmd) Handle() (err error) {
+	var ctx *cli.Context
+	var afterErr error
+	var befores []cli.BeforeFunc
+	var afters []cli.AfterFunc
+
+	ctx, err = c.newContext()
+	if err != nil {
+		err = cliutil.WithErrClass(err, cliutil.UsageErr)
+		goto end
+	}
+
+	befores = append(befores, c.app.Before)
+	afters = append(afters, c.app.After)
+	for _, command := range append(c.ancestors, c.command) {
+		befores = append(befores, command.Before)
+		afters = append([]cli.AfterFunc{command.After}, afters...)
+	}
+
+	for _, before := range befores {
+		if before == nil {
+			continue
+		}
+		err = before(ctx)
+		if err != nil {
+			goto end
+		}
+	}
+	defer func() {
+		for _, after := range afters {
+			if after == nil {
+				continue
+			}
+			afterErr = after(ctx)
+			if afterErr != nil {
+				err = cliutil.CombineErrs([]error{err, afterErr})
+			}
+		}
+	}()
+	err = c.command.Action(ctx)
+end:
+	return err
+}
+
+// newContext returns a cli.Context whose flag set holds c's flags, set to
+// the values given on the command line, and c's positional args
+func (c *Cmd) newContext() (ctx *cli.Context, err error) {
+	var errs []error
+	var set *flag.FlagSet
+
+	if c.Writer != nil {
+		c.app.Writer = c.Writer.Writer()
+		c.app.ErrWriter = c.Writer.ErrWriter()
+	}
+
+	set = flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	for _, f := range c.flags() {
+		errs = cliutil.AppendErr(errs, f.Apply(set))
+	}
+	if c.flagSet.FlagSet != nil {
+		c.flagSet.FlagSet.Visit(func(f *flag.Flag) {
+			errs = cliutil.AppendErr(errs, set.Set(c.flagName(f.Name), f.Value.String()))
+		})
+	}
+	// "--" keeps positional args that start with a dash from parsing as flags
+	errs = cliutil.AppendErr(errs, set.Parse(append([]string{"--"}, c.args...)))
+	err = cliutil.CombineErrs(errs)
+	if err != nil {
+		goto end
+	}
+
+	ctx = cli.NewContext(c.app, set, nil)
+	ctx.Command = c.command
+	ctx.Context = c.Context
+	if ctx.Context == nil {
+		ctx.Context = context.Background()
+	}
+end:
+	return ctx, err
+}
+
+// flagName returns the first name of the flag whose name or alias is name.
+// urfave/cli gives each alias its own variable and reconciles them while
+// parsing, so values must be set using the first name.
+func (c *Cmd) flagName(name string) string {
+	first, ok := c.aliases[name]
+	if ok {
+		return first
+	}
+	return name
+}
+
+// appendFlagDefs appends FlagDefs for f unless fds already defines it,
+// recording each of f's aliases in aliases. The first one-letter alias
+// becomes the Shortcut; longer aliases get their own FlagDef. Bool, int, and int64 flags keep their type; all other flag types
+// are parsed by urfave/cli from the string given on the command line.
+func appendFlagDefs(fds []cliutil.FlagDef, f cli.Flag, aliases map[string]string) []cliutil.FlagDef {
+	var fd cliutil.FlagDef
+	var names []string
+
+	names = f.Names()
+	if len(names) == 0 || names[0] == "help" {
+		goto end
+	}
+	for _, existing := range fds {
+		if existing.Name == names[0] {
+			goto end
+		}
+	}
+
+	fd = flagDef(f)
+	for _, alias := range names[1:] {
+		aliases[alias] = fd.Name
+		if len(alias) == 1 && fd.Shortcut == 0 {
+			fd.Shortcut = alias[0]
+		}
+	}
+	fds = append(fds, fd)
+	for _, alias := range names[1:] {
+		if len(alias) == 1 {
+			continue
+		}
+		aliasDef := flagDef(f)
+		aliasDef.Name = alias
+		aliasDef.Usage = "Alias for --" + fd.Name
+		aliasDef.Required = false
+		fds = append(fds, aliasDef)
+	}
+end:
+	return fds
+}
+
+// flagDef converts f to a cliutil.FlagDef named for its first name
+func flagDef(f cli.Flag) (fd cliutil.FlagDef) {
+	fd.Name = f.Names()[0]
+
+	doc, ok := f.(cli.DocGenerationFlag)
+	if ok {
+		fd.Usage = doc.GetUsage()
+	}
+	required, ok := f.(cli.RequiredFlag)
+	if ok {
+		fd.Required = required.IsRequired()
+	}
+
+	switch t := f.(type) {
+	case *cli.BoolFlag:
+		fd.Bool, fd.Default = new(bool), t.Value
+	case *cli.IntFlag:
+		fd.Int, fd.Default = new(int), t.Value
+	case *cli.Int64Flag:
+		fd.Int64, fd.Default = new(int64), t.Value
+	case *cli.Float64Flag:
+		fd.Float64, fd.Default = new(float64), t.Value
+	case *cli.DurationFlag:
+		fd.Duration, fd.Default = new(time.Duration), t.Value
+	case *cli.StringSliceFlag:
+		fd.Strings = new([]string)
+		if t.Value != nil && len(t.Value.Value()) > 0 {
+			fd.Default = t.Value.Value()
+		}
+	case *cli.IntSliceFlag:
+		fd.Ints = new([]int)
+		if t.Value != nil && len(t.Value.Value()) > 0 {
+			fd.Default = t.Value.Value()
+		}
+	case *cli.StringFlag:
+		fd.String, fd.Default = new(string), t.Value
+	case *cli.PathFlag:
+		fd.String, fd.Default = new(string), t.Value
+	default:
+		fd.String = new(string)
+		if doc != nil {
+			fd.Default = strings.Trim(doc.GetDefaultText(), `"`)
+		}
+	}
+	return fd
+}
@@ -2,19 +2,26 @@ package cliutil
 
 import (
 	"fmt"
+	"iter"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/mikeschinkel/go-dt"
 	"github.com/mikeschinkel/go-dt/appinfo"
 )
 
 type TopCmdRow struct {
-	Display string // e.g. "serve [sub]" padded in template
-	Desc    string
-	Order   int // Display order (0=last, 1+=ordered)
+	Display  string // e.g. "serve [sub]" padded in template
+	Desc     string
+	Order    int    // Display order (0=last, 1+=ordered)
+	Category string // See CmdArgs.Category
+	Heading  string // Heading to show before this row, when it starts a group of commands
 }
 
+// CommandsHeading heads the commands without a Category in the main help
+const CommandsHeading = "COMMANDS"
+
 type Usage struct {
 	appinfo.AppInfo
 	CLIWriter   Writer
@@ -22,100 +29,198 @@ type Usage struct {
 	GlobalFlags []FlagRow
 	Examples    []Example
 }
+
+// UsageStream is the data for the main help template, like Usage, except its
+// rows are produced one at a time as the template ranges over them, so help
+// is written as it is rendered and help for thousands of commands never
+// holds all of their rows at once. A range or if in the template works the
+// same on both; len and index do not, so ShowMainHelp renders templates that
+// use them from a Usage.
+type UsageStream struct {
+	appinfo.AppInfo
+	CLIWriter   Writer
+	TopCmdRows  iter.Seq[TopCmdRow]
+	GlobalFlags iter.Seq[FlagRow] // nil when there are no global flags
+	Examples    iter.Seq[Example]
+}
+
 type UsageArgs struct {
 	appinfo.AppInfo
 	Writer Writer
+	CLI    *CLI // OPTIONAL: whose help is shown; defaults to the default CLI
+}
+
+// cli returns the CLI whose help is shown
+func (args UsageArgs) cli() *CLI {
+	return cliOrDefault(args.CLI)
 }
 
 // BuildUsage Build the data for the template (auto + optional custom examples)
 func BuildUsage(args UsageArgs) Usage {
-	var rows []TopCmdRow
-	var cmd Command
-	var sub []Command
-	var display string
 	var globalFlags []FlagRow
-	var globalFS *FlagSet
-	var fd FlagDef
-	var shortcut string
 
-	// COMMANDS rows
-	for _, cmd = range GetTopLevelCmds() {
-		// Skip hidden commands
-		if cmd.IsHidden() {
-			continue
-		}
+	stream := StreamUsage(args)
+	if stream.GlobalFlags != nil {
+		globalFlags = slices.Collect(stream.GlobalFlags)
+	}
+	return Usage{
+		AppInfo:     stream.AppInfo,
+		CLIWriter:   stream.CLIWriter,
+		TopCmdRows:  slices.Collect(stream.TopCmdRows),
+		GlobalFlags: globalFlags,
+		Examples:    collectExamples(args.cli(), args.ExeName()),
+	}
+}
 
-		sub = GetSubCmds(cmd.Name())
-		display = cmd.Name()
-		if len(sub) > 0 {
-			display += " [" + sub[0].Name() + "]"
-		}
-		rows = append(rows, TopCmdRow{
-			Display: display,
-			Desc:    cmd.Description(),
-			Order:   cmd.Order(),
-		})
+// StreamUsage returns the data for the main help template without building
+// its rows; ShowMainHelp renders UsageTemplateText from it.
+func StreamUsage(args UsageArgs) UsageStream {
+	cli := args.cli()
+	stream := UsageStream{
+		AppInfo: appinfo.New(appinfo.Args{
+			Name:        args.Name(),
+			Description: args.Description(),
+			Version:     args.Version(),
+			ExeName:     args.ExeName(),
+			InfoURL:     args.InfoURL(),
+		}),
+		CLIWriter:  args.Writer,
+		TopCmdRows: topCmdRows(cli),
+		Examples: func(yield func(Example) bool) {
+			for _, ex := range cachedExamples(cli, args.ExeName()) {
+				if !yield(ex) {
+					return
+				}
+			}
+		},
+	}
+	globalFS := cli.GlobalFlagSet()
+	if globalFS != nil && len(globalFS.FlagDefs) > 0 {
+		stream.GlobalFlags = globalFlagRows(globalFS)
 	}
-	// Sort by Order first (1-N), then by name alphabetically within each order
-	// Commands with Order=0 (unspecified) appear last
-	slices.SortFunc(rows, func(a, b TopCmdRow) int {
-		// If orders are different
-		if a.Order != b.Order {
-			// Order 0 (unspecified) should come last
-			if a.Order == 0 {
-				return 1 // a comes after b
+	return stream
+}
+
+// topCmdRows yields cli's COMMANDS rows grouped by Category, with commands
+// without one first and then each category in the order its first command
+// appears; within a group, rows are in Order then name order
+func topCmdRows(cli *CLI) iter.Seq[TopCmdRow] {
+	return func(yield func(TopCmdRow) bool) {
+		var sub []Command
+		var display string
+		var heading string
+
+		cmds := cli.GetTopLevelCmds()
+		categories := []string{""}
+		for _, cmd := range cmds {
+			if !cmd.IsHidden() && !slices.Contains(categories, cmdCategory(cmd)) {
+				categories = append(categories, cmdCategory(cmd))
+			}
+		}
+		for _, category := range categories {
+			heading = CommandsHeading
+			if category != "" {
+				heading = strings.ToUpper(category)
 			}
-			if b.Order == 0 {
-				return -1 // a comes before b
+			for _, cmd := range cmds {
+				// Skip hidden commands
+				if cmd.IsHidden() || cmdCategory(cmd) != category {
+					continue
+				}
+
+				sub = cli.GetSubCmds(cmd.Name())
+				display = cmd.Name()
+				if len(sub) > 0 {
+					display += " [" + sub[0].Name() + "]"
+				}
+				if !yield(TopCmdRow{
+					Display:  display,
+					Desc:     cmd.Description(),
+					Order:    cmd.Order(),
+					Category: category,
+					Heading:  heading,
+				}) {
+					return
+				}
+				heading = ""
 			}
-			// Both have explicit orders, sort ascending
-			return a.Order - b.Order
 		}
-		// Same order, sort alphabetically by display name
-		return strings.Compare(a.Display, b.Display)
-	})
+	}
+}
+
+// cmdCategory returns the CmdArgs.Category of cmd, if it has one
+func cmdCategory(cmd Command) string {
+	cc, ok := cmd.(interface{ Category() string })
+	if !ok {
+		return ""
+	}
+	return cc.Category()
+}
+
+// globalFlagRows yields the GLOBAL FLAGS rows of fs
+func globalFlagRows(fs *FlagSet) iter.Seq[FlagRow] {
+	return func(yield func(FlagRow) bool) {
+		var shortcut string
 
-	// GLOBAL FLAGS rows
-	globalFS = GetGlobalFlagSet()
-	if globalFS != nil {
-		for _, fd = range globalFS.FlagDefs {
+		for _, fd := range fs.FlagDefs {
 			shortcut = ""
 			if fd.Shortcut != 0 {
 				shortcut = string(fd.Shortcut)
 			}
-
-			globalFlags = append(globalFlags, FlagRow{
-				Name:     fd.Name,
-				Shortcut: shortcut,
-				Descr:    fd.Usage,
-				Usage:    fd.Usage,
-				Default:  fmt.Sprintf("%v", fd.Default),
-				Required: fd.Required,
-			})
+			if !yield(FlagRow{
+				Name:       fd.Name,
+				Shortcut:   shortcut,
+				Descr:      fd.Usage,
+				Usage:      fd.Usage,
+				Default:    helpDefault(fd),
+				Required:   fd.Required,
+				Repeatable: fd.IsRepeatable(),
+				Negated:    negatedName(fd),
+				Deprecated: fd.deprecationNote(),
+			}) {
+				return
+			}
 		}
 	}
+}
 
-	// EXAMPLES rows
-	examples := collectExamples(args.ExeName())
+// --- Example generation ----
 
-	return Usage{
-		AppInfo: appinfo.New(appinfo.Args{
-			Name:        args.Name(),
-			Description: args.Description(),
-			Version:     args.Version(),
-			ExeName:     args.ExeName(),
-			InfoURL:     args.InfoURL(),
-		}),
-		CLIWriter:   args.Writer,
-		TopCmdRows:  rows,
-		GlobalFlags: globalFlags,
-		Examples:    examples,
-	}
+// examplesCache memoizes the main help's examples for one executable name
+// until the registry changes, since they are the same on every help render.
+// No two CLIs share a registryGen, so gen also identifies the CLI.
+var examplesCache struct {
+	sync.Mutex
+	exe      dt.Filename
+	gen      uint64
+	examples []Example
 }
 
-// --- Example generation ----
+// collectExamples returns a copy of the main help's examples for cli
+func collectExamples(cli *CLI, exe dt.Filename) []Example {
+	return slices.Clone(cachedExamples(cli, exe))
+}
+
+// cachedExamples returns the main help's examples for cli, building them
+// only when the executable name or the registry has changed since the last
+// call. The slice is shared, so callers must not modify it; a rebuild
+// replaces it rather than writing to it.
+func cachedExamples(cli *CLI, exe dt.Filename) []Example {
+	cli.registryMu.RLock()
+	gen := cli.registryGen
+	cli.registryMu.RUnlock()
+
+	examplesCache.Lock()
+	defer examplesCache.Unlock()
+	if examplesCache.examples == nil || examplesCache.exe != exe || examplesCache.gen != gen {
+		examplesCache.examples = buildExamples(cli, exe)
+		examplesCache.exe = exe
+		examplesCache.gen = gen
+	}
+	return examplesCache.examples
+}
 
-func collectExamples(exe dt.Filename) []Example {
+func buildExamples(cli *CLI, exe dt.Filename) []Example {
 	// Start with universal help patterns:
 	all := []Example{
 		{Descr: "Show help for a specific command", Cmd: fmt.Sprintf("%s help <command>", exe)},
@@ -126,7 +231,7 @@ func collectExamples(exe dt.Filename) []Example {
 	// If a command implements ExampleProvider, use its Examples()
 	// (and append autos depending on IncludeAutoExamples()).
 	// Otherwise, auto-generate for that command.
-	for _, cmd := range GetTopLevelCmds() {
+	for _, cmd := range cli.GetTopLevelCmds() {
 		// Skip hidden commands
 		if cmd.IsHidden() {
 			continue
@@ -164,7 +269,7 @@ func autoExamplesForCommand(exe dt.Filename, cmd Command) []Example {
 	})
 
 	// 2) If it has subcommands, show help for the first subcommand
-	sub := GetSubCmds(cmd.Name())
+	sub := cliOf(cmd).GetSubCmds(cmd.Name())
 	if len(sub) > 0 {
 		out = append(out, Example{
 			Descr: fmt.Sprintf("Help for %s %s", cmd.Name(), sub[0].Name()),
@@ -207,6 +312,17 @@ func sampleFlags(cmd Command) []string {
 	var parts []string
 	for _, fs := range cmd.FlagSets() {
 		for _, fd := range fs.FlagDefs {
+			// Examples should not teach flags that are on their way out
+			if fd.IsDeprecated() {
+				continue
+			}
+			// A repeatable flag without an example is given once per default
+			if values, ok := defaultValues(fd.Default); ok && fd.Example == "" {
+				for _, val := range values {
+					parts = append(parts, fmt.Sprintf("--%s=%s", fd.Name, quoteIfNeeded(val)))
+				}
+				continue
+			}
 			val := fd.Example
 			if val == "" && fd.Default != nil {
 				val = fmt.Sprintf("%v", fd.Default)
@@ -240,6 +356,52 @@ func sampleArgs(cmd Command) (parts []string) {
 	return
 }
 
+// negatedName returns the --no-<name> form of fd that help shows, which is
+// only for a BoolFlag whose default is true, since others are off anyway
+func negatedName(fd FlagDef) (name string) {
+	if fd.Type() == BoolFlag && fd.Default == true {
+		name = NegatedFlagPrefix + fd.Name
+	}
+	return name
+}
+
+// defaultText returns def as help shows it, with the values of a
+// repeatable flag's default joined by commas
+func defaultText(def any) string {
+	if def == nil {
+		return ""
+	}
+	if values, ok := defaultValues(def); ok {
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprintf("%v", def)
+}
+
+// helpDefault returns fd's default as help shows it. Help does not call
+// DefaultFunc, whose result may depend on where and when the command runs,
+// so it shows DefaultText instead.
+func helpDefault(fd FlagDef) (text string) {
+	switch {
+	case fd.DefaultFunc == nil:
+		text = defaultText(fd.Default)
+	case fd.DefaultText != "":
+		text = fd.DefaultText
+	default:
+		text = "computed"
+	}
+	return text
+}
+
+// defaultValues returns the values of def formatted for the command line
+// when def is the default of a repeatable flag
+func defaultValues(def any) (values []string, ok bool) {
+	elems, ok := repeatedValues(def)
+	for _, elem := range elems {
+		values = append(values, fmt.Sprint(elem))
+	}
+	return values, ok
+}
+
 func quoteIfNeeded(s string) string {
 	if strings.ContainsAny(s, " \t\"'") {
 		s = fmt.Sprintf("%q", s)
@@ -268,13 +430,16 @@ func dedupeExamples(in []Example) []Example {
 // --- Command-specific help ---
 
 type FlagRow struct {
-	Flag     string
-	Descr    string
-	Name     string
-	Shortcut string
-	Usage    string
-	Default  string
-	Required bool
+	Flag       string
+	Descr      string
+	Name       string
+	Shortcut   string
+	Usage      string
+	Default    string
+	Required   bool
+	Repeatable bool
+	Negated    string // The --no-<name> form, for a BoolFlag whose default is true
+	Deprecated string // What to use instead, for a deprecated flag
 }
 
 type SubCmdRow struct {
@@ -319,6 +484,9 @@ func BuildCmdUsage(cmd Command) CmdUsage {
 	// Collect arguments
 	for i, ad := range argDefs {
 		arg := fmt.Sprintf("<%s>", ad.Name)
+		if ad.IsVariadic() {
+			arg += "..."
+		}
 		if !ad.Required {
 			hasOptArgs = true
 			args.WriteString("[")
@@ -329,20 +497,17 @@ func BuildCmdUsage(cmd Command) CmdUsage {
 		}
 
 		descr := ad.Usage
-		def := fmt.Sprintf("%v", ad.Default)
+		def := defaultText(ad.Default)
 		if def != "" {
 			descr = fmt.Sprintf("%s (default=%s)", descr, def)
 		}
-		if ad.Required {
-			descr = fmt.Sprintf("%s [required]", descr)
-		}
 		argRow := ArgRow{
 			Arg:      arg,
 			Descr:    appendCompulsion(descr, ad.Required),
 			Name:     ad.Name,
 			Usage:    ad.Usage,
 			Required: ad.Required,
-			Default:  fmt.Sprintf("%v", ad.Default),
+			Default:  def,
 			Example:  ad.Example,
 		}
 		argRows = append(argRows, argRow)
@@ -360,29 +525,44 @@ func BuildCmdUsage(cmd Command) CmdUsage {
 			if fd.Shortcut != 0 {
 				flag = fmt.Sprintf("-%c, %s", fd.Shortcut, flag)
 			}
+			if fd.Type() == MapFlag {
+				flag += " key=value"
+			}
+			if negated := negatedName(fd); negated != "" {
+				flag += ", --" + negated
+			}
 			descr := fd.Usage
-			def := fmt.Sprintf("%v", fd.Default)
+			def := helpDefault(fd)
 			if def != "" {
 				descr = fmt.Sprintf("%s [default=%s]", descr, def)
 			}
+			if fd.IsRepeatable() {
+				descr += " [repeatable]"
+			}
+			if fd.IsDeprecated() {
+				descr += " [deprecated: " + fd.deprecationNote() + "]"
+			}
 			if fd.Required {
 				hasOptArgs = true
 			}
 			flagRows = append(flagRows, FlagRow{
-				Flag:     flag,
-				Descr:    appendCompulsion(descr, fd.Required),
-				Name:     fd.Name,
-				Shortcut: string(fd.Shortcut),
-				Usage:    fd.Usage,
-				Default:  fmt.Sprintf("%v", fd.Default),
-				Required: fd.Required,
+				Flag:       flag,
+				Descr:      appendCompulsion(descr, fd.Required),
+				Name:       fd.Name,
+				Shortcut:   string(fd.Shortcut),
+				Usage:      fd.Usage,
+				Default:    def,
+				Required:   fd.Required,
+				Repeatable: fd.IsRepeatable(),
+				Negated:    negatedName(fd),
+				Deprecated: fd.deprecationNote(),
 			})
 			maxSize = max(len(flag)+2, maxSize)
 		}
 	}
 
 	// Collect subcommands
-	for _, subCmd = range GetSubCmds(cmd.Name()) {
+	for _, subCmd = range cliOf(cmd).GetSubCmds(cmd.Name()) {
 		if subCmd.IsHidden() {
 			continue
 		}
@@ -0,0 +1,171 @@
+package cliutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+var ErrWarningsAsErrors = newMessageErr(MsgWarningsAsErrors)
+
+// Package-level warning collection
+var (
+	warnings   []string   // warnings recorded during the current run
+	warningsMu sync.Mutex // synchronizes access to warnings
+)
+
+// Warnf records a non-fatal warning and writes it to the error stream of the
+// global Writer, if one has been set. Recorded warnings are summarized at the
+// end of the next CmdRunner.RunCmd to complete, and fail it when --strict is
+// set.
+//
+// Deprecated: Use CmdRunnerArgs.Warnf in a command, or WarnfContext with the
+// handler's Context, so the warning is written to and summarized by the run
+// it belongs to, even when runs are concurrent.
+func Warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	warningsMu.Lock()
+	warnings = append(warnings, msg)
+	warningsMu.Unlock()
+
+	w := GetWriter()
+	if w != nil {
+		w.Errorf("%s: %s\n", Msg(MsgWarningPrefix), msg)
+	}
+}
+
+// Warnings returns a copy of the warnings recorded so far
+func Warnings() []string {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	return append([]string(nil), warnings...)
+}
+
+// ResetWarnings discards all recorded warnings
+func ResetWarnings() {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	warnings = nil
+	deprecationCount.Store(0)
+}
+
+// WarnfContext records a non-fatal warning for the run whose handler was
+// given ctx, writing it to the error stream of that run's Writer (see
+// CmdRunnerArgs.Warnf). With no run in ctx it is Warnf.
+func WarnfContext(ctx context.Context, format string, args ...any) {
+	rw, _ := ctx.Value(runWarningsKey{}).(*runWarnings)
+	rw.warnf(format, args...)
+}
+
+// DeprecatedfContext records a deprecation warning for the run whose handler
+// was given ctx, like WarnfContext (see CmdRunnerArgs.Deprecatedf). With no
+// run in ctx it is Deprecatedf.
+func DeprecatedfContext(ctx context.Context, format string, args ...any) {
+	rw, _ := ctx.Value(runWarningsKey{}).(*runWarnings)
+	rw.deprecatedf(format, args...)
+}
+
+// runWarningsKey is the Context key of the handler's runWarnings
+type runWarningsKey struct{}
+
+// runWarnings are the warnings recorded during one CmdRunner.RunCmd
+type runWarnings struct {
+	mu           sync.Mutex
+	writer       Writer
+	msgs         []string
+	deprecations int64 // How many of msgs are deprecations
+}
+
+func newRunWarnings(w Writer) *runWarnings {
+	return &runWarnings{writer: w}
+}
+
+// warnf records a warning and writes it to the run's Writer; on a nil
+// runWarnings, as outside of RunCmd, it is Warnf
+func (rw *runWarnings) warnf(format string, args ...any) {
+	if rw == nil {
+		Warnf(format, args...)
+		return
+	}
+	rw.record(false, fmt.Sprintf(format, args...))
+}
+
+// deprecatedf records a deprecation warning like warnf; on a nil runWarnings
+// it is Deprecatedf
+func (rw *runWarnings) deprecatedf(format string, args ...any) {
+	if rw == nil {
+		Deprecatedf(format, args...)
+		return
+	}
+	rw.record(true, fmt.Sprintf(format, args...))
+}
+
+// record adds msg to the run's warnings and writes it to the run's Writer
+func (rw *runWarnings) record(deprecation bool, msg string) {
+	rw.mu.Lock()
+	rw.msgs = append(rw.msgs, msg)
+	if deprecation {
+		rw.deprecations++
+	}
+	rw.mu.Unlock()
+
+	if rw.writer != nil {
+		rw.writer.Errorf("%s: %s\n", Msg(MsgWarningPrefix), msg)
+	}
+}
+
+// take returns how many warnings, and of them deprecations, were recorded
+// and forgets them
+func (rw *runWarnings) take() (n int, deprecations int64) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	n, deprecations = len(rw.msgs), rw.deprecations
+	rw.msgs, rw.deprecations = nil, 0
+	return n, deprecations
+}
+
+// ReportWarnings writes a "completed with N warning(s)" summary to w when any
+// warnings were recorded, then resets them. When warnings were recorded
+// under --strict (see IsStrict) it returns ErrDeprecatedUsage if any were
+// deprecations (see Deprecatedf), else ErrWarningsAsErrors.
+func ReportWarnings(w Writer) (err error) {
+	return defaultCLI.ReportWarnings(w)
+}
+
+// ReportWarnings is ReportWarnings under cli's --strict (see CLI.IsStrict)
+func (cli *CLI) ReportWarnings(w Writer) (err error) {
+	return cli.reportRunWarnings(w, nil)
+}
+
+// reportRunWarnings is ReportWarnings for the warnings of rw, if not nil,
+// together with those recorded by Warnf
+func (cli *CLI) reportRunWarnings(w Writer, rw *runWarnings) (err error) {
+	var n int
+	var deprecations int64
+
+	warningsMu.Lock()
+	n = len(warnings)
+	warnings = nil
+	deprecations = deprecationCount.Swap(0)
+	warningsMu.Unlock()
+	if rw != nil {
+		runN, runDeprecations := rw.take()
+		n += runN
+		deprecations += runDeprecations
+	}
+
+	if n == 0 {
+		goto end
+	}
+	if n == 1 {
+		w.Errorf("%s\n", Msg(MsgCompletedWarning))
+	} else {
+		w.Errorf("%s\n", Msg(MsgCompletedWarnings, n))
+	}
+	if !cli.IsStrict() {
+		goto end
+	}
+	err = strictErr(n, deprecations)
+end:
+	return err
+}
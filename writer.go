@@ -6,7 +6,6 @@ import (
 	"io"
 	"os"
 	"strings"
-	"sync"
 )
 
 // Writer defines the interface for user-facing writer
@@ -47,8 +46,9 @@ func (w *cliWriter) V2() Writer {
 		goto end
 	}
 	w.v2 = &cliWriter{
-		writer:    os.Stdout,
-		errWriter: os.Stderr,
+		writer:    w.writer,
+		errWriter: w.errWriter,
+		quiet:     w.quiet,
 		verbosity: w.verbosity,
 		useLevel:  2,
 	}
@@ -61,8 +61,9 @@ func (w *cliWriter) V3() Writer {
 		goto end
 	}
 	w.v3 = &cliWriter{
-		writer:    os.Stdout,
-		errWriter: os.Stderr,
+		writer:    w.writer,
+		errWriter: w.errWriter,
+		quiet:     w.quiet,
 		verbosity: w.verbosity,
 		useLevel:  3,
 	}
@@ -75,9 +76,10 @@ func (w *cliWriter) Loud() Writer {
 		goto end
 	}
 	w.loud = &cliWriter{
-		writer:    os.Stdout,
-		errWriter: os.Stderr,
+		writer:    w.writer,
+		errWriter: w.errWriter,
 		quiet:     false,
+		verbosity: w.verbosity,
 	}
 end:
 	return w.loud
@@ -86,6 +88,8 @@ end:
 type WriterArgs struct {
 	Quiet     bool
 	Verbosity Verbosity
+	Stdout    io.Writer // OPTIONAL: defaults to os.Stdout
+	Stderr    io.Writer // OPTIONAL: defaults to os.Stderr
 }
 
 // NewWriter creates a console writer writer
@@ -100,12 +104,19 @@ func NewWriter(args *WriterArgs) Writer {
 	if args.Verbosity < 1 || 3 < args.Verbosity {
 		panic(fmt.Sprintf("Invalid verbosity for cliutil.Writer.SetVerbosity(); must be between 1-3; got %d", args.Verbosity))
 	}
-	return &cliWriter{
-		writer:    os.Stdout,
-		errWriter: os.Stderr,
+	w := &cliWriter{
+		writer:    args.Stdout,
+		errWriter: args.Stderr,
 		quiet:     args.Quiet,
 		verbosity: args.Verbosity,
 	}
+	if w.writer == nil {
+		w.writer = os.Stdout
+	}
+	if w.errWriter == nil {
+		w.errWriter = os.Stderr
+	}
+	return w
 }
 
 // Printf writes formatted writer to stdout
@@ -134,60 +145,64 @@ func (w *cliWriter) Errorf(format string, args ...any) {
 	_, _ = fmt.Fprintf(w.errWriter, format, args...)
 }
 
-// Package-level output variables and synchronization
-var (
-	writer  Writer       // writer is the global output writer instance used for CLI operations
-	printMu sync.RWMutex // synchronizes Printf access
-	errorMu sync.RWMutex // synchronizes Errorf access
-)
-
-// SetWriter sets the global writer writer (primarily for testing)
+// SetWriter sets the default CLI's writer (see CLI.SetWriter)
 func SetWriter(w Writer) {
-	printMu.Lock()
-	defer printMu.Unlock()
-	writer = w
-	ensureWriter()
+	defaultCLI.SetWriter(w)
 }
 
-// GetWriter returns the current writer writer
+// SetWriter sets the CLI's writer (primarily for testing)
+func (cli *CLI) SetWriter(w Writer) {
+	cli.writerMu.Lock()
+	defer cli.writerMu.Unlock()
+	cli.writer = w
+	cli.ensureWriter()
+}
+
+// GetWriter returns the default CLI's writer (see CLI.Writer)
 //
 //goland:noinspection GoUnusedExportedFunction
 func GetWriter() Writer {
-	printMu.RLock()
-	defer printMu.RUnlock()
-	return writer
+	return defaultCLI.Writer()
+}
+
+// Writer returns the CLI's current writer
+func (cli *CLI) Writer() Writer {
+	cli.writerMu.RLock()
+	defer cli.writerMu.RUnlock()
+	return cli.writer
 }
 
-// Package-level convenience functions
+// Package-level convenience functions, which write to the default CLI's
+// writer
 
 // Loud returns a Writer that ignores Quiet setting
 //
 //goland:noinspection GoUnusedExportedFunction
 func Loud() Writer {
-	return writer.Loud()
+	return GetWriter().Loud()
 }
 
 // Printf writes formatted writer
 //
 //goland:noinspection GoUnusedExportedFunction
 func Printf(format string, args ...any) {
-	printMu.RLock()
-	defer printMu.RUnlock()
-	writer.Printf(format, args...)
+	defaultCLI.writerMu.RLock()
+	defer defaultCLI.writerMu.RUnlock()
+	defaultCLI.writer.Printf(format, args...)
 }
 
 // Errorf writes to formatted error writer
 //
 //goland:noinspection GoUnusedExportedFunction
 func Errorf(format string, args ...any) {
-	errorMu.RLock()
-	defer errorMu.RUnlock()
-	writer.Errorf(format, args...)
+	defaultCLI.writerMu.RLock()
+	defer defaultCLI.writerMu.RUnlock()
+	defaultCLI.writer.Errorf(format, args...)
 }
 
 // ensureWriter panics if no Writer has been set, preventing uninitialized usage
-func ensureWriter() {
-	if writer == nil {
+func (cli *CLI) ensureWriter() {
+	if cli.writer == nil {
 		panic("Must set Writer with cliutil.SetWriter() before using cliutil package")
 	}
 }
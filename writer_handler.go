@@ -0,0 +1,119 @@
+package cliutil
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+var _ slog.Handler = (*WriterHandler)(nil)
+
+// WriterHandlerOptions configures a WriterHandler
+type WriterHandlerOptions struct {
+	// Level is the minimum level handled; defaults to slog.LevelDebug so the
+	// Writer's verbosity alone decides what is shown
+	Level slog.Leveler
+	// IgnoreVerbosity writes Debug and Info records that pass Level whatever
+	// the Writer's verbosity, for when Level was chosen explicitly, such as
+	// with --log-level
+	IgnoreVerbosity bool
+	// CLI is the CLI whose --no-color decides whether the "Error" prefix is
+	// colored; defaults to the default CLI
+	CLI *CLI
+}
+
+// WriterHandler is a slog.Handler that writes records through a Writer so
+// application logging follows the same verbosity and quiet policy as CLI
+// output: Debug goes to V3(), Info to V2() (or both to Printf() with
+// IgnoreVerbosity), and Warn and Error to Errorf() with a "Warning:" or
+// "Error:" prefix. Records are written as the message
+// followed by key=value pairs, without a timestamp.
+type WriterHandler struct {
+	writer          Writer
+	level           slog.Leveler
+	ignoreVerbosity bool
+	cli             *CLI
+	attrs           string // pre-formatted attributes from WithAttrs
+	group           string // dotted key prefix from WithGroup
+}
+
+// NewWriterHandler returns a WriterHandler writing to w; opts may be nil
+func NewWriterHandler(w Writer, opts *WriterHandlerOptions) *WriterHandler {
+	h := &WriterHandler{writer: w, level: slog.LevelDebug, cli: defaultCLI}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	if opts != nil {
+		h.ignoreVerbosity = opts.IgnoreVerbosity
+		h.cli = cliOrDefault(opts.CLI)
+	}
+	return h
+}
+
+func (h *WriterHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *WriterHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(r.Message)
+	sb.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&sb, h.group, a)
+		return true
+	})
+	line := sb.String()
+
+	switch {
+	case r.Level >= slog.LevelError:
+		h.writer.Errorf("%s: %s\n", h.cli.errorPrefix(h.writer), line)
+	case r.Level >= slog.LevelWarn:
+		h.writer.Errorf("%s: %s\n", Msg(MsgWarningPrefix), line)
+	case h.ignoreVerbosity:
+		h.writer.Printf("%s\n", line)
+	case r.Level >= slog.LevelInfo:
+		h.writer.V2().Printf("%s\n", line)
+	default:
+		h.writer.V3().Printf("%s\n", line)
+	}
+	return nil
+}
+
+func (h *WriterHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var sb strings.Builder
+	clone := *h
+	sb.WriteString(h.attrs)
+	for _, a := range attrs {
+		writeAttr(&sb, h.group, a)
+	}
+	clone.attrs = sb.String()
+	return &clone
+}
+
+func (h *WriterHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// writeAttr appends " key=value" for a, flattening groups into dotted keys
+func writeAttr(sb *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix = prefix + a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeAttr(sb, prefix, ga)
+		}
+		return
+	}
+	sb.WriteString(fmt.Sprintf(" %s%s=%s", prefix, a.Key, quoteIfNeeded(a.Value.String())))
+}
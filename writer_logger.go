@@ -1,17 +1,21 @@
 package cliutil
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 )
 
 type WriterLogger struct {
 	Writer
 	*slog.Logger
-	v2 *WriterLogger
-	v3 *WriterLogger
+	v2       *WriterLogger
+	v3       *WriterLogger
+	json     bool
+	throttle *LogThrottle
 }
 
 func NewWriterLogger(writer Writer, logger *slog.Logger) WriterLogger {
@@ -29,8 +33,10 @@ func (wl WriterLogger) Printf(format string, args ...any) {
 func (wl WriterLogger) V2() WriterLogger {
 	if wl.v2 == nil {
 		wl.v2 = &WriterLogger{
-			Writer: wl.Writer.V2(),
-			Logger: wl.Logger,
+			Writer:   wl.Writer.V2(),
+			Logger:   wl.Logger,
+			json:     wl.json,
+			throttle: wl.throttle,
 		}
 	}
 	return *wl.v2
@@ -39,18 +45,134 @@ func (wl WriterLogger) V2() WriterLogger {
 func (wl WriterLogger) V3() WriterLogger {
 	if wl.v3 == nil {
 		wl.v3 = &WriterLogger{
-			Writer: wl.Writer.V3(),
-			Logger: wl.Logger,
+			Writer:   wl.Writer.V3(),
+			Logger:   wl.Logger,
+			json:     wl.json,
+			throttle: wl.throttle,
 		}
 	}
 	return *wl.v3
 }
 
+// WithJSONOutput returns a copy of wl that, when enabled, writes the console
+// side of InfoPrint, InfoLoud, WarnError, and ErrorError as single-line JSON
+// records ({"level":..., "msg":..., key: value...}) to the error stream
+// instead of human-readable text. Records are written regardless of quiet or
+// verbosity; consumers filter on "level". Enable it when output is
+// machine-readable:
+//
+//	wl = wl.WithJSONOutput(opts.OutputFormat().IsMachineReadable())
+func (wl WriterLogger) WithJSONOutput(enabled bool) WriterLogger {
+	wl.json = enabled
+	wl.v2 = nil
+	wl.v3 = nil
+	return wl
+}
+
+// WithThrottle returns a copy of wl whose InfoPrint, InfoLoud, WarnError, and
+// ErrorError calls are rate-limited by lt, keyed by level and message (not
+// args), which keeps retry and polling loops from flooding output. When a
+// throttled message is next allowed, a "suppressed N similar messages"
+// summary is written first; call FlushThrottle when the loop ends to report
+// any remaining suppressions. ErrorError still returns its error when
+// throttled. Pass nil to disable throttling.
+func (wl WriterLogger) WithThrottle(lt *LogThrottle) WriterLogger {
+	wl.throttle = lt
+	wl.v2 = nil
+	wl.v3 = nil
+	return wl
+}
+
+// FlushThrottle writes a summary for every message suppressed by the
+// throttle and not yet reported
+func (wl WriterLogger) FlushThrottle() {
+	var level slog.Level
+	if wl.throttle == nil {
+		return
+	}
+	for _, s := range wl.throttle.Flush() {
+		levelText, msg, _ := strings.Cut(s.Key, throttleKeySep)
+		if level.UnmarshalText([]byte(levelText)) != nil {
+			level = slog.LevelInfo
+		}
+		wl.writeSummary(level, s.Suppressed, msg)
+	}
+}
+
+// throttleKeySep separates the level from the message in throttle keys
+const throttleKeySep = "\x00"
+
+// suppressed reports whether a message should be dropped by the throttle,
+// first writing a summary of the previous window's suppressions if any
+func (wl WriterLogger) suppressed(level slog.Level, msg string) bool {
+	if wl.throttle == nil {
+		return false
+	}
+	ok, n := wl.throttle.Allow(level.String() + throttleKeySep + msg)
+	if n > 0 {
+		wl.writeSummary(level, n, msg)
+	}
+	return !ok
+}
+
+// writeSummary logs and prints a "suppressed N similar messages" line at level
+func (wl WriterLogger) writeSummary(level slog.Level, n int, msg string) {
+	summary := Msg(MsgLogSuppressed, n, msg)
+	if n == 1 {
+		summary = Msg(MsgLogSuppressedOne, msg)
+	}
+	wl.Logger.Log(context.Background(), level, summary)
+	switch {
+	case wl.json:
+		wl.writeJSON(level, summary)
+	case level >= slog.LevelWarn:
+		wl.Errorf("%s\n", summary)
+	default:
+		wl.Writer.Printf("%s\n", summary)
+	}
+}
+
+// IsJSONOutput returns true when console output is written as JSON records
+func (wl WriterLogger) IsJSONOutput() bool {
+	return wl.json
+}
+
+// writeJSON writes one JSON record for msg and its key/value args to the
+// error stream; a trailing unpaired arg (typically an error) is keyed "error"
+func (wl WriterLogger) writeJSON(level slog.Level, msg string, args ...any) {
+	if len(args)%2 == 1 {
+		args = append(args[:len(args)-1:len(args)-1], "error", args[len(args)-1])
+	}
+	h := slog.NewJSONHandler(wl.ErrWriter(), &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			if err, ok := a.Value.Any().(error); ok {
+				a.Value = slog.StringValue(err.Error())
+			}
+			return a
+		},
+	})
+	r := slog.NewRecord(time.Time{}, level, msg, 0)
+	r.Add(args...)
+	_ = h.Handle(context.Background(), r)
+}
+
 func (wl WriterLogger) ErrorError(msg string, args ...any) (err error) {
 	var ok bool
-	wl.Error(msg, args...)
+	emit := !wl.suppressed(slog.LevelError, msg)
+	if emit {
+		wl.Error(msg, args...)
+	}
+	if emit && wl.json {
+		wl.writeJSON(slog.LevelError, msg, args...)
+	}
 	msg = wl.concatMsgAndArgs("ErrorError", msg, args...)
-	wl.Errorf(msg + "\n")
+	if emit && !wl.json {
+		wl.Errorf(msg + "\n")
+	}
 	if len(args) == 0 {
 		err = errors.New(msg)
 		goto end
@@ -70,17 +192,38 @@ end:
 }
 
 func (wl WriterLogger) WarnError(msg string, args ...any) {
+	if wl.suppressed(slog.LevelWarn, msg) {
+		return
+	}
 	wl.Warn(msg, args...)
+	if wl.json {
+		wl.writeJSON(slog.LevelWarn, msg, args...)
+		return
+	}
 	wl.Errorf(wl.concatMsgAndArgs("WarnError", msg, args...) + "\n")
 }
 
 func (wl WriterLogger) InfoPrint(msg string, args ...any) {
+	if wl.suppressed(slog.LevelInfo, msg) {
+		return
+	}
 	wl.Logger.Info(msg, args...)
+	if wl.json {
+		wl.writeJSON(slog.LevelInfo, msg, args...)
+		return
+	}
 	wl.Writer.Printf(wl.concatMsgAndArgs("InfoPrint", msg, args...) + "\n")
 }
 
 func (wl WriterLogger) InfoLoud(msg string, args ...any) {
+	if wl.suppressed(slog.LevelInfo, msg) {
+		return
+	}
 	wl.Logger.Info(msg, args...)
+	if wl.json {
+		wl.writeJSON(slog.LevelInfo, msg, args...)
+		return
+	}
 	wl.Writer.Loud().Printf(wl.concatMsgAndArgs("InfoPrint", msg, args...) + "\n")
 }
 
@@ -0,0 +1,16 @@
+module github.com/mikeschinkel/go-cliutil/yamlcliutil
+
+go 1.25.3
+
+replace github.com/mikeschinkel/go-cliutil => ..
+
+require (
+	github.com/mikeschinkel/go-cliutil v0.3.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/mikeschinkel/go-dt v0.3.3 // indirect
+	github.com/mikeschinkel/go-dt/appinfo v0.2.1 // indirect
+	github.com/mikeschinkel/go-dt/dtx v0.2.1 // indirect
+)
@@ -0,0 +1,45 @@
+// Package yamlcliutil lets cliutil load YAML config files and write YAML
+// output for --output=yaml, which cliutil cannot do itself without depending
+// on a YAML package:
+//
+//	yamlcliutil.Register()
+//	err := cliutil.EnableConfig(cliutil.ConfigArgs{Files: []string{"myapp.yaml"}})
+package yamlcliutil
+
+import (
+	"io"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"gopkg.in/yaml.v3"
+)
+
+var _ cliutil.ConfigDecoder = Decode
+var _ cliutil.OutputEncoder = Encode
+
+// Register makes cliutil decode config files ending in .yaml or .yml with
+// Decode, and accept --output=yaml, encoding records with Encode
+func Register() {
+	cliutil.RegisterConfigFormat(".yaml", Decode)
+	cliutil.RegisterConfigFormat(".yml", Decode)
+	cliutil.RegisterOutputFormat(cliutil.YAMLOutput, Encode)
+}
+
+// Encode writes record to w as a YAML document starting with "---", so a
+// stream of records is a stream of documents
+func Encode(w io.Writer, record any) (err error) {
+	var data []byte
+
+	data, err = yaml.Marshal(record)
+	if err != nil {
+		goto end
+	}
+	_, err = io.WriteString(w, "---\n"+string(data))
+end:
+	return err
+}
+
+// Decode decodes a YAML mapping into the keys and values of a config file
+func Decode(data []byte) (values map[string]any, err error) {
+	err = yaml.Unmarshal(data, &values)
+	return values, err
+}